@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/yourname/15min-life-circle/internal/classify"
+	"github.com/yourname/15min-life-circle/internal/config"
+	"github.com/yourname/15min-life-circle/internal/database"
+	"github.com/yourname/15min-life-circle/internal/dem"
+	"github.com/yourname/15min-life-circle/internal/importer"
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/store/postgis"
+)
+
+func main() {
+	var opts importer.Options
+	flag.StringVar(&opts.File, "file", "", "OSM PBF 文件路径 (.osm.pbf)，默认取配置 cities.<city>.file")
+	flag.StringVar(&opts.City, "city", "", "城市代码，如 hangzhou")
+	flag.StringVar(&opts.CityName, "name", "", "城市显示名称，如 杭州（可选）")
+	flag.StringVar(&opts.Version, "version", time.Now().Format("20060102150405"), "导入批次版本")
+	flag.IntVar(&opts.Procs, "procs", 0, "PBF 解码并发数（默认 CPU 数）")
+	demFile := flag.String("dem", "", "GeoTIFF 高程文件（EPSG:4326），采样路网节点高程并计算坡度；不指定 -file 时只更新已导入城市的高程")
+	fromOSM2PGSQL := flag.Bool("osm2pgsql", false, "按分类规则从 osm2pgsql（--hstore-all）导入的表提取 POI，代替 -file；-city 可省略")
+	flag.Parse()
+
+	// 加载配置
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// 未指定的文件与名称取自配置中的城市数据（cities.<city>）
+	if city, ok := cfg.Cities[opts.City]; ok && opts.File == "" && *demFile == "" {
+		opts.File, *demFile = city.File, city.DEM
+	}
+	if city, ok := cfg.Cities[opts.City]; ok && opts.CityName == "" {
+		opts.CityName = city.Name
+	}
+
+	if !*fromOSM2PGSQL && (opts.City == "" || opts.File == "" && *demFile == "") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// 连接数据库
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if *fromOSM2PGSQL {
+		rules := loadRules(ctx, db, cfg)
+		n, err := importer.ExtractOSM2PGSQL(ctx, db, rules, opts.City)
+		if err != nil {
+			log.Fatalf("Extract failed: %v", err)
+		}
+		log.Printf("从 osm2pgsql 表提取 POI %d 个", n)
+	} else if opts.File != "" {
+		rules := loadRules(ctx, db, cfg)
+
+		log.Printf("导入 %s -> 城市 %s（版本 %s）", opts.File, opts.City, opts.Version)
+		start := time.Now()
+
+		stats, err := importer.New(db, rules, opts).Run(ctx)
+		if err != nil {
+			log.Fatalf("Import failed: %v", err)
+		}
+
+		log.Printf("导入完成：POI %d 个，路网节点 %d 个，边 %d 条，屏障 %d 个，耗时 %s",
+			stats.POIs, stats.Vertices, stats.Edges, stats.Barriers, time.Since(start).Round(time.Second))
+	}
+
+	if *demFile != "" {
+		d, err := dem.Open(*demFile)
+		if err != nil {
+			log.Fatalf("Failed to load DEM: %v", err)
+		}
+
+		stats, err := importer.ApplyDEM(ctx, db, d, opts.City)
+		if err != nil {
+			log.Fatalf("Elevation import failed: %v", err)
+		}
+		log.Printf("高程采样完成：节点 %d 个（缺失 %d 个），坡度 %d 条边",
+			stats.Sampled, stats.Missing, stats.Edges)
+	}
+}
+
+// loadRules 加载分类规则，并确认评价标准中的必备设施都能由规则产生，
+// 否则导入的数据在所有位置都缺少该设施
+func loadRules(ctx context.Context, db *database.DB, cfg *config.Config) *classify.RuleSet {
+	rules, err := classify.Load(ctx, db)
+	if err != nil {
+		log.Fatalf("Failed to load classification rules: %v", err)
+	}
+
+	// 与 EvaluationService.GetStandards 一致：表为空或不可用时使用内置标准
+	standards := model.GetDefaultStandards()
+	if cfg.Evaluation.Profile != config.ProfileBuiltin {
+		if s, err := postgis.NewStandardStore(db).Standards(ctx); err == nil && len(s) > 0 {
+			standards = s
+		}
+	}
+	if err := rules.CheckStandards(standards); err != nil {
+		log.Fatalf("Classification rules do not cover the evaluation standards: %v", err)
+	}
+	return rules
+}
@@ -0,0 +1,158 @@
+// precompute 预计算城市路网节点的可达性
+//
+// 对城市内每个路网节点、每个速度档计算 -minutes 分钟内的可达节点，写入 node_reach
+// （见 020_reach_cache.sql）；综合评价与默认参数的等时圈命中时跳过路网分析，
+// 多边形仍按点击位置生成。
+//
+// 已是当前路网版本的节点默认跳过，中断后重新运行即可继续；重新导入路网后旧结果自动失效。
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/yourname/15min-life-circle/internal/config"
+	"github.com/yourname/15min-life-circle/internal/database"
+	"github.com/yourname/15min-life-circle/internal/service"
+	"golang.org/x/sync/errgroup"
+)
+
+// node 待预计算的路网节点
+type node struct {
+	ID       int64
+	Lng, Lat float64
+}
+
+func main() {
+	city := flag.String("city", "", "城市代码，如 hangzhou")
+	speedList := flag.String("speeds", "5", "步行速度档（km/h），逗号分隔，按 0.1 km/h 取整")
+	minutes := flag.Int("minutes", 15, "预计算的最大步行时间（分钟）")
+	workers := flag.Int("workers", runtime.NumCPU(), "并发数（不超过数据库连接池大小）")
+	all := flag.Bool("all", false, "重新计算已是当前路网版本的节点")
+	flag.Parse()
+
+	if *city == "" || *minutes <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	speeds, err := parseSpeeds(*speedList)
+	if err != nil {
+		log.Fatalf("Invalid -speeds: %v", err)
+	}
+
+	// 加载配置
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// 连接数据库
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	iso := service.NewIsochroneService(db)
+
+	for _, speed := range speeds {
+		nodes, err := pendingNodes(ctx, db, *city, speed, *minutes, *all)
+		if err != nil {
+			log.Fatalf("Failed to list nodes: %v", err)
+		}
+		log.Printf("城市 %s，速度 %.1f km/h：待计算节点 %d 个", *city, speed, len(nodes))
+
+		start := time.Now()
+		var done, reached atomic.Int64
+		g, gctx := errgroup.WithContext(ctx)
+		g.SetLimit(max(*workers, 1))
+		for _, n := range nodes {
+			g.Go(func() error {
+				count, err := iso.PrecomputeReach(gctx, n.ID, speed, *minutes)
+				if err != nil {
+					return err
+				}
+				reached.Add(int64(count))
+				if d := done.Add(1); d%1000 == 0 {
+					log.Printf("  %d / %d（%.0f 个/秒）", d, len(nodes), float64(d)/time.Since(start).Seconds())
+				}
+				return nil
+			})
+		}
+		if err := g.Wait(); err != nil {
+			log.Fatalf("Precompute failed after %d nodes: %v", done.Load(), err)
+		}
+
+		avg := 0.0
+		if d := done.Load(); d > 0 {
+			avg = float64(reached.Load()) / float64(d)
+		}
+		log.Printf("完成 %d 个节点，平均可达节点 %.0f 个，耗时 %s",
+			done.Load(), avg, time.Since(start).Round(time.Second))
+	}
+}
+
+// pendingNodes 列出城市中需要计算的节点；all 为 false 时跳过已是当前路网版本的节点
+func pendingNodes(ctx context.Context, db *database.DB, city string, speed float64, minutes int, all bool) ([]node, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT v.id, ST_X(v.the_geom), ST_Y(v.the_geom)
+		FROM ways_vertices_pgr v
+		WHERE v.city = $1
+		  AND ($4 OR NOT EXISTS (
+			SELECT 1 FROM node_reach r
+			WHERE r.node = v.id
+			  AND r.speed_bucket = speed_bucket($2)
+			  AND r.max_minutes >= $3
+			  AND r.network_version = node_network_version(v.id)
+		  ))
+		ORDER BY v.id`,
+		city, speed, minutes, all,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nodes []node
+	for rows.Next() {
+		var n node
+		if err := rows.Scan(&n.ID, &n.Lng, &n.Lat); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, rows.Err()
+}
+
+// parseSpeeds 解析逗号分隔的速度档
+func parseSpeeds(s string) ([]float64, error) {
+	var speeds []float64
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(item, 64)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("invalid speed %q", item)
+		}
+		speeds = append(speeds, v)
+	}
+	if len(speeds) == 0 {
+		return nil, fmt.Errorf("no speeds given")
+	}
+	return speeds, nil
+}
@@ -0,0 +1,232 @@
+// regions 导入行政区划边界并按区域汇总生活圈评分
+//
+//	regions import  -city hangzhou -level subdistrict -file subdistricts.geojson
+//	regions import  -city hangzhou -level community -file communities.geojson -parent-field street_code
+//	regions compute -city hangzhou [-spacing 250] [-speed 5]
+//	regions rescore -city hangzhou
+//
+// compute 为尚无样点的区域布置格网样点，计算每个样点 15 分钟步行圈内各子类型 POI 数量
+// （已是当前路网版本与步行速度的样点默认跳过，中断后重新运行即可继续），最后重新评分汇总。
+// 只修改评价标准或分类权重时运行 rescore 即可，无需重新做路网分析。
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"runtime"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/yourname/15min-life-circle/internal/config"
+	"github.com/yourname/15min-life-circle/internal/database"
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/service"
+	"golang.org/x/sync/errgroup"
+)
+
+const usage = `usage: regions <command> [flags]
+
+  import   从 GeoJSON FeatureCollection 导入（更新）街道或社区边界
+  compute  布置格网样点、计算样点 POI 数量并重新评分汇总
+  rescore  按当前评价标准重新计算样点评分与区域汇总
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var run func(ctx context.Context, regions *service.RegionService, args []string)
+	switch os.Args[1] {
+	case "import":
+		run = runImport
+	case "compute":
+		run = runCompute
+	case "rescore":
+		run = runRescore
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	// 加载配置
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// 连接数据库
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	evaluation := service.NewEvaluationService(db, service.NewPOIService(db), cfg)
+	run(ctx, service.NewRegionService(db, evaluation), os.Args[2:])
+}
+
+// runImport 导入区域边界，街道需先于社区导入以关联父级
+func runImport(ctx context.Context, regions *service.RegionService, args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	city := fs.String("city", "", "城市代码，如 hangzhou")
+	level := fs.String("level", "", "层级：subdistrict（街道）或 community（社区）")
+	file := fs.String("file", "", "GeoJSON FeatureCollection 文件（WGS84）")
+	codeField := fs.String("code-field", "code", "区划代码属性名")
+	nameField := fs.String("name-field", "name", "名称属性名")
+	parentField := fs.String("parent-field", "parent_code", "上级区划代码属性名（社区对应的街道）")
+	source := fs.String("source", "", "数据来源说明")
+	fs.Parse(args)
+	if *city == "" || *level == "" || *file == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	items, err := readRegions(*file, *codeField, *nameField, *parentField)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", *file, err)
+	}
+	imported, orphans, err := regions.Import(ctx, *city, *level, *source, items)
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+	log.Printf("城市 %s：导入 %s %d 个", *city, *level, imported)
+	if orphans > 0 {
+		log.Printf("  %d 个区域未找到上级区划（需先导入街道）", orphans)
+	}
+}
+
+// runCompute 布置样点、计算 POI 数量并重新评分
+func runCompute(ctx context.Context, regions *service.RegionService, args []string) {
+	fs := flag.NewFlagSet("compute", flag.ExitOnError)
+	city := fs.String("city", "", "城市代码，如 hangzhou")
+	spacing := fs.Float64("spacing", 250, "格网间距（米）")
+	speed := fs.Float64("speed", 5, "步行速度（km/h）")
+	workers := fs.Int("workers", runtime.NumCPU(), "并发数（不超过数据库连接池大小）")
+	all := fs.Bool("all", false, "重新布置全部样点并重新计算")
+	fs.Parse(args)
+	if *city == "" || *speed <= 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	added, err := regions.Sample(ctx, *city, *spacing, *all)
+	if err != nil {
+		log.Fatalf("Sampling failed: %v", err)
+	}
+	log.Printf("城市 %s：新增样点 %d 个", *city, added)
+
+	samples, err := regions.PendingSamples(ctx, *city, *speed, *all)
+	if err != nil {
+		log.Fatalf("Failed to list samples: %v", err)
+	}
+	log.Printf("待计算样点 %d 个", len(samples))
+
+	start := time.Now()
+	var done atomic.Int64
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(max(*workers, 1))
+	for _, p := range samples {
+		g.Go(func() error {
+			if err := regions.Measure(gctx, p, *speed); err != nil {
+				return err
+			}
+			if d := done.Add(1); d%200 == 0 {
+				log.Printf("  %d / %d（%.1f 个/秒）", d, len(samples), float64(d)/time.Since(start).Seconds())
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		log.Fatalf("Measure failed after %d samples: %v", done.Load(), err)
+	}
+	log.Printf("完成 %d 个样点，耗时 %s", done.Load(), time.Since(start).Round(time.Second))
+
+	rescore(ctx, regions, *city, *speed)
+}
+
+// runRescore 按当前评价标准重新评分
+func runRescore(ctx context.Context, regions *service.RegionService, args []string) {
+	fs := flag.NewFlagSet("rescore", flag.ExitOnError)
+	city := fs.String("city", "", "城市代码，如 hangzhou")
+	speed := fs.Float64("speed", model.DefaultWalkSpeed, "步行速度（km/h），按其他速度计算的样点不参与评分")
+	fs.Parse(args)
+	if *city == "" || *speed <= 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	rescore(ctx, regions, *city, *speed)
+}
+
+func rescore(ctx context.Context, regions *service.RegionService, city string, speed float64) {
+	res, err := regions.Rescore(ctx, city, speed)
+	if err != nil {
+		log.Fatalf("Rescore failed: %v", err)
+	}
+	log.Printf("重新评分：区域 %d 个，样点 %d 个，未计算样点 %d 个", res.Regions, res.Samples, res.Unmeasured)
+	if res.Stale > 0 {
+		log.Printf("  %d 个样点按其他步行速度或旧路网计算，未参与评分（需运行 compute）", res.Stale)
+	}
+}
+
+// readRegions 读取 GeoJSON FeatureCollection，属性值可为字符串或数字
+func readRegions(path, codeField, nameField, parentField string) ([]model.RegionImport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var fc struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Properties map[string]any  `json:"properties"`
+			Geometry   json.RawMessage `json:"geometry"`
+		} `json:"features"`
+	}
+	dec := json.NewDecoder(f)
+	dec.UseNumber()
+	if err := dec.Decode(&fc); err != nil {
+		return nil, err
+	}
+	if fc.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("expected FeatureCollection, got %q", fc.Type)
+	}
+
+	regions := make([]model.RegionImport, 0, len(fc.Features))
+	for i, f := range fc.Features {
+		r := model.RegionImport{
+			Code:       property(f.Properties, codeField),
+			Name:       property(f.Properties, nameField),
+			ParentCode: property(f.Properties, parentField),
+			Geometry:   f.Geometry,
+		}
+		if r.Code == "" || r.Name == "" {
+			return nil, fmt.Errorf("feature #%d: missing %s or %s", i+1, codeField, nameField)
+		}
+		regions = append(regions, r)
+	}
+	return regions, nil
+}
+
+// property 属性值转字符串，数字保留原始写法
+func property(props map[string]any, key string) string {
+	switch v := props[key].(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		return ""
+	}
+}
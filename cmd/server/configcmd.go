@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/yourname/15min-life-circle/internal/config"
+)
+
+const configUsage = `usage: server config check [-format yaml|toml]
+
+  check  加载并校验配置（配置文件 + 环境变量），输出生效的配置，密钥以 <redacted> 代替
+`
+
+// runConfig 执行 config 子命令；配置无效时列出全部问题并以状态 1 退出
+func runConfig(args []string) {
+	if len(args) == 0 || args[0] != "check" {
+		fmt.Fprint(os.Stderr, configUsage)
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("config check", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, configUsage) }
+	format := fs.String("format", "yaml", "输出格式：yaml 或 toml")
+	fs.Parse(args[1:])
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if cfg.File != "" {
+		fmt.Printf("# config file: %s\n", cfg.File)
+	} else {
+		fmt.Println("# config file: none (defaults and environment only)")
+	}
+	if err := cfg.Redacted().Write(os.Stdout, *format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
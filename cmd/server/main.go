@@ -10,19 +10,34 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/yourname/15min-life-circle/internal/api"
 	"github.com/yourname/15min-life-circle/internal/config"
 	"github.com/yourname/15min-life-circle/internal/database"
+	"github.com/yourname/15min-life-circle/internal/logging"
+	"github.com/yourname/15min-life-circle/internal/metrics"
+	"github.com/yourname/15min-life-circle/internal/ratelimit"
 	"github.com/yourname/15min-life-circle/internal/service"
+	"github.com/yourname/15min-life-circle/internal/tracing"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			runMigrate(os.Args[2:])
+			return
+		case "config":
+			runConfig(os.Args[2:])
+			return
+		}
+	}
+
 	// 加载配置
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
-
 	// 连接数据库
 	db, err := database.Connect(cfg.Database)
 	if err != nil {
@@ -30,10 +45,70 @@ func main() {
 	}
 	defer db.Close()
 
+	// 结构化日志，标准库 log 的输出同样转为 JSON
+	logging.Setup(os.Stdout, cfg.Server.LogLevel)
+	if cfg.File != "" {
+		log.Printf("Config loaded from %s", cfg.File)
+	}
+
+	// 链路追踪，退出前导出缓冲中的 span
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Printf("Tracing shutdown: %v", err)
+		}
+	}()
+
+	// 检查数据库函数与迁移状态
+	checkSchema(context.Background(), db)
+
+	if err := metrics.RegisterPool(db.Pool); err != nil {
+		log.Fatalf("Failed to register pool metrics: %v", err)
+	}
+
 	// 初始化服务层
 	isochroneService := service.NewIsochroneService(db)
 	poiService := service.NewPOIService(db)
 	evaluationService := service.NewEvaluationService(db, poiService, cfg)
+	cityService := service.NewCityService(db)
+	classifyService := service.NewClassificationService(db)
+	facilityService := service.NewFacilityService(db)
+	householdService := service.NewHouseholdService(db, isochroneService, poiService, evaluationService)
+	apiKeyService := service.NewAPIKeyService(db, cfg.Auth.Tiers)
+	userService := service.NewUserService(db, cfg.Auth)
+	regionService := service.NewRegionService(db, evaluationService)
+	equityService := service.NewEquityService(db, evaluationService)
+	limiter := ratelimit.New()
+
+	// 定期写入 API Key 用量，退出时写入剩余计数
+	usageCtx, stopUsage := context.WithCancel(context.Background())
+	usageDone := make(chan struct{})
+	go func() {
+		apiKeyService.Run(usageCtx, 10*time.Second)
+		close(usageDone)
+	}()
+
+	// 必备设施的子类型没有分类规则产生时，所有位置都会被判为缺失该设施
+	if standards, err := evaluationService.GetStandards(context.Background()); err == nil {
+		if err := classifyService.CheckStandards(context.Background(), standards); err != nil {
+			log.Printf("分类规则检查未通过: %v", err)
+		}
+	}
+
+	if len(cfg.Auth.Tokens) == 0 {
+		log.Println("未配置 API_TOKENS，POI 维护与 API Key 管理接口不可用")
+	}
+	if !cfg.Auth.AllowRegistration {
+		log.Println("用户注册已关闭")
+	}
+	if !cfg.Auth.AllowAnonymous {
+		log.Println("匿名访问已关闭，/api/v1 需要 API Key")
+	}
 
 	// 打印高德API状态
 	if cfg.Amap.Enabled {
@@ -43,7 +118,15 @@ func main() {
 	}
 
 	// 设置 Gin 路由
-	router := gin.Default()
+	router := gin.New()
+	// 只采信可信代理转发的 X-Forwarded-For，否则客户端可伪造 IP 绕过匿名限速与配额
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("Invalid server.trusted_proxies: %v", err)
+	}
+	router.Use(gin.Recovery(), api.Tracing(), api.RequestLogger())
+
+	// Prometheus 指标
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	// 静态文件
 	router.Static("/static", "./web/static")
@@ -53,21 +136,85 @@ func main() {
 	router.GET("/", func(c *gin.Context) {
 		c.HTML(http.StatusOK, "index.html", nil)
 	})
+	// 分享链接：页面按路径中的 ID 读取 /api/v1/analyses/{id} 并展示保存的结果
+	router.GET("/a/:id", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "index.html", nil)
+	})
 
 	// API 路由
-	apiGroup := router.Group("/api/v1")
+	// 全部 API 经过 API Key 认证与限速
+	apiGroup := router.Group("/api/v1", api.APIKeyAuth(apiKeyService, limiter, cfg.Auth.AllowAnonymous))
 	{
-		handler := api.NewHandler(isochroneService, poiService, evaluationService, cfg)
-		apiGroup.POST("/isochrone", handler.CalculateIsochrone)
-		apiGroup.POST("/analyze", handler.AnalyzePoint)
+		handler := api.NewHandler(isochroneService, poiService, evaluationService, cityService, classifyService, facilityService, householdService, apiKeyService, userService, regionService, equityService, cfg)
+		apiGroup.GET("/usage", handler.GetOwnUsage)
+
+		// 需要路网计算的接口计入每日配额；登录用户的 analyze 结果计入其评价历史
+		quota := api.DailyQuota(apiKeyService)
+		apiGroup.POST("/analyze", api.OptionalUser(userService), quota, handler.AnalyzePoint)
+		apiGroup.GET("/analyses/:id", handler.GetSharedAnalysis)
+		expensive := apiGroup.Group("", quota)
+		expensive.POST("/isochrone", handler.CalculateIsochrone)
+		expensive.POST("/nearest", handler.NearestFacilities)
+		expensive.POST("/catchment", handler.CalculateCatchment)
+		expensive.POST("/household", handler.CalculateHousehold)
+		expensive.POST("/equity/points", handler.CalculatePointsEquity)
+
+		// 用户账号
+		apiGroup.POST("/auth/register", handler.Register)
+		apiGroup.POST("/auth/login", handler.Login)
+		apiGroup.POST("/auth/logout", handler.Logout)
+
+		// 常用地点与评价历史（需要登录）
+		me := apiGroup.Group("/me", api.RequireUser(userService))
+		me.GET("", handler.GetMe)
+		me.GET("/places", handler.ListPlaces)
+		me.POST("/places", handler.CreatePlace)
+		me.PUT("/places/:id", handler.UpdatePlace)
+		me.DELETE("/places/:id", handler.DeletePlace)
+		me.POST("/places/:id/analyze", quota, handler.AnalyzePlace)
+		me.GET("/analyses", handler.ListMyAnalyses)
+		me.GET("/analyses/:id", handler.GetMyAnalysis)
+		me.DELETE("/analyses/:id", handler.DeleteMyAnalysis)
+		me.POST("/analyses/:id/rerun", quota, handler.RerunMyAnalysis)
+
 		apiGroup.GET("/poi/categories", handler.GetPOICategories)
+		apiGroup.GET("/pois", api.MinutesSearchQuota(quota), handler.SearchPOIs)
 		apiGroup.GET("/evaluation/standards", handler.GetEvaluationStandards)
+		apiGroup.GET("/cities", handler.GetCities)
+		apiGroup.GET("/classification/rules", handler.GetClassificationRules)
+		apiGroup.POST("/classification/dry-run", handler.DryRunClassification)
+		apiGroup.GET("/classification/report", handler.GetClassificationReport)
+
+		// 街道、社区评分汇总与公平性分析（由 cmd/regions 计算）
+		apiGroup.GET("/regions", handler.ListRegions)
+		apiGroup.GET("/regions/:id/summary", handler.GetRegionSummary)
+		apiGroup.GET("/equity", handler.GetGridEquity)
+
+		// POI 人工维护（需要令牌）
+		poiAdmin := apiGroup.Group("/pois", api.RequireToken(cfg.Auth))
+		poiAdmin.POST("", handler.CreatePOI)
+		poiAdmin.PATCH("/:id", handler.UpdatePOI)
+		poiAdmin.DELETE("/:id", handler.DeletePOI)
+		poiAdmin.POST("/:id/reclassify", handler.ReclassifyPOI)
+		poiAdmin.GET("/:id/history", handler.GetPOIHistory)
+
+		// API Key 管理（需要令牌）
+		keyAdmin := apiGroup.Group("/admin/keys", api.RequireToken(cfg.Auth))
+		keyAdmin.POST("", handler.CreateAPIKey)
+		keyAdmin.GET("", handler.ListAPIKeys)
+		keyAdmin.DELETE("/:id", handler.RevokeAPIKey)
+		keyAdmin.GET("/:id/usage", handler.GetAPIKeyUsage)
+
+		// 评价标准调整后重新汇总区域评分（需要令牌）
+		apiGroup.POST("/admin/regions/rescore", api.RequireToken(cfg.Auth), handler.RescoreRegions)
 	}
 
 	// 启动服务器
 	srv := &http.Server{
-		Addr:    cfg.Server.Addr,
-		Handler: router,
+		Addr:         cfg.Server.Addr,
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout.D(),
+		WriteTimeout: cfg.Server.WriteTimeout.D(),
 	}
 
 	// 优雅关闭
@@ -83,11 +230,13 @@ func main() {
 	<-quit
 	log.Println("Shutting down server...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.D())
 	defer cancel()
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
+	stopUsage()
+	<-usageDone
 
 	log.Println("Server exited")
 }
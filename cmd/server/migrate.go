@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/yourname/15min-life-circle/internal/config"
+	"github.com/yourname/15min-life-circle/internal/database"
+	"github.com/yourname/15min-life-circle/internal/migrate"
+	"github.com/yourname/15min-life-circle/migrations"
+)
+
+const migrateUsage = `usage: server migrate <command>
+
+  up                 执行全部待执行的迁移
+  status             列出迁移脚本及执行状态
+  baseline [version] 将不超过 version（默认最新）的迁移标记为已执行，用于已由旧方式初始化的数据库
+  verify             检查服务所需的数据库函数及签名
+`
+
+// runMigrate 执行 migrate 子命令
+func runMigrate(args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, migrateUsage) }
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	all, err := migrate.Load(migrations.FS)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+	m := migrate.New(db, all)
+	m.Logf = log.Printf
+
+	ctx := context.Background()
+	switch cmd := fs.Arg(0); cmd {
+	case "up":
+		done, err := m.Up(ctx)
+		if errors.Is(err, migrate.ErrUntracked) {
+			log.Fatalf("Migration failed: %v\n数据库由旧版 docker-entrypoint 初始化，确认表结构为最新后运行 `server migrate baseline`", err)
+		}
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		log.Printf("%d migration(s) applied", len(done))
+		if err := migrate.VerifyFunctions(ctx, db); err != nil {
+			log.Fatalf("Verification failed: %v", err)
+		}
+
+	case "status":
+		states, unknown, err := m.Status(ctx)
+		if err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+		for _, st := range states {
+			status := "pending"
+			when := ""
+			if st.Applied != nil {
+				status = "applied"
+				if st.Applied.Baseline {
+					status = "baseline"
+				}
+				if st.Modified {
+					status = "MODIFIED"
+				}
+				when = st.Applied.AppliedAt.Local().Format(time.DateTime)
+			}
+			fmt.Printf("%-8s  %-48s %s\n", status, st.Migration.File(), when)
+		}
+		for _, a := range unknown {
+			fmt.Printf("%-8s  %03d_%s.sql (not in this build)\n", "UNKNOWN", a.Version, a.Name)
+		}
+
+	case "baseline":
+		through := all[len(all)-1].Version
+		if fs.NArg() > 1 {
+			if through, err = strconv.Atoi(fs.Arg(1)); err != nil {
+				log.Fatalf("Invalid version %q", fs.Arg(1))
+			}
+		}
+		marked, err := m.Baseline(ctx, through)
+		if err != nil {
+			log.Fatalf("Baseline failed: %v", err)
+		}
+		log.Printf("%d migration(s) marked as applied through version %d", len(marked), through)
+
+	case "verify":
+		if err := migrate.VerifyFunctions(ctx, db); err != nil {
+			log.Fatalf("Verification failed: %v", err)
+		}
+		log.Println("Database functions OK")
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate command %q\n\n", cmd)
+		fs.Usage()
+		os.Exit(2)
+	}
+}
+
+// checkSchema 启动时检查数据库：必需函数缺失或签名不一致时退出，有待执行的迁移时告警
+func checkSchema(ctx context.Context, db *database.DB) {
+	if err := migrate.VerifyFunctions(ctx, db); err != nil {
+		log.Fatalf("%v\n请先运行 `server migrate up`", err)
+	}
+
+	all, err := migrate.Load(migrations.FS)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+	states, _, err := migrate.New(db, all).Status(ctx)
+	if err != nil {
+		log.Printf("Failed to read migration status: %v", err)
+		return
+	}
+	pending := 0
+	for _, st := range states {
+		if st.Pending() {
+			pending++
+		}
+		if st.Modified {
+			log.Printf("迁移脚本 %s 在执行后被修改", st.Migration.File())
+		}
+	}
+	if pending > 0 {
+		log.Printf("%d 个迁移待执行，请运行 `server migrate up`", pending)
+	}
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourname/15min-life-circle/internal/model"
+)
+
+// 公平性报告可导出的 CSV 表
+var equityTables = map[string]func(*model.EquityReport) [][]string{
+	"metrics":    equityMetricsTable,
+	"lorenz":     equityLorenzTable,
+	"facilities": equityFacilitiesTable,
+	"deserts":    equityDesertsTable,
+}
+
+// equityExport 解析导出参数：format 为 json（默认）或 csv，csv 时 table 选择导出的表（默认 metrics）
+// 返回空表名表示输出 JSON；参数不合法时直接返回 400。在计算报告之前调用
+func equityExport(c *gin.Context) (table string, ok bool) {
+	switch c.Query("format") {
+	case "", "json":
+		return "", true
+	case "csv":
+		table = c.DefaultQuery("table", "metrics")
+		if _, ok := equityTables[table]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid request",
+				"details": "table must be metrics, lorenz, facilities or deserts",
+			})
+			return "", false
+		}
+		return table, true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": "format must be json or csv",
+		})
+		return "", false
+	}
+}
+
+// writeEquityReport 输出公平性报告，table 为空时输出 JSON
+func writeEquityReport(c *gin.Context, report *model.EquityReport, table string) {
+	if table == "" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+	writeCSV(c, "equity-"+table+".csv", equityTables[table](report))
+}
+
+// writeCSV 以附件形式输出 CSV，带 UTF-8 BOM 以便 Excel 正确识别中文
+// 响应头已发出，写入失败（通常是客户端断开）只能记录日志
+func writeCSV(c *gin.Context, filename string, rows [][]string) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	for _, row := range rows {
+		for i, cell := range row {
+			row[i] = escapeFormula(cell)
+		}
+	}
+	_, err := c.Writer.WriteString("\ufeff")
+	if err == nil {
+		err = csv.NewWriter(c.Writer).WriteAll(rows)
+	}
+	if err != nil {
+		traceError(c, err)
+		slog.WarnContext(c.Request.Context(), "write csv failed", "file", filename, "error", err)
+	}
+}
+
+// escapeFormula 以 = + - @ 等开头的文本（如用户提交的标签）在电子表格中会被当作公式执行，
+// 前面加单引号按文本显示；数值（如负经度）保持不变
+func escapeFormula(cell string) string {
+	if cell == "" || !strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return cell
+	}
+	if _, err := strconv.ParseFloat(cell, 64); err == nil {
+		return cell
+	}
+	return "'" + cell
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// equityMetricsTable 总分与各分类得分的分布指标，每行一个指标
+func equityMetricsTable(r *model.EquityReport) [][]string {
+	rows := [][]string{{"indicator", "name", "mean", "min", "max", "gini", "theil", "p10", "p25", "p50", "p75", "p90"}}
+	row := func(code, name string, d model.Distribution) []string {
+		p := d.Percentiles
+		return []string{
+			code, name,
+			formatFloat(d.Mean), formatFloat(d.Min), formatFloat(d.Max),
+			formatFloat(d.Gini), formatFloat(d.Theil),
+			formatFloat(p.P10), formatFloat(p.P25), formatFloat(p.P50), formatFloat(p.P75), formatFloat(p.P90),
+		}
+	}
+	rows = append(rows, row("total", "总分", r.Total))
+	for _, cd := range r.Categories {
+		rows = append(rows, row(cd.Category, cd.Name, cd.Distribution))
+	}
+	return rows
+}
+
+// equityLorenzTable 洛伦兹曲线，每个指标一组点
+func equityLorenzTable(r *model.EquityReport) [][]string {
+	rows := [][]string{{"indicator", "population_share", "score_share"}}
+	add := func(code string, d model.Distribution) {
+		for _, p := range d.Lorenz {
+			rows = append(rows, []string{code, formatFloat(p.Population), formatFloat(p.Score)})
+		}
+	}
+	add("total", r.Total)
+	for _, cd := range r.Categories {
+		add(cd.Category, cd.Distribution)
+	}
+	return rows
+}
+
+// equityFacilitiesTable 各必备设施的缺失情况
+func equityFacilitiesTable(r *model.EquityReport) [][]string {
+	rows := [][]string{{"category", "sub_type", "name", "missing_count", "missing_share"}}
+	for _, g := range r.Deserts.Facilities {
+		rows = append(rows, []string{g.Category, g.SubType, g.Name, strconv.Itoa(g.Count), formatFloat(g.Share)})
+	}
+	return rows
+}
+
+// equityDesertsTable 服务盲区点，缺失设施以名称列出
+func equityDesertsTable(r *model.EquityReport) [][]string {
+	names := make(map[string]string, len(r.Deserts.Facilities))
+	for _, g := range r.Deserts.Facilities {
+		names[g.SubType] = g.Name
+	}
+	rows := [][]string{{"label", "region_id", "region_name", "lng", "lat", "weight", "total_score", "missing_count", "missing"}}
+	for _, p := range r.Deserts.Points {
+		region := ""
+		if p.RegionID != nil {
+			region = strconv.Itoa(*p.RegionID)
+		}
+		missing := make([]string, len(p.Missing))
+		for i, m := range p.Missing {
+			missing[i] = names[m]
+		}
+		rows = append(rows, []string{
+			p.Label, region, p.RegionName,
+			formatFloat(p.Lng), formatFloat(p.Lat), formatFloat(p.Weight), formatFloat(p.TotalScore),
+			strconv.Itoa(len(p.Missing)), strings.Join(missing, "、"),
+		})
+	}
+	return rows
+}
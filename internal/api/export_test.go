@@ -0,0 +1,47 @@
+package api
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWriteCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		cell string
+		want string
+	}{
+		{"plain text", "阳光小区", "阳光小区"},
+		{"formula", "=HYPERLINK(\"http://x\")", "'=HYPERLINK(\"http://x\")"},
+		{"plus", "+cmd", "'+cmd"},
+		{"minus", "-2+3", "'-2+3"},
+		{"at", "@SUM(A1)", "'@SUM(A1)"},
+		{"tab", "\t=1", "'\t=1"},
+		{"negative number", "-73.9857", "-73.9857"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			writeCSV(c, "test.csv", [][]string{{"label", "weight"}, {tt.cell, "-1"}})
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d", w.Code)
+			}
+			rows, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(w.Body.String(), "\ufeff"))).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != 2 || rows[1][0] != tt.want || rows[1][1] != "-1" {
+				t.Errorf("rows = %q, want cell %q", rows, tt.want)
+			}
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yourname/15min-life-circle/internal/config"
@@ -14,7 +17,16 @@ type Handler struct {
 	isochroneService  *service.IsochroneService
 	poiService        *service.POIService
 	evaluationService *service.EvaluationService
+	cityService       *service.CityService
+	classifyService   *service.ClassificationService
+	facilityService   *service.FacilityService
+	householdService  *service.HouseholdService
+	apiKeyService     *service.APIKeyService
+	userService       *service.UserService
+	regionService     *service.RegionService
+	equityService     *service.EquityService
 	amapService       *service.AmapPOIService
+	evaluation        config.EvaluationConfig
 }
 
 // NewHandler 创建处理器
@@ -22,16 +34,47 @@ func NewHandler(
 	isoService *service.IsochroneService,
 	poiService *service.POIService,
 	evalService *service.EvaluationService,
+	cityService *service.CityService,
+	classifyService *service.ClassificationService,
+	facilityService *service.FacilityService,
+	householdService *service.HouseholdService,
+	apiKeyService *service.APIKeyService,
+	userService *service.UserService,
+	regionService *service.RegionService,
+	equityService *service.EquityService,
 	cfg *config.Config,
 ) *Handler {
 	return &Handler{
 		isochroneService:  isoService,
 		poiService:        poiService,
 		evaluationService: evalService,
+		cityService:       cityService,
+		classifyService:   classifyService,
+		facilityService:   facilityService,
+		householdService:  householdService,
+		apiKeyService:     apiKeyService,
+		userService:       userService,
+		regionService:     regionService,
+		equityService:     equityService,
 		amapService:       service.NewAmapPOIService(cfg.Amap),
+		evaluation:        cfg.Evaluation,
 	}
 }
 
+// rejectDisabledModes 请求使用了未启用的出行方式（evaluation.modes）时返回 400
+func (h *Handler) rejectDisabledModes(c *gin.Context, modes ...string) bool {
+	for _, mode := range modes {
+		if !h.evaluation.ModeEnabled(mode) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid request",
+				"details": "mode " + mode + " is not enabled",
+			})
+			return true
+		}
+	}
+	return false
+}
+
 // CalculateIsochrone 计算等时圈
 // POST /api/v1/isochrone
 func (h *Handler) CalculateIsochrone(c *gin.Context) {
@@ -45,7 +88,29 @@ func (h *Handler) CalculateIsochrone(c *gin.Context) {
 	}
 
 	result, err := h.isochroneService.CalculateAsGeoJSON(c.Request.Context(), &req)
+	if errors.Is(err, service.ErrInvalidPolygonMethod) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid polygon method",
+			"details": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidTerrainModel) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid terrain model",
+			"details": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrOutsideCoverage) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "origin outside coverage",
+			"details": err.Error(),
+		})
+		return
+	}
 	if err != nil {
+		traceError(c, err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "calculation failed",
 			"details": err.Error(),
@@ -56,7 +121,7 @@ func (h *Handler) CalculateIsochrone(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
-// AnalyzePoint 综合分析某点
+// AnalyzePoint 综合分析某点，登录用户的结果计入其评价历史
 // POST /api/v1/analyze
 func (h *Handler) AnalyzePoint(c *gin.Context) {
 	var req model.EvaluationRequest
@@ -67,9 +132,13 @@ func (h *Handler) AnalyzePoint(c *gin.Context) {
 		})
 		return
 	}
+	if u := currentUser(c); u != nil {
+		req.UserID = &u.ID
+	}
 
-	result, err := h.evaluationService.Evaluate(c.Request.Context(), &req)
+	result, err := h.evaluationService.EvaluateAndSave(c.Request.Context(), &req)
 	if err != nil {
+		traceError(c, err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "analysis failed",
 			"details": err.Error(),
@@ -80,11 +149,141 @@ func (h *Handler) AnalyzePoint(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// GetSharedAnalysis 按分享链接读取评价结果、参数与数据版本
+// GET /api/v1/analyses/:id
+func (h *Handler) GetSharedAnalysis(c *gin.Context) {
+	shared, err := h.evaluationService.SharedAnalysis(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		analysisError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, shared)
+}
+
+// CalculateHousehold 多起点家庭等时圈
+// POST /api/v1/household
+func (h *Handler) CalculateHousehold(c *gin.Context) {
+	var req model.HouseholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	modes := make([]string, len(req.Origins))
+	for i, o := range req.Origins {
+		modes[i] = o.Mode
+	}
+	if h.rejectDisabledModes(c, modes...) {
+		return
+	}
+
+	result, err := h.householdService.Calculate(c.Request.Context(), &req)
+	if errors.Is(err, service.ErrInvalidHousehold) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		traceError(c, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "calculation failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// CalculateCatchment 计算设施服务范围（反向等时圈）
+// POST /api/v1/catchment
+func (h *Handler) CalculateCatchment(c *gin.Context) {
+	var req model.CatchmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if h.rejectDisabledModes(c, req.Mode) {
+		return
+	}
+
+	result, err := h.isochroneService.Catchment(c.Request.Context(), &req)
+	switch {
+	case errors.Is(err, service.ErrInvalidCatchment):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	case errors.Is(err, service.ErrPOINotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "poi not found",
+		})
+		return
+	case err != nil:
+		traceError(c, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "calculation failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// NearestFacilities 按路网步行时间查找最近设施及步行路径
+// POST /api/v1/nearest
+func (h *Handler) NearestFacilities(c *gin.Context) {
+	var req model.NearestFacilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	standards, err := h.evaluationService.GetStandards(ctx)
+	if err != nil {
+		traceError(c, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to get standards",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	result, err := h.facilityService.Nearest(ctx, &req, standards)
+	if err != nil {
+		traceError(c, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "nearest facility query failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // GetPOICategories 获取 POI 分类
 // GET /api/v1/poi/categories
 func (h *Handler) GetPOICategories(c *gin.Context) {
 	categories, err := h.poiService.GetCategories(c.Request.Context())
 	if err != nil {
+		traceError(c, err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "failed to get categories",
 			"details": err.Error(),
@@ -102,6 +301,7 @@ func (h *Handler) GetPOICategories(c *gin.Context) {
 func (h *Handler) GetEvaluationStandards(c *gin.Context) {
 	standards, err := h.evaluationService.GetStandards(c.Request.Context())
 	if err != nil {
+		traceError(c, err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "failed to get standards",
 			"details": err.Error(),
@@ -114,6 +314,915 @@ func (h *Handler) GetEvaluationStandards(c *gin.Context) {
 	})
 }
 
+// GetCities 获取已导入数据的城市列表
+// GET /api/v1/cities
+func (h *Handler) GetCities(c *gin.Context) {
+	cities, err := h.cityService.List(c.Request.Context())
+	if err != nil {
+		traceError(c, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to get cities",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cities": cities,
+	})
+}
+
+// GetClassificationRules 获取已启用的分类规则
+// GET /api/v1/classification/rules
+func (h *Handler) GetClassificationRules(c *gin.Context) {
+	rules, err := h.classifyService.Rules(c.Request.Context())
+	if err != nil {
+		traceError(c, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to get rules",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rules": rules,
+	})
+}
+
+// DryRunClassification 用规则集对样例标签试运行分类
+// POST /api/v1/classification/dry-run
+func (h *Handler) DryRunClassification(c *gin.Context) {
+	var req model.ClassificationDryRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	result, err := h.classifyService.DryRun(c.Request.Context(), &req)
+	if errors.Is(err, service.ErrInvalidRule) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid rule",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		traceError(c, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "dry run failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetClassificationReport 检查评价标准引用的子类型是否都有规则产生
+// GET /api/v1/classification/report
+func (h *Handler) GetClassificationReport(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	standards, err := h.evaluationService.GetStandards(ctx)
+	if err != nil {
+		traceError(c, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to get standards",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	report, err := h.classifyService.Report(ctx, standards)
+	if err != nil {
+		traceError(c, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to build report",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// SearchPOIs 按空间范围与属性搜索 POI
+// GET /api/v1/pois?bbox=...|lng=&lat=&radius=...|lng=&lat=&minutes=...|polygon=...
+func (h *Handler) SearchPOIs(c *gin.Context) {
+	var req model.POISearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	result, err := h.poiService.Search(c.Request.Context(), &req)
+	if errors.Is(err, service.ErrInvalidSearch) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid search",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		traceError(c, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "search failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// CreatePOI 人工新增 POI
+// POST /api/v1/pois
+func (h *Handler) CreatePOI(c *gin.Context) {
+	var req model.POICreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	result, err := h.poiService.Create(c.Request.Context(), actor(c), &req)
+	if err != nil {
+		poiEditError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// UpdatePOI 人工修改 POI
+// PATCH /api/v1/pois/:id
+func (h *Handler) UpdatePOI(c *gin.Context) {
+	id, ok := poiID(c)
+	if !ok {
+		return
+	}
+
+	var req model.POIUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	result, err := h.poiService.Update(c.Request.Context(), actor(c), id, &req)
+	if err != nil {
+		poiEditError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ReclassifyPOI 人工修改 POI 分类
+// POST /api/v1/pois/:id/reclassify
+func (h *Handler) ReclassifyPOI(c *gin.Context) {
+	id, ok := poiID(c)
+	if !ok {
+		return
+	}
+
+	var req model.POIReclassifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	result, err := h.poiService.Reclassify(c.Request.Context(), actor(c), id, &req)
+	if err != nil {
+		poiEditError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// DeletePOI 软删除 POI
+// DELETE /api/v1/pois/:id?comment=...
+func (h *Handler) DeletePOI(c *gin.Context) {
+	id, ok := poiID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.poiService.Delete(c.Request.Context(), actor(c), id, c.Query("comment"))
+	if err != nil {
+		poiEditError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetPOIHistory 获取 POI 审计记录
+// GET /api/v1/pois/:id/history
+func (h *Handler) GetPOIHistory(c *gin.Context) {
+	id, ok := poiID(c)
+	if !ok {
+		return
+	}
+
+	history, err := h.poiService.History(c.Request.Context(), id)
+	if err != nil {
+		traceError(c, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to get history",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"history": history,
+	})
+}
+
+// poiEditError 人工维护错误映射为 HTTP 状态码
+func poiEditError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrPOINotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "poi not found",
+		})
+	case errors.Is(err, service.ErrUnknownSubType):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "unknown sub_type",
+			"details": err.Error(),
+		})
+	default:
+		traceError(c, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "poi edit failed",
+			"details": err.Error(),
+		})
+	}
+}
+
+// poiID 解析路径中的 POI ID，失败时直接返回 400
+func poiID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid poi id",
+			"details": err.Error(),
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// CreateAPIKey 签发 API Key，明文只在响应中返回一次
+// POST /api/v1/admin/keys
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	var req model.APIKeyCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	issued, err := h.apiKeyService.Issue(c.Request.Context(), actor(c), &req)
+	if err != nil {
+		apiKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, issued)
+}
+
+// ListAPIKeys 列出 API Key（不含明文）
+// GET /api/v1/admin/keys
+func (h *Handler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.apiKeyService.List(c.Request.Context())
+	if err != nil {
+		apiKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"keys": keys,
+	})
+}
+
+// RevokeAPIKey 吊销 API Key
+// DELETE /api/v1/admin/keys/:id
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	id, ok := apiKeyID(c)
+	if !ok {
+		return
+	}
+
+	key, err := h.apiKeyService.Revoke(c.Request.Context(), actor(c), id)
+	if err != nil {
+		apiKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+// GetAPIKeyUsage 查询 API Key 最近 days 天（默认 30）的用量
+// GET /api/v1/admin/keys/:id/usage
+func (h *Handler) GetAPIKeyUsage(c *gin.Context) {
+	id, ok := apiKeyID(c)
+	if !ok {
+		return
+	}
+	h.apiKeyUsage(c, id)
+}
+
+// GetOwnUsage 查询当前 API Key 的用量
+// GET /api/v1/usage
+func (h *Handler) GetOwnUsage(c *gin.Context) {
+	key := apiKey(c)
+	if key == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "missing api key",
+		})
+		return
+	}
+	h.apiKeyUsage(c, key.ID)
+}
+
+func (h *Handler) apiKeyUsage(c *gin.Context, id int) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid days",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	report, err := h.apiKeyService.Usage(c.Request.Context(), id, days)
+	if err != nil {
+		apiKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// apiKeyError 将 API Key 服务的错误映射为 HTTP 响应
+func apiKeyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrAPIKeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "api key not found",
+		})
+	case errors.Is(err, service.ErrInvalidAPIKeyRequest):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+	default:
+		traceError(c, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "api key operation failed",
+			"details": err.Error(),
+		})
+	}
+}
+
+// apiKeyID 解析路径中的 API Key ID，失败时直接返回 400
+func apiKeyID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid api key id",
+			"details": err.Error(),
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// Register 注册账号
+// POST /api/v1/auth/register
+func (h *Handler) Register(c *gin.Context) {
+	var req model.RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	u, err := h.userService.Register(c.Request.Context(), &req)
+	if err != nil {
+		userError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, u)
+}
+
+// Login 登录，会话令牌在响应中返回并写入 HttpOnly Cookie
+// POST /api/v1/auth/login
+func (h *Handler) Login(c *gin.Context) {
+	var req model.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	result, err := h.userService.Login(c.Request.Context(), &req, c.Request.UserAgent())
+	if err != nil {
+		userError(c, err)
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(SessionCookie, result.Token, int(time.Until(result.ExpiresAt).Seconds()), "/", "", c.Request.TLS != nil, true)
+	c.JSON(http.StatusOK, result)
+}
+
+// Logout 注销当前会话并清除 Cookie
+// POST /api/v1/auth/logout
+func (h *Handler) Logout(c *gin.Context) {
+	if token := sessionToken(c); token != "" {
+		if err := h.userService.Logout(c.Request.Context(), token); err != nil {
+			userError(c, err)
+			return
+		}
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(SessionCookie, "", -1, "/", "", c.Request.TLS != nil, true)
+	c.JSON(http.StatusOK, gin.H{
+		"logged_out": true,
+	})
+}
+
+// GetMe 当前登录用户
+// GET /api/v1/me
+func (h *Handler) GetMe(c *gin.Context) {
+	c.JSON(http.StatusOK, currentUser(c))
+}
+
+// ListPlaces 列出常用地点
+// GET /api/v1/me/places
+func (h *Handler) ListPlaces(c *gin.Context) {
+	places, err := h.userService.Places(c.Request.Context(), currentUser(c).ID)
+	if err != nil {
+		userError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"places": places,
+	})
+}
+
+// CreatePlace 保存地点
+// POST /api/v1/me/places
+func (h *Handler) CreatePlace(c *gin.Context) {
+	var req model.SavedPlaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	place, err := h.userService.CreatePlace(c.Request.Context(), currentUser(c).ID, &req)
+	if err != nil {
+		userError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, place)
+}
+
+// UpdatePlace 修改地点
+// PUT /api/v1/me/places/:id
+func (h *Handler) UpdatePlace(c *gin.Context) {
+	id, ok := placeID(c)
+	if !ok {
+		return
+	}
+	var req model.SavedPlaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	place, err := h.userService.UpdatePlace(c.Request.Context(), currentUser(c).ID, id, &req)
+	if err != nil {
+		userError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, place)
+}
+
+// DeletePlace 删除地点，历史评价记录保留
+// DELETE /api/v1/me/places/:id
+func (h *Handler) DeletePlace(c *gin.Context) {
+	id, ok := placeID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.DeletePlace(c.Request.Context(), currentUser(c).ID, id); err != nil {
+		userError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deleted": id,
+	})
+}
+
+// AnalyzePlace 评价常用地点，结果计入评价历史
+// POST /api/v1/me/places/:id/analyze
+func (h *Handler) AnalyzePlace(c *gin.Context) {
+	id, ok := placeID(c)
+	if !ok {
+		return
+	}
+	var body model.PlaceAnalyzeRequest
+	// 请求体可省略，全部使用默认参数
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid request",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	user := currentUser(c)
+	place, err := h.userService.Place(c.Request.Context(), user.ID, id)
+	if err != nil {
+		userError(c, err)
+		return
+	}
+
+	req := model.EvaluationRequest{
+		Lng:           place.Lng,
+		Lat:           place.Lat,
+		TimeThreshold: body.TimeThreshold,
+		WalkSpeed:     body.WalkSpeed,
+		At:            body.At,
+		Profile:       body.Profile,
+		UserID:        &user.ID,
+		PlaceID:       &place.ID,
+	}
+	result, err := h.evaluationService.EvaluateAndSave(c.Request.Context(), &req)
+	if err != nil {
+		traceError(c, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "analysis failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ListMyAnalyses 评价历史（新到旧，不含完整结果）
+// GET /api/v1/me/analyses?limit=20&before=2024-05-01T00:00:00Z
+// 翻页时以上一页最后一条的 created_at 作为 before
+func (h *Handler) ListMyAnalyses(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "limit must be between 1 and 100",
+		})
+		return
+	}
+	var before *time.Time
+	if v := c.Query("before"); v != "" {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid before",
+				"details": err.Error(),
+			})
+			return
+		}
+		before = &t
+	}
+
+	records, err := h.evaluationService.UserAnalyses(c.Request.Context(), currentUser(c).ID, before, limit)
+	if err != nil {
+		traceError(c, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to list analyses",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"analyses": records,
+	})
+}
+
+// GetMyAnalysis 评价历史中的一条记录（含完整结果）
+// GET /api/v1/me/analyses/:id
+func (h *Handler) GetMyAnalysis(c *gin.Context) {
+	rec, err := h.evaluationService.UserAnalysis(c.Request.Context(), currentUser(c).ID, c.Param("id"))
+	if err != nil {
+		analysisError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rec)
+}
+
+// RerunMyAnalysis 以原参数和当前数据重新评价，返回新结果与总分变化
+// POST /api/v1/me/analyses/:id/rerun
+func (h *Handler) RerunMyAnalysis(c *gin.Context) {
+	rerun, err := h.evaluationService.Rerun(c.Request.Context(), currentUser(c).ID, c.Param("id"))
+	if err != nil {
+		analysisError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rerun)
+}
+
+// DeleteMyAnalysis 删除评价历史中的一条记录
+// DELETE /api/v1/me/analyses/:id
+func (h *Handler) DeleteMyAnalysis(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.evaluationService.DeleteUserAnalysis(c.Request.Context(), currentUser(c).ID, id); err != nil {
+		analysisError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deleted": id,
+	})
+}
+
+// userError 将用户服务的错误映射为 HTTP 响应
+func userError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidUserRequest):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+	case errors.Is(err, service.ErrInvalidCredentials):
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "invalid username or password",
+		})
+	case errors.Is(err, service.ErrRegistrationClosed):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "registration closed",
+		})
+	case errors.Is(err, service.ErrUserExists):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "username already taken",
+		})
+	case errors.Is(err, service.ErrPlaceExists):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "place name already used",
+		})
+	case errors.Is(err, service.ErrPlaceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "place not found",
+		})
+	default:
+		traceError(c, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "user operation failed",
+			"details": err.Error(),
+		})
+	}
+}
+
+// analysisError 将评价历史操作的错误映射为 HTTP 响应
+func analysisError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrAnalysisNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "analysis not found",
+		})
+		return
+	}
+	traceError(c, err)
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   "analysis operation failed",
+		"details": err.Error(),
+	})
+}
+
+// placeID 解析路径中的地点 ID，失败时直接返回 400
+func placeID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid place id",
+			"details": err.Error(),
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// ListRegions 城市内街道、社区按生活圈评分排名
+// GET /api/v1/regions?city=hangzhou&level=community&sort=mean&order=desc
+func (h *Handler) ListRegions(c *gin.Context) {
+	ranking, err := h.regionService.Ranking(c.Request.Context(),
+		c.Query("city"), c.Query("level"), c.Query("sort"), c.Query("order"))
+	if err != nil {
+		regionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ranking)
+}
+
+// GetRegionSummary 区域评分汇总与边界
+// GET /api/v1/regions/:id/summary
+func (h *Handler) GetRegionSummary(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid region id",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	summary, err := h.regionService.Summary(c.Request.Context(), id)
+	if err != nil {
+		regionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// RescoreRegions 按当前评价标准重新计算城市样点评分与区域汇总（不做路网分析）
+// POST /api/v1/admin/regions/rescore
+func (h *Handler) RescoreRegions(c *gin.Context) {
+	var req struct {
+		City      string  `json:"city" binding:"required"`
+		WalkSpeed float64 `json:"walk_speed"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+	if req.WalkSpeed == 0 {
+		req.WalkSpeed = model.DefaultWalkSpeed
+	}
+
+	result, err := h.regionService.Rescore(c.Request.Context(), req.City, req.WalkSpeed)
+	if err != nil {
+		regionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func regionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrRegionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "region not found",
+		})
+	case errors.Is(err, service.ErrInvalidRegion):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+	default:
+		traceError(c, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "region query failed",
+			"details": err.Error(),
+		})
+	}
+}
+
+// GetGridEquity 城市（或单个区域）格网样点评分分布的公平性分析
+// GET /api/v1/equity?city=hangzhou&level=community&region_id=12&weight=population&deserts=100&format=csv&table=metrics
+func (h *Handler) GetGridEquity(c *gin.Context) {
+	table, ok := equityExport(c)
+	if !ok {
+		return
+	}
+	var regionID *int
+	if v := c.Query("region_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid region id",
+				"details": err.Error(),
+			})
+			return
+		}
+		regionID = &id
+	}
+	deserts, err := strconv.Atoi(c.DefaultQuery("deserts", strconv.Itoa(service.DefaultDesertPoints)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid deserts",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	report, err := h.equityService.Grid(c.Request.Context(),
+		c.Query("city"), c.Query("level"), regionID, c.Query("weight"), deserts)
+	if err != nil {
+		equityError(c, err)
+		return
+	}
+
+	writeEquityReport(c, report, table)
+}
+
+// CalculatePointsEquity 指定居住点集评分分布的公平性分析
+// POST /api/v1/equity/points?format=csv&table=deserts
+func (h *Handler) CalculatePointsEquity(c *gin.Context) {
+	table, ok := equityExport(c)
+	if !ok {
+		return
+	}
+	var req model.EquityPointsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// 点数有限，列出全部盲区点
+	report, err := h.equityService.Points(c.Request.Context(), &req, len(req.Points))
+	if err != nil {
+		equityError(c, err)
+		return
+	}
+
+	writeEquityReport(c, report, table)
+}
+
+func equityError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrRegionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "region not found",
+		})
+	case errors.Is(err, service.ErrNoEquitySamples):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "no measured samples",
+			"details": "import regions and run cmd/regions compute first",
+		})
+	case errors.Is(err, service.ErrInvalidEquityRequest):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+	default:
+		traceError(c, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "equity analysis failed",
+			"details": err.Error(),
+		})
+	}
+}
+
 // Response 统一响应结构
 type Response struct {
 	Success bool        `json:"success"`
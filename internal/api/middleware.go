@@ -0,0 +1,400 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourname/15min-life-circle/internal/config"
+	"github.com/yourname/15min-life-circle/internal/logging"
+	"github.com/yourname/15min-life-circle/internal/metrics"
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/ratelimit"
+	"github.com/yourname/15min-life-circle/internal/service"
+	"github.com/yourname/15min-life-circle/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader 请求 ID 头，客户端或网关传入时沿用，否则生成
+const RequestIDHeader = "X-Request-ID"
+
+// actorKey 认证通过后操作人名称在 gin.Context 中的键
+const actorKey = "actor"
+
+// RequireToken 校验 Authorization: Bearer <token>，通过后记录操作人
+// 未配置任何令牌时拒绝所有请求
+func RequireToken(cfg config.AuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "missing bearer token",
+			})
+			return
+		}
+
+		for known, name := range cfg.Tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(known)) == 1 {
+				c.Set(actorKey, name)
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "invalid token",
+		})
+	}
+}
+
+// actor 当前请求的操作人
+func actor(c *gin.Context) string {
+	return c.GetString(actorKey)
+}
+
+// APIKeyHeader 携带 API Key 的请求头，也可使用 Authorization: ApiKey <key>
+const APIKeyHeader = "X-API-Key"
+
+// clientKey 认证通过的 API Key（*model.APIKey，匿名请求为空）在 gin.Context 中的键
+const clientKey = "api_key"
+
+// limitsKey 当前客户端生效的限额（config.TierConfig）在 gin.Context 中的键
+const limitsKey = "api_limits"
+
+// apiKey 当前请求的 API Key，匿名请求返回 nil
+func apiKey(c *gin.Context) *model.APIKey {
+	k, _ := c.Get(clientKey)
+	key, _ := k.(*model.APIKey)
+	return key
+}
+
+// clientTier 当前请求的客户端等级
+func clientTier(c *gin.Context) string {
+	if k := apiKey(c); k != nil {
+		return k.Tier
+	}
+	return model.TierAnonymous
+}
+
+// APIKeyAuthenticator APIKeyAuth 所需的 API Key 操作，由 *service.APIKeyService 实现
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, plain string) (*model.APIKey, error)
+	Limits(k *model.APIKey) config.TierConfig
+	RecordUsage(keyID int, route string, status int, rejected bool)
+}
+
+// requestAPIKey 请求携带的 API Key：X-API-Key 头优先，其次 Authorization: ApiKey <key>；
+// 其他 Authorization 方案（会话的 Session、管理令牌的 Bearer）不视为 API Key
+func requestAPIKey(c *gin.Context) string {
+	if plain := c.GetHeader(APIKeyHeader); plain != "" {
+		return plain
+	}
+	if plain, ok := strings.CutPrefix(c.GetHeader("Authorization"), "ApiKey "); ok {
+		return plain
+	}
+	return ""
+}
+
+// APIKeyAuth 认证 API Key 并按等级令牌桶限速
+// 未携带 Key 时：allowAnonymous 为 true 按客户端 IP 使用 anonymous 等级，否则返回 401。
+// 响应头 X-RateLimit-Limit（每分钟）、X-RateLimit-Remaining；超限时返回 429 与 Retry-After。
+// 携带 Key 的请求计入 api_key_usage。
+func APIKeyAuth(keys APIKeyAuthenticator, limiter *ratelimit.Limiter, allowAnonymous bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		plain := requestAPIKey(c)
+
+		var key *model.APIKey
+		bucket := "ip:" + service.ClientNetwork(c.ClientIP())
+		if plain != "" {
+			k, err := keys.Authenticate(c.Request.Context(), plain)
+			if errors.Is(err, service.ErrInvalidAPIKey) {
+				metrics.RejectRequest(model.TierAnonymous, metrics.RejectAuth)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+					"error": "invalid api key",
+				})
+				return
+			}
+			if err != nil {
+				traceError(c, err)
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+					"error":   "authentication unavailable",
+					"details": err.Error(),
+				})
+				return
+			}
+			key = k
+			bucket = "key:" + strconv.Itoa(k.ID)
+			c.Set(clientKey, k)
+			trace.SpanFromContext(c.Request.Context()).SetAttributes(
+				attribute.String("client.api_key", k.Prefix),
+				attribute.String("client.tier", k.Tier),
+			)
+		} else if !allowAnonymous {
+			metrics.RejectRequest(model.TierAnonymous, metrics.RejectAuth)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "missing api key",
+			})
+			return
+		}
+
+		limits := keys.Limits(key)
+		c.Set(limitsKey, limits)
+
+		res := limiter.Allow(bucket, limits.RatePerMinute, limits.Burst)
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limits.RatePerMinute))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		if !res.Allowed {
+			metrics.RejectRequest(clientTier(c), metrics.RejectRate)
+			c.Header("Retry-After", strconv.Itoa(int(res.RetryAfter.Seconds())+1))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error": "rate limit exceeded",
+			})
+		} else {
+			c.Next()
+		}
+
+		if key != nil {
+			// 429 包括此处的速率限制和 DailyQuota 的配额拒绝
+			status := c.Writer.Status()
+			keys.RecordUsage(key.ID, c.FullPath(), status, status == http.StatusTooManyRequests)
+		}
+	}
+}
+
+// DailyQuota 高开销接口的每日配额，需在 APIKeyAuth 之后使用
+// 每个被接受的请求计一次（无论处理结果）；响应头 X-Quota-Limit、X-Quota-Remaining，用尽时返回 429
+func DailyQuota(keys *service.APIKeyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v, _ := c.Get(limitsKey)
+		limits, _ := v.(config.TierConfig)
+		if limits.DailyQuota <= 0 {
+			c.Next()
+			return
+		}
+
+		var (
+			used int
+			ok   bool
+		)
+		if key := apiKey(c); key != nil {
+			var err error
+			used, ok, err = keys.ConsumeQuota(c.Request.Context(), key.ID, limits.DailyQuota)
+			if err != nil {
+				traceError(c, err)
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+					"error":   "quota check unavailable",
+					"details": err.Error(),
+				})
+				return
+			}
+		} else {
+			used, ok = keys.ConsumeAnonymousQuota(c.ClientIP(), limits.DailyQuota)
+		}
+
+		c.Header("X-Quota-Limit", strconv.Itoa(limits.DailyQuota))
+		c.Header("X-Quota-Remaining", strconv.Itoa(limits.DailyQuota-used))
+		if !ok {
+			metrics.RejectRequest(clientTier(c), metrics.RejectQuota)
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error": "daily quota exceeded",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// MinutesSearchQuota POI 搜索只有按步行时间（minutes）过滤时才做路网计算，此时计入每日配额，
+// 其他搜索方式直接放行
+func MinutesSearchQuota(quota gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if minutes, _ := strconv.Atoi(c.Query("minutes")); minutes <= 0 {
+			c.Next()
+			return
+		}
+		quota(c)
+	}
+}
+
+// SessionCookie 登录会话 Cookie
+const SessionCookie = "lc_session"
+
+// SessionHeader 脚本携带会话令牌的请求头，也可使用 Authorization: Session <token>；
+// Authorization: Bearer 留给管理令牌（RequireToken）
+const SessionHeader = "X-Session-Token"
+
+// userKey 登录用户（*model.User）在 gin.Context 中的键
+const userKey = "user"
+
+// sessionToken 请求携带的会话令牌：X-Session-Token 头优先，其次 Authorization: Session <token>，最后 Cookie
+func sessionToken(c *gin.Context) string {
+	if token := c.GetHeader(SessionHeader); token != "" {
+		return token
+	}
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Session "); ok && token != "" {
+		return token
+	}
+	token, _ := c.Cookie(SessionCookie)
+	return token
+}
+
+// currentUser 当前登录用户，未登录返回 nil
+func currentUser(c *gin.Context) *model.User {
+	v, _ := c.Get(userKey)
+	u, _ := v.(*model.User)
+	return u
+}
+
+// OptionalUser 携带会话令牌时认证用户，未携带时按匿名继续
+// 令牌无效时返回 401，而不是静默按匿名处理（否则评价不会计入用户历史）
+func OptionalUser(users *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if sessionToken(c) == "" {
+			c.Next()
+			return
+		}
+		authenticateUser(c, users)
+	}
+}
+
+// RequireUser 要求登录
+func RequireUser(users *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if sessionToken(c) == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "login required",
+			})
+			return
+		}
+		authenticateUser(c, users)
+	}
+}
+
+func authenticateUser(c *gin.Context, users *service.UserService) {
+	u, err := users.Authenticate(c.Request.Context(), sessionToken(c))
+	if errors.Is(err, service.ErrInvalidSession) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "invalid or expired session",
+		})
+		return
+	}
+	if err != nil {
+		traceError(c, err)
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "authentication unavailable",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.Set(userKey, u)
+	trace.SpanFromContext(c.Request.Context()).SetAttributes(attribute.Int("user.id", u.ID))
+	c.Next()
+}
+
+// RequestLogger 分配请求 ID，记录结构化访问日志和 HTTP 指标
+// 请求 ID 写入响应头和请求 context，服务层用 slog.*Context 记录的日志自动带上
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > 64 {
+			id = newRequestID()
+		}
+		c.Header(RequestIDHeader, id)
+		ctx := logging.WithRequestID(c.Request.Context(), id)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		// 未匹配路由统一归为一类，避免路径作为指标标签导致基数膨胀
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := c.Writer.Status()
+		elapsed := time.Since(start)
+		metrics.ObserveHTTP(c.Request.Method, route, strconv.Itoa(status), elapsed)
+
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", route),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(elapsed.Microseconds())/1000),
+			slog.String("client_ip", c.ClientIP()),
+			slog.Int("bytes", c.Writer.Size()),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+		slog.LogAttrs(ctx, level, "request", attrs...)
+	}
+}
+
+// Tracing 为每个请求创建服务端 span，沿用上游 traceparent
+// span 以路由模板命名，服务层和 SQL 的 span 挂在其下
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		switch route {
+		case "/metrics":
+			// Prometheus 抓取不记录链路
+			c.Next()
+			return
+		case "":
+			route = "unmatched"
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := tracing.StartKind(ctx, trace.SpanKindServer, c.Request.Method+" "+route,
+			attribute.String("http.request.method", c.Request.Method),
+			attribute.String("http.route", route),
+			attribute.String("url.path", c.Request.URL.Path),
+			attribute.String("client.address", c.ClientIP()),
+		)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+// traceError 将处理失败的原因记录到当前请求的 span
+func traceError(c *gin.Context, err error) {
+	trace.SpanFromContext(c.Request.Context()).RecordError(err)
+}
+
+// newRequestID 生成 16 字节随机请求 ID
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourname/15min-life-circle/internal/config"
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/ratelimit"
+	"github.com/yourname/15min-life-circle/internal/service"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// fakeKeys 只认识 "lc_valid" 的 API Key 存储
+type fakeKeys struct {
+	seen []string
+}
+
+func (f *fakeKeys) Authenticate(_ context.Context, plain string) (*model.APIKey, error) {
+	f.seen = append(f.seen, plain)
+	if plain != "lc_valid" {
+		return nil, service.ErrInvalidAPIKey
+	}
+	return &model.APIKey{ID: 1, Prefix: "valid", Tier: model.TierPublic}, nil
+}
+
+func (f *fakeKeys) Limits(k *model.APIKey) config.TierConfig {
+	return config.TierConfig{RatePerMinute: 60, Burst: 10}
+}
+
+func (f *fakeKeys) RecordUsage(int, string, int, bool) {}
+
+func TestAPIKeyAuth(t *testing.T) {
+	tests := []struct {
+		name           string
+		header         string
+		value          string
+		allowAnonymous bool
+		wantStatus     int
+		wantTier       string
+		// 传给 Authenticate 的 Key，为空表示未查询
+		wantLookup string
+	}{
+		{"bearer token is not an api key", "Authorization", "Bearer secret", true, http.StatusOK, model.TierAnonymous, ""},
+		{"bearer without anonymous", "Authorization", "Bearer secret", false, http.StatusUnauthorized, "", ""},
+		{"session is not an api key", "Authorization", "Session lcs_abc", true, http.StatusOK, model.TierAnonymous, ""},
+		{"authorization api key", "Authorization", "ApiKey lc_valid", false, http.StatusOK, model.TierPublic, "lc_valid"},
+		{"x-api-key header", "X-API-Key", "lc_valid", false, http.StatusOK, model.TierPublic, "lc_valid"},
+		{"unknown api key", "X-API-Key", "lc_unknown", true, http.StatusUnauthorized, "", "lc_unknown"},
+		{"no credentials", "", "", true, http.StatusOK, model.TierAnonymous, ""},
+		{"no credentials without anonymous", "", "", false, http.StatusUnauthorized, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := &fakeKeys{}
+			var tier string
+			r := gin.New()
+			r.GET("/", APIKeyAuth(keys, ratelimit.New(), tt.allowAnonymous), func(c *gin.Context) {
+				tier = clientTier(c)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body)
+			}
+			if tier != tt.wantTier {
+				t.Errorf("tier = %q, want %q", tier, tt.wantTier)
+			}
+			switch {
+			case tt.wantLookup == "" && len(keys.seen) > 0:
+				t.Errorf("unexpected key lookup %q", keys.seen)
+			case tt.wantLookup != "" && (len(keys.seen) != 1 || keys.seen[0] != tt.wantLookup):
+				t.Errorf("lookups = %q, want [%q]", keys.seen, tt.wantLookup)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuthRateLimit(t *testing.T) {
+	r := gin.New()
+	// 与服务端默认配置一致：不采信任何代理
+	if err := r.SetTrustedProxies(nil); err != nil {
+		t.Fatal(err)
+	}
+	r.GET("/", APIKeyAuth(&fakeKeys{}, ratelimit.New(), true), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	// 匿名 burst 为 10：第 11 个请求被拒绝，伪造 X-Forwarded-For 不能换桶
+	for i := 1; i <= 11; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Forwarded-For", "203.0.113."+strconv.Itoa(i))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		want := http.StatusOK
+		if i == 11 {
+			want = http.StatusTooManyRequests
+		}
+		if w.Code != want {
+			t.Fatalf("request %d: status = %d, want %d", i, w.Code, want)
+		}
+	}
+}
+
+func TestRequireToken(t *testing.T) {
+	cfg := config.AuthConfig{Tokens: map[string]string{"secret": "ops"}}
+	tests := []struct {
+		name       string
+		auth       string
+		wantStatus int
+	}{
+		{"valid", "Bearer secret", http.StatusOK},
+		{"wrong token", "Bearer other", http.StatusUnauthorized},
+		{"api key scheme", "ApiKey secret", http.StatusUnauthorized},
+		{"session scheme", "Session secret", http.StatusUnauthorized},
+		{"missing", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var who string
+			r := gin.New()
+			r.GET("/", RequireToken(cfg), func(c *gin.Context) {
+				who = actor(c)
+				c.Status(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && who != "ops" {
+				t.Errorf("actor = %q, want ops", who)
+			}
+		})
+	}
+}
+
+func TestMinutesSearchQuota(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		wantQuota bool
+	}{
+		{"minutes", "?minutes=15&lng=116.4&lat=39.9", true},
+		{"radius", "?radius=500&lng=116.4&lat=39.9", false},
+		{"zero minutes", "?minutes=0", false},
+		{"invalid minutes", "?minutes=abc", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var counted, handled bool
+			quota := func(c *gin.Context) {
+				counted = true
+				c.Next()
+			}
+			r := gin.New()
+			r.GET("/", MinutesSearchQuota(quota), func(c *gin.Context) {
+				handled = true
+				c.Status(http.StatusOK)
+			})
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+tt.query, nil))
+
+			if counted != tt.wantQuota {
+				t.Errorf("quota counted = %v, want %v", counted, tt.wantQuota)
+			}
+			if !handled || w.Code != http.StatusOK {
+				t.Errorf("handled = %v, status = %d", handled, w.Code)
+			}
+		})
+	}
+}
+
+func TestSessionToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		cookie string
+		want   string
+	}{
+		{"session header", SessionHeader, "lcs_header", "", "lcs_header"},
+		{"authorization session", "Authorization", "Session lcs_auth", "", "lcs_auth"},
+		{"cookie", "", "", "lcs_cookie", "lcs_cookie"},
+		{"header over cookie", SessionHeader, "lcs_header", "lcs_cookie", "lcs_header"},
+		{"admin bearer is not a session", "Authorization", "Bearer secret", "", ""},
+		{"api key is not a session", "Authorization", "ApiKey lc_valid", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			r := gin.New()
+			r.GET("/", func(c *gin.Context) {
+				got = sessionToken(c)
+				c.Status(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: SessionCookie, Value: tt.cookie})
+			}
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("sessionToken = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
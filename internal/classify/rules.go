@@ -0,0 +1,279 @@
+// Package classify 基于标签表达式的 POI 分类规则引擎
+//
+// 规则存储在数据库 poi_rule 表中，由导入器和服务层共同加载，
+// 取代 003_import_osm_poi.sql 中的 CASE 语句和 poi_sub_type.osm_tags。
+//
+// 表达式由若干条件以 & 连接，全部满足才匹配：
+//
+//	amenity=school                 键等于值
+//	amenity=clinic|doctors         键等于任一值
+//	amenity!=parking               键不等于值（键不存在也满足）
+//	shop=*                         键存在
+//	!disused                       键不存在
+//	name~小学|小学校               值匹配正则
+//
+// 多条规则同时匹配时，priority 高者优先，相同时 id 小者优先。
+package classify
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/yourname/15min-life-circle/internal/model"
+)
+
+// ErrUncovered 必备设施的子类型没有任何规则产生
+var ErrUncovered = errors.New("required sub_type has no classification rule")
+
+// op 条件运算符
+type op int
+
+const (
+	opEq op = iota
+	opNe
+	opExists
+	opAbsent
+	opMatch
+)
+
+// condition 单个标签条件
+type condition struct {
+	key    string
+	op     op
+	values []string
+	re     *regexp.Regexp
+}
+
+func (c condition) eval(tags map[string]string) bool {
+	v, ok := tags[c.key]
+	switch c.op {
+	case opExists:
+		return ok
+	case opAbsent:
+		return !ok
+	case opEq:
+		return ok && contains(c.values, v)
+	case opNe:
+		return !ok || !contains(c.values, v)
+	case opMatch:
+		return ok && c.re.MatchString(v)
+	}
+	return false
+}
+
+// positive 条件是否要求键存在（用于快速过滤）
+func (c condition) positive() bool {
+	return c.op == opEq || c.op == opExists || c.op == opMatch
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Rule 分类规则
+type Rule struct {
+	ID         int64  `json:"id"`
+	Expression string `json:"expression"`
+	Category   string `json:"category"`
+	SubType    string `json:"sub_type"`
+	Priority   int    `json:"priority"`
+
+	conds []condition
+}
+
+// Compile 解析规则表达式
+func (r *Rule) Compile() error {
+	conds, err := parseExpression(r.Expression)
+	if err != nil {
+		return fmt.Errorf("rule %d %q: %w", r.ID, r.Expression, err)
+	}
+	if r.Category == "" || r.SubType == "" {
+		return fmt.Errorf("rule %d %q: category and sub_type are required", r.ID, r.Expression)
+	}
+	r.conds = conds
+	return nil
+}
+
+// Match 判断标签是否满足规则
+func (r *Rule) Match(tags map[string]string) bool {
+	for _, c := range r.conds {
+		if !c.eval(tags) {
+			return false
+		}
+	}
+	return len(r.conds) > 0
+}
+
+// parseExpression 解析 & 连接的条件列表
+func parseExpression(expr string) ([]condition, error) {
+	var (
+		conds    []condition
+		positive bool
+	)
+	for _, term := range strings.Split(expr, "&") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			return nil, fmt.Errorf("empty condition")
+		}
+		c, err := parseCondition(term)
+		if err != nil {
+			return nil, err
+		}
+		positive = positive || c.positive()
+		conds = append(conds, c)
+	}
+	if !positive {
+		return nil, fmt.Errorf("at least one condition must require a tag")
+	}
+	return conds, nil
+}
+
+// parseCondition 按第一个运算符切分键和值，值中可以包含 = 或 ~（如 name=a~b）
+func parseCondition(term string) (condition, error) {
+	i := strings.IndexAny(term, "=~")
+	if i < 0 {
+		if key := strings.TrimSpace(strings.TrimPrefix(term, "!")); key != "" && key != term {
+			return condition{key: key, op: opAbsent}, nil
+		}
+		return condition{}, fmt.Errorf("invalid condition %q", term)
+	}
+
+	c := condition{key: term[:i]}
+	value := strings.TrimSpace(term[i+1:])
+	switch {
+	case term[i] == '~':
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return condition{}, fmt.Errorf("invalid pattern %q: %w", value, err)
+		}
+		c.op, c.re = opMatch, re
+	case strings.HasSuffix(c.key, "!"):
+		c.key = strings.TrimSuffix(c.key, "!")
+		c.op, c.values = opNe, splitValues(value)
+	case value == "*":
+		c.op = opExists
+	default:
+		c.op, c.values = opEq, splitValues(value)
+	}
+	if c.key = strings.TrimSpace(c.key); c.key == "" {
+		return condition{}, fmt.Errorf("invalid condition %q", term)
+	}
+	return c, nil
+}
+
+func splitValues(s string) []string {
+	parts := strings.Split(s, "|")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return parts
+}
+
+// RuleSet 按优先级排序的规则集合
+type RuleSet struct {
+	rules []*Rule
+	keys  map[string]bool
+}
+
+// NewRuleSet 编译并排序规则
+func NewRuleSet(rules []Rule) (*RuleSet, error) {
+	rs := &RuleSet{keys: make(map[string]bool)}
+	for i := range rules {
+		r := rules[i]
+		if err := r.Compile(); err != nil {
+			return nil, err
+		}
+		for _, c := range r.conds {
+			if c.positive() {
+				rs.keys[c.key] = true
+			}
+		}
+		rs.rules = append(rs.rules, &r)
+	}
+	sort.SliceStable(rs.rules, func(i, j int) bool {
+		if rs.rules[i].Priority != rs.rules[j].Priority {
+			return rs.rules[i].Priority > rs.rules[j].Priority
+		}
+		return rs.rules[i].ID < rs.rules[j].ID
+	})
+	return rs, nil
+}
+
+// Rules 返回排序后的规则
+func (rs *RuleSet) Rules() []*Rule {
+	return rs.rules
+}
+
+// Classify 返回第一条（优先级最高的）匹配规则
+func (rs *RuleSet) Classify(tags map[string]string) (*Rule, bool) {
+	for _, r := range rs.rules {
+		if r.Match(tags) {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// MatchAll 返回所有匹配规则（按优先级），用于试运行时排查冲突
+func (rs *RuleSet) MatchAll(tags map[string]string) []*Rule {
+	var matched []*Rule
+	for _, r := range rs.rules {
+		if r.Match(tags) {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+// HasKey 是否有规则要求该标签键存在（用于导入时快速跳过无关要素）
+func (rs *RuleSet) HasKey(key string) bool {
+	return rs.keys[key]
+}
+
+// Keys 规则要求存在的全部标签键（已排序），候选要素至少带有其中一个
+func (rs *RuleSet) Keys() []string {
+	keys := make([]string, 0, len(rs.keys))
+	for k := range rs.keys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Produces 规则集能产生的所有子类型及其分类（同一子类型以优先级最高的规则为准）
+func (rs *RuleSet) Produces() map[string]string {
+	produced := make(map[string]string)
+	for _, r := range rs.rules {
+		if _, ok := produced[r.SubType]; !ok {
+			produced[r.SubType] = r.Category
+		}
+	}
+	return produced
+}
+
+// CheckStandards 确认每项必备设施的子类型都能由规则产生，且分类一致；
+// 否则该设施在所有位置都会被判为缺失
+func (rs *RuleSet) CheckStandards(standards []model.EvaluationStandard) error {
+	produced := rs.Produces()
+	var missing []string
+	for _, std := range standards {
+		if !std.Required {
+			continue
+		}
+		if category, ok := produced[std.SubType]; !ok || category != std.Category {
+			missing = append(missing, std.Category+"/"+std.SubType)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrUncovered, strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package classify
+
+import (
+	"errors"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/migrations"
+)
+
+func TestRuleMatch(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		tags map[string]string
+		want bool
+	}{
+		{"equal", "amenity=school", map[string]string{"amenity": "school"}, true},
+		{"equal other value", "amenity=school", map[string]string{"amenity": "college"}, false},
+		{"any of values", "amenity=clinic|doctors", map[string]string{"amenity": "doctors"}, true},
+		{"spaces around operator", " amenity = clinic | doctors ", map[string]string{"amenity": "doctors"}, true},
+		{"not equal", "amenity=parking & access!=private", map[string]string{"amenity": "parking", "access": "yes"}, true},
+		{"not equal matches", "amenity=parking & access!=private", map[string]string{"amenity": "parking", "access": "private"}, false},
+		{"not equal missing key", "amenity=parking & access!=private", map[string]string{"amenity": "parking"}, true},
+		{"exists", "shop=*", map[string]string{"shop": "bakery"}, true},
+		{"exists missing", "shop=*", map[string]string{"amenity": "cafe"}, false},
+		{"absent", "amenity=school & !disused", map[string]string{"amenity": "school"}, true},
+		{"absent present", "amenity=school & !disused", map[string]string{"amenity": "school", "disused": "yes"}, false},
+		{"regex", "amenity=school & name~中学", map[string]string{"amenity": "school", "name": "实验中学"}, true},
+		{"regex no match", "amenity=school & name~中学", map[string]string{"amenity": "school", "name": "实验小学"}, false},
+		{"regex anchored", "isced:level~^[23]", map[string]string{"isced:level": "2;3"}, true},
+		// 运算符以第一个为准：值中的 ~ 和 = 不是运算符
+		{"tilde inside value", "name=a~b", map[string]string{"name": "a~b"}, true},
+		{"tilde inside value is not regex", "name=a~b", map[string]string{"name": "ab"}, false},
+		{"equals inside pattern", "note~k=v", map[string]string{"note": "k=v"}, true},
+		{"equals inside not-equal value", "shop=* & note!=a=b", map[string]string{"shop": "kiosk", "note": "a=b"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Rule{ID: 1, Expression: tt.expr, Category: "c", SubType: "s"}
+			if err := r.Compile(); err != nil {
+				t.Fatal(err)
+			}
+			if got := r.Match(tt.tags); got != tt.want {
+				t.Errorf("Match(%v) = %v, want %v", tt.tags, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleCompileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{"empty", Rule{Expression: "", Category: "c", SubType: "s"}},
+		{"empty condition", Rule{Expression: "amenity=school &", Category: "c", SubType: "s"}},
+		{"no operator", Rule{Expression: "amenity", Category: "c", SubType: "s"}},
+		{"missing key", Rule{Expression: "=school", Category: "c", SubType: "s"}},
+		{"bare negation", Rule{Expression: "amenity=school & !", Category: "c", SubType: "s"}},
+		{"bad regex", Rule{Expression: "name~(", Category: "c", SubType: "s"}},
+		{"only negative conditions", Rule{Expression: "!disused & access!=private", Category: "c", SubType: "s"}},
+		{"missing sub_type", Rule{Expression: "amenity=school", Category: "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.Compile(); err == nil {
+				t.Errorf("Compile(%q) succeeded", tt.rule.Expression)
+			}
+		})
+	}
+}
+
+func TestRuleSetClassify(t *testing.T) {
+	rs, err := NewRuleSet([]Rule{
+		{ID: 1, Expression: "amenity=school", Category: "education", SubType: "primary", Priority: 10},
+		{ID: 2, Expression: "amenity=school & name~中学", Category: "education", SubType: "secondary", Priority: 20},
+		{ID: 3, Expression: "amenity=school|college", Category: "education", SubType: "other", Priority: 10},
+		{ID: 4, Expression: "shop=supermarket", Category: "commerce", SubType: "supermarket", Priority: 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		tags    map[string]string
+		want    string
+		matches int
+	}{
+		{"higher priority wins", map[string]string{"amenity": "school", "name": "第一中学"}, "secondary", 3},
+		{"same priority lower id wins", map[string]string{"amenity": "school"}, "primary", 2},
+		{"single match", map[string]string{"amenity": "college"}, "other", 1},
+		{"no match", map[string]string{"amenity": "bench"}, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			if r, ok := rs.Classify(tt.tags); ok {
+				got = r.SubType
+			}
+			if got != tt.want {
+				t.Errorf("Classify = %q, want %q", got, tt.want)
+			}
+			if n := len(rs.MatchAll(tt.tags)); n != tt.matches {
+				t.Errorf("MatchAll = %d rules, want %d", n, tt.matches)
+			}
+		})
+	}
+
+	if keys := rs.Keys(); len(keys) != 3 || keys[0] != "amenity" || keys[1] != "name" || keys[2] != "shop" {
+		t.Errorf("Keys = %v", keys)
+	}
+}
+
+func TestCheckStandards(t *testing.T) {
+	rs, err := NewRuleSet([]Rule{
+		{ID: 1, Expression: "amenity=school", Category: "education", SubType: "primary"},
+		{ID: 2, Expression: "amenity=clinic", Category: "medical", SubType: "community_health"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		standards []model.EvaluationStandard
+		wantErr   bool
+	}{
+		{"covered", []model.EvaluationStandard{
+			{Category: "education", SubType: "primary", Required: true},
+			{Category: "medical", SubType: "community_health", Required: true},
+		}, false},
+		{"optional standard without rule", []model.EvaluationStandard{
+			{Category: "education", SubType: "primary", Required: true},
+			{Category: "commerce", SubType: "restaurant"},
+		}, false},
+		{"required standard without rule", []model.EvaluationStandard{
+			{Category: "medical", SubType: "clinic", Required: true},
+		}, true},
+		{"rule in another category", []model.EvaluationStandard{
+			{Category: "public", SubType: "primary", Required: true},
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := rs.CheckStandards(tt.standards)
+			if tt.wantErr != errors.Is(err, ErrUncovered) {
+				t.Errorf("CheckStandards = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// seedRule 010_poi_rules.sql 初始规则中的一行
+var seedRule = regexp.MustCompile(`(?m)^\s*\('((?:[^']|'')*)',\s*'(\w+)',\s*'(\w+)',\s*(\d+)`)
+
+// TestSeedRules 初始规则可以编译，覆盖内置评价标准的全部必备设施，且子类型属于内置分类体系
+func TestSeedRules(t *testing.T) {
+	sql, err := fs.ReadFile(migrations.FS, "010_poi_rules.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rules []Rule
+	for i, m := range seedRule.FindAllStringSubmatch(string(sql), -1) {
+		priority, _ := strconv.Atoi(m[4])
+		rules = append(rules, Rule{ID: int64(i + 1), Expression: m[1], Category: m[2], SubType: m[3], Priority: priority})
+	}
+	if len(rules) == 0 {
+		t.Fatal("no seed rules found")
+	}
+	rs, err := NewRuleSet(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rs.CheckStandards(model.GetDefaultStandards()); err != nil {
+		t.Error(err)
+	}
+
+	categories := make(map[string]string)
+	for _, c := range model.GetDefaultCategories() {
+		for _, st := range c.SubTypes {
+			categories[st.Code] = c.Code
+		}
+	}
+	for subType, category := range rs.Produces() {
+		if categories[subType] != category {
+			t.Errorf("rule sub_type %s/%s is not in the default categories", category, subType)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package classify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yourname/15min-life-circle/internal/database"
+)
+
+// Load 从 poi_rule 表加载已启用的规则
+func Load(ctx context.Context, db *database.DB) (*RuleSet, error) {
+	query := `
+		SELECT id, expression, category, sub_type, priority
+		FROM poi_rule
+		WHERE enabled
+		ORDER BY priority DESC, id
+	`
+
+	rows, err := db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("load rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []Rule
+	for rows.Next() {
+		var r Rule
+		if err := rows.Scan(&r.ID, &r.Expression, &r.Category, &r.SubType, &r.Priority); err != nil {
+			return nil, fmt.Errorf("scan rule: %w", err)
+		}
+		rules = append(rules, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no enabled rules in poi_rule")
+	}
+
+	return NewRuleSet(rules)
+}
@@ -1,35 +1,194 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
 )
 
 // Config 应用配置
+//
+// 加载顺序：默认值 -> 配置文件（YAML / TOML，可选）-> 环境变量 -> 校验。
+// 代码中不包含任何密钥默认值，数据库密码、高德 Key、API 令牌须由配置文件或环境变量提供。
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Amap     AmapConfig
+	Server     ServerConfig          `yaml:"server" toml:"server"`
+	Database   DatabaseConfig        `yaml:"database" toml:"database"`
+	Amap       AmapConfig            `yaml:"amap" toml:"amap"`
+	Auth       AuthConfig            `yaml:"auth" toml:"auth"`
+	Tracing    TracingConfig         `yaml:"tracing" toml:"tracing"`
+	Evaluation EvaluationConfig      `yaml:"evaluation" toml:"evaluation"`
+	Cities     map[string]CityConfig `yaml:"cities" toml:"cities"`
+
+	// 实际读取的配置文件，未使用配置文件时为空
+	File string `yaml:"-" toml:"-"`
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
-	Addr string
+	Addr string `yaml:"addr" toml:"addr"`
+	// 日志级别：debug / info / warn / error
+	LogLevel     string   `yaml:"log_level" toml:"log_level"`
+	ReadTimeout  Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout Duration `yaml:"write_timeout" toml:"write_timeout"`
+	// 优雅关闭时等待进行中请求的时长
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// 可信反向代理（IP 或 CIDR），只有来自这些地址的请求才采用 X-Forwarded-For 中的客户端 IP；
+	// 为空时以连接地址作为客户端 IP（匿名限速与配额按此计）
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies"`
 }
 
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
+	Host     string `yaml:"host" toml:"host"`
+	Port     string `yaml:"port" toml:"port"`
+	User     string `yaml:"user" toml:"user"`
+	Password string `yaml:"password" toml:"password"`
+	DBName   string `yaml:"dbname" toml:"dbname"`
+	SSLMode  string `yaml:"sslmode" toml:"sslmode"`
+	// 连接池大小
+	MaxConns int32 `yaml:"max_conns" toml:"max_conns"`
+	MinConns int32 `yaml:"min_conns" toml:"min_conns"`
+	// 连接最长存活时间，0 表示使用 pgx 默认值（1 小时）
+	MaxConnLifetime Duration `yaml:"max_conn_lifetime" toml:"max_conn_lifetime"`
+	// 建立连接池（含首次 Ping）的超时
+	ConnectTimeout Duration `yaml:"connect_timeout" toml:"connect_timeout"`
 }
 
 // AmapConfig 高德地图API配置
 type AmapConfig struct {
-	Key     string
-	Enabled bool
+	Key string `yaml:"key" toml:"key"`
+	// 配置了 Key 时启用
+	Enabled bool `yaml:"-" toml:"-"`
+	// 单次请求超时
+	Timeout Duration `yaml:"timeout" toml:"timeout"`
+	// 本进程每日调用上限（按本地日期计），0 表示不限制
+	DailyQuota int `yaml:"daily_quota" toml:"daily_quota"`
+}
+
+// AuthConfig 认证配置
+type AuthConfig struct {
+	// 管理令牌（POI 维护、API Key 管理）：令牌 -> 操作人名称，用于审计记录
+	Tokens map[string]string `yaml:"tokens" toml:"tokens"`
+	// 未携带 API Key 的请求按客户端 IP 使用 anonymous 等级；false 时返回 401
+	AllowAnonymous bool `yaml:"allow_anonymous" toml:"allow_anonymous"`
+	// 各等级默认限额，API Key 可单独覆盖
+	Tiers TiersConfig `yaml:"tiers" toml:"tiers"`
+	// 用户登录会话有效期
+	SessionTTL Duration `yaml:"session_ttl" toml:"session_ttl"`
+	// 是否开放用户注册；false 时已有账号仍可登录
+	AllowRegistration bool `yaml:"allow_registration" toml:"allow_registration"`
+}
+
+// TiersConfig 客户端等级
+type TiersConfig struct {
+	// 未携带 API Key 的请求（按 IP）
+	Anonymous TierConfig `yaml:"anonymous" toml:"anonymous"`
+	// 外部客户端
+	Public TierConfig `yaml:"public" toml:"public"`
+	// 内部系统
+	Internal TierConfig `yaml:"internal" toml:"internal"`
+}
+
+// Tier 按名称取等级限额
+func (t TiersConfig) Tier(name string) (TierConfig, bool) {
+	switch name {
+	case "anonymous":
+		return t.Anonymous, true
+	case "public":
+		return t.Public, true
+	case "internal":
+		return t.Internal, true
+	}
+	return TierConfig{}, false
+}
+
+// TierConfig 等级限额
+type TierConfig struct {
+	// 令牌桶：每分钟补充的请求数与桶容量
+	RatePerMinute int `yaml:"rate_per_minute" toml:"rate_per_minute"`
+	Burst         int `yaml:"burst" toml:"burst"`
+	// 高开销接口（analyze、household、catchment）每日次数，0 表示不限制
+	DailyQuota int `yaml:"daily_quota" toml:"daily_quota"`
+}
+
+// TracingConfig 链路追踪配置
+type TracingConfig struct {
+	// 导出方式：none / stdout / otlp
+	Exporter string `yaml:"exporter" toml:"exporter"`
+	// OTLP/HTTP 采集器地址，如 http://localhost:4318
+	Endpoint    string `yaml:"endpoint" toml:"endpoint"`
+	ServiceName string `yaml:"service_name" toml:"service_name"`
+	// 采样比例 0~1，上游已采样的请求始终跟随上游
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+}
+
+// 评价标准来源
+const (
+	// ProfileDatabase 使用 evaluation_standard 表，表为空或不可用时退回内置标准
+	ProfileDatabase = "database"
+	// ProfileBuiltin 始终使用内置标准（model.GetDefaultStandards）
+	ProfileBuiltin = "builtin"
+)
+
+// EvaluationConfig 评价配置
+type EvaluationConfig struct {
+	// 默认评价标准来源：database / builtin
+	Profile string `yaml:"profile" toml:"profile"`
+	// 启用的出行方式（walk / bike），服务范围与家庭等时圈只接受其中的方式
+	Modes []string `yaml:"modes" toml:"modes"`
+}
+
+// ModeEnabled 出行方式是否启用；空字符串按步行处理
+func (c EvaluationConfig) ModeEnabled(mode string) bool {
+	if mode == "" {
+		mode = "walk"
+	}
+	for _, m := range c.Modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// CityConfig 城市数据配置，键为城市代码（与导入器 -city 一致）
+type CityConfig struct {
+	// 显示名称
+	Name string `yaml:"name" toml:"name"`
+	// OSM PBF 文件
+	File string `yaml:"file" toml:"file"`
+	// GeoTIFF 高程文件（可选）
+	DEM string `yaml:"dem" toml:"dem"`
+}
+
+// Duration 配置文件中的时长，写作 "10s"、"1m30s"
+type Duration time.Duration
+
+// D 转为 time.Duration
+func (d Duration) D() time.Duration { return time.Duration(d) }
+
+// String 格式化为 "10s"
+func (d Duration) String() string { return time.Duration(d).String() }
+
+// MarshalText 实现 encoding.TextMarshaler
+func (d Duration) MarshalText() ([]byte, error) { return []byte(d.String()), nil }
+
+// UnmarshalText 实现 encoding.TextUnmarshaler
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
 }
 
 // DSN 返回数据库连接字符串
@@ -42,31 +201,114 @@ func (c DatabaseConfig) DSN() string {
 		" sslmode=" + c.SSLMode
 }
 
-// Load 加载配置（从环境变量）
-func Load() (*Config, error) {
-	amapKey := getEnv("AMAP_KEY", "b8c46da854c65a844724a50cbaa9ca54")
+// defaultFiles 未设置 CONFIG_FILE 时依次查找的配置文件
+var defaultFiles = []string{"config.yaml", "config.yml", "config.toml"}
+
+// Default 默认配置（不含任何密钥）
+func Default() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Addr: getEnv("SERVER_ADDR", ":8080"),
+			Addr:            ":8080",
+			LogLevel:        "info",
+			ReadTimeout:     Duration(15 * time.Second),
+			WriteTimeout:    Duration(60 * time.Second),
+			ShutdownTimeout: Duration(5 * time.Second),
 		},
 		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "5432"),
-			User:     getEnv("DB_USER", "postgres"),
-			Password: getEnv("DB_PASSWORD", "postgres"),
-			DBName:   getEnv("DB_NAME", "life_circle_15min"),
-			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+			Host:           "localhost",
+			Port:           "5432",
+			User:           "postgres",
+			DBName:         "life_circle_15min",
+			SSLMode:        "disable",
+			MaxConns:       10,
+			MinConns:       2,
+			ConnectTimeout: Duration(10 * time.Second),
 		},
 		Amap: AmapConfig{
-			Key:     amapKey,
-			Enabled: amapKey != "",
+			Timeout: Duration(10 * time.Second),
+		},
+		Auth: AuthConfig{
+			Tokens:         map[string]string{},
+			AllowAnonymous: true,
+			Tiers: TiersConfig{
+				Anonymous: TierConfig{RatePerMinute: 30, Burst: 10, DailyQuota: 50},
+				Public:    TierConfig{RatePerMinute: 60, Burst: 20, DailyQuota: 500},
+				Internal:  TierConfig{RatePerMinute: 600, Burst: 100},
+			},
+			SessionTTL:        Duration(30 * 24 * time.Hour),
+			AllowRegistration: true,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "life-circle-server",
+			SampleRatio: 1,
+		},
+		Evaluation: EvaluationConfig{
+			Profile: ProfileDatabase,
+			Modes:   []string{"walk", "bike"},
 		},
-	}, nil
+		Cities: map[string]CityConfig{},
+	}
+}
+
+// Load 加载配置：CONFIG_FILE 指定的文件，未设置时使用当前目录下的
+// config.yaml / config.yml / config.toml（均不存在则只用默认值与环境变量）
+func Load() (*Config, error) {
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		for _, f := range defaultFiles {
+			if _, err := os.Stat(f); err == nil {
+				path = f
+				break
+			}
+		}
+	}
+	return LoadFile(path)
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// LoadFile 从指定文件加载配置（path 为空时不读文件），再应用环境变量并校验
+func LoadFile(path string) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		if err := cfg.decodeFile(path); err != nil {
+			return nil, err
+		}
+		cfg.File = path
+	}
+	envErr := cfg.applyEnv()
+	cfg.Amap.Enabled = cfg.Amap.Key != ""
+	if err := errors.Join(envErr, cfg.Validate()); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// decodeFile 按扩展名解析 YAML / TOML，未知字段视为错误（通常是拼写错误）
+func (c *Config) decodeFile(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(raw))
+		dec.KnownFields(true)
+		if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+	case ".toml":
+		dec := toml.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(c); err != nil {
+			var sme *toml.StrictMissingError
+			if errors.As(err, &sme) {
+				return fmt.Errorf("parse %s: unknown field\n%s", path, sme.String())
+			}
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("config %s: unsupported format (use .yaml, .yml or .toml)", path)
 	}
-	return defaultValue
+	return nil
 }
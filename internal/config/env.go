@@ -0,0 +1,154 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envVar 一个环境变量覆盖项；值为空时不覆盖
+type envVar struct {
+	Name  string
+	Field string
+	set   func(c *Config, v string) error
+}
+
+// envVars 支持的环境变量，变量名沿用 .env.example
+var envVars = []envVar{
+	{"SERVER_ADDR", "server.addr", func(c *Config, v string) error { c.Server.Addr = v; return nil }},
+	{"LOG_LEVEL", "server.log_level", func(c *Config, v string) error { c.Server.LogLevel = v; return nil }},
+	{"SERVER_READ_TIMEOUT", "server.read_timeout", durationVar(func(c *Config) *Duration { return &c.Server.ReadTimeout })},
+	{"SERVER_WRITE_TIMEOUT", "server.write_timeout", durationVar(func(c *Config) *Duration { return &c.Server.WriteTimeout })},
+	{"SERVER_SHUTDOWN_TIMEOUT", "server.shutdown_timeout", durationVar(func(c *Config) *Duration { return &c.Server.ShutdownTimeout })},
+	{"TRUSTED_PROXIES", "server.trusted_proxies", func(c *Config, v string) error {
+		c.Server.TrustedProxies = splitList(v)
+		return nil
+	}},
+
+	{"DB_HOST", "database.host", func(c *Config, v string) error { c.Database.Host = v; return nil }},
+	{"DB_PORT", "database.port", func(c *Config, v string) error { c.Database.Port = v; return nil }},
+	{"DB_USER", "database.user", func(c *Config, v string) error { c.Database.User = v; return nil }},
+	{"DB_PASSWORD", "database.password", func(c *Config, v string) error { c.Database.Password = v; return nil }},
+	{"DB_NAME", "database.dbname", func(c *Config, v string) error { c.Database.DBName = v; return nil }},
+	{"DB_SSLMODE", "database.sslmode", func(c *Config, v string) error { c.Database.SSLMode = v; return nil }},
+	{"DB_MAX_CONNS", "database.max_conns", int32Var(func(c *Config) *int32 { return &c.Database.MaxConns })},
+	{"DB_MIN_CONNS", "database.min_conns", int32Var(func(c *Config) *int32 { return &c.Database.MinConns })},
+	{"DB_MAX_CONN_LIFETIME", "database.max_conn_lifetime", durationVar(func(c *Config) *Duration { return &c.Database.MaxConnLifetime })},
+	{"DB_CONNECT_TIMEOUT", "database.connect_timeout", durationVar(func(c *Config) *Duration { return &c.Database.ConnectTimeout })},
+
+	{"AMAP_KEY", "amap.key", func(c *Config, v string) error { c.Amap.Key = v; return nil }},
+	{"AMAP_TIMEOUT", "amap.timeout", durationVar(func(c *Config) *Duration { return &c.Amap.Timeout })},
+	{"AMAP_DAILY_QUOTA", "amap.daily_quota", func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err == nil {
+			c.Amap.DailyQuota = n
+		}
+		return err
+	}},
+
+	// 环境变量中的令牌追加到配置文件的令牌之后
+	{"API_TOKENS", "auth.tokens", func(c *Config, v string) error {
+		for token, name := range parseTokens(v) {
+			c.Auth.Tokens[token] = name
+		}
+		return nil
+	}},
+
+	{"API_ALLOW_ANONYMOUS", "auth.allow_anonymous", func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err == nil {
+			c.Auth.AllowAnonymous = b
+		}
+		return err
+	}},
+	{"SESSION_TTL", "auth.session_ttl", durationVar(func(c *Config) *Duration { return &c.Auth.SessionTTL })},
+	{"ALLOW_REGISTRATION", "auth.allow_registration", func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err == nil {
+			c.Auth.AllowRegistration = b
+		}
+		return err
+	}},
+
+	{"TRACING_EXPORTER", "tracing.exporter", func(c *Config, v string) error { c.Tracing.Exporter = v; return nil }},
+	{"OTEL_EXPORTER_OTLP_ENDPOINT", "tracing.endpoint", func(c *Config, v string) error { c.Tracing.Endpoint = v; return nil }},
+	{"OTEL_SERVICE_NAME", "tracing.service_name", func(c *Config, v string) error { c.Tracing.ServiceName = v; return nil }},
+	{"TRACING_SAMPLE_RATIO", "tracing.sample_ratio", func(c *Config, v string) error {
+		f, err := strconv.ParseFloat(v, 64)
+		if err == nil {
+			c.Tracing.SampleRatio = f
+		}
+		return err
+	}},
+
+	{"EVALUATION_PROFILE", "evaluation.profile", func(c *Config, v string) error { c.Evaluation.Profile = v; return nil }},
+	{"EVALUATION_MODES", "evaluation.modes", func(c *Config, v string) error {
+		c.Evaluation.Modes = splitList(v)
+		return nil
+	}},
+}
+
+// applyEnv 用环境变量覆盖配置，格式错误的变量一并报告
+func (c *Config) applyEnv() error {
+	if c.Auth.Tokens == nil {
+		c.Auth.Tokens = map[string]string{}
+	}
+	var errs []error
+	for _, e := range envVars {
+		v := os.Getenv(e.Name)
+		if v == "" {
+			continue
+		}
+		if err := e.set(c, v); err != nil {
+			errs = append(errs, fmt.Errorf("%s (%s): invalid value %q", e.Name, e.Field, v))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func durationVar(field func(c *Config) *Duration) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		if err == nil {
+			*field(c) = Duration(d)
+		}
+		return err
+	}
+}
+
+func int32Var(field func(c *Config) *int32) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err == nil {
+			*field(c) = int32(n)
+		}
+		return err
+	}
+}
+
+// parseTokens 解析 "name:token,name2:token2" 格式的令牌列表
+func parseTokens(s string) map[string]string {
+	tokens := make(map[string]string)
+	for _, item := range strings.Split(s, ",") {
+		name, token, ok := strings.Cut(strings.TrimSpace(item), ":")
+		if !ok || name == "" || token == "" {
+			continue
+		}
+		tokens[token] = name
+	}
+	return tokens
+}
+
+// splitList 解析逗号分隔的列表，忽略空项
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
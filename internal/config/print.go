@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// redacted 替换密钥后的占位值
+const redacted = "<redacted>"
+
+// Redacted 返回隐去密钥（数据库密码、高德 Key、API 令牌）的副本
+func (c *Config) Redacted() *Config {
+	out := *c
+	out.Database.Password = redact(c.Database.Password)
+	out.Amap.Key = redact(c.Amap.Key)
+
+	// 令牌是键，按操作人名称排序后编号，保证输出稳定
+	out.Auth.Tokens = make(map[string]string, len(c.Auth.Tokens))
+	names := slices.Sorted(maps.Values(c.Auth.Tokens))
+	for i, name := range names {
+		out.Auth.Tokens[fmt.Sprintf("%s#%d", redacted, i+1)] = name
+	}
+
+	out.Server.TrustedProxies = slices.Clone(c.Server.TrustedProxies)
+	out.Evaluation.Modes = slices.Clone(c.Evaluation.Modes)
+	out.Cities = maps.Clone(c.Cities)
+	return &out
+}
+
+func redact(s string) string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// Write 以 YAML 或 TOML 格式输出配置
+func (c *Config) Write(w io.Writer, format string) error {
+	switch format {
+	case "yaml", "yml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(c); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "toml":
+		return toml.NewEncoder(w).Encode(c)
+	default:
+		return fmt.Errorf("unsupported format %q (yaml, toml)", format)
+	}
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ValidationError 配置校验失败，Problems 为全部问题（"字段: 原因"）
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate 校验配置，返回 *ValidationError，列出所有不合法的字段
+func (c *Config) Validate() error {
+	var p []string
+	add := func(field, format string, args ...any) {
+		p = append(p, field+": "+fmt.Sprintf(format, args...))
+	}
+
+	if c.Server.Addr == "" {
+		add("server.addr", "must not be empty")
+	}
+	if !slices.Contains([]string{"debug", "info", "warn", "error"}, strings.ToLower(c.Server.LogLevel)) {
+		add("server.log_level", "%q is not one of debug, info, warn, error", c.Server.LogLevel)
+	}
+	for _, p := range c.Server.TrustedProxies {
+		if _, err := netip.ParsePrefix(p); err != nil {
+			if _, err := netip.ParseAddr(p); err != nil {
+				add("server.trusted_proxies", "%q is not an IP address or CIDR", p)
+			}
+		}
+	}
+	for field, d := range map[string]Duration{
+		"server.read_timeout":      c.Server.ReadTimeout,
+		"server.write_timeout":     c.Server.WriteTimeout,
+		"server.shutdown_timeout":  c.Server.ShutdownTimeout,
+		"database.connect_timeout": c.Database.ConnectTimeout,
+		"amap.timeout":             c.Amap.Timeout,
+	} {
+		if d <= 0 {
+			add(field, "must be positive, got %s", d)
+		}
+	}
+
+	if c.Database.Host == "" {
+		add("database.host", "must not be empty")
+	}
+	if c.Database.DBName == "" {
+		add("database.dbname", "must not be empty")
+	}
+	if !slices.Contains([]string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}, c.Database.SSLMode) {
+		add("database.sslmode", "%q is not a valid libpq sslmode", c.Database.SSLMode)
+	}
+	if c.Database.MaxConns < 1 {
+		add("database.max_conns", "must be at least 1, got %d", c.Database.MaxConns)
+	}
+	if c.Database.MinConns < 0 || c.Database.MinConns > c.Database.MaxConns {
+		add("database.min_conns", "must be between 0 and max_conns (%d), got %d", c.Database.MaxConns, c.Database.MinConns)
+	}
+	if c.Database.MaxConnLifetime < 0 {
+		add("database.max_conn_lifetime", "must not be negative")
+	}
+
+	if c.Amap.DailyQuota < 0 {
+		add("amap.daily_quota", "must not be negative")
+	}
+	for token, name := range c.Auth.Tokens {
+		if token == "" || name == "" {
+			add("auth.tokens", "token and operator name must not be empty")
+			break
+		}
+	}
+
+	for _, name := range []string{"anonymous", "public", "internal"} {
+		t, _ := c.Auth.Tiers.Tier(name)
+		field := "auth.tiers." + name
+		if t.RatePerMinute < 1 {
+			add(field+".rate_per_minute", "must be at least 1, got %d", t.RatePerMinute)
+		}
+		if t.Burst < 1 {
+			add(field+".burst", "must be at least 1, got %d", t.Burst)
+		}
+		if t.DailyQuota < 0 {
+			add(field+".daily_quota", "must not be negative")
+		}
+	}
+
+	if c.Auth.SessionTTL < Duration(time.Minute) {
+		add("auth.session_ttl", "must be at least 1m, got %s", c.Auth.SessionTTL.D())
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		add("tracing.exporter", "%q is not one of none, stdout, otlp", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		add("tracing.sample_ratio", "must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+	}
+
+	if c.Evaluation.Profile != ProfileDatabase && c.Evaluation.Profile != ProfileBuiltin {
+		add("evaluation.profile", "%q is not one of %s, %s", c.Evaluation.Profile, ProfileDatabase, ProfileBuiltin)
+	}
+	if len(c.Evaluation.Modes) == 0 {
+		add("evaluation.modes", "at least one mode must be enabled")
+	}
+	for _, m := range c.Evaluation.Modes {
+		if m != "walk" && m != "bike" {
+			add("evaluation.modes", "%q is not one of walk, bike", m)
+		}
+	}
+
+	codes := make([]string, 0, len(c.Cities))
+	for code := range c.Cities {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	for _, code := range codes {
+		if code == "" || strings.ToLower(code) != code || strings.ContainsAny(code, " \t/") {
+			add("cities."+code, "city code must be lowercase without spaces or slashes")
+		}
+	}
+
+	if len(p) == 0 {
+		return nil
+	}
+	slices.Sort(p)
+	return &ValidationError{Problems: p}
+}
@@ -3,11 +3,11 @@ package database
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/yourname/15min-life-circle/internal/config"
+	"github.com/yourname/15min-life-circle/internal/tracing"
 )
 
 // DB 数据库连接池封装
@@ -17,7 +17,7 @@ type DB struct {
 
 // Connect 建立数据库连接
 func Connect(cfg config.DatabaseConfig) (*DB, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout.D())
 	defer cancel()
 
 	poolConfig, err := pgxpool.ParseConfig(cfg.DSN())
@@ -26,8 +26,14 @@ func Connect(cfg config.DatabaseConfig) (*DB, error) {
 	}
 
 	// 连接池配置
-	poolConfig.MaxConns = 10
-	poolConfig.MinConns = 2
+	poolConfig.MaxConns = cfg.MaxConns
+	poolConfig.MinConns = cfg.MinConns
+	if cfg.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.MaxConnLifetime.D()
+	}
+
+	// 每条 SQL 生成链路追踪 span（未启用追踪时为空操作）
+	poolConfig.ConnConfig.Tracer = tracing.QueryTracer{}
 
 	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
@@ -0,0 +1,402 @@
+// Package dem 读取本地 GeoTIFF 数字高程模型（DEM）
+//
+// 只实现高程数据常用的 GeoTIFF 子集：单波段、经纬度坐标（EPSG:4326），
+// 条带或分块存储，无压缩或 Deflate 压缩（可选水平差分预测），
+// 整数或浮点采样。其他格式可先用 GDAL 转换：
+//
+//	gdal_translate -of GTiff -co COMPRESS=DEFLATE -a_srs EPSG:4326 in.tif dem.tif
+package dem
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupported GeoTIFF 使用了不支持的特性
+var ErrUnsupported = errors.New("unsupported geotiff")
+
+// TIFF / GeoTIFF 标签
+const (
+	tagImageWidth      = 256
+	tagImageLength     = 257
+	tagBitsPerSample   = 258
+	tagCompression     = 259
+	tagStripOffsets    = 273
+	tagSamplesPerPixel = 277
+	tagRowsPerStrip    = 278
+	tagStripByteCounts = 279
+	tagPredictor       = 317
+	tagTileWidth       = 322
+	tagTileLength      = 323
+	tagTileOffsets     = 324
+	tagTileByteCounts  = 325
+	tagSampleFormat    = 339
+	tagPixelScale      = 33550
+	tagTiepoint        = 33922
+	tagGeoKeyDirectory = 34735
+	tagGDALNoData      = 42113
+)
+
+// GeoKey
+const (
+	keyModelType      = 1024
+	keyGeographicType = 2048
+	keyProjectedType  = 3072
+
+	modelTypeGeographic = 2
+)
+
+// DEM 内存中的高程栅格
+type DEM struct {
+	width, height int
+	// 左上角像元左上角坐标与像元大小（度）
+	originX, originY float64
+	scaleX, scaleY   float64
+	data             []float32
+	noData           float64
+	hasNoData        bool
+}
+
+// Open 读取 GeoTIFF 文件
+func Open(path string) (*DEM, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read dem: %w", err)
+	}
+	return Decode(buf)
+}
+
+// Decode 解析 GeoTIFF 数据
+func Decode(buf []byte) (*DEM, error) {
+	if len(buf) < 8 {
+		return nil, fmt.Errorf("%w: file too short", ErrUnsupported)
+	}
+	var order binary.ByteOrder
+	switch string(buf[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("%w: not a tiff file", ErrUnsupported)
+	}
+	if order.Uint16(buf[2:]) != 42 {
+		return nil, fmt.Errorf("%w: bigtiff is not supported", ErrUnsupported)
+	}
+
+	ifd, err := readIFD(buf, order, order.Uint32(buf[4:]))
+	if err != nil {
+		return nil, err
+	}
+	return ifd.decode(buf)
+}
+
+// Elevation 双线性插值取点位高程（米），超出范围或无数据时返回 false
+func (d *DEM) Elevation(lng, lat float64) (float64, bool) {
+	// 像元中心坐标系
+	fx := (lng-d.originX)/d.scaleX - 0.5
+	fy := (d.originY-lat)/d.scaleY - 0.5
+	if fx < -0.5 || fy < -0.5 || fx > float64(d.width)-0.5 || fy > float64(d.height)-0.5 {
+		return 0, false
+	}
+
+	x0 := clamp(int(math.Floor(fx)), 0, d.width-1)
+	y0 := clamp(int(math.Floor(fy)), 0, d.height-1)
+	x1 := clamp(x0+1, 0, d.width-1)
+	y1 := clamp(y0+1, 0, d.height-1)
+	tx := math.Min(math.Max(fx-float64(x0), 0), 1)
+	ty := math.Min(math.Max(fy-float64(y0), 0), 1)
+
+	var sum, weight float64
+	for _, c := range []struct {
+		x, y int
+		w    float64
+	}{
+		{x0, y0, (1 - tx) * (1 - ty)},
+		{x1, y0, tx * (1 - ty)},
+		{x0, y1, (1 - tx) * ty},
+		{x1, y1, tx * ty},
+	} {
+		v := float64(d.data[c.y*d.width+c.x])
+		if c.w == 0 || d.isNoData(v) {
+			continue
+		}
+		sum += v * c.w
+		weight += c.w
+	}
+	// 周围像元有一半以上无数据时视为无数据
+	if weight < 0.5 {
+		return 0, false
+	}
+	return sum / weight, true
+}
+
+// Bounds 覆盖范围（最小经度、最小纬度、最大经度、最大纬度）
+func (d *DEM) Bounds() (minLng, minLat, maxLng, maxLat float64) {
+	return d.originX, d.originY - d.scaleY*float64(d.height),
+		d.originX + d.scaleX*float64(d.width), d.originY
+}
+
+func (d *DEM) isNoData(v float64) bool {
+	return math.IsNaN(v) || d.hasNoData && v == d.noData
+}
+
+func clamp(v, lo, hi int) int {
+	return min(max(v, lo), hi)
+}
+
+// ifd 第一个图像文件目录中用到的字段
+type ifd struct {
+	order  binary.ByteOrder
+	fields map[uint16][]float64
+	ascii  map[uint16]string
+}
+
+func (f *ifd) int(tag uint16, def int) int {
+	if v, ok := f.fields[tag]; ok && len(v) > 0 {
+		return int(v[0])
+	}
+	return def
+}
+
+func readIFD(buf []byte, order binary.ByteOrder, offset uint32) (*ifd, error) {
+	if uint64(offset)+2 > uint64(len(buf)) {
+		return nil, fmt.Errorf("%w: bad ifd offset", ErrUnsupported)
+	}
+	n := int(order.Uint16(buf[offset:]))
+	f := &ifd{order: order, fields: make(map[uint16][]float64), ascii: make(map[uint16]string)}
+
+	// 各字段类型的字节数
+	sizes := map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+	for i := 0; i < n; i++ {
+		e := int(offset) + 2 + i*12
+		if e+12 > len(buf) {
+			return nil, fmt.Errorf("%w: truncated ifd", ErrUnsupported)
+		}
+		tag := order.Uint16(buf[e:])
+		typ := order.Uint16(buf[e+2:])
+		size, ok := sizes[typ]
+		if !ok {
+			continue
+		}
+		// 按 64 位计算长度和偏移，避免计数或偏移过大时溢出
+		count := uint64(order.Uint32(buf[e+4:]))
+		data := buf[e+8 : e+12]
+		if n := uint64(size) * count; n > 4 {
+			off := uint64(order.Uint32(buf[e+8:]))
+			if off+n > uint64(len(buf)) {
+				return nil, fmt.Errorf("%w: tag %d out of range", ErrUnsupported, tag)
+			}
+			data = buf[off : off+n]
+		}
+
+		if typ == 2 {
+			f.ascii[tag] = strings.TrimRight(string(data[:count]), "\x00")
+			continue
+		}
+		values := make([]float64, count)
+		for j := range values {
+			p := data[j*size:]
+			switch typ {
+			case 1, 7:
+				values[j] = float64(p[0])
+			case 6:
+				values[j] = float64(int8(p[0]))
+			case 3:
+				values[j] = float64(order.Uint16(p))
+			case 8:
+				values[j] = float64(int16(order.Uint16(p)))
+			case 4:
+				values[j] = float64(order.Uint32(p))
+			case 9:
+				values[j] = float64(int32(order.Uint32(p)))
+			case 5:
+				values[j] = float64(order.Uint32(p)) / float64(order.Uint32(p[4:]))
+			case 10:
+				values[j] = float64(int32(order.Uint32(p))) / float64(int32(order.Uint32(p[4:])))
+			case 11:
+				values[j] = float64(math.Float32frombits(order.Uint32(p)))
+			case 12:
+				values[j] = math.Float64frombits(order.Uint64(p))
+			}
+		}
+		f.fields[tag] = values
+	}
+	return f, nil
+}
+
+// decode 校验地理参考并解码像元
+func (f *ifd) decode(buf []byte) (*DEM, error) {
+	width := f.int(tagImageWidth, 0)
+	height := f.int(tagImageLength, 0)
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("%w: missing image size", ErrUnsupported)
+	}
+	if f.int(tagSamplesPerPixel, 1) != 1 {
+		return nil, fmt.Errorf("%w: only single-band rasters are supported", ErrUnsupported)
+	}
+	if p := f.int(tagPredictor, 1); p != 1 && (p != 2 || f.int(tagSampleFormat, 1) == 3) {
+		return nil, fmt.Errorf("%w: predictor %d", ErrUnsupported, p)
+	}
+	if err := f.checkGeographic(); err != nil {
+		return nil, err
+	}
+
+	scale, tie := f.fields[tagPixelScale], f.fields[tagTiepoint]
+	if len(scale) < 2 || len(tie) < 6 {
+		return nil, fmt.Errorf("%w: missing ModelPixelScale/ModelTiepoint", ErrUnsupported)
+	}
+
+	d := &DEM{
+		width:   width,
+		height:  height,
+		scaleX:  scale[0],
+		scaleY:  scale[1],
+		originX: tie[3] - tie[0]*scale[0],
+		originY: tie[4] + tie[1]*scale[1],
+		data:    make([]float32, width*height),
+	}
+	if s, ok := f.ascii[tagGDALNoData]; ok {
+		if v, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			// 像元以 float32 保存，无数据值按相同精度比较
+			d.noData, d.hasNoData = float64(float32(v)), true
+		}
+	}
+
+	sample, err := f.sampleReader()
+	if err != nil {
+		return nil, err
+	}
+
+	// 条带可视为宽度等于图像宽度的分块
+	blockW, blockH := width, f.int(tagRowsPerStrip, height)
+	offsets, counts := f.fields[tagStripOffsets], f.fields[tagStripByteCounts]
+	if _, tiled := f.fields[tagTileWidth]; tiled {
+		blockW, blockH = f.int(tagTileWidth, 0), f.int(tagTileLength, 0)
+		offsets, counts = f.fields[tagTileOffsets], f.fields[tagTileByteCounts]
+	}
+	if blockW <= 0 || blockH <= 0 || len(offsets) == 0 || len(offsets) != len(counts) {
+		return nil, fmt.Errorf("%w: missing strip/tile layout", ErrUnsupported)
+	}
+
+	across := (width + blockW - 1) / blockW
+	bytesPerSample := f.int(tagBitsPerSample, 8) / 8
+	for i := range offsets {
+		off, n := int(offsets[i]), int(counts[i])
+		if off < 0 || n < 0 || off+n > len(buf) {
+			return nil, fmt.Errorf("%w: block %d out of range", ErrUnsupported, i)
+		}
+		block, err := f.decompress(buf[off : off+n])
+		if err != nil {
+			return nil, err
+		}
+		if want := blockW * blockH * bytesPerSample; len(block) < want {
+			// 最后一个条带可能不足 RowsPerStrip 行
+			block = append(block, make([]byte, want-len(block))...)
+		}
+		if f.int(tagPredictor, 1) == 2 {
+			undoPredictor(block, blockW, blockH, bytesPerSample, f.order)
+		}
+
+		bx, by := (i%across)*blockW, (i/across)*blockH
+		for y := 0; y < blockH && by+y < height; y++ {
+			for x := 0; x < blockW && bx+x < width; x++ {
+				p := (y*blockW + x) * bytesPerSample
+				d.data[(by+y)*width+bx+x] = float32(sample(block[p:]))
+			}
+		}
+	}
+	return d, nil
+}
+
+// checkGeographic 只接受经纬度坐标（EPSG:4326）
+func (f *ifd) checkGeographic() error {
+	keys := f.fields[tagGeoKeyDirectory]
+	if len(keys) < 4 {
+		// 缺少 GeoKey 时按经纬度处理
+		return nil
+	}
+	for i := 4; i+3 < len(keys); i += 4 {
+		id, value := int(keys[i]), int(keys[i+3])
+		switch id {
+		case keyModelType:
+			if value != modelTypeGeographic {
+				return fmt.Errorf("%w: projected rasters must be reprojected to EPSG:4326", ErrUnsupported)
+			}
+		case keyProjectedType:
+			return fmt.Errorf("%w: projected rasters must be reprojected to EPSG:4326", ErrUnsupported)
+		case keyGeographicType:
+			if value != 4326 && value != 32767 {
+				return fmt.Errorf("%w: geographic CRS %d, expected 4326", ErrUnsupported, value)
+			}
+		}
+	}
+	return nil
+}
+
+// sampleReader 按位深和采样格式读取单个像元
+func (f *ifd) sampleReader() (func([]byte) float64, error) {
+	bits := f.int(tagBitsPerSample, 8)
+	format := f.int(tagSampleFormat, 1)
+	o := f.order
+	switch {
+	case format == 1 && bits == 8:
+		return func(b []byte) float64 { return float64(b[0]) }, nil
+	case format == 1 && bits == 16:
+		return func(b []byte) float64 { return float64(o.Uint16(b)) }, nil
+	case format == 1 && bits == 32:
+		return func(b []byte) float64 { return float64(o.Uint32(b)) }, nil
+	case format == 2 && bits == 16:
+		return func(b []byte) float64 { return float64(int16(o.Uint16(b))) }, nil
+	case format == 2 && bits == 32:
+		return func(b []byte) float64 { return float64(int32(o.Uint32(b))) }, nil
+	case format == 3 && bits == 32:
+		return func(b []byte) float64 { return float64(math.Float32frombits(o.Uint32(b))) }, nil
+	case format == 3 && bits == 64:
+		return func(b []byte) float64 { return math.Float64frombits(o.Uint64(b)) }, nil
+	}
+	return nil, fmt.Errorf("%w: sample format %d with %d bits", ErrUnsupported, format, bits)
+}
+
+// decompress 解压单个条带/分块
+func (f *ifd) decompress(block []byte) ([]byte, error) {
+	switch c := f.int(tagCompression, 1); c {
+	case 1:
+		return block, nil
+	case 8, 32946:
+		r, err := zlib.NewReader(bytes.NewReader(block))
+		if err != nil {
+			return nil, fmt.Errorf("deflate: %w", err)
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	default:
+		return nil, fmt.Errorf("%w: compression %d (use COMPRESS=DEFLATE or NONE)", ErrUnsupported, c)
+	}
+}
+
+// undoPredictor 还原水平差分预测（Predictor=2），仅用于整数采样
+func undoPredictor(block []byte, w, h, size int, o binary.ByteOrder) {
+	for y := 0; y < h; y++ {
+		row := block[y*w*size : (y+1)*w*size]
+		for x := 1; x < w; x++ {
+			cur, prev := row[x*size:], row[(x-1)*size:]
+			switch size {
+			case 1:
+				cur[0] += prev[0]
+			case 2:
+				o.PutUint16(cur, o.Uint16(cur)+o.Uint16(prev))
+			case 4:
+				o.PutUint32(cur, o.Uint32(cur)+o.Uint32(prev))
+			}
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package dem
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+)
+
+// tiffField 测试用 TIFF 字段，data 为小端序编码的值
+type tiffField struct {
+	tag, typ uint16
+	count    uint32
+	data     []byte
+}
+
+func u16s(vs ...uint16) []byte {
+	b := make([]byte, 2*len(vs))
+	for i, v := range vs {
+		binary.LittleEndian.PutUint16(b[2*i:], v)
+	}
+	return b
+}
+
+func u32s(vs ...uint32) []byte {
+	b := make([]byte, 4*len(vs))
+	for i, v := range vs {
+		binary.LittleEndian.PutUint32(b[4*i:], v)
+	}
+	return b
+}
+
+func f64s(vs ...float64) []byte {
+	b := make([]byte, 8*len(vs))
+	for i, v := range vs {
+		binary.LittleEndian.PutUint64(b[8*i:], math.Float64bits(v))
+	}
+	return b
+}
+
+// encodeTIFF 生成小端序 TIFF：像元数据紧跟文件头，随后是 IFD 和超出 4 字节的字段值。
+// StripOffsets 字段的值由这里填写
+func encodeTIFF(fields []tiffField, strip []byte) []byte {
+	ifdOffset := 8 + len(strip)
+	extraOffset := ifdOffset + 2 + 12*len(fields) + 4
+
+	var ifd, extra bytes.Buffer
+	ifd.Write(u16s(uint16(len(fields))))
+	for _, f := range fields {
+		if f.tag == tagStripOffsets {
+			f.data = u32s(8)
+		}
+		ifd.Write(u16s(f.tag, f.typ))
+		ifd.Write(u32s(f.count))
+		if len(f.data) <= 4 {
+			ifd.Write(append(f.data, make([]byte, 4-len(f.data))...))
+			continue
+		}
+		ifd.Write(u32s(uint32(extraOffset + extra.Len())))
+		extra.Write(f.data)
+	}
+	ifd.Write(u32s(0))
+
+	var buf bytes.Buffer
+	buf.WriteString("II")
+	buf.Write(u16s(42))
+	buf.Write(u32s(uint32(ifdOffset)))
+	buf.Write(strip)
+	buf.Write(ifd.Bytes())
+	buf.Write(extra.Bytes())
+	return buf.Bytes()
+}
+
+// demFields 2×2 单条带 int16 栅格：左上角 (120, 30)，像元 0.01 度
+func demFields(strip []byte, compression, predictor uint16) []tiffField {
+	return []tiffField{
+		{tagImageWidth, 3, 1, u16s(2)},
+		{tagImageLength, 3, 1, u16s(2)},
+		{tagBitsPerSample, 3, 1, u16s(16)},
+		{tagCompression, 3, 1, u16s(compression)},
+		{tagStripOffsets, 4, 1, nil},
+		{tagSamplesPerPixel, 3, 1, u16s(1)},
+		{tagRowsPerStrip, 3, 1, u16s(2)},
+		{tagStripByteCounts, 4, 1, u32s(uint32(len(strip)))},
+		{tagPredictor, 3, 1, u16s(predictor)},
+		{tagSampleFormat, 3, 1, u16s(2)},
+		{tagPixelScale, 12, 3, f64s(0.01, 0.01, 0)},
+		{tagTiepoint, 12, 6, f64s(0, 0, 0, 120, 30, 0)},
+		{tagGeoKeyDirectory, 3, 8, u16s(1, 1, 0, 1, keyGeographicType, 0, 1, 4326)},
+	}
+}
+
+func deflate(t *testing.T, b []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	// 高程 100 200 / 300 400；差分预测按行存储与前一像元的差
+	raw := u16s(100, 200, 300, 400)
+	diff := u16s(100, 100, 300, 100)
+
+	tests := []struct {
+		name        string
+		strip       []byte
+		compression uint16
+		predictor   uint16
+	}{
+		{"uncompressed", raw, 1, 1},
+		{"deflate", deflate(t, raw), 8, 1},
+		{"deflate with predictor", deflate(t, diff), 8, 2},
+	}
+	points := []struct {
+		lng, lat float64
+		want     float64
+	}{
+		{120.005, 29.995, 100},
+		{120.015, 29.995, 200},
+		{120.005, 29.985, 300},
+		{120.015, 29.985, 400},
+		{120.01, 29.99, 250},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := Decode(encodeTIFF(demFields(tt.strip, tt.compression, tt.predictor), tt.strip))
+			if err != nil {
+				t.Fatal(err)
+			}
+			minLng, minLat, maxLng, maxLat := d.Bounds()
+			if !near(minLng, 120) || !near(minLat, 29.98) || !near(maxLng, 120.02) || !near(maxLat, 30) {
+				t.Errorf("bounds = %v %v %v %v", minLng, minLat, maxLng, maxLat)
+			}
+			for _, p := range points {
+				got, ok := d.Elevation(p.lng, p.lat)
+				if !ok || !near(got, p.want) {
+					t.Errorf("Elevation(%v, %v) = %v, %v; want %v", p.lng, p.lat, got, ok, p.want)
+				}
+			}
+			if _, ok := d.Elevation(121, 30); ok {
+				t.Error("point outside the raster has an elevation")
+			}
+		})
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	strip := u16s(100, 200, 300, 400)
+	valid := encodeTIFF(demFields(strip, 1, 1), strip)
+	// 修改第 i 个字段后重新编码
+	withField := func(i int, f func(*tiffField)) []byte {
+		fields := demFields(strip, 1, 1)
+		f(&fields[i])
+		return encodeTIFF(fields, strip)
+	}
+	// IFD 中第 i 个字段的起始位置
+	entry := func(i int) int { return 8 + len(strip) + 2 + 12*i }
+
+	tests := []struct {
+		name string
+		buf  []byte
+	}{
+		{"empty", nil},
+		{"not a tiff", []byte("GIF89a\x00\x00\x00\x00")},
+		{"ifd offset past end", append(valid[:4:4], u32s(uint32(len(valid)))...)},
+		{"truncated ifd", valid[:entry(3)]},
+		{"truncated field values", valid[:len(valid)-8]},
+		{"field offset past end", func() []byte {
+			b := bytes.Clone(valid)
+			binary.LittleEndian.PutUint32(b[entry(10)+8:], 0xFFFFFFF0)
+			return b
+		}()},
+		{"field count overflow", func() []byte {
+			b := bytes.Clone(valid)
+			binary.LittleEndian.PutUint32(b[entry(10)+4:], 0xFFFFFFFF)
+			return b
+		}()},
+		{"strip past end", withField(7, func(f *tiffField) { f.data = u32s(1 << 20) })},
+		{"missing tiepoint", withField(11, func(f *tiffField) { f.tag = 40000 })},
+		{"projected crs", withField(12, func(f *tiffField) {
+			f.data = u16s(1, 1, 0, 1, keyModelType, 0, 1, 1)
+		})},
+		{"lzw compression", withField(3, func(f *tiffField) { f.data = u16s(5) })},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(tt.buf); !errors.Is(err, ErrUnsupported) {
+				t.Errorf("err = %v, want ErrUnsupported", err)
+			}
+		})
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
@@ -0,0 +1,195 @@
+package importer
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmpbf"
+)
+
+// 步行屏障类型，与 barrier.kind 对应
+const (
+	BarrierWater    = "water"
+	BarrierRail     = "rail"
+	BarrierMotorway = "motorway"
+)
+
+// Barrier 阻隔步行的要素：水面、铁路、高速公路
+// 等时圈多边形按屏障裁剪（见 016_barriers.sql）
+type Barrier struct {
+	OSMID   int64
+	OSMType string // way / relation
+	Kind    string
+	// 是否为面状要素（水面），否则为线状（河道中心线、铁路、高速）
+	Area  bool
+	Refs  [][]osm.NodeID
+	Lines [][][2]float64
+}
+
+// barrierKind 判断道路/水体是否构成步行屏障，返回类型和是否为面
+// 桥梁、隧道上的铁路和高速不阻隔地面步行
+func barrierKind(tags osm.Tags) (string, bool, bool) {
+	switch {
+	case tags.Find("natural") == "water",
+		tags.Find("waterway") == "riverbank",
+		tags.Find("landuse") == "reservoir" || tags.Find("landuse") == "basin":
+		return BarrierWater, true, true
+	case tags.Find("waterway") == "river" || tags.Find("waterway") == "canal":
+		return BarrierWater, false, true
+	}
+
+	if elevated(tags) {
+		return "", false, false
+	}
+	switch tags.Find("railway") {
+	case "rail", "light_rail", "narrow_gauge", "subway":
+		return BarrierRail, false, true
+	}
+	if tags.Find("highway") == "motorway" {
+		return BarrierMotorway, false, true
+	}
+	return "", false, false
+}
+
+// elevated 是否位于桥梁或隧道
+func elevated(tags osm.Tags) bool {
+	switch tags.Find("bridge") {
+	case "", "no":
+	default:
+		return true
+	}
+	switch tags.Find("tunnel") {
+	case "", "no":
+		return false
+	}
+	return true
+}
+
+// isWaterRelation 水面多边形关系（长江、钱塘江等大型水面通常以关系表示）
+func isWaterRelation(r *osm.Relation) bool {
+	if r.Tags.Find("type") != "multipolygon" {
+		return false
+	}
+	kind, area, ok := barrierKind(r.Tags)
+	return ok && area && kind == BarrierWater
+}
+
+// scanWaterRelations 只读关系，收集水面多边形关系
+func (im *Importer) scanWaterRelations(ctx context.Context) ([]*osm.Relation, error) {
+	f, err := os.Open(im.opts.File)
+	if err != nil {
+		return nil, fmt.Errorf("open pbf: %w", err)
+	}
+	defer f.Close()
+
+	scanner := osmpbf.New(ctx, f, im.opts.Procs)
+	defer scanner.Close()
+	scanner.SkipNodes = true
+	scanner.SkipWays = true
+
+	var relations []*osm.Relation
+	for scanner.Scan() {
+		r, ok := scanner.Object().(*osm.Relation)
+		if ok && isWaterRelation(r) {
+			relations = append(relations, r)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan relations: %w", err)
+	}
+	return relations, nil
+}
+
+// scanMemberWays 额外一遍扫描：读取水面关系成员道路的节点
+// 成员道路通常没有标签，第一遍扫描时无法识别
+func (im *Importer) scanMemberWays(ctx context.Context, members map[osm.WayID]struct{}) (map[osm.WayID][]osm.NodeID, error) {
+	f, err := os.Open(im.opts.File)
+	if err != nil {
+		return nil, fmt.Errorf("open pbf: %w", err)
+	}
+	defer f.Close()
+
+	scanner := osmpbf.New(ctx, f, im.opts.Procs)
+	defer scanner.Close()
+	scanner.SkipNodes = true
+	scanner.SkipRelations = true
+
+	refs := make(map[osm.WayID][]osm.NodeID, len(members))
+	for scanner.Scan() {
+		w, ok := scanner.Object().(*osm.Way)
+		if !ok {
+			continue
+		}
+		if _, ok := members[w.ID]; ok {
+			refs[w.ID] = w.Nodes.NodeIDs()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan member ways: %w", err)
+	}
+	return refs, nil
+}
+
+// relationBarriers 将水面关系的成员道路组装为屏障（成环在数据库端完成）
+func relationBarriers(relations []*osm.Relation, memberRefs map[osm.WayID][]osm.NodeID) []Barrier {
+	barriers := make([]Barrier, 0, len(relations))
+	for _, r := range relations {
+		b := Barrier{OSMID: int64(r.ID), OSMType: "relation", Kind: BarrierWater, Area: true}
+		for _, m := range r.Members {
+			if m.Type != osm.TypeWay {
+				continue
+			}
+			if refs, ok := memberRefs[osm.WayID(m.Ref)]; ok {
+				b.Refs = append(b.Refs, refs)
+			}
+		}
+		if len(b.Refs) > 0 {
+			barriers = append(barriers, b)
+		}
+	}
+	return barriers
+}
+
+// resolveBarriers 填充屏障坐标，丢弃缺少节点的线段
+func resolveBarriers(barriers []Barrier, coords map[osm.NodeID][2]float64) []Barrier {
+	out := barriers[:0]
+	for _, b := range barriers {
+		b.Lines = nil
+		for _, refs := range b.Refs {
+			line := make([][2]float64, 0, len(refs))
+			for _, ref := range refs {
+				if c, ok := coords[ref]; ok {
+					line = append(line, c)
+				}
+			}
+			if len(line) >= 2 {
+				b.Lines = append(b.Lines, line)
+			}
+		}
+		if len(b.Lines) > 0 {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// multiLineStringWKB 编码 WKB MultiLineString
+func multiLineStringWKB(lines [][][2]float64) []byte {
+	size := 9
+	for _, l := range lines {
+		size += 9 + 16*len(l)
+	}
+	buf := make([]byte, 0, size)
+	header := make([]byte, 9)
+	header[0] = 1 // little endian
+	binary.LittleEndian.PutUint32(header[1:], 5)
+	binary.LittleEndian.PutUint32(header[5:], uint32(len(lines)))
+	buf = append(buf, header...)
+	for _, l := range lines {
+		buf = append(buf, lineStringWKB(l)...)
+	}
+	return buf
+}
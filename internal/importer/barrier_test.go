@@ -0,0 +1,145 @@
+package importer
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func TestBarrierKind(t *testing.T) {
+	tests := []struct {
+		name string
+		tags osm.Tags
+		kind string
+		area bool
+		ok   bool
+	}{
+		{"lake", tags("natural", "water"), BarrierWater, true, true},
+		{"riverbank", tags("waterway", "riverbank"), BarrierWater, true, true},
+		{"reservoir", tags("landuse", "reservoir"), BarrierWater, true, true},
+		{"river centerline", tags("waterway", "river"), BarrierWater, false, true},
+		{"canal", tags("waterway", "canal"), BarrierWater, false, true},
+		{"stream", tags("waterway", "stream"), "", false, false},
+		{"rail", tags("railway", "rail"), BarrierRail, false, true},
+		{"subway", tags("railway", "subway"), BarrierRail, false, true},
+		{"tram", tags("railway", "tram"), "", false, false},
+		{"rail bridge", tags("railway", "rail", "bridge", "yes"), "", false, false},
+		{"rail tunnel", tags("railway", "rail", "tunnel", "yes"), "", false, false},
+		{"bridge no", tags("railway", "rail", "bridge", "no"), BarrierRail, false, true},
+		{"motorway", tags("highway", "motorway"), BarrierMotorway, false, true},
+		{"motorway viaduct", tags("highway", "motorway", "bridge", "viaduct"), "", false, false},
+		{"trunk", tags("highway", "trunk"), "", false, false},
+		// 桥梁不影响水面
+		{"water under bridge tag", tags("natural", "water", "bridge", "yes"), BarrierWater, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, area, ok := barrierKind(tt.tags)
+			if kind != tt.kind || area != tt.area || ok != tt.ok {
+				t.Errorf("barrierKind(%v) = %q, %v, %v; want %q, %v, %v", tt.tags, kind, area, ok, tt.kind, tt.area, tt.ok)
+			}
+		})
+	}
+}
+
+func TestIsWaterRelation(t *testing.T) {
+	tests := []struct {
+		name string
+		tags osm.Tags
+		want bool
+	}{
+		{"water multipolygon", tags("type", "multipolygon", "natural", "water"), true},
+		{"riverbank multipolygon", tags("type", "multipolygon", "waterway", "riverbank"), true},
+		{"river route", tags("type", "waterway", "waterway", "river"), false},
+		{"landuse multipolygon", tags("type", "multipolygon", "landuse", "park"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isWaterRelation(&osm.Relation{Tags: tt.tags}); got != tt.want {
+				t.Errorf("isWaterRelation(%v) = %v, want %v", tt.tags, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRelationBarriers(t *testing.T) {
+	relations := []*osm.Relation{
+		{ID: 10, Members: osm.Members{
+			{Type: osm.TypeWay, Ref: 1, Role: "outer"},
+			{Type: osm.TypeNode, Ref: 7},
+			{Type: osm.TypeWay, Ref: 2, Role: "inner"},
+			{Type: osm.TypeWay, Ref: 3, Role: "outer"}, // 成员道路不在文件中
+		}},
+		{ID: 11, Members: osm.Members{{Type: osm.TypeWay, Ref: 4}}},
+	}
+	memberRefs := map[osm.WayID][]osm.NodeID{
+		1: {1, 2, 3, 1},
+		2: {4, 5, 6, 4},
+	}
+
+	barriers := relationBarriers(relations, memberRefs)
+	if len(barriers) != 1 {
+		t.Fatalf("got %d barriers, want 1 (relation without resolved members dropped)", len(barriers))
+	}
+	b := barriers[0]
+	if b.OSMID != 10 || b.OSMType != "relation" || b.Kind != BarrierWater || !b.Area || len(b.Refs) != 2 {
+		t.Errorf("barrier = %+v", b)
+	}
+}
+
+func TestResolveBarriers(t *testing.T) {
+	coords := map[osm.NodeID][2]float64{1: {120, 30}, 2: {120.001, 30}, 3: {120.002, 30}}
+	tests := []struct {
+		name  string
+		refs  [][]osm.NodeID
+		lines []int // 每条保留线段的点数，nil 表示丢弃整个屏障
+	}{
+		{"complete", [][]osm.NodeID{{1, 2, 3}}, []int{3}},
+		{"missing node skipped", [][]osm.NodeID{{1, 9, 3}}, []int{2}},
+		{"line too short dropped", [][]osm.NodeID{{1, 9}, {2, 3}}, []int{2}},
+		{"nothing left", [][]osm.NodeID{{8, 9}, {1}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := resolveBarriers([]Barrier{{OSMID: 1, Kind: BarrierRail, Refs: tt.refs}}, coords)
+			if tt.lines == nil {
+				if len(out) != 0 {
+					t.Errorf("barrier kept with lines %v", out[0].Lines)
+				}
+				return
+			}
+			if len(out) != 1 || len(out[0].Lines) != len(tt.lines) {
+				t.Fatalf("resolved = %+v", out)
+			}
+			for i, n := range tt.lines {
+				if len(out[0].Lines[i]) != n {
+					t.Errorf("line %d has %d points, want %d", i, len(out[0].Lines[i]), n)
+				}
+			}
+		})
+	}
+}
+
+func TestMultiLineStringWKB(t *testing.T) {
+	lines := [][][2]float64{
+		{{120, 30}, {120.001, 30}},
+		{{121, 31}, {121.001, 31}, {121.002, 31}},
+	}
+	b := multiLineStringWKB(lines)
+
+	if b[0] != 1 || binary.LittleEndian.Uint32(b[1:]) != 5 || binary.LittleEndian.Uint32(b[5:]) != 2 {
+		t.Errorf("header = %x", b[:9])
+	}
+	off := 9
+	for i, l := range lines {
+		part := lineStringWKB(l)
+		if string(b[off:off+len(part)]) != string(part) {
+			t.Errorf("line %d is not encoded as a WKB LineString", i)
+		}
+		off += len(part)
+	}
+	if off != len(b) {
+		t.Errorf("wkb has %d bytes, want %d", len(b), off)
+	}
+}
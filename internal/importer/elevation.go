@@ -0,0 +1,108 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yourname/15min-life-circle/internal/database"
+	"github.com/yourname/15min-life-circle/internal/dem"
+)
+
+// ElevationStats 高程采样统计
+type ElevationStats struct {
+	// 采样到高程的路网节点数
+	Sampled int
+	// 超出 DEM 范围或无数据的节点数
+	Missing int
+	// 写入坡度的边数
+	Edges int64
+}
+
+// ApplyDEM 在城市路网节点处采样 DEM 高程，并计算各边坡度（见 018_terrain.sql）
+// 可在导入路网后单独运行，更换 DEM 时无需重新导入 OSM 数据
+func ApplyDEM(ctx context.Context, db *database.DB, d *dem.DEM, city string) (*ElevationStats, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT id, ST_X(the_geom), ST_Y(the_geom) FROM ways_vertices_pgr WHERE city = $1`, city)
+	if err != nil {
+		return nil, fmt.Errorf("query vertices: %w", err)
+	}
+
+	stats := &ElevationStats{}
+	var samples [][]any
+	for rows.Next() {
+		var (
+			id       int64
+			lng, lat float64
+		)
+		if err := rows.Scan(&id, &lng, &lat); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan vertex: %w", err)
+		}
+		if elev, ok := d.Elevation(lng, lat); ok {
+			samples = append(samples, []any{id, elev})
+			stats.Sampled++
+		} else {
+			stats.Missing++
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query vertices: %w", err)
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `CREATE TEMP TABLE staging_elevation (id BIGINT, elevation DOUBLE PRECISION) ON COMMIT DROP`); err != nil {
+		return nil, fmt.Errorf("create staging table: %w", err)
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"staging_elevation"},
+		[]string{"id", "elevation"}, pgx.CopyFromRows(samples)); err != nil {
+		return nil, fmt.Errorf("copy elevations: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE ways_vertices_pgr SET elevation = NULL WHERE city = $1`, city); err != nil {
+		return nil, fmt.Errorf("reset elevations: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE ways SET rise_m = NULL, gradient = NULL WHERE city = $1`, city); err != nil {
+		return nil, fmt.Errorf("reset gradients: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE ways_vertices_pgr v
+		SET elevation = s.elevation
+		FROM staging_elevation s
+		WHERE v.id = s.id`); err != nil {
+		return nil, fmt.Errorf("update elevations: %w", err)
+	}
+
+	// 坡度按 source -> target 方向。过短的边不计坡度，避免 DEM 噪声放大；
+	// 桥梁、高架两端采样的是地面高程，坡度限制在 ±30% 以内
+	tag, err := tx.Exec(ctx, `
+		UPDATE ways w
+		SET rise_m = t.elevation - s.elevation,
+		    gradient = CASE WHEN w.length_m >= 5
+		                    THEN GREATEST(-0.3, LEAST(0.3, (t.elevation - s.elevation) / w.length_m))
+		                    ELSE 0 END
+		FROM ways_vertices_pgr s, ways_vertices_pgr t
+		WHERE w.city = $1
+		  AND s.id = w.source AND t.id = w.target
+		  AND s.elevation IS NOT NULL AND t.elevation IS NOT NULL`, city)
+	if err != nil {
+		return nil, fmt.Errorf("update gradients: %w", err)
+	}
+	stats.Edges = tag.RowsAffected()
+
+	// 坡度变化影响 terrain 模式的等时圈，使该城市的缓存失效
+	if _, err := tx.Exec(ctx, `SELECT bump_network_version($1)`, city); err != nil {
+		return nil, fmt.Errorf("bump network version: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,189 @@
+package importer
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/paulmach/osm"
+)
+
+// walkableHighways 步行路网包含的道路类型
+// 与 scripts/import_osm.sh 中 osm2pgrouting 的 mapconfig 保持一致
+var walkableHighways = map[string]bool{
+	"footway":       true,
+	"pedestrian":    true,
+	"path":          true,
+	"steps":         true,
+	"residential":   true,
+	"living_street": true,
+	"service":       true,
+	"tertiary":      true,
+	"secondary":     true,
+	"primary":       true,
+	"cycleway":      true,
+}
+
+// isWalkable 判断道路是否可步行
+func isWalkable(tags osm.Tags) bool {
+	if !walkableHighways[tags.Find("highway")] {
+		return false
+	}
+	switch tags.Find("foot") {
+	case "no", "private":
+		return false
+	}
+	switch tags.Find("access") {
+	case "no", "private":
+		return tags.Find("foot") == "yes" || tags.Find("foot") == "designated"
+	}
+	return true
+}
+
+// oneWay 解析单行标记：1 正向单行，-1 反向单行，0 双向
+func oneWay(tags osm.Tags) int {
+	switch tags.Find("oneway") {
+	case "yes", "true", "1":
+		return 1
+	case "-1", "reverse":
+		return -1
+	}
+	return 0
+}
+
+// roadWay 第一遍扫描时记录的可步行道路
+type roadWay struct {
+	ID      osm.WayID
+	Refs    []osm.NodeID
+	Name    string
+	Highway string
+	OneWay  int
+	Tags    osm.Tags
+}
+
+// Vertex 路网节点
+type Vertex struct {
+	ID  int64
+	Lng float64
+	Lat float64
+}
+
+// Edge 路网边（两个路网节点之间的道路片段）
+type Edge struct {
+	OSMID   int64
+	Source  int64
+	Target  int64
+	LengthM float64
+	Coords  [][2]float64 // [lng, lat]
+	Name    string
+	Highway string
+	OneWay  int
+	Tags    osm.Tags
+}
+
+// buildGraph 在交叉口（被多条道路引用的节点）和道路端点处切分道路，生成边和节点
+// 缺少坐标的节点（裁剪边界外）会截断所在的边；回到起点的环路在中间节点处再切分一次，
+// 避免生成路径分析无法使用的自环边
+func buildGraph(roads []roadWay, nodeUse map[osm.NodeID]int, coords map[osm.NodeID][2]float64) ([]Vertex, []Edge) {
+	vertexSeen := make(map[osm.NodeID]bool)
+	var (
+		vertices []Vertex
+		edges    []Edge
+	)
+
+	addVertex := func(id osm.NodeID) {
+		if vertexSeen[id] {
+			return
+		}
+		vertexSeen[id] = true
+		c := coords[id]
+		vertices = append(vertices, Vertex{ID: int64(id), Lng: c[0], Lat: c[1]})
+	}
+
+	for _, r := range roads {
+		addEdge := func(source, target osm.NodeID, segment [][2]float64) {
+			addVertex(source)
+			addVertex(target)
+			edges = append(edges, Edge{
+				OSMID:   int64(r.ID),
+				Source:  int64(source),
+				Target:  int64(target),
+				LengthM: lineLength(segment),
+				Coords:  segment,
+				Name:    r.Name,
+				Highway: r.Highway,
+				OneWay:  r.OneWay,
+				Tags:    r.Tags,
+			})
+		}
+
+		var (
+			refs    []osm.NodeID
+			segment [][2]float64
+		)
+		for i, ref := range r.Refs {
+			c, ok := coords[ref]
+			if !ok {
+				// 坐标缺失，丢弃当前片段
+				refs, segment = nil, nil
+				continue
+			}
+			refs = append(refs, ref)
+			segment = append(segment, c)
+			if len(refs) == 1 {
+				continue
+			}
+
+			isLast := i == len(r.Refs)-1
+			if !isLast && nodeUse[ref] < 2 {
+				continue
+			}
+			switch start := refs[0]; {
+			case ref != start:
+				addEdge(start, ref, segment)
+			case len(refs) > 3:
+				mid := len(refs) / 2
+				addEdge(start, refs[mid], segment[:mid+1])
+				addEdge(refs[mid], ref, segment[mid:])
+			}
+			refs = []osm.NodeID{ref}
+			segment = [][2]float64{c}
+		}
+	}
+
+	return vertices, edges
+}
+
+// haversine 计算两点间球面距离（米）
+func haversine(a, b [2]float64) float64 {
+	const earthRadius = 6371008.8
+	lat1 := a[1] * math.Pi / 180
+	lat2 := b[1] * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := (b[0] - a[0]) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
+
+// lineLength 计算折线长度（米）
+func lineLength(coords [][2]float64) float64 {
+	total := 0.0
+	for i := 1; i < len(coords); i++ {
+		total += haversine(coords[i-1], coords[i])
+	}
+	return total
+}
+
+// lineStringWKB 将折线编码为 WKB（小端序），由 ST_GeomFromWKB 读取
+func lineStringWKB(coords [][2]float64) []byte {
+	buf := make([]byte, 9+16*len(coords))
+	buf[0] = 1 // little endian
+	binary.LittleEndian.PutUint32(buf[1:], 2)
+	binary.LittleEndian.PutUint32(buf[5:], uint32(len(coords)))
+	off := 9
+	for _, c := range coords {
+		binary.LittleEndian.PutUint64(buf[off:], math.Float64bits(c[0]))
+		binary.LittleEndian.PutUint64(buf[off+8:], math.Float64bits(c[1]))
+		off += 16
+	}
+	return buf
+}
@@ -0,0 +1,176 @@
+package importer
+
+import (
+	"encoding/binary"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func tags(kv ...string) osm.Tags {
+	var t osm.Tags
+	for i := 0; i+1 < len(kv); i += 2 {
+		t = append(t, osm.Tag{Key: kv[i], Value: kv[i+1]})
+	}
+	return t
+}
+
+func TestIsWalkable(t *testing.T) {
+	tests := []struct {
+		name string
+		tags osm.Tags
+		want bool
+	}{
+		{"footway", tags("highway", "footway"), true},
+		{"residential", tags("highway", "residential"), true},
+		{"motorway", tags("highway", "motorway"), false},
+		{"not a road", tags("building", "yes"), false},
+		{"foot no", tags("highway", "primary", "foot", "no"), false},
+		{"private access", tags("highway", "service", "access", "private"), false},
+		{"private access, foot allowed", tags("highway", "service", "access", "private", "foot", "yes"), true},
+		{"no access, foot designated", tags("highway", "path", "access", "no", "foot", "designated"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isWalkable(tt.tags); got != tt.want {
+				t.Errorf("isWalkable(%v) = %v, want %v", tt.tags, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOneWay(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"yes", 1},
+		{"true", 1},
+		{"1", 1},
+		{"-1", -1},
+		{"reverse", -1},
+		{"no", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := oneWay(tags("highway", "residential", "oneway", tt.value)); got != tt.want {
+				t.Errorf("oneWay(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildGraph(t *testing.T) {
+	// 节点 i 位于 (120 + i·0.001, 30)
+	coords := func(ids ...osm.NodeID) map[osm.NodeID][2]float64 {
+		m := make(map[osm.NodeID][2]float64)
+		for _, id := range ids {
+			m[id] = [2]float64{120 + float64(id)*0.001, 30}
+		}
+		return m
+	}
+	road := func(id osm.WayID, refs ...osm.NodeID) roadWay {
+		return roadWay{ID: id, Refs: refs, Highway: "footway"}
+	}
+
+	tests := []struct {
+		name     string
+		roads    []roadWay
+		coords   map[osm.NodeID][2]float64
+		edges    [][2]int64 // source, target
+		vertices []int64
+	}{
+		{"single way", []roadWay{road(1, 1, 2, 3)}, coords(1, 2, 3),
+			[][2]int64{{1, 3}}, []int64{1, 3}},
+		{"split at crossing", []roadWay{road(1, 1, 2, 3), road(2, 4, 2, 5)}, coords(1, 2, 3, 4, 5),
+			[][2]int64{{1, 2}, {2, 3}, {4, 2}, {2, 5}}, []int64{1, 2, 3, 4, 5}},
+		{"missing node truncates", []roadWay{road(1, 1, 2, 3, 4, 5), road(2, 6, 4)}, coords(1, 2, 4, 5, 6),
+			[][2]int64{{4, 5}, {6, 4}}, []int64{4, 5, 6}},
+		{"missing node between crossings", []roadWay{road(1, 1, 2, 3), road(2, 2, 6)}, coords(1, 2, 6),
+			[][2]int64{{1, 2}, {2, 6}}, []int64{1, 2, 6}},
+		// 闭合道路回到起点时在中间节点切分
+		{"closed way", []roadWay{road(1, 1, 2, 3, 4, 1)}, coords(1, 2, 3, 4),
+			[][2]int64{{1, 3}, {3, 1}}, []int64{1, 3}},
+		{"closed way with crossing", []roadWay{road(1, 1, 2, 3, 4, 1), road(2, 5, 3)}, coords(1, 2, 3, 4, 5),
+			[][2]int64{{1, 3}, {3, 1}, {5, 3}}, []int64{1, 3, 5}},
+		{"degenerate loop", []roadWay{road(1, 1, 2, 1)}, coords(1, 2), nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodeUse := make(map[osm.NodeID]int)
+			for _, r := range tt.roads {
+				for _, ref := range r.Refs {
+					nodeUse[ref]++
+				}
+			}
+			vertices, edges := buildGraph(tt.roads, nodeUse, tt.coords)
+
+			var gotEdges [][2]int64
+			for _, e := range edges {
+				gotEdges = append(gotEdges, [2]int64{e.Source, e.Target})
+				if len(e.Coords) < 2 || e.Coords[0] != tt.coords[osm.NodeID(e.Source)] || e.Coords[len(e.Coords)-1] != tt.coords[osm.NodeID(e.Target)] {
+					t.Errorf("edge %d→%d coords %v do not run from source to target", e.Source, e.Target, e.Coords)
+				}
+				if want := lineLength(e.Coords); e.LengthM != want || want <= 0 {
+					t.Errorf("edge %d→%d length = %v, want %v", e.Source, e.Target, e.LengthM, want)
+				}
+			}
+			if !slices.Equal(gotEdges, tt.edges) {
+				t.Errorf("edges = %v, want %v", gotEdges, tt.edges)
+			}
+
+			var gotVertices []int64
+			for _, v := range vertices {
+				gotVertices = append(gotVertices, v.ID)
+			}
+			slices.Sort(gotVertices)
+			if !slices.Equal(gotVertices, tt.vertices) {
+				t.Errorf("vertices = %v, want %v", gotVertices, tt.vertices)
+			}
+		})
+	}
+}
+
+func TestLineLength(t *testing.T) {
+	tests := []struct {
+		name   string
+		coords [][2]float64
+		want   float64
+	}{
+		{"empty", nil, 0},
+		{"single point", [][2]float64{{120, 30}}, 0},
+		// 赤道上 0.001 度约 111.2 米
+		{"along equator", [][2]float64{{0, 0}, {0.001, 0}}, 111.195},
+		{"meridian", [][2]float64{{120, 30}, {120, 30.001}, {120, 30.002}}, 222.39},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lineLength(tt.coords); math.Abs(got-tt.want) > 0.01 {
+				t.Errorf("lineLength = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineStringWKB(t *testing.T) {
+	coords := [][2]float64{{120.1, 30.2}, {120.3, 30.4}}
+	b := lineStringWKB(coords)
+
+	if len(b) != 9+16*len(coords) {
+		t.Fatalf("wkb has %d bytes", len(b))
+	}
+	if b[0] != 1 || binary.LittleEndian.Uint32(b[1:]) != 2 || binary.LittleEndian.Uint32(b[5:]) != 2 {
+		t.Errorf("header = %x", b[:9])
+	}
+	for i, c := range coords {
+		off := 9 + 16*i
+		lng := math.Float64frombits(binary.LittleEndian.Uint64(b[off:]))
+		lat := math.Float64frombits(binary.LittleEndian.Uint64(b[off+8:]))
+		if lng != c[0] || lat != c[1] {
+			t.Errorf("point %d = (%v, %v), want %v", i, lng, lat, c)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+// Package importer 从 OSM PBF 文件导入 POI 和步行路网
+//
+// 取代 osm2pgsql + 003_import_osm_poi.sql 和 osm2pgrouting 的组合：
+// 流式读取 .osm.pbf 两遍（先道路、后节点），按 poi_rule 表中的分类规则
+// （见 internal/classify）分类 POI，在交叉口切分道路生成路网，
+// 同时提取水面、铁路、高速公路等步行屏障，最后通过 COPY 批量写入数据库。
+package importer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmpbf"
+	"github.com/yourname/15min-life-circle/internal/classify"
+	"github.com/yourname/15min-life-circle/internal/database"
+)
+
+// Options 导入参数
+type Options struct {
+	// PBF 文件路径
+	File string
+	// 城市代码（city.code），所有导入数据都带此标记
+	City string
+	// 城市显示名称（可选）
+	CityName string
+	// 导入批次版本
+	Version string
+	// 解码并发数，默认 CPU 数
+	Procs int
+}
+
+// POI 待写入的兴趣点
+type POI struct {
+	OSMID    int64
+	OSMType  string // node / way
+	Name     string
+	Category string
+	SubType  string
+	Lng      float64
+	Lat      float64
+	Address  string
+	Tags     osm.Tags
+}
+
+// Stats 导入统计
+type Stats struct {
+	POIs     int
+	Vertices int
+	Edges    int
+	Barriers int
+	Bounds   *osm.Bounds
+}
+
+// Importer OSM PBF 导入器
+type Importer struct {
+	db    *database.DB
+	rules *classify.RuleSet
+	opts  Options
+}
+
+// New 创建导入器
+func New(db *database.DB, rules *classify.RuleSet, opts Options) *Importer {
+	if opts.Procs <= 0 {
+		opts.Procs = runtime.NumCPU()
+	}
+	return &Importer{db: db, rules: rules, opts: opts}
+}
+
+// poiWay 第一遍扫描时记录的面状 POI（取节点平均位置）
+type poiWay struct {
+	ID       osm.WayID
+	Refs     []osm.NodeID
+	Category string
+	SubType  string
+	Tags     osm.Tags
+}
+
+// Run 执行导入
+func (im *Importer) Run(ctx context.Context) (*Stats, error) {
+	// 第一遍：道路与面状 POI
+	roads, poiWays, barriers, nodeUse, bounds, err := im.scanWays(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 水面多边形关系及其成员道路
+	relations, err := im.scanWaterRelations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(relations) > 0 {
+		members := make(map[osm.WayID]struct{})
+		for _, r := range relations {
+			for _, m := range r.Members {
+				if m.Type == osm.TypeWay {
+					members[osm.WayID(m.Ref)] = struct{}{}
+				}
+			}
+		}
+		memberRefs, err := im.scanMemberWays(ctx, members)
+		if err != nil {
+			return nil, err
+		}
+		barriers = append(barriers, relationBarriers(relations, memberRefs)...)
+	}
+	log.Printf("道路 %d 条，面状POI %d 个，屏障 %d 个", len(roads), len(poiWays), len(barriers))
+
+	needed := make(map[osm.NodeID]struct{}, len(nodeUse))
+	for id := range nodeUse {
+		needed[id] = struct{}{}
+	}
+	for _, w := range poiWays {
+		for _, ref := range w.Refs {
+			needed[ref] = struct{}{}
+		}
+	}
+	for _, b := range barriers {
+		for _, refs := range b.Refs {
+			for _, ref := range refs {
+				needed[ref] = struct{}{}
+			}
+		}
+	}
+
+	// 第二遍：节点坐标与点状 POI
+	coords, pois, err := im.scanNodes(ctx, needed)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, w := range poiWays {
+		if poi, ok := wayPOI(w, coords); ok {
+			pois = append(pois, poi)
+		}
+	}
+
+	vertices, edges := buildGraph(roads, nodeUse, coords)
+	barriers = resolveBarriers(barriers, coords)
+	log.Printf("POI %d 个，路网节点 %d 个，边 %d 条，屏障 %d 个", len(pois), len(vertices), len(edges), len(barriers))
+
+	if err := im.load(ctx, pois, vertices, edges, barriers); err != nil {
+		return nil, err
+	}
+	if bounds == nil {
+		bounds = verticesBounds(vertices)
+	}
+	if err := im.registerCity(ctx, bounds); err != nil {
+		return nil, err
+	}
+
+	return &Stats{
+		POIs:     len(pois),
+		Vertices: len(vertices),
+		Edges:    len(edges),
+		Barriers: len(barriers),
+		Bounds:   bounds,
+	}, nil
+}
+
+// scanWays 第一遍扫描：只读道路
+func (im *Importer) scanWays(ctx context.Context) ([]roadWay, []poiWay, []Barrier, map[osm.NodeID]int, *osm.Bounds, error) {
+	f, err := os.Open(im.opts.File)
+	if err != nil {
+		return nil, nil, nil, nil, nil, fmt.Errorf("open pbf: %w", err)
+	}
+	defer f.Close()
+
+	scanner := osmpbf.New(ctx, f, im.opts.Procs)
+	defer scanner.Close()
+	scanner.SkipNodes = true
+	scanner.SkipRelations = true
+
+	var bounds *osm.Bounds
+	if header, err := scanner.Header(); err == nil && header != nil {
+		bounds = header.Bounds
+	}
+
+	var (
+		roads    []roadWay
+		poiWays  []poiWay
+		barriers []Barrier
+		nodeUse  = make(map[osm.NodeID]int)
+	)
+	for scanner.Scan() {
+		w, ok := scanner.Object().(*osm.Way)
+		if !ok || len(w.Nodes) < 2 {
+			continue
+		}
+
+		if isWalkable(w.Tags) {
+			refs := w.Nodes.NodeIDs()
+			for _, ref := range refs {
+				nodeUse[ref]++
+			}
+			roads = append(roads, roadWay{
+				ID:      w.ID,
+				Refs:    refs,
+				Name:    displayName(w.Tags),
+				Highway: w.Tags.Find("highway"),
+				OneWay:  oneWay(w.Tags),
+				Tags:    w.Tags,
+			})
+			continue
+		}
+
+		if kind, area, ok := barrierKind(w.Tags); ok {
+			refs := w.Nodes.NodeIDs()
+			// 面状水体须闭合，否则按线处理
+			area = area && refs[0] == refs[len(refs)-1]
+			barriers = append(barriers, Barrier{
+				OSMID:   int64(w.ID),
+				OSMType: "way",
+				Kind:    kind,
+				Area:    area,
+				Refs:    [][]osm.NodeID{refs},
+			})
+		}
+
+		if rule, ok := im.classify(w.Tags); ok {
+			poiWays = append(poiWays, poiWay{
+				ID:       w.ID,
+				Refs:     w.Nodes.NodeIDs(),
+				Category: rule.Category,
+				SubType:  rule.SubType,
+				Tags:     w.Tags,
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, nil, nil, nil, fmt.Errorf("scan ways: %w", err)
+	}
+
+	return roads, poiWays, barriers, nodeUse, bounds, nil
+}
+
+// scanNodes 第二遍扫描：只读节点，记录所需坐标并提取点状 POI
+func (im *Importer) scanNodes(ctx context.Context, needed map[osm.NodeID]struct{}) (map[osm.NodeID][2]float64, []POI, error) {
+	f, err := os.Open(im.opts.File)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open pbf: %w", err)
+	}
+	defer f.Close()
+
+	scanner := osmpbf.New(ctx, f, im.opts.Procs)
+	defer scanner.Close()
+	scanner.SkipWays = true
+	scanner.SkipRelations = true
+
+	coords := make(map[osm.NodeID][2]float64, len(needed))
+	var pois []POI
+	for scanner.Scan() {
+		n, ok := scanner.Object().(*osm.Node)
+		if !ok {
+			continue
+		}
+		if _, ok := needed[n.ID]; ok {
+			coords[n.ID] = [2]float64{n.Lon, n.Lat}
+		}
+		if rule, ok := im.classify(n.Tags); ok {
+			pois = append(pois, POI{
+				OSMID:    int64(n.ID),
+				OSMType:  "node",
+				Name:     displayName(n.Tags),
+				Category: rule.Category,
+				SubType:  rule.SubType,
+				Lng:      n.Lon,
+				Lat:      n.Lat,
+				Address:  address(n.Tags),
+				Tags:     n.Tags,
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("scan nodes: %w", err)
+	}
+
+	return coords, pois, nil
+}
+
+// classify 按规则分类，先按标签键快速过滤以避免为每个要素构造 map
+func (im *Importer) classify(tags osm.Tags) (*classify.Rule, bool) {
+	interesting := false
+	for _, t := range tags {
+		if im.rules.HasKey(t.Key) {
+			interesting = true
+			break
+		}
+	}
+	if !interesting {
+		return nil, false
+	}
+	return im.rules.Classify(tags.Map())
+}
+
+// wayPOI 面状 POI 取节点坐标平均值作为位置
+func wayPOI(w poiWay, coords map[osm.NodeID][2]float64) (POI, bool) {
+	var sumLng, sumLat float64
+	cnt := 0
+	for i, ref := range w.Refs {
+		// 闭合多边形首尾节点相同，只计一次
+		if i == len(w.Refs)-1 && ref == w.Refs[0] {
+			break
+		}
+		if c, ok := coords[ref]; ok {
+			sumLng += c[0]
+			sumLat += c[1]
+			cnt++
+		}
+	}
+	if cnt == 0 {
+		return POI{}, false
+	}
+	return POI{
+		OSMID:    int64(w.ID),
+		OSMType:  "way",
+		Name:     displayName(w.Tags),
+		Category: w.Category,
+		SubType:  w.SubType,
+		Lng:      sumLng / float64(cnt),
+		Lat:      sumLat / float64(cnt),
+		Address:  address(w.Tags),
+		Tags:     w.Tags,
+	}, true
+}
+
+// verticesBounds PBF 文件头缺少边界时，使用路网节点的外包框
+func verticesBounds(vertices []Vertex) *osm.Bounds {
+	if len(vertices) == 0 {
+		return nil
+	}
+	b := &osm.Bounds{MinLat: 90, MaxLat: -90, MinLon: 180, MaxLon: -180}
+	for _, v := range vertices {
+		b.MinLat = math.Min(b.MinLat, v.Lat)
+		b.MaxLat = math.Max(b.MaxLat, v.Lat)
+		b.MinLon = math.Min(b.MinLon, v.Lng)
+		b.MaxLon = math.Max(b.MaxLon, v.Lng)
+	}
+	return b
+}
+
+// displayName 名称优先使用 name，其次 name:zh
+func displayName(tags osm.Tags) string {
+	if name := tags.Find("name"); name != "" {
+		return name
+	}
+	return tags.Find("name:zh")
+}
+
+// address 拼接 addr:* 标签
+func address(tags osm.Tags) string {
+	var parts []string
+	for _, key := range []string{"addr:city", "addr:district", "addr:street", "addr:housenumber"} {
+		if v := tags.Find(key); v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, "")
+}
@@ -0,0 +1,185 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/paulmach/osm"
+)
+
+// load 通过 COPY 写入临时表，再在同一事务中替换该城市的数据
+// 几何在数据库端构造（ST_MakePoint / ST_GeomFromWKB），避免依赖 PostGIS 二进制类型编码
+// 人工维护的 POI（data_source = 'manual'）保留，对应的 OSM 要素不再导入
+func (im *Importer) load(ctx context.Context, pois []POI, vertices []Vertex, edges []Edge, barriers []Barrier) error {
+	tx, err := im.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	staging := `
+		CREATE TEMP TABLE staging_poi (
+			osm_id BIGINT, osm_type TEXT, name TEXT, category TEXT, sub_type TEXT,
+			lng DOUBLE PRECISION, lat DOUBLE PRECISION, address TEXT,
+			tag_keys TEXT[], tag_values TEXT[]
+		) ON COMMIT DROP;
+		CREATE TEMP TABLE staging_vertex (
+			id BIGINT, lng DOUBLE PRECISION, lat DOUBLE PRECISION
+		) ON COMMIT DROP;
+		CREATE TEMP TABLE staging_edge (
+			osm_id BIGINT, source BIGINT, target BIGINT, length_m DOUBLE PRECISION,
+			wkb BYTEA, name TEXT, highway TEXT, one_way INT,
+			tag_keys TEXT[], tag_values TEXT[]
+		) ON COMMIT DROP;
+		CREATE TEMP TABLE staging_barrier (
+			osm_id BIGINT, osm_type TEXT, kind TEXT, area BOOLEAN, wkb BYTEA
+		) ON COMMIT DROP;
+	`
+	if _, err := tx.Exec(ctx, staging); err != nil {
+		return fmt.Errorf("create staging tables: %w", err)
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"staging_poi"},
+		[]string{"osm_id", "osm_type", "name", "category", "sub_type", "lng", "lat", "address", "tag_keys", "tag_values"},
+		pgx.CopyFromSlice(len(pois), func(i int) ([]any, error) {
+			p := pois[i]
+			keys, values := splitTags(p.Tags)
+			return []any{p.OSMID, p.OSMType, p.Name, p.Category, p.SubType, p.Lng, p.Lat, p.Address, keys, values}, nil
+		}),
+	); err != nil {
+		return fmt.Errorf("copy pois: %w", err)
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"staging_vertex"},
+		[]string{"id", "lng", "lat"},
+		pgx.CopyFromSlice(len(vertices), func(i int) ([]any, error) {
+			v := vertices[i]
+			return []any{v.ID, v.Lng, v.Lat}, nil
+		}),
+	); err != nil {
+		return fmt.Errorf("copy vertices: %w", err)
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"staging_edge"},
+		[]string{"osm_id", "source", "target", "length_m", "wkb", "name", "highway", "one_way", "tag_keys", "tag_values"},
+		pgx.CopyFromSlice(len(edges), func(i int) ([]any, error) {
+			e := edges[i]
+			keys, values := splitTags(e.Tags)
+			return []any{e.OSMID, e.Source, e.Target, e.LengthM, lineStringWKB(e.Coords), e.Name, e.Highway, e.OneWay, keys, values}, nil
+		}),
+	); err != nil {
+		return fmt.Errorf("copy edges: %w", err)
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"staging_barrier"},
+		[]string{"osm_id", "osm_type", "kind", "area", "wkb"},
+		pgx.CopyFromSlice(len(barriers), func(i int) ([]any, error) {
+			b := barriers[i]
+			return []any{b.OSMID, b.OSMType, b.Kind, b.Area, multiLineStringWKB(b.Lines)}, nil
+		}),
+	); err != nil {
+		return fmt.Errorf("copy barriers: %w", err)
+	}
+
+	city, version := im.opts.City, im.opts.Version
+	statements := []struct {
+		name string
+		sql  string
+		args []any
+	}{
+		{"delete pois", `DELETE FROM poi WHERE city = $1 AND data_source = 'osm'`, []any{city}},
+		{"delete edges", `DELETE FROM ways WHERE city = $1`, []any{city}},
+		// 节点以 OSM 节点 ID 为主键，范围重叠的城市共用边界处的节点（归属先导入的城市），
+		// 仍被其他城市的边引用的节点保留
+		{"delete vertices", `
+			DELETE FROM ways_vertices_pgr v
+			WHERE v.city = $1
+			  AND NOT EXISTS (SELECT 1 FROM ways w WHERE w.source = v.id)
+			  AND NOT EXISTS (SELECT 1 FROM ways w WHERE w.target = v.id)`, []any{city}},
+		{"delete barriers", `DELETE FROM barrier WHERE city = $1`, []any{city}},
+		{"insert vertices", `
+			INSERT INTO ways_vertices_pgr (id, osm_id, the_geom, city, import_version)
+			SELECT id, id, ST_SetSRID(ST_MakePoint(lng, lat), 4326), $1, $2
+			FROM staging_vertex
+			ON CONFLICT (id) DO NOTHING`, []any{city, version}},
+		{"insert edges", `
+			INSERT INTO ways (osm_id, source, target, length_m, the_geom, name, highway, one_way, tags, city, import_version)
+			SELECT osm_id, source, target, length_m, ST_GeomFromWKB(wkb, 4326), NULLIF(name, ''), highway, one_way,
+			       hstore(tag_keys, tag_values), $1, $2
+			FROM staging_edge`, []any{city, version}},
+		{"insert pois", `
+			INSERT INTO poi (osm_id, name, category, sub_type, geom, address, tags, data_source, city, import_version)
+			SELECT osm_id, NULLIF(name, ''), category, sub_type, ST_SetSRID(ST_MakePoint(lng, lat), 4326),
+			       NULLIF(address, ''), hstore(tag_keys, tag_values) || hstore('osm_type', osm_type), 'osm', $1, $2
+			FROM staging_poi s
+			WHERE NOT EXISTS (
+				SELECT 1 FROM poi m
+				WHERE m.data_source = 'manual' AND m.osm_id = s.osm_id AND m.tags->'osm_type' = s.osm_type
+			)`, []any{city, version}},
+		// 面状水体由外环组装成面，无法成面的（如不完整的关系）按线保留
+		{"insert barriers", `
+			INSERT INTO barrier (osm_id, osm_type, kind, geom, city, import_version)
+			SELECT s.osm_id, s.osm_type, s.kind,
+			       CASE WHEN s.area AND NOT ST_IsEmpty(a.g) THEN a.g ELSE l.g END, $1, $2
+			FROM staging_barrier s
+			CROSS JOIN LATERAL (SELECT ST_GeomFromWKB(s.wkb, 4326) AS g) l
+			LEFT JOIN LATERAL (
+				SELECT ST_Multi(ST_CollectionExtract(ST_BuildArea(ST_Node(l.g)), 3)) AS g
+				WHERE s.area
+			) a ON TRUE
+			WHERE NOT ST_IsEmpty(l.g)`, []any{city, version}},
+	}
+	for _, st := range statements {
+		if _, err := tx.Exec(ctx, st.sql, st.args...); err != nil {
+			return fmt.Errorf("%s: %w", st.name, err)
+		}
+	}
+
+	// 路网已替换，使该城市的可达性预计算失效（见 020_reach_cache.sql）
+	if _, err := tx.Exec(ctx, `SELECT bump_network_version($1)`, city); err != nil {
+		return fmt.Errorf("bump network version: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	for _, table := range []string{"poi", "ways", "ways_vertices_pgr", "barrier"} {
+		if _, err := im.db.Pool.Exec(ctx, "ANALYZE "+table); err != nil {
+			return fmt.Errorf("analyze %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// registerCity 登记城市，刷新覆盖范围与统计（见 008_city_registry.sql）
+func (im *Importer) registerCity(ctx context.Context, bounds *osm.Bounds) error {
+	var name *string
+	if im.opts.CityName != "" {
+		name = &im.opts.CityName
+	}
+
+	var err error
+	if bounds != nil {
+		_, err = im.db.Pool.Exec(ctx, `SELECT register_city($1, $2, NULL, $3, $4, $5, $6)`,
+			im.opts.City, name, bounds.MinLon, bounds.MinLat, bounds.MaxLon, bounds.MaxLat)
+	} else {
+		_, err = im.db.Pool.Exec(ctx, `SELECT register_city($1, $2)`, im.opts.City, name)
+	}
+	if err != nil {
+		return fmt.Errorf("register city: %w", err)
+	}
+	return nil
+}
+
+// splitTags 拆分标签为键、值数组，由 hstore(text[], text[]) 组装
+func splitTags(tags osm.Tags) ([]string, []string) {
+	keys := make([]string, len(tags))
+	values := make([]string, len(tags))
+	for i, t := range tags {
+		keys[i] = t.Key
+		values[i] = t.Value
+	}
+	return keys, values
+}
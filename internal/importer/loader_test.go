@@ -0,0 +1,116 @@
+package importer
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/paulmach/osm"
+	"github.com/yourname/15min-life-circle/internal/config"
+	"github.com/yourname/15min-life-circle/internal/database"
+)
+
+// TestLoadOverlappingCities 范围重叠的两个城市共用边界处的节点，重新导入其中一个城市时
+// 另一个城市的边仍能找到两端节点。需设置 SCENARIO_POSTGIS=1，连接参数取自配置，请使用测试库
+func TestLoadOverlappingCities(t *testing.T) {
+	if os.Getenv("SCENARIO_POSTGIS") == "" {
+		t.Skip("set SCENARIO_POSTGIS=1 to run importer tests against PostGIS")
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		t.Fatalf("connect database: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	ctx := context.Background()
+	const west, east = "test_overlap_west", "test_overlap_east"
+	remove := func() {
+		for _, sql := range []string{
+			`DELETE FROM ways WHERE city = ANY($1)`,
+			`DELETE FROM ways_vertices_pgr WHERE city = ANY($1)`,
+			`DELETE FROM node_reach WHERE city = ANY($1)`,
+			`DELETE FROM network_version WHERE city = ANY($1)`,
+		} {
+			if _, err := db.Pool.Exec(ctx, sql, []string{west, east}); err != nil {
+				t.Errorf("cleanup: %v", err)
+			}
+		}
+	}
+	remove()
+	t.Cleanup(remove)
+
+	// 节点 ID 取远超 OSM 现有范围的值，不与测试库中的真实数据冲突；节点 3 两个城市共用
+	const base = osm.NodeID(9_000_000_000_000)
+	coords := map[osm.NodeID][2]float64{
+		base + 1: {120.100, 30.2}, base + 2: {120.101, 30.2}, base + 3: {120.102, 30.2},
+		base + 4: {120.103, 30.2}, base + 5: {120.104, 30.2},
+	}
+	graph := func(refs ...osm.NodeID) ([]Vertex, []Edge) {
+		road := roadWay{ID: osm.WayID(base), Refs: refs, Highway: "footway"}
+		nodeUse := make(map[osm.NodeID]int)
+		for _, ref := range refs {
+			nodeUse[ref] = 2 // 每个节点都是交叉口，逐段成边
+		}
+		return buildGraph([]roadWay{road}, nodeUse, coords)
+	}
+	load := func(city string, refs ...osm.NodeID) {
+		t.Helper()
+		vertices, edges := graph(refs...)
+		im := New(db, nil, Options{City: city, Version: "test"})
+		if err := im.load(ctx, nil, vertices, edges, nil); err != nil {
+			t.Fatalf("load %s: %v", city, err)
+		}
+	}
+	// 每条边的两端节点都存在
+	checkEdges := func(step string) {
+		t.Helper()
+		var dangling int
+		if err := db.Pool.QueryRow(ctx, `
+			SELECT count(*) FROM ways w
+			WHERE w.city = ANY($1)
+			  AND (NOT EXISTS (SELECT 1 FROM ways_vertices_pgr v WHERE v.id = w.source)
+			       OR NOT EXISTS (SELECT 1 FROM ways_vertices_pgr v WHERE v.id = w.target))`,
+			[]string{west, east}).Scan(&dangling); err != nil {
+			t.Fatal(err)
+		}
+		if dangling > 0 {
+			t.Errorf("%s: %d edges lost a vertex", step, dangling)
+		}
+	}
+	countVertices := func() int {
+		t.Helper()
+		var n int
+		if err := db.Pool.QueryRow(ctx, `SELECT count(*) FROM ways_vertices_pgr WHERE city = ANY($1)`,
+			[]string{west, east}).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	load(west, base+1, base+2, base+3)
+	load(east, base+3, base+4, base+5)
+	checkEdges("initial import")
+
+	load(west, base+1, base+2, base+3)
+	checkEdges("west re-imported")
+	load(east, base+3, base+4, base+5)
+	checkEdges("east re-imported")
+
+	// 西侧不再包含共用节点，节点仍供东侧使用
+	load(west, base+1, base+2)
+	checkEdges("west shrunk")
+	if n := countVertices(); n != 5 {
+		t.Errorf("vertices = %d, want 5", n)
+	}
+	// 东侧也不再使用时随下一次导入删除
+	load(east, base+4, base+5)
+	load(west, base+1, base+2)
+	checkEdges("both shrunk")
+	if n := countVertices(); n != 4 {
+		t.Errorf("vertices = %d, want 4", n)
+	}
+}
@@ -0,0 +1,126 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yourname/15min-life-circle/internal/classify"
+	"github.com/yourname/15min-life-circle/internal/database"
+)
+
+// osm2pgsqlVersion osm2pgsql 流程提取的 POI 的导入批次，重新提取时整体替换
+const osm2pgsqlVersion = "osm2pgsql"
+
+// candidate osm2pgsql 表中按规则分类的要素
+type candidate struct {
+	osmType  string
+	osmID    int64
+	category string
+	subType  string
+}
+
+// ExtractOSM2PGSQL 按分类规则从 osm2pgsql 导入的 planet_osm_point / planet_osm_polygon 提取 POI
+//
+// osm2pgsql 需以 --hstore-all 导入，使全部标签都在 tags 列中。只读取带有规则所需标签键的要素，
+// 分类在 Go 中完成，与 cmd/importer 读取 PBF 时使用同一规则集；面状要素取质心。
+// city 为空时 POI 不标记城市。返回写入的 POI 数量
+func ExtractOSM2PGSQL(ctx context.Context, db *database.DB, rules *classify.RuleSet, city string) (int, error) {
+	query := `
+		SELECT 'node', osm_id, hstore_to_json(tags) FROM planet_osm_point WHERE tags ?| $1
+		UNION ALL
+		SELECT CASE WHEN osm_id < 0 THEN 'relation' ELSE 'way' END, osm_id, hstore_to_json(tags)
+		FROM planet_osm_polygon WHERE tags ?| $1
+	`
+	rows, err := db.Pool.Query(ctx, query, rules.Keys())
+	if err != nil {
+		return 0, fmt.Errorf("query osm2pgsql tables: %w", err)
+	}
+
+	var candidates []candidate
+	seen := make(map[candidate]bool)
+	for rows.Next() {
+		var (
+			c    candidate
+			tags map[string]string
+		)
+		if err := rows.Scan(&c.osmType, &c.osmID, &tags); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan osm2pgsql feature: %w", err)
+		}
+		rule, ok := rules.Classify(tags)
+		if !ok {
+			continue
+		}
+		// osm2pgsql 可能把一个多边形拆成多行
+		if key := (candidate{osmType: c.osmType, osmID: c.osmID}); !seen[key] {
+			seen[key] = true
+			c.category, c.subType = rule.Category, rule.SubType
+			candidates = append(candidates, c)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("read osm2pgsql features: %w", err)
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		CREATE TEMP TABLE staging_classified (
+			osm_type TEXT, osm_id BIGINT, category TEXT, sub_type TEXT
+		) ON COMMIT DROP`); err != nil {
+		return 0, fmt.Errorf("create staging table: %w", err)
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"staging_classified"},
+		[]string{"osm_type", "osm_id", "category", "sub_type"},
+		pgx.CopyFromSlice(len(candidates), func(i int) ([]any, error) {
+			c := candidates[i]
+			return []any{c.osmType, c.osmID, c.category, c.subType}, nil
+		}),
+	); err != nil {
+		return 0, fmt.Errorf("copy classified features: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM poi
+		WHERE data_source = 'osm' AND import_version = $2 AND city IS NOT DISTINCT FROM NULLIF($1, '')`,
+		city, osm2pgsqlVersion); err != nil {
+		return 0, fmt.Errorf("delete pois: %w", err)
+	}
+
+	// 人工维护的 POI 保留，对应的 OSM 要素不再导入（与 load 一致）
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO poi (osm_id, name, category, sub_type, geom, tags, data_source, city, import_version)
+		SELECT f.osm_id, COALESCE(f.tags->'name', f.tags->'name:zh'), s.category, s.sub_type,
+		       ST_Transform(f.geom, 4326), f.tags || hstore('osm_type', s.osm_type), 'osm', NULLIF($1, ''), $2
+		FROM staging_classified s
+		CROSS JOIN LATERAL (
+			SELECT p.osm_id, p.tags, p.way AS geom FROM planet_osm_point p
+			WHERE s.osm_type = 'node' AND p.osm_id = s.osm_id
+			UNION ALL
+			SELECT p.osm_id, p.tags, ST_Centroid(p.way) FROM planet_osm_polygon p
+			WHERE s.osm_type <> 'node' AND p.osm_id = s.osm_id
+			LIMIT 1
+		) f
+		WHERE NOT EXISTS (
+			SELECT 1 FROM poi m
+			WHERE m.data_source = 'manual' AND m.osm_id = s.osm_id AND m.tags->'osm_type' = s.osm_type
+		)`,
+		city, osm2pgsqlVersion)
+	if err != nil {
+		return 0, fmt.Errorf("insert pois: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+	if _, err := db.Pool.Exec(ctx, "ANALYZE poi"); err != nil {
+		return 0, fmt.Errorf("analyze poi: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,78 @@
+// Package logging 基于 log/slog 的结构化日志
+//
+// Setup 将 slog 默认 Logger 设为 JSON 输出，标准库 log 的输出也会经由 slog 写出。
+// 使用 slog.InfoContext 等带 context 的方法时，自动附带请求 ID 和 trace_id / span_id。
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type ctxKey struct{}
+
+// WithRequestID 在 context 中记录请求 ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// RequestID 读取 context 中的请求 ID
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// Setup 设置默认 Logger，level 为 debug / info / warn / error
+func Setup(w io.Writer, level string) *slog.Logger {
+	var lv slog.Level
+	switch strings.ToLower(level) {
+	case "debug":
+		lv = slog.LevelDebug
+	case "warn":
+		lv = slog.LevelWarn
+	case "error":
+		lv = slog.LevelError
+	default:
+		lv = slog.LevelInfo
+	}
+
+	logger := slog.New(&requestIDHandler{slog.NewJSONHandler(w, &slog.HandlerOptions{Level: lv})})
+	slog.SetDefault(logger)
+	return logger
+}
+
+// requestIDHandler 为带请求 ID 的 context 添加 request_id 字段，
+// 处于采样链路中时添加 trace_id / span_id，便于从日志跳转到链路
+type requestIDHandler struct {
+	slog.Handler
+}
+
+func (h *requestIDHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if ctx != nil {
+		if sc := trace.SpanContextFromContext(ctx); sc.IsSampled() {
+			r.AddAttrs(
+				slog.String("trace_id", sc.TraceID().String()),
+				slog.String("span_id", sc.SpanID().String()),
+			)
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *requestIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &requestIDHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *requestIDHandler) WithGroup(name string) slog.Handler {
+	return &requestIDHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,127 @@
+// Package metrics Prometheus 指标
+//
+// 所有指标注册在默认 Registry，由 /metrics 暴露：
+//   - HTTP 请求数与耗时（按路由模板）
+//   - 服务阶段耗时（吸附、路网分析、多边形、POI 查询、外部数据源等）
+//   - 外部 API 调用次数（成功、失败、配额耗尽）与耗时
+//   - 缓存命中率
+//   - 认证、限速与配额拒绝的请求数
+//   - pgxpool 连接池状态
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "lifecircle"
+
+// 服务阶段
+const (
+	StageSnap     = "snap"      // 起点吸附路网 / 覆盖检查
+	StageRouting  = "routing"   // 路网分析（pgr_drivingDistance / pgr_dijkstra）
+	StagePolygon  = "polygon"   // 等时圈多边形（数据库函数内含路网分析）
+	StagePOIQuery = "poi_query" // POI 空间查询
+	StageExternal = "external"  // 外部数据源（高德）
+	StageScore    = "score"     // 评分
+	StageRoads    = "roads"     // 可达道路
+)
+
+// 外部调用结果
+const (
+	OutcomeOK    = "ok"
+	OutcomeError = "error"
+	OutcomeQuota = "quota" // 配额耗尽或调用过于频繁
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP 请求数",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP 请求耗时",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2, 4, 8, 16},
+	}, []string{"method", "route"})
+
+	stageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "stage_duration_seconds",
+		Help:      "服务各阶段耗时",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2, 4, 8},
+	}, []string{"service", "stage"})
+
+	externalRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "external_requests_total",
+		Help:      "外部 API 调用次数",
+	}, []string{"provider", "outcome"})
+
+	externalDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "external_request_duration_seconds",
+		Help:      "外部 API 调用耗时",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10},
+	}, []string{"provider"})
+
+	cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "缓存查询次数，result 为 hit / miss",
+	}, []string{"cache", "result"})
+
+	rejectedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rejected_requests_total",
+		Help:      "被拒绝的 API 请求数，reason 为 auth / rate / quota",
+	}, []string{"tier", "reason"})
+)
+
+// 请求被拒绝的原因
+const (
+	RejectAuth  = "auth"
+	RejectRate  = "rate"
+	RejectQuota = "quota"
+)
+
+// RejectRequest 记录一次被拒绝的 API 请求
+func RejectRequest(tier, reason string) {
+	rejectedRequests.WithLabelValues(tier, reason).Inc()
+}
+
+// ObserveHTTP 记录一次 HTTP 请求
+func ObserveHTTP(method, route, status string, elapsed time.Duration) {
+	httpRequests.WithLabelValues(method, route, status).Inc()
+	httpDuration.WithLabelValues(method, route).Observe(elapsed.Seconds())
+}
+
+// Stage 开始计时一个服务阶段，返回结束函数：
+//
+//	defer metrics.Stage("isochrone", metrics.StagePolygon)()
+func Stage(service, stage string) func() {
+	start := time.Now()
+	return func() {
+		stageDuration.WithLabelValues(service, stage).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveExternal 记录一次外部 API 调用
+func ObserveExternal(provider, outcome string, elapsed time.Duration) {
+	externalRequests.WithLabelValues(provider, outcome).Inc()
+	externalDuration.WithLabelValues(provider).Observe(elapsed.Seconds())
+}
+
+// CacheLookup 记录一次缓存查询
+func CacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheRequests.WithLabelValues(cache, result).Inc()
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector 采集时读取 pgxpool 状态
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquired, idle, constructing, total, max *prometheus.Desc
+	acquireCount, acquireSeconds             *prometheus.Desc
+	emptyAcquire, canceledAcquire            *prometheus.Desc
+	newConns                                 *prometheus.Desc
+}
+
+// RegisterPool 注册数据库连接池指标
+// empty_acquire_total 持续增长说明请求需要等待空闲连接（连接池饱和）
+func RegisterPool(pool *pgxpool.Pool) error {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+	return prometheus.Register(&poolCollector{
+		pool:            pool,
+		acquired:        desc("acquired_conns", "正在使用的连接数"),
+		idle:            desc("idle_conns", "空闲连接数"),
+		constructing:    desc("constructing_conns", "正在建立的连接数"),
+		total:           desc("total_conns", "连接总数"),
+		max:             desc("max_conns", "最大连接数"),
+		acquireCount:    desc("acquire_total", "获取连接次数"),
+		acquireSeconds:  desc("acquire_duration_seconds_total", "获取连接累计耗时"),
+		emptyAcquire:    desc("empty_acquire_total", "无空闲连接需要等待的获取次数"),
+		canceledAcquire: desc("canceled_acquire_total", "等待期间被取消的获取次数"),
+		newConns:        desc("new_conns_total", "新建连接次数"),
+	})
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.acquired, c.idle, c.constructing, c.total, c.max,
+		c.acquireCount, c.acquireSeconds, c.emptyAcquire, c.canceledAcquire, c.newConns,
+	} {
+		ch <- d
+	}
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+	gauge := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v)
+	}
+	counter := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v)
+	}
+	gauge(c.acquired, float64(s.AcquiredConns()))
+	gauge(c.idle, float64(s.IdleConns()))
+	gauge(c.constructing, float64(s.ConstructingConns()))
+	gauge(c.total, float64(s.TotalConns()))
+	gauge(c.max, float64(s.MaxConns()))
+	counter(c.acquireCount, float64(s.AcquireCount()))
+	counter(c.acquireSeconds, s.AcquireDuration().Seconds())
+	counter(c.emptyAcquire, float64(s.EmptyAcquireCount()))
+	counter(c.canceledAcquire, float64(s.CanceledAcquireCount()))
+	counter(c.newConns, float64(s.NewConnsCount()))
+}
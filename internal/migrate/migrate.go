@@ -0,0 +1,340 @@
+// Package migrate 执行内嵌的数据库迁移脚本（migrations/*.sql）
+//
+// 文件名形如 NNN_name.sql，按版本号升序执行，每个脚本在独立事务中运行，
+// 执行结果记录在 schema_migrations（版本、文件名、SHA-256 校验和、耗时）。
+// 已执行脚本的内容被修改时拒绝继续，避免数据库与代码中的定义不一致。
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yourname/15min-life-circle/internal/database"
+)
+
+var (
+	// ErrChecksumMismatch 已执行的迁移脚本内容被修改
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+	// ErrOutOfOrder 待执行的迁移版本低于已执行的最高版本
+	ErrOutOfOrder = errors.New("migration out of order")
+	// ErrUntracked 数据库已有表结构但没有迁移记录（旧版由 docker-entrypoint 初始化）
+	ErrUntracked = errors.New("database schema exists but is not tracked in schema_migrations")
+)
+
+// advisoryLock 迁移期间持有的咨询锁，避免多个实例同时迁移
+const advisoryLock int64 = 15_0000_0001
+
+// Migration 一个迁移脚本
+type Migration struct {
+	Version  int
+	Name     string
+	SQL      string
+	Checksum string
+}
+
+// File 脚本文件名
+func (m Migration) File() string {
+	return fmt.Sprintf("%03d_%s.sql", m.Version, m.Name)
+}
+
+// Load 读取 fsys 根目录下的 NNN_name.sql，按版本号排序；版本号重复时报错
+func Load(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int]string)
+	migrations := make([]Migration, 0, len(files))
+	for _, file := range files {
+		prefix, name, ok := strings.Cut(strings.TrimSuffix(path.Base(file), ".sql"), "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: file name must be NNN_name.sql", file)
+		}
+		if other, dup := seen[version]; dup {
+			return nil, fmt.Errorf("migration version %d used by both %s and %s", version, other, file)
+		}
+		seen[version] = file
+
+		raw, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(raw)
+		migrations = append(migrations, Migration{
+			Version:  version,
+			Name:     name,
+			SQL:      string(raw),
+			Checksum: hex.EncodeToString(sum[:]),
+		})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Applied 一条执行记录
+type Applied struct {
+	Version   int
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+	Duration  time.Duration
+	// 由 baseline 标记，未实际执行
+	Baseline bool
+}
+
+// State 迁移状态
+type State struct {
+	Migration Migration
+	Applied   *Applied
+	// 已执行但脚本内容已变化
+	Modified bool
+}
+
+// Pending 是否待执行
+func (s State) Pending() bool { return s.Applied == nil }
+
+// Migrator 迁移执行器
+type Migrator struct {
+	db         *database.DB
+	migrations []Migration
+	// 执行进度输出，默认不输出
+	Logf func(format string, args ...any)
+}
+
+// New 创建迁移执行器
+func New(db *database.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations, Logf: func(string, ...any) {}}
+}
+
+// ensureTable 创建 schema_migrations
+func (m *Migrator) ensureTable(ctx context.Context, conn *pgx.Conn) error {
+	_, err := conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name VARCHAR(200) NOT NULL,
+			checksum CHAR(64) NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			duration_ms INTEGER NOT NULL DEFAULT 0,
+			baseline BOOLEAN NOT NULL DEFAULT FALSE
+		)`)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// applied 读取执行记录；schema_migrations 不存在时返回 nil
+func applied(ctx context.Context, q interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}) (map[int]*Applied, error) {
+	var exists bool
+	if err := q.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("check schema_migrations: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT version, name, checksum, applied_at, duration_ms, baseline
+		FROM schema_migrations
+		ORDER BY version`)
+	if err != nil {
+		return nil, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	out := make(map[int]*Applied)
+	for rows.Next() {
+		var a Applied
+		var ms int
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt, &ms, &a.Baseline); err != nil {
+			return nil, fmt.Errorf("scan schema_migrations: %w", err)
+		}
+		a.Duration = time.Duration(ms) * time.Millisecond
+		out[a.Version] = &a
+	}
+	return out, rows.Err()
+}
+
+// Status 各迁移脚本的执行状态，以及数据库中有记录但当前代码中不存在的版本
+func (m *Migrator) Status(ctx context.Context) ([]State, []Applied, error) {
+	done, err := applied(ctx, m.db.Pool)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.states(done)
+}
+
+func (m *Migrator) states(done map[int]*Applied) ([]State, []Applied, error) {
+	states := make([]State, 0, len(m.migrations))
+	known := make(map[int]bool, len(m.migrations))
+	for _, mig := range m.migrations {
+		known[mig.Version] = true
+		st := State{Migration: mig, Applied: done[mig.Version]}
+		st.Modified = st.Applied != nil && st.Applied.Checksum != mig.Checksum
+		states = append(states, st)
+	}
+
+	var unknown []Applied
+	for v, a := range done {
+		if !known[v] {
+			unknown = append(unknown, *a)
+		}
+	}
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i].Version < unknown[j].Version })
+	return states, unknown, nil
+}
+
+// check 检查已执行脚本的校验和与执行顺序，返回待执行的迁移
+func check(states []State) ([]Migration, error) {
+	var pending []Migration
+	latest := 0
+	for _, st := range states {
+		if st.Modified {
+			return nil, fmt.Errorf("%w: %s (applied %s, now %s)",
+				ErrChecksumMismatch, st.Migration.File(), st.Applied.Checksum[:12], st.Migration.Checksum[:12])
+		}
+		if st.Applied != nil {
+			latest = st.Migration.Version
+		}
+	}
+	for _, st := range states {
+		if !st.Pending() {
+			continue
+		}
+		if st.Migration.Version < latest {
+			return nil, fmt.Errorf("%w: %s is pending but version %d is already applied",
+				ErrOutOfOrder, st.Migration.File(), latest)
+		}
+		pending = append(pending, st.Migration)
+	}
+	return pending, nil
+}
+
+// Up 按版本顺序执行全部待执行的迁移，返回本次执行的迁移
+// 尚无执行记录而数据库中已有表结构时返回 ErrUntracked，需先运行 Baseline
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *pgx.Conn) error {
+		records, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			var legacy bool
+			if err := conn.QueryRow(ctx, `SELECT to_regclass('poi') IS NOT NULL`).Scan(&legacy); err != nil {
+				return err
+			}
+			if legacy {
+				return ErrUntracked
+			}
+		}
+		if err := m.ensureTable(ctx, conn); err != nil {
+			return err
+		}
+
+		states, _, err := m.states(records)
+		if err != nil {
+			return err
+		}
+		pending, err := check(states)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range pending {
+			m.Logf("applying %s", mig.File())
+			start := time.Now()
+			if err := apply(ctx, conn, mig, start); err != nil {
+				return err
+			}
+			m.Logf("applied %s in %s", mig.File(), time.Since(start).Round(time.Millisecond))
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// apply 在事务中执行脚本并写入执行记录
+func apply(ctx context.Context, conn *pgx.Conn, mig Migration, start time.Time) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// 无参数的 Exec 使用简单协议，可一次执行多条语句
+	if _, err := tx.Exec(ctx, mig.SQL); err != nil {
+		return fmt.Errorf("migration %s: %w", mig.File(), err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO schema_migrations (version, name, checksum, duration_ms)
+		VALUES ($1, $2, $3, $4)`,
+		mig.Version, mig.Name, mig.Checksum, time.Since(start).Milliseconds(),
+	); err != nil {
+		return fmt.Errorf("record migration %s: %w", mig.File(), err)
+	}
+	return tx.Commit(ctx)
+}
+
+// Baseline 将版本不超过 through 的迁移标记为已执行（不实际执行），
+// 用于由 docker-entrypoint 或手工执行脚本初始化的已有数据库；返回新标记的迁移
+func (m *Migrator) Baseline(ctx context.Context, through int) ([]Migration, error) {
+	var marked []Migration
+	err := m.locked(ctx, func(conn *pgx.Conn) error {
+		if err := m.ensureTable(ctx, conn); err != nil {
+			return err
+		}
+		records, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if mig.Version > through || records[mig.Version] != nil {
+				continue
+			}
+			if _, err := conn.Exec(ctx, `
+				INSERT INTO schema_migrations (version, name, checksum, baseline)
+				VALUES ($1, $2, $3, TRUE)`,
+				mig.Version, mig.Name, mig.Checksum,
+			); err != nil {
+				return fmt.Errorf("baseline %s: %w", mig.File(), err)
+			}
+			marked = append(marked, mig)
+		}
+		return nil
+	})
+	return marked, err
+}
+
+// locked 在持有咨询锁的连接上执行 fn
+func (m *Migrator) locked(ctx context.Context, fn func(conn *pgx.Conn) error) error {
+	c, err := m.db.Pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire connection: %w", err)
+	}
+	defer c.Release()
+	conn := c.Conn()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, advisoryLock); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, advisoryLock)
+
+	return fn(conn)
+}
@@ -0,0 +1,75 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/yourname/15min-life-circle/internal/database"
+)
+
+// Function 服务依赖的数据库函数及其参数类型（oidvectortypes 格式）
+type Function struct {
+	Name string
+	Args string
+}
+
+// RequiredFunctions 服务运行所需的 SQL 函数
+// 参数类型与 migrations 中的定义一致；同名函数存在其他重载时调用可能解析到错误的版本，同样视为错误。
+var RequiredFunctions = []Function{
+	{Name: "calculate_isochrones", Args: "double precision, double precision, integer[], double precision"},
+	{Name: "get_reachable_roads", Args: "double precision, double precision, integer, double precision"},
+	{Name: "evaluate_life_circle", Args: "double precision, double precision, double precision"},
+	{Name: "query_pois_in_isochrone", Args: "double precision, double precision, integer, double precision, character varying"},
+}
+
+// VerifyFunctions 检查必需函数是否存在且签名一致，返回全部不一致项
+func VerifyFunctions(ctx context.Context, db *database.DB) error {
+	names := make([]string, len(RequiredFunctions))
+	for i, f := range RequiredFunctions {
+		names[i] = f.Name
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT p.proname, oidvectortypes(p.proargtypes)
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE p.proname = ANY($1)
+		  AND n.nspname = ANY(current_schemas(false))`, names)
+	if err != nil {
+		return fmt.Errorf("query pg_proc: %w", err)
+	}
+	defer rows.Close()
+
+	found := make(map[string][]string)
+	for rows.Next() {
+		var name, args string
+		if err := rows.Scan(&name, &args); err != nil {
+			return err
+		}
+		found[name] = append(found[name], args)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var problems []string
+	for _, f := range RequiredFunctions {
+		sigs := found[f.Name]
+		ok := false
+		for _, args := range sigs {
+			if args == f.Args {
+				ok = true
+			} else {
+				problems = append(problems, fmt.Sprintf("unexpected overload %s(%s)", f.Name, args))
+			}
+		}
+		if !ok {
+			problems = append(problems, fmt.Sprintf("missing %s(%s)", f.Name, f.Args))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("database functions do not match: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// 客户端等级
+const (
+	TierAnonymous = "anonymous"
+	TierPublic    = "public"
+	TierInternal  = "internal"
+)
+
+// APIKey API Key（不含明文与摘要）
+type APIKey struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Prefix string `json:"prefix"`
+	Tier   string `json:"tier"`
+	// 覆盖等级默认限额，为空时使用等级默认值
+	RatePerMinute *int       `json:"rate_per_minute,omitempty"`
+	Burst         *int       `json:"burst,omitempty"`
+	DailyQuota    *int       `json:"daily_quota,omitempty"`
+	CreatedBy     string     `json:"created_by"`
+	CreatedAt     time.Time  `json:"created_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy     string     `json:"revoked_by,omitempty"`
+	LastUsedAt    *time.Time `json:"last_used_at,omitempty"`
+}
+
+// Revoked 是否已吊销
+func (k *APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+// APIKeyCreateRequest 签发 API Key 请求
+type APIKeyCreateRequest struct {
+	Name string `json:"name" binding:"required"`
+	// public（默认）/ internal
+	Tier          string `json:"tier"`
+	RatePerMinute *int   `json:"rate_per_minute"`
+	Burst         *int   `json:"burst"`
+	DailyQuota    *int   `json:"daily_quota"`
+}
+
+// Validate 验证请求参数并填充默认值
+func (r *APIKeyCreateRequest) Validate() error {
+	switch r.Tier {
+	case "":
+		r.Tier = TierPublic
+	case TierPublic, TierInternal:
+	default:
+		return errors.New("tier must be public or internal")
+	}
+	if r.RatePerMinute != nil && *r.RatePerMinute < 1 {
+		return errors.New("rate_per_minute must be at least 1")
+	}
+	if r.Burst != nil && *r.Burst < 1 {
+		return errors.New("burst must be at least 1")
+	}
+	if r.DailyQuota != nil && *r.DailyQuota < 0 {
+		return errors.New("daily_quota must not be negative")
+	}
+	return nil
+}
+
+// APIKeyIssued 新签发的 API Key，明文只在此返回一次
+type APIKeyIssued struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyUsage 某日某路由的请求数
+type APIKeyUsage struct {
+	Day      string `json:"day"`
+	Route    string `json:"route"`
+	Requests int    `json:"requests"`
+	Rejected int    `json:"rejected"`
+	Errors   int    `json:"errors"`
+}
+
+// APIKeyUsageReport API Key 用量
+type APIKeyUsageReport struct {
+	Key APIKey `json:"key"`
+	// 生效的限额（Key 覆盖值或等级默认值）
+	RatePerMinute int `json:"rate_per_minute"`
+	Burst         int `json:"burst"`
+	// 0 表示不限制
+	DailyQuota int `json:"daily_quota"`
+	// 今日已用的高开销接口次数
+	QuotaUsedToday int           `json:"quota_used_today"`
+	Days           int           `json:"days"`
+	Usage          []APIKeyUsage `json:"usage"`
+}
@@ -0,0 +1,84 @@
+package model
+
+import "errors"
+
+// 出行方式
+const (
+	ModeWalk = "walk"
+	ModeBike = "bike"
+)
+
+// 默认速度 (km/h)
+const (
+	DefaultWalkSpeed = 5.0
+	DefaultBikeSpeed = 15.0
+)
+
+// CatchmentRequest 设施服务范围请求，POI ID 与坐标二选一
+type CatchmentRequest struct {
+	// 设施 POI ID
+	POIID *int64 `json:"poi_id"`
+	// 设施坐标
+	Lng *float64 `json:"lng"`
+	Lat *float64 `json:"lat"`
+	// 时间阈值（分钟），默认 [5, 10, 15]
+	TimeThresholds []int `json:"time_thresholds"`
+	// 出行方式：walk（默认）/ bike
+	Mode string `json:"mode"`
+	// 速度 (km/h)，步行默认 5，骑行默认 15
+	Speed float64 `json:"speed"`
+}
+
+// Validate 验证请求参数并填充默认值
+func (r *CatchmentRequest) Validate() error {
+	if r.POIID == nil && (r.Lng == nil || r.Lat == nil) {
+		return errors.New("poi_id or lng/lat is required")
+	}
+	if r.POIID != nil && r.Lng != nil {
+		return errors.New("give either poi_id or lng/lat, not both")
+	}
+	if len(r.TimeThresholds) == 0 {
+		r.TimeThresholds = []int{5, 10, 15}
+	}
+	for _, t := range r.TimeThresholds {
+		if t <= 0 || t > 60 {
+			return errors.New("time thresholds must be between 1 and 60 minutes")
+		}
+	}
+	switch r.Mode {
+	case "", ModeWalk:
+		r.Mode = ModeWalk
+		if r.Speed <= 0 {
+			r.Speed = DefaultWalkSpeed
+		}
+	case ModeBike:
+		if r.Speed <= 0 {
+			r.Speed = DefaultBikeSpeed
+		}
+	default:
+		return errors.New("mode must be walk or bike")
+	}
+	return nil
+}
+
+// CatchmentPolygon 某一时间阈值的服务范围
+type CatchmentPolygon struct {
+	Minutes  int      `json:"minutes"`
+	Geometry Geometry `json:"geometry"`
+	Fallback string   `json:"fallback,omitempty"`
+	// 覆盖人口，未导入人口数据时为空
+	Population *float64 `json:"population,omitempty"`
+}
+
+// CatchmentResult 设施服务范围结果
+type CatchmentResult struct {
+	// 设施位置
+	Origin   Point              `json:"origin"`
+	POIID    *int64             `json:"poi_id,omitempty"`
+	POIName  string             `json:"poi_name,omitempty"`
+	Mode     string             `json:"mode"`
+	Speed    float64            `json:"speed"`
+	Polygons []CatchmentPolygon `json:"polygons"`
+	// 是否已导入人口数据
+	PopulationAvailable bool `json:"population_available"`
+}
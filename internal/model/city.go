@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// City 已导入数据的城市
+type City struct {
+	// 城市代码，如 hangzhou
+	Code string `json:"code"`
+	// 显示名称
+	Name string `json:"name"`
+	// 描述，如 浙江省杭州市
+	Description string `json:"description"`
+	// 地图默认中心 [lng, lat]
+	Center Point `json:"center"`
+	// 地图默认缩放级别
+	Zoom int `json:"zoom"`
+	// 数据边界 [[west, south], [east, north]]
+	Bounds [2]Point `json:"bounds"`
+	// 数据导入时间（未导入时为空）
+	DataImportedAt *time.Time `json:"data_imported_at,omitempty"`
+	// 各分类 POI 数量
+	POICounts map[string]int `json:"poi_counts"`
+	// 路网规模
+	Network CityNetwork `json:"network"`
+}
+
+// CityNetwork 城市路网规模
+type CityNetwork struct {
+	Nodes    int     `json:"nodes"`
+	Edges    int     `json:"edges"`
+	LengthKm float64 `json:"length_km"`
+}
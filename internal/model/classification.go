@@ -0,0 +1,55 @@
+package model
+
+// ClassificationRule 分类规则（对应 poi_rule 表）
+type ClassificationRule struct {
+	ID         int64  `json:"id"`
+	Expression string `json:"expression"`
+	Category   string `json:"category"`
+	SubType    string `json:"sub_type"`
+	Priority   int    `json:"priority"`
+}
+
+// ClassificationDryRunRequest 规则试运行请求
+type ClassificationDryRunRequest struct {
+	// 待测试的规则集，为空时使用数据库中已启用的规则
+	Rules []ClassificationRule `json:"rules"`
+	// 样例 OSM 标签
+	Samples []map[string]string `json:"samples" binding:"required,min=1"`
+}
+
+// ClassificationSampleResult 单个样例的分类结果
+type ClassificationSampleResult struct {
+	Tags map[string]string `json:"tags"`
+	// 最终采用的规则（优先级最高），未匹配时为空
+	Matched *ClassificationRule `json:"matched"`
+	// 所有匹配的规则（按优先级），多于一条时说明存在规则重叠
+	Matches []ClassificationRule `json:"matches"`
+}
+
+// ClassificationDryRunResult 规则试运行结果
+type ClassificationDryRunResult struct {
+	RuleCount int                          `json:"rule_count"`
+	Results   []ClassificationSampleResult `json:"results"`
+	// 未匹配任何规则的样例数
+	Unmatched int `json:"unmatched"`
+}
+
+// ClassificationGap 评价标准引用但规则无法产生的子类型
+type ClassificationGap struct {
+	Category string `json:"category"`
+	SubType  string `json:"sub_type"`
+	Required bool   `json:"required"`
+	// 规则产生了该子类型但归入了其他分类时，记录规则中的分类
+	RuleCategory string `json:"rule_category,omitempty"`
+}
+
+// ClassificationReport 规则覆盖报告
+type ClassificationReport struct {
+	RuleCount int `json:"rule_count"`
+	// 规则能产生的子类型 -> 分类
+	Produced map[string]string `json:"produced"`
+	// 评价标准中引用、但没有任何规则产生的子类型
+	Missing []ClassificationGap `json:"missing"`
+	// 子类型存在但分类与评价标准不一致
+	Mismatched []ClassificationGap `json:"mismatched"`
+}
@@ -0,0 +1,159 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 公平性分析的权重方式
+const (
+	// 格网样点按代表的面积加权，指定点集等权
+	EquityWeightDefault = ""
+	// 按人口加权
+	EquityWeightPopulation = "population"
+)
+
+// MaxEquityPoints 指定点集公平性分析的点数上限（每个点都需要一次路网分析）
+const MaxEquityPoints = 200
+
+// EquityPoint 参与公平性分析的居住点
+type EquityPoint struct {
+	Label string  `json:"label"`
+	Lng   float64 `json:"lng" binding:"required"`
+	Lat   float64 `json:"lat" binding:"required"`
+	// 该点代表的人口，按人口加权时必填
+	Population *float64 `json:"population"`
+}
+
+// EquityPointsRequest 指定点集的公平性分析请求
+type EquityPointsRequest struct {
+	Points    []EquityPoint `json:"points" binding:"required,dive"`
+	WalkSpeed float64       `json:"walk_speed"`
+	// 权重方式：空（等权）/ population
+	Weight string `json:"weight"`
+}
+
+// Validate 验证请求参数并填充默认值
+func (r *EquityPointsRequest) Validate() error {
+	if len(r.Points) < 2 || len(r.Points) > MaxEquityPoints {
+		return fmt.Errorf("points must contain 2 to %d points", MaxEquityPoints)
+	}
+	if r.Weight != EquityWeightDefault && r.Weight != EquityWeightPopulation {
+		return errors.New("weight must be empty or population")
+	}
+	for i := range r.Points {
+		p := &r.Points[i]
+		if p.Label == "" {
+			p.Label = fmt.Sprintf("点%d", i+1)
+		}
+		if p.Lng < -180 || p.Lng > 180 || p.Lat < -90 || p.Lat > 90 {
+			return fmt.Errorf("point #%d: coordinates out of range", i+1)
+		}
+		if p.Population != nil && *p.Population < 0 {
+			return fmt.Errorf("point #%d: population must not be negative", i+1)
+		}
+		if r.Weight == EquityWeightPopulation && p.Population == nil {
+			return fmt.Errorf("point #%d: population is required when weighting by population", i+1)
+		}
+	}
+	if r.WalkSpeed <= 0 {
+		r.WalkSpeed = DefaultWalkSpeed
+	}
+	if r.WalkSpeed < 3 || r.WalkSpeed > 7 {
+		return errors.New("walk_speed must be between 3 and 7 km/h")
+	}
+	return nil
+}
+
+// EquityReport 生活圈评分分布的公平性分析
+type EquityReport struct {
+	// 数据来源：grid（区域格网样点）/ points（指定点集）
+	Source   string `json:"source"`
+	City     string `json:"city,omitempty"`
+	Level    string `json:"level,omitempty"`
+	RegionID *int   `json:"region_id,omitempty"`
+	// 权重方式：area / population / equal
+	Weight string `json:"weight"`
+	// 参与分析的点数；格网中尚未计算 POI 数量的样点不参与
+	Samples    int `json:"samples"`
+	Unmeasured int `json:"unmeasured,omitempty"`
+	// 权重为 0 的点数（按人口加权时缺少人口或人口为 0），不计入分布与服务盲区
+	Unweighted int `json:"unweighted,omitempty"`
+	// 总分与各分类得分的分布
+	Total      Distribution           `json:"total"`
+	Categories []CategoryDistribution `json:"categories"`
+	// 缺少必备设施的点（服务盲区）
+	Deserts       DesertSummary `json:"deserts"`
+	StandardsHash string        `json:"standards_hash"`
+	ComputedAt    time.Time     `json:"computed_at"`
+}
+
+// Distribution 一组（加权）评分的分布
+type Distribution struct {
+	Mean float64 `json:"mean"`
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	// 基尼系数（0 完全均等 ~ 1）
+	Gini float64 `json:"gini"`
+	// 泰尔指数（0 完全均等，上限 ln N）
+	Theil       float64     `json:"theil"`
+	Percentiles Percentiles `json:"percentiles"`
+	// 洛伦兹曲线，按权重累计占比每 5% 取一点
+	Lorenz []LorenzPoint `json:"lorenz"`
+}
+
+// Percentiles 加权分位数
+type Percentiles struct {
+	P10 float64 `json:"p10"`
+	P25 float64 `json:"p25"`
+	P50 float64 `json:"p50"`
+	P75 float64 `json:"p75"`
+	P90 float64 `json:"p90"`
+}
+
+// LorenzPoint 洛伦兹曲线上的点：得分最低的 Population 占比所拥有的得分占比
+type LorenzPoint struct {
+	Population float64 `json:"population"`
+	Score      float64 `json:"score"`
+}
+
+// CategoryDistribution 分类得分的分布
+type CategoryDistribution struct {
+	Category string `json:"category"`
+	Name     string `json:"name"`
+	Distribution
+}
+
+// DesertSummary 服务盲区汇总
+type DesertSummary struct {
+	// 缺少至少一项必备设施的点数及其权重占比
+	Count int     `json:"count"`
+	Share float64 `json:"share"`
+	// 各必备设施缺失的权重占比
+	Facilities []FacilityGap `json:"facilities"`
+	// 缺失项最多的盲区点（缺失项数、权重从高到低），至多 Limit 个
+	Points    []DesertPoint `json:"points"`
+	Truncated bool          `json:"truncated"`
+}
+
+// FacilityGap 必备设施在 15 分钟圈内未达标的权重占比
+type FacilityGap struct {
+	Category string  `json:"category"`
+	SubType  string  `json:"sub_type"`
+	Name     string  `json:"name"`
+	Count    int     `json:"count"`
+	Share    float64 `json:"share"`
+}
+
+// DesertPoint 服务盲区点
+type DesertPoint struct {
+	Label      string   `json:"label,omitempty"`
+	RegionID   *int     `json:"region_id,omitempty"`
+	RegionName string   `json:"region_name,omitempty"`
+	Lng        float64  `json:"lng"`
+	Lat        float64  `json:"lat"`
+	Weight     float64  `json:"weight"`
+	TotalScore float64  `json:"total_score"`
+	Missing    []string `json:"missing"`
+}
@@ -1,5 +1,10 @@
 package model
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // EvaluationRequest 综合评价请求
 type EvaluationRequest struct {
 	// 起点经度
@@ -10,6 +15,14 @@ type EvaluationRequest struct {
 	TimeThreshold int `json:"time_threshold"`
 	// 步行速度（km/h，默认5.0）
 	WalkSpeed float64 `json:"walk_speed"`
+	// 评价时刻（RFC 3339），指定后只计入该时刻营业的设施
+	At *time.Time `json:"at,omitempty"`
+	// 同时计算早间、日间、晚间、夜间各时段的可用性评分
+	Profile bool `json:"profile"`
+
+	// 登录用户与常用地点，由处理器填写，评价记录归入该用户的历史
+	UserID  *int `json:"-"`
+	PlaceID *int `json:"-"`
 }
 
 // Validate 验证请求参数
@@ -45,30 +58,113 @@ type EvaluationResult struct {
 	POIs *FeatureCollection `json:"pois"`
 	// 可达道路网络 GeoJSON（可选）
 	Roads interface{} `json:"roads,omitempty"`
+	// 起点覆盖检查结果
+	Coverage *CoverageInfo `json:"coverage,omitempty"`
+	// 回退方式：空表示路网分析，"buffer" 表示圆形缓冲区
+	Fallback string `json:"fallback,omitempty"`
+	// 各必备设施的最近路网步行时间
+	NearestRequired []NearestRequired `json:"nearest_required,omitempty"`
+	// 按时刻评价时的营业情况（请求指定 at 时返回）
+	OpenStatus *OpenStatus `json:"open_status,omitempty"`
+	// 24 小时可用性曲线（请求 profile 时返回）
+	Profile []AvailabilitySlot `json:"profile,omitempty"`
 	// 评价说明
 	Summary string `json:"summary"`
 	// 改进建议
 	Suggestions []string `json:"suggestions"`
+	// 评价记录 ID（analysis_history），保存失败时为空
+	AnalysisID string `json:"analysis_id,omitempty"`
+	// 分享链接 ID 与页面路径（/a/{share_id}），保存失败时为空
+	ShareID   string `json:"share_id,omitempty"`
+	Permalink string `json:"permalink,omitempty"`
+}
+
+// Permalink 分享链接 ID 对应的页面路径
+func Permalink(shareID string) string {
+	return "/a/" + shareID
+}
+
+// AnalysisRecord 综合评价记录（analysis_history）
+type AnalysisRecord struct {
+	ID             string  `json:"id"`
+	Lng            float64 `json:"lng"`
+	Lat            float64 `json:"lat"`
+	TimeThresholds []int   `json:"time_thresholds"`
+	WalkSpeed      float64 `json:"walk_speed"`
+	TotalScore     float64 `json:"total_score"`
+	Grade          string  `json:"grade"`
+	// 完整评价结果
+	Result json.RawMessage `json:"result,omitempty"`
+	// 5/10/15 分钟等时圈，用于 POI 变更时使记录失效
+	Isochrones []IsochronePolygon `json:"-"`
+	CreatedAt  time.Time          `json:"created_at"`
+	// POI 变更后标记失效的时间
+	InvalidatedAt *time.Time `json:"invalidated_at,omitempty"`
+	// 所属用户，匿名评价为空
+	UserID *int `json:"-"`
+	// 评价的常用地点（地点删除后为空）
+	PlaceID   *int   `json:"place_id,omitempty"`
+	PlaceName string `json:"place_name,omitempty"`
+	// 完整的评价请求，重新评价时使用
+	Params json.RawMessage `json:"params,omitempty"`
+	// 分享链接 ID，024 迁移之前的记录为空
+	ShareID string `json:"share_id,omitempty"`
+	// 评价时起点所在城市及其路网版本
+	City           string `json:"city,omitempty"`
+	NetworkVersion int64  `json:"network_version"`
+	// 城市当前的路网版本（仅 GetSharedAnalysis 填写）
+	CurrentNetworkVersion int64 `json:"-"`
+	// 起点吸附的路网节点，保存时据此确定城市与路网版本
+	Node int64 `json:"-"`
+}
+
+// SharedAnalysis 通过分享链接查看的评价记录
+type SharedAnalysis struct {
+	// 分享链接 ID
+	ID        string    `json:"id"`
+	Permalink string    `json:"permalink"`
+	CreatedAt time.Time `json:"created_at"`
+	// 评价参数
+	Params EvaluationRequest `json:"params"`
+	// 保存的评价结果（EvaluationResult）
+	Result      json.RawMessage `json:"result"`
+	DataVersion DataVersion     `json:"data_version"`
+}
+
+// DataVersion 评价结果所基于的数据版本
+type DataVersion struct {
+	City string `json:"city,omitempty"`
+	// 评价时的路网版本
+	NetworkVersion int64 `json:"network_version"`
+	// 当前路网版本，与 NetworkVersion 不同说明路网已重新导入
+	CurrentNetworkVersion int64 `json:"current_network_version"`
+	// 等时圈内 POI 变更后标记失效的时间
+	InvalidatedAt *time.Time `json:"invalidated_at,omitempty"`
+	// 路网或 POI 已变化，重新评价的结果可能不同
+	Stale bool `json:"stale"`
 }
 
 // CategoryScore 分类评分
 type CategoryScore struct {
-	Category    string  `json:"category"`
-	Name        string  `json:"name"`
-	Score       float64 `json:"score"`       // 0-100
-	Weight      float64 `json:"weight"`      // 权重
-	WeightedScore float64 `json:"weighted_score"` // 加权得分
-	POICount    int     `json:"poi_count"`
-	HasRequired bool    `json:"has_required"` // 是否满足必备设施
-	Details     []SubTypeScore `json:"details"`
+	Category      string         `json:"category"`
+	Name          string         `json:"name"`
+	Score         float64        `json:"score"`          // 0-100
+	Weight        float64        `json:"weight"`         // 权重
+	WeightedScore float64        `json:"weighted_score"` // 加权得分
+	POICount      int            `json:"poi_count"`
+	HasRequired   bool           `json:"has_required"` // 是否满足必备设施
+	Details       []SubTypeScore `json:"details"`
 }
 
 // SubTypeScore 子类型评分
 type SubTypeScore struct {
-	SubType  string `json:"sub_type"`
-	Name     string `json:"name"`
-	Count    int    `json:"count"`
-	Required int    `json:"required"` // 标准要求数量
+	SubType string `json:"sub_type"`
+	Name    string `json:"name"`
+	Count   int    `json:"count"`
+	// 5、10 分钟圈内的数量，仅综合评价返回
+	Count5   int     `json:"count_5,omitempty"`
+	Count10  int     `json:"count_10,omitempty"`
+	Required int     `json:"required"` // 标准要求数量
 	Score    float64 `json:"score"`
 }
 
@@ -76,65 +172,67 @@ type SubTypeScore struct {
 // 参考《城市居住区规划设计标准》GB 50180-2018
 // 以及各城市15分钟生活圈规划导则
 type EvaluationStandard struct {
-	Category     string `json:"category"`
-	SubType      string `json:"sub_type"`
+	Category string `json:"category"`
+	SubType  string `json:"sub_type"`
 	// 15分钟圈内要求的最少数量
-	MinCount15   int    `json:"min_count_15"`
+	MinCount15 int `json:"min_count_15"`
 	// 10分钟圈内要求的最少数量
-	MinCount10   int    `json:"min_count_10"`
+	MinCount10 int `json:"min_count_10"`
 	// 5分钟圈内要求的最少数量
-	MinCount5    int    `json:"min_count_5"`
+	MinCount5 int `json:"min_count_5"`
 	// 是否为必备设施
-	Required     bool   `json:"required"`
+	Required bool `json:"required"`
 	// 分值基础
-	BaseScore    float64 `json:"base_score"`
+	BaseScore float64 `json:"base_score"`
 }
 
 // GetDefaultStandards 返回默认评价标准
-// 注意：子类型必须与数据库中 poi 表的 sub_type 字段匹配
+// 与 001_init_schema.sql 中 evaluation_standard 的初始数据一致，
+// 子类型取自 POI 分类体系，由 poi_rule 中的分类规则产生
 func GetDefaultStandards() []EvaluationStandard {
 	return []EvaluationStandard{
-		// 医疗卫生 (数据库: hospital, pharmacy, clinic, dentist)
-		{Category: "medical", SubType: "clinic", MinCount15: 1, MinCount10: 1, MinCount5: 0, Required: true, BaseScore: 30},
-		{Category: "medical", SubType: "pharmacy", MinCount15: 2, MinCount10: 1, MinCount5: 1, Required: false, BaseScore: 10},
-		{Category: "medical", SubType: "hospital", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: false, BaseScore: 10},
-		{Category: "medical", SubType: "dentist", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: false, BaseScore: 5},
-
-		// 教育设施 (数据库: kindergarten, school, college, university, library)
-		{Category: "education", SubType: "kindergarten", MinCount15: 1, MinCount10: 1, MinCount5: 0, Required: true, BaseScore: 25},
-		{Category: "education", SubType: "school", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: true, BaseScore: 25},
-		{Category: "education", SubType: "college", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: false, BaseScore: 10},
-		{Category: "education", SubType: "library", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: false, BaseScore: 10},
-
-		// 商业服务 (数据库: supermarket, convenience, marketplace, restaurant, cafe...)
-		{Category: "commerce", SubType: "supermarket", MinCount15: 1, MinCount10: 1, MinCount5: 0, Required: true, BaseScore: 20},
-		{Category: "commerce", SubType: "convenience", MinCount15: 3, MinCount10: 2, MinCount5: 1, Required: false, BaseScore: 10},
-		{Category: "commerce", SubType: "marketplace", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: false, BaseScore: 10},
-		{Category: "commerce", SubType: "restaurant", MinCount15: 2, MinCount10: 1, MinCount5: 0, Required: false, BaseScore: 5},
-
-		// 文化体育 (数据库: park, playground, cinema, pitch, garden, sports_centre, fitness_centre, community_centre)
-		{Category: "culture", SubType: "park", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: true, BaseScore: 20},
-		{Category: "culture", SubType: "playground", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: false, BaseScore: 10},
-		{Category: "culture", SubType: "sports_centre", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: false, BaseScore: 10},
-		{Category: "culture", SubType: "community_centre", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: false, BaseScore: 10},
-		{Category: "culture", SubType: "cinema", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: false, BaseScore: 5},
-
-		// 公共管理 (数据库: police, post_office, townhall)
-		{Category: "public", SubType: "police", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: false, BaseScore: 15},
-		{Category: "public", SubType: "post_office", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: false, BaseScore: 15},
-		{Category: "public", SubType: "townhall", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: false, BaseScore: 20},
-
-		// 交通设施 (数据库: platform, stop_position, station, bus_station, ferry_terminal)
-		{Category: "transport", SubType: "platform", MinCount15: 2, MinCount10: 1, MinCount5: 1, Required: true, BaseScore: 15},
-		{Category: "transport", SubType: "stop_position", MinCount15: 2, MinCount10: 1, MinCount5: 1, Required: false, BaseScore: 10},
-		{Category: "transport", SubType: "station", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: false, BaseScore: 20},
-		{Category: "transport", SubType: "bus_station", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: false, BaseScore: 10},
-
-		// 养老服务 (数据库: social_facility)
-		{Category: "elderly", SubType: "social_facility", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: false, BaseScore: 25},
-
-		// 托幼托育 (需要高德API补充，数据库无相关数据)
-		{Category: "child", SubType: "nursery", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: false, BaseScore: 25},
+		// 医疗卫生
+		{Category: "medical", SubType: "community_health", MinCount15: 1, MinCount10: 1, MinCount5: 0, Required: true, BaseScore: 40},
+		{Category: "medical", SubType: "pharmacy", MinCount15: 3, MinCount10: 2, MinCount5: 1, Required: false, BaseScore: 15},
+		{Category: "medical", SubType: "hospital", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: false, BaseScore: 15},
+
+		// 教育设施
+		{Category: "education", SubType: "kindergarten", MinCount15: 1, MinCount10: 1, MinCount5: 0, Required: true, BaseScore: 35},
+		{Category: "education", SubType: "primary", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: true, BaseScore: 35},
+		{Category: "education", SubType: "secondary", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: false, BaseScore: 15},
+
+		// 养老服务
+		{Category: "elderly", SubType: "elderly_center", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: true, BaseScore: 35},
+		{Category: "elderly", SubType: "daycare", MinCount15: 1, MinCount10: 1, MinCount5: 0, Required: false, BaseScore: 25},
+		{Category: "elderly", SubType: "elderly_activity", MinCount15: 2, MinCount10: 1, MinCount5: 0, Required: false, BaseScore: 15},
+
+		// 商业服务
+		{Category: "commerce", SubType: "market", MinCount15: 1, MinCount10: 1, MinCount5: 0, Required: true, BaseScore: 30},
+		{Category: "commerce", SubType: "supermarket", MinCount15: 2, MinCount10: 1, MinCount5: 0, Required: false, BaseScore: 20},
+		{Category: "commerce", SubType: "convenience", MinCount15: 4, MinCount10: 2, MinCount5: 1, Required: false, BaseScore: 15},
+		{Category: "commerce", SubType: "restaurant", MinCount15: 4, MinCount10: 2, MinCount5: 0, Required: false, BaseScore: 10},
+
+		// 文化体育
+		{Category: "culture", SubType: "culture_center", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: true, BaseScore: 25},
+		{Category: "culture", SubType: "sports_field", MinCount15: 2, MinCount10: 1, MinCount5: 0, Required: true, BaseScore: 25},
+		{Category: "culture", SubType: "park", MinCount15: 1, MinCount10: 1, MinCount5: 0, Required: true, BaseScore: 25},
+		{Category: "culture", SubType: "library", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: false, BaseScore: 15},
+
+		// 公共管理
+		{Category: "public", SubType: "community_service", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: true, BaseScore: 30},
+		{Category: "public", SubType: "police", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: false, BaseScore: 20},
+		{Category: "public", SubType: "bank", MinCount15: 2, MinCount10: 1, MinCount5: 0, Required: false, BaseScore: 15},
+		{Category: "public", SubType: "post", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: false, BaseScore: 10},
+
+		// 交通设施
+		{Category: "transport", SubType: "bus_stop", MinCount15: 3, MinCount10: 2, MinCount5: 1, Required: true, BaseScore: 35},
+		{Category: "transport", SubType: "metro", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: false, BaseScore: 25},
+		{Category: "transport", SubType: "parking", MinCount15: 2, MinCount10: 1, MinCount5: 0, Required: false, BaseScore: 15},
+		{Category: "transport", SubType: "bike_parking", MinCount15: 4, MinCount10: 2, MinCount5: 1, Required: false, BaseScore: 10},
+
+		// 托幼托育
+		{Category: "child", SubType: "nursery", MinCount15: 1, MinCount10: 0, MinCount5: 0, Required: false, BaseScore: 40},
+		{Category: "child", SubType: "playground", MinCount15: 2, MinCount10: 1, MinCount5: 0, Required: false, BaseScore: 30},
 	}
 }
 
@@ -165,3 +263,43 @@ func GetGradeDescription(grade string) string {
 	}
 	return descriptions[grade]
 }
+
+// OpenStatus 某一时刻参与评分的设施（15 分钟圈内的本地 POI）的营业情况
+type OpenStatus struct {
+	At time.Time `json:"at"`
+	// 营业中
+	Open int `json:"open"`
+	// 已打烊
+	Closed int `json:"closed"`
+	// 营业时间未知或无法解析（视为营业）
+	Unknown int `json:"unknown"`
+}
+
+// AvailabilitySlot 一个时段的设施可用性评分
+type AvailabilitySlot struct {
+	// 时段：morning / daytime / evening / night
+	Slot string `json:"slot"`
+	Name string `json:"name"`
+	// 时段范围（本地时间），如 "06:00-09:00"
+	Hours string `json:"hours"`
+	// 时段内按此时刻判断营业
+	OpenStatus
+	TotalScore float64 `json:"total_score"`
+	Grade      string  `json:"grade"`
+	// 各分类得分
+	CategoryScores []CategoryScore `json:"category_scores"`
+}
+
+// 24 小时可用性曲线的时段，Hour/Minute 为判断营业的代表时刻
+var AvailabilitySlots = []struct {
+	Slot, Name, Hours string
+	Hour, Minute      int
+}{
+	{"morning", "早间", "06:00-09:00", 7, 30},
+	{"daytime", "日间", "09:00-17:00", 13, 0},
+	{"evening", "晚间", "17:00-22:00", 19, 30},
+	{"night", "夜间", "22:00-06:00", 23, 30},
+}
+
+// LocalZone 营业时间按北京时间判断（中国不实行夏令时）
+var LocalZone = time.FixedZone("CST", 8*3600)
@@ -0,0 +1,81 @@
+package model
+
+import "encoding/json"
+
+// NearestFacilityRequest 最近设施查询请求
+type NearestFacilityRequest struct {
+	// 起点经度
+	Lng float64 `json:"lng" binding:"required"`
+	// 起点纬度
+	Lat float64 `json:"lat" binding:"required"`
+	// 查询的子类型，默认使用评价标准中的全部子类型
+	SubTypes []string `json:"sub_types"`
+	// 每个子类型返回的设施数，默认 1，最多 5
+	K int `json:"k"`
+	// 步行速度 (km/h)，默认 5
+	WalkSpeed float64 `json:"walk_speed"`
+	// 搜索的最大步行时间（分钟），默认 30
+	MaxMinutes int `json:"max_minutes"`
+	// 不返回路径几何（只需要时间时更快）
+	SkipRoute bool `json:"skip_route"`
+}
+
+// Validate 验证请求参数
+func (r *NearestFacilityRequest) Validate() {
+	if r.K <= 0 {
+		r.K = 1
+	}
+	if r.K > 5 {
+		r.K = 5
+	}
+	if r.WalkSpeed <= 0 {
+		r.WalkSpeed = 5.0
+	}
+	if r.MaxMinutes <= 0 {
+		r.MaxMinutes = 30
+	}
+	if r.MaxMinutes > 60 {
+		r.MaxMinutes = 60
+	}
+}
+
+// NearestFacility 按路网步行时间排序的设施
+type NearestFacility struct {
+	Rank  int     `json:"rank"`
+	POIID int64   `json:"poi_id"`
+	Name  string  `json:"name"`
+	Lng   float64 `json:"lng"`
+	Lat   float64 `json:"lat"`
+	// 路网步行距离（米）
+	DistanceM float64 `json:"distance_m"`
+	// 路网步行时间（分钟）
+	WalkTimeMin float64 `json:"walk_time_min"`
+	// 步行路径 GeoJSON 几何
+	Route json.RawMessage `json:"route,omitempty"`
+}
+
+// NearestFacilityGroup 某一子类型的最近设施
+type NearestFacilityGroup struct {
+	Category string `json:"category"`
+	SubType  string `json:"sub_type"`
+	Required bool   `json:"required"`
+	// 为空表示最大步行时间内没有该类设施
+	Facilities []NearestFacility `json:"facilities"`
+}
+
+// NearestFacilityResult 最近设施查询结果
+type NearestFacilityResult struct {
+	Origin Point                  `json:"origin"`
+	Groups []NearestFacilityGroup `json:"groups"`
+}
+
+// NearestRequired 必备设施的最近步行时间（用于评价结果）
+type NearestRequired struct {
+	Category string `json:"category"`
+	SubType  string `json:"sub_type"`
+	// 是否在最大步行时间内找到
+	Found       bool    `json:"found"`
+	Name        string  `json:"name,omitempty"`
+	WalkTimeMin float64 `json:"walk_time_min,omitempty"`
+	DistanceM   float64 `json:"distance_m,omitempty"`
+}
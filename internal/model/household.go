@@ -0,0 +1,106 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 家庭等时圈起点数量限制
+const (
+	MinHouseholdOrigins = 2
+	MaxHouseholdOrigins = 5
+)
+
+// HouseholdOrigin 家庭成员的起点（如工作地、学校）
+type HouseholdOrigin struct {
+	// 起点名称，如“公司”“学校”
+	Label string  `json:"label"`
+	Lng   float64 `json:"lng" binding:"required"`
+	Lat   float64 `json:"lat" binding:"required"`
+	// 时间阈值（分钟），默认 15
+	Minutes int `json:"minutes"`
+	// 出行方式：walk（默认）/ bike
+	Mode string `json:"mode"`
+	// 速度 (km/h)，步行默认 5，骑行默认 15
+	Speed float64 `json:"speed"`
+}
+
+// HouseholdRequest 多起点家庭等时圈请求
+type HouseholdRequest struct {
+	Origins []HouseholdOrigin `json:"origins" binding:"required,dive"`
+}
+
+// Validate 验证请求参数并填充默认值
+func (r *HouseholdRequest) Validate() error {
+	if len(r.Origins) < MinHouseholdOrigins || len(r.Origins) > MaxHouseholdOrigins {
+		return fmt.Errorf("origins must contain %d to %d points", MinHouseholdOrigins, MaxHouseholdOrigins)
+	}
+	for i := range r.Origins {
+		o := &r.Origins[i]
+		if o.Label == "" {
+			o.Label = fmt.Sprintf("起点%d", i+1)
+		}
+		if o.Minutes <= 0 {
+			o.Minutes = 15
+		}
+		if o.Minutes > 60 {
+			return errors.New("minutes must not exceed 60")
+		}
+		switch o.Mode {
+		case "", ModeWalk:
+			o.Mode = ModeWalk
+			if o.Speed <= 0 {
+				o.Speed = DefaultWalkSpeed
+			}
+		case ModeBike:
+			if o.Speed <= 0 {
+				o.Speed = DefaultBikeSpeed
+			}
+		default:
+			return errors.New("mode must be walk or bike")
+		}
+	}
+	return nil
+}
+
+// HouseholdOriginResult 单个起点的等时圈
+type HouseholdOriginResult struct {
+	Label    string        `json:"label"`
+	Origin   Point         `json:"origin"`
+	Mode     string        `json:"mode"`
+	Speed    float64       `json:"speed"`
+	Minutes  int           `json:"minutes"`
+	Geometry Geometry      `json:"geometry"`
+	Fallback string        `json:"fallback,omitempty"`
+	Coverage *CoverageInfo `json:"coverage,omitempty"`
+}
+
+// HouseholdArea 并集或交集区域
+type HouseholdArea struct {
+	// 交集为空时为 nil
+	Geometry *Geometry `json:"geometry"`
+	AreaM2   float64   `json:"area_m2"`
+	// 覆盖人口，未导入人口数据时为空
+	Population *float64 `json:"population,omitempty"`
+}
+
+// AreaEvaluation 按区域内设施数量评价（不区分 5/10/15 分钟圈层）
+type AreaEvaluation struct {
+	TotalScore     float64         `json:"total_score"`
+	Grade          string          `json:"grade"`
+	Summary        string          `json:"summary"`
+	CategoryScores []CategoryScore `json:"category_scores"`
+	Suggestions    []string        `json:"suggestions"`
+}
+
+// HouseholdResult 多起点家庭等时圈结果
+type HouseholdResult struct {
+	Origins []HouseholdOriginResult `json:"origins"`
+	Union   *HouseholdArea          `json:"union"`
+	// 所有起点都能到达的区域
+	Intersection *HouseholdArea `json:"intersection"`
+	// 交集内的 POI
+	POIs *FeatureCollection `json:"pois"`
+	// 交集区域评价，交集为空时为 nil
+	Evaluation *AreaEvaluation `json:"evaluation"`
+}
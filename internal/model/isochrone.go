@@ -10,6 +10,21 @@ type IsochroneRequest struct {
 	TimeThresholds []int `json:"time_thresholds"`
 	// 步行速度 (km/h)，默认 5
 	WalkSpeed float64 `json:"walk_speed"`
+	// 起点不在数据覆盖范围内时直接拒绝（默认仅标记）
+	RequireCoverage bool `json:"require_coverage"`
+	// 多边形生成算法：concave（默认）/ alpha / road_buffer / interpolated
+	PolygonMethod string `json:"polygon_method"`
+	// 算法参数：concave 为凹度 (0,1]，alpha 为三角形最长边（米），
+	// road_buffer / interpolated 为道路缓冲宽度（米）；为 0 时使用默认值
+	PolygonParam float64 `json:"polygon_param"`
+	// 不按水面、铁路、高速公路屏障裁剪（默认裁剪）
+	IgnoreBarriers bool `json:"ignore_barriers"`
+	// 按路网坡度计算步行时间（需导入 DEM 高程）
+	Terrain bool `json:"terrain"`
+	// 地形模型：tobler（默认，Tobler 徒步函数）/ penalty（上坡线性加罚）
+	TerrainModel string `json:"terrain_model"`
+	// penalty 模型的加罚系数，上坡耗时为 1 + 系数 × 坡度，为 0 时默认 5
+	SlopePenalty float64 `json:"slope_penalty"`
 }
 
 // Validate 验证请求参数
@@ -20,6 +35,40 @@ func (r *IsochroneRequest) Validate() {
 	if r.WalkSpeed <= 0 {
 		r.WalkSpeed = 5.0 // 默认步行速度 5 km/h
 	}
+	if r.PolygonMethod == "" {
+		r.PolygonMethod = PolygonConcave
+	}
+	if r.Terrain && r.TerrainModel == "" {
+		r.TerrainModel = TerrainTobler
+	}
+}
+
+// 等时圈多边形生成算法
+const (
+	PolygonConcave      = "concave"
+	PolygonAlpha        = "alpha"
+	PolygonRoadBuffer   = "road_buffer"
+	PolygonInterpolated = "interpolated"
+)
+
+// ValidPolygonMethod 是否为支持的多边形生成算法
+func ValidPolygonMethod(method string) bool {
+	switch method {
+	case PolygonConcave, PolygonAlpha, PolygonRoadBuffer, PolygonInterpolated:
+		return true
+	}
+	return false
+}
+
+// 地形模型
+const (
+	TerrainTobler  = "tobler"
+	TerrainPenalty = "penalty"
+)
+
+// ValidTerrainModel 是否为支持的地形模型
+func ValidTerrainModel(terrain string) bool {
+	return terrain == TerrainTobler || terrain == TerrainPenalty
 }
 
 // MaxDistanceMeters 计算最大距离（米）
@@ -46,6 +95,18 @@ type IsochroneResult struct {
 	Origin Point `json:"origin"`
 	// 各时间阈值对应的多边形（GeoJSON）
 	Polygons []IsochronePolygon `json:"polygons"`
+	// 起点覆盖检查结果
+	Coverage *CoverageInfo `json:"coverage,omitempty"`
+	// 回退方式：空表示路网分析，"buffer" 表示圆形缓冲区
+	Fallback string `json:"fallback,omitempty"`
+	// 实际使用的多边形生成算法
+	Method string `json:"method"`
+	// 是否有多边形被屏障裁剪
+	Clipped bool `json:"clipped,omitempty"`
+	// 按坡度计算步行时间时使用的地形模型
+	Terrain string `json:"terrain,omitempty"`
+	// 可达节点是否读取自节点可达性预计算（node_reach），多边形仍按起点生成
+	Cached bool `json:"cached,omitempty"`
 }
 
 // IsochronePolygon 单个等时圈多边形
@@ -56,4 +117,31 @@ type IsochronePolygon struct {
 	Distance float64 `json:"distance"`
 	// GeoJSON 几何
 	Geometry Geometry `json:"geometry"`
+	// 回退方式：空表示路网分析，"buffer" 表示圆形缓冲区
+	Fallback string `json:"fallback,omitempty"`
+	// 多边形生成算法
+	Method string `json:"method,omitempty"`
+	// 是否被水面、铁路、高速公路等屏障裁剪
+	Clipped bool `json:"clipped,omitempty"`
+}
+
+// FallbackBuffer 等时圈退化为圆形缓冲区（非路网结果）
+const FallbackBuffer = "buffer"
+
+// MaxSnapDistanceMeters 起点吸附路网节点的最大距离（米）
+// 与数据库函数 find_nearest_node 的默认值保持一致
+const MaxSnapDistanceMeters = 500.0
+
+// CoverageInfo 起点覆盖检查结果
+type CoverageInfo struct {
+	// 起点是否在已导入城市的覆盖范围内
+	InCoverage bool `json:"in_coverage"`
+	// 所在城市代码
+	City string `json:"city,omitempty"`
+	// 到最近路网节点的距离（米），-1 表示路网为空
+	SnapDistance float64 `json:"snap_distance_m"`
+	// 是否成功吸附到路网（吸附距离不超过 MaxSnapDistanceMeters）
+	Snapped bool `json:"snapped"`
+	// 最近路网节点，路网为空时为 0
+	Node int64 `json:"-"`
 }
@@ -1,41 +1,57 @@
 package model
 
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
 // POI 兴趣点
 type POI struct {
-	ID         int64    `json:"id"`
-	Name       string   `json:"name"`
-	Category   string   `json:"category"`   // 大类：医疗、教育、商业等
-	SubType    string   `json:"sub_type"`   // 小类：医院、诊所、药店等
-	Lng        float64  `json:"lng"`
-	Lat        float64  `json:"lat"`
-	Address    string   `json:"address,omitempty"`
-	Tags       []string `json:"tags,omitempty"`
-	Source     string   `json:"source,omitempty"` // 数据来源：osm、amap
+	ID       int64    `json:"id"`
+	Name     string   `json:"name"`
+	Category string   `json:"category"` // 大类：医疗、教育、商业等
+	SubType  string   `json:"sub_type"` // 小类：医院、诊所、药店等
+	Lng      float64  `json:"lng"`
+	Lat      float64  `json:"lat"`
+	Address  string   `json:"address,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Source   string   `json:"source,omitempty"` // 数据来源：osm、amap
+	// 距查询点的直线距离（米）
+	DistanceM float64 `json:"distance_m,omitempty"`
+	// 步行时间（分钟）：POI 搜索（minutes 过滤）为路网时间，其他接口按直线距离估算
+	WalkTimeMin float64 `json:"walk_time_min,omitempty"`
+	// 营业时间（OSM opening_hours 或高德营业时间原文）
+	OpeningHours string `json:"opening_hours,omitempty"`
+	// 所在的最小等时圈（分钟），仅综合评价返回
+	WithinMinutes int `json:"within_minutes,omitempty"`
 }
 
 // POICategory POI 分类（基于城乡规划标准）
 type POICategory struct {
-	Code        string        `json:"code"`
-	Name        string        `json:"name"`
-	Description string        `json:"description"`
-	SubTypes    []POISubType  `json:"sub_types"`
-	Weight      float64       `json:"weight"` // 评价权重
+	Code        string       `json:"code"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	SubTypes    []POISubType `json:"sub_types"`
+	Weight      float64      `json:"weight"` // 评价权重
 }
 
 // POISubType POI 子类型
 type POISubType struct {
-	Code   string `json:"code"`
-	Name   string `json:"name"`
-	OSMTag string `json:"osm_tag"` // 对应 OSM 标签
+	Code string `json:"code"`
+	Name string `json:"name"`
+	// 优先级最高的分类规则表达式（poi_rule），内置分类体系不含规则时为空
+	OSMTag string `json:"osm_tag"`
 }
 
 // POIStatistics POI 统计结果
 type POIStatistics struct {
-	Category     string `json:"category"`
-	SubType      string `json:"sub_type"`
-	Count        int    `json:"count"`
+	Category string `json:"category"`
+	SubType  string `json:"sub_type"`
+	Count    int    `json:"count"`
 	// 在不同等时圈内的数量
-	CountByTime  map[int]int `json:"count_by_time"`
+	CountByTime map[int]int `json:"count_by_time"`
 }
 
 // POIQueryResult POI 查询结果
@@ -46,6 +62,7 @@ type POIQueryResult struct {
 }
 
 // GetDefaultCategories 返回默认的 POI 分类体系
+// OSM 标签到子类型的映射只由 poi_rule 中的分类规则定义，这里不重复
 // 参考标准:
 //   - 《城市居住区规划设计标准》GB 50180-2018
 //   - 《社区生活圈规划技术指南》TD/T 1062-2021
@@ -58,9 +75,9 @@ func GetDefaultCategories() []POICategory {
 			Description: "社区卫生服务中心/站、诊所、药店等基层医疗设施",
 			Weight:      0.18,
 			SubTypes: []POISubType{
-				{Code: "community_health", Name: "社区卫生服务中心/站", OSMTag: "amenity=clinic"},
-				{Code: "hospital", Name: "医院", OSMTag: "amenity=hospital"},
-				{Code: "pharmacy", Name: "药店", OSMTag: "amenity=pharmacy"},
+				{Code: "community_health", Name: "社区卫生服务中心/站"},
+				{Code: "hospital", Name: "医院"},
+				{Code: "pharmacy", Name: "药店"},
 			},
 		},
 		{
@@ -69,9 +86,9 @@ func GetDefaultCategories() []POICategory {
 			Description: "幼儿园、小学、中学等基础教育设施",
 			Weight:      0.18,
 			SubTypes: []POISubType{
-				{Code: "kindergarten", Name: "幼儿园", OSMTag: "amenity=kindergarten"},
-				{Code: "primary", Name: "小学", OSMTag: "amenity=school"},
-				{Code: "secondary", Name: "初中", OSMTag: "amenity=school"},
+				{Code: "kindergarten", Name: "幼儿园"},
+				{Code: "primary", Name: "小学"},
+				{Code: "secondary", Name: "初中"},
 			},
 		},
 		{
@@ -80,9 +97,9 @@ func GetDefaultCategories() []POICategory {
 			Description: "社区养老服务中心、日间照料中心、老年活动室",
 			Weight:      0.12,
 			SubTypes: []POISubType{
-				{Code: "elderly_center", Name: "社区养老服务中心", OSMTag: "amenity=social_facility"},
-				{Code: "daycare", Name: "日间照料中心", OSMTag: "amenity=social_facility"},
-				{Code: "elderly_activity", Name: "老年活动室", OSMTag: "amenity=community_centre"},
+				{Code: "elderly_center", Name: "社区养老服务中心"},
+				{Code: "daycare", Name: "日间照料中心"},
+				{Code: "elderly_activity", Name: "老年活动室"},
 			},
 		},
 		{
@@ -91,10 +108,10 @@ func GetDefaultCategories() []POICategory {
 			Description: "菜市场/生鲜超市、综合超市、便利店、餐饮等",
 			Weight:      0.15,
 			SubTypes: []POISubType{
-				{Code: "market", Name: "菜市场/生鲜超市", OSMTag: "amenity=marketplace"},
-				{Code: "supermarket", Name: "综合超市", OSMTag: "shop=supermarket"},
-				{Code: "convenience", Name: "便利店", OSMTag: "shop=convenience"},
-				{Code: "restaurant", Name: "餐饮服务", OSMTag: "amenity=restaurant"},
+				{Code: "market", Name: "菜市场/生鲜超市"},
+				{Code: "supermarket", Name: "综合超市"},
+				{Code: "convenience", Name: "便利店"},
+				{Code: "restaurant", Name: "餐饮服务"},
 			},
 		},
 		{
@@ -103,10 +120,10 @@ func GetDefaultCategories() []POICategory {
 			Description: "社区文化活动中心、健身场地、公园绿地、阅览室",
 			Weight:      0.12,
 			SubTypes: []POISubType{
-				{Code: "culture_center", Name: "文化活动中心", OSMTag: "amenity=community_centre"},
-				{Code: "sports_field", Name: "健身场地/球场", OSMTag: "leisure=pitch"},
-				{Code: "park", Name: "公园绿地", OSMTag: "leisure=park"},
-				{Code: "library", Name: "图书室/阅览室", OSMTag: "amenity=library"},
+				{Code: "culture_center", Name: "文化活动中心"},
+				{Code: "sports_field", Name: "健身场地/球场"},
+				{Code: "park", Name: "公园绿地"},
+				{Code: "library", Name: "图书室/阅览室"},
 			},
 		},
 		{
@@ -115,10 +132,10 @@ func GetDefaultCategories() []POICategory {
 			Description: "社区服务中心、派出所、银行网点、邮政服务",
 			Weight:      0.10,
 			SubTypes: []POISubType{
-				{Code: "community_service", Name: "社区服务中心", OSMTag: "amenity=townhall"},
-				{Code: "police", Name: "派出所/警务室", OSMTag: "amenity=police"},
-				{Code: "bank", Name: "银行网点", OSMTag: "amenity=bank"},
-				{Code: "post", Name: "邮政服务", OSMTag: "amenity=post_office"},
+				{Code: "community_service", Name: "社区服务中心"},
+				{Code: "police", Name: "派出所/警务室"},
+				{Code: "bank", Name: "银行网点"},
+				{Code: "post", Name: "邮政服务"},
 			},
 		},
 		{
@@ -127,10 +144,10 @@ func GetDefaultCategories() []POICategory {
 			Description: "公交站点、轨道交通站点、公共停车场",
 			Weight:      0.10,
 			SubTypes: []POISubType{
-				{Code: "bus_stop", Name: "公交站点", OSMTag: "highway=bus_stop"},
-				{Code: "metro", Name: "轨道交通站", OSMTag: "railway=station"},
-				{Code: "parking", Name: "公共停车场", OSMTag: "amenity=parking"},
-				{Code: "bike_parking", Name: "非机动车停车", OSMTag: "amenity=bicycle_parking"},
+				{Code: "bus_stop", Name: "公交站点"},
+				{Code: "metro", Name: "轨道交通站"},
+				{Code: "parking", Name: "公共停车场"},
+				{Code: "bike_parking", Name: "非机动车停车"},
 			},
 		},
 		{
@@ -139,9 +156,211 @@ func GetDefaultCategories() []POICategory {
 			Description: "托儿所、托育机构、儿童游乐设施",
 			Weight:      0.05,
 			SubTypes: []POISubType{
-				{Code: "nursery", Name: "托儿所/托育机构", OSMTag: "amenity=childcare"},
-				{Code: "playground", Name: "儿童游乐设施", OSMTag: "leisure=playground"},
+				{Code: "nursery", Name: "托儿所/托育机构"},
+				{Code: "playground", Name: "儿童游乐设施"},
 			},
 		},
 	}
 }
+
+// POI 数据来源
+const (
+	SourceOSM    = "osm"
+	SourceManual = "manual"
+)
+
+// POIRecord 数据库中的 POI 完整记录（人工维护接口使用）
+type POIRecord struct {
+	ID        int64             `json:"id"`
+	OSMID     *int64            `json:"osm_id,omitempty"`
+	Name      string            `json:"name"`
+	Category  string            `json:"category"`
+	SubType   string            `json:"sub_type"`
+	Lng       float64           `json:"lng"`
+	Lat       float64           `json:"lat"`
+	Address   string            `json:"address,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Source    string            `json:"source"`
+	City      string            `json:"city,omitempty"`
+	UpdatedBy string            `json:"updated_by,omitempty"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	DeletedAt *time.Time        `json:"deleted_at,omitempty"`
+}
+
+// POICreateRequest 新建 POI 请求
+type POICreateRequest struct {
+	Name string `json:"name" binding:"required"`
+	// 子类型，分类由 poi_sub_type 推导
+	SubType string            `json:"sub_type" binding:"required"`
+	Lng     float64           `json:"lng" binding:"required"`
+	Lat     float64           `json:"lat" binding:"required"`
+	Address string            `json:"address"`
+	Tags    map[string]string `json:"tags"`
+	// 城市代码，为空时按覆盖范围推断
+	City string `json:"city"`
+	// 修改说明，写入审计记录
+	Comment string `json:"comment"`
+}
+
+// POIUpdateRequest 修改 POI 请求，未提供的字段保持不变
+type POIUpdateRequest struct {
+	Name    *string  `json:"name"`
+	Lng     *float64 `json:"lng"`
+	Lat     *float64 `json:"lat"`
+	Address *string  `json:"address"`
+	// 提供时与现有标签合并：同名键覆盖，值为空字符串的键删除，未提及的键保留
+	Tags    map[string]string `json:"tags"`
+	Comment string            `json:"comment"`
+}
+
+// POIReclassifyRequest 重新分类请求
+type POIReclassifyRequest struct {
+	SubType string `json:"sub_type" binding:"required"`
+	Comment string `json:"comment"`
+}
+
+// POIEditResult 人工维护结果
+type POIEditResult struct {
+	POI *POIRecord `json:"poi"`
+	// 因本次修改而失效的缓存分析数
+	InvalidatedAnalyses int `json:"invalidated_analyses"`
+}
+
+// POI 审计动作
+const (
+	AuditCreate     = "create"
+	AuditUpdate     = "update"
+	AuditDelete     = "delete"
+	AuditReclassify = "reclassify"
+)
+
+// POIAudit POI 审计记录
+type POIAudit struct {
+	ID        int64      `json:"id"`
+	POIID     int64      `json:"poi_id"`
+	Action    string     `json:"action"`
+	Actor     string     `json:"actor"`
+	Before    *POIRecord `json:"before,omitempty"`
+	After     *POIRecord `json:"after,omitempty"`
+	Comment   string     `json:"comment,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// POI 搜索的空间过滤方式
+const (
+	SearchBBox    = "bbox"
+	SearchRadius  = "radius"
+	SearchPolygon = "polygon"
+	SearchMinutes = "minutes"
+)
+
+// 搜索分页限制
+const (
+	DefaultSearchLimit = 50
+	MaxSearchLimit     = 500
+)
+
+// POISearchRequest POI 搜索请求（GET 查询参数）
+// 空间过滤 bbox / radius / polygon / minutes 至多选择一种
+type POISearchRequest struct {
+	// 外包框 "west,south,east,north"
+	BBox string `form:"bbox"`
+	// 参考点，radius / minutes 过滤必填，提供时结果按距离排序
+	Lng *float64 `form:"lng"`
+	Lat *float64 `form:"lat"`
+	// 半径（米）
+	Radius float64 `form:"radius"`
+	// 步行 N 分钟等时圈
+	Minutes int `form:"minutes"`
+	// 步行速度（km/h，默认5.0）
+	WalkSpeed float64 `form:"walk_speed"`
+	// GeoJSON Polygon / MultiPolygon 几何
+	Polygon string `form:"polygon"`
+	// 属性过滤，多个值以逗号分隔
+	Category string `form:"category"`
+	SubType  string `form:"sub_type"`
+	Source   string `form:"source"`
+	// 名称模糊匹配
+	Name  string `form:"name"`
+	Limit int    `form:"limit"`
+	// 上一页返回的 next_cursor
+	Cursor string `form:"cursor"`
+}
+
+// Validate 验证搜索参数并填充默认值
+func (r *POISearchRequest) Validate() error {
+	if r.Limit <= 0 {
+		r.Limit = DefaultSearchLimit
+	}
+	if r.Limit > MaxSearchLimit {
+		r.Limit = MaxSearchLimit
+	}
+	if r.WalkSpeed <= 0 {
+		r.WalkSpeed = 5.0
+	}
+	if (r.Lng == nil) != (r.Lat == nil) {
+		return errors.New("lng and lat must be given together")
+	}
+
+	modes := 0
+	for _, set := range []bool{r.BBox != "", r.Radius > 0, r.Polygon != "", r.Minutes > 0} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return errors.New("only one of bbox, radius, polygon, minutes may be given")
+	}
+	if (r.Radius > 0 || r.Minutes > 0) && r.Lng == nil {
+		return errors.New("radius and minutes filters require lng and lat")
+	}
+	if r.Minutes > 60 {
+		return errors.New("minutes must not exceed 60")
+	}
+	if r.Polygon != "" {
+		var g struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal([]byte(r.Polygon), &g); err != nil {
+			return errors.New("polygon must be a GeoJSON geometry")
+		}
+		if g.Type != "Polygon" && g.Type != "MultiPolygon" {
+			return errors.New("polygon must be a GeoJSON Polygon or MultiPolygon")
+		}
+	}
+	return nil
+}
+
+// Mode 空间过滤方式，未指定时为空
+func (r *POISearchRequest) Mode() string {
+	switch {
+	case r.BBox != "":
+		return SearchBBox
+	case r.Radius > 0:
+		return SearchRadius
+	case r.Polygon != "":
+		return SearchPolygon
+	case r.Minutes > 0:
+		return SearchMinutes
+	}
+	return ""
+}
+
+// SplitList 解析逗号分隔的过滤值
+func SplitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// POISearchResult POI 搜索结果
+type POISearchResult struct {
+	POIs  []POI `json:"pois"`
+	Count int   `json:"count"`
+	// 下一页游标，为空表示没有更多结果
+	NextCursor string `json:"next_cursor,omitempty"`
+}
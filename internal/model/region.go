@@ -0,0 +1,111 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 行政区划层级
+const (
+	// 街道
+	RegionSubdistrict = "subdistrict"
+	// 社区
+	RegionCommunity = "community"
+)
+
+// Region 行政区划（街道、社区）
+type Region struct {
+	ID         int     `json:"id"`
+	City       string  `json:"city"`
+	Code       string  `json:"code"`
+	Name       string  `json:"name"`
+	Level      string  `json:"level"`
+	ParentID   *int    `json:"parent_id,omitempty"`
+	ParentName string  `json:"parent_name,omitempty"`
+	AreaM2     float64 `json:"area_m2"`
+}
+
+// RegionImport 导入的一个区域边界
+type RegionImport struct {
+	Code       string
+	Name       string
+	ParentCode string
+	// GeoJSON Polygon / MultiPolygon（WGS84）
+	Geometry json.RawMessage
+}
+
+// RegionStats 区域生活圈评分汇总
+// 均值、中位数按样点代表的面积加权；人口加权平均分与人口在部分样点缺少人口格网时为空
+type RegionStats struct {
+	SampleCount   int      `json:"sample_count"`
+	SampledAreaM2 float64  `json:"sampled_area_m2"`
+	Mean          float64  `json:"mean"`
+	Median        float64  `json:"median"`
+	WeightedMean  *float64 `json:"population_weighted_mean"`
+	Population    *float64 `json:"population"`
+	// 按面积加权平均分对应的等级
+	Grade string `json:"grade"`
+	// 各分类得分的面积加权平均
+	CategoryMeans []RegionCategoryMean `json:"category_means"`
+	// 各必备设施在 15 分钟圈内达标的面积（人口）占比
+	Required   []RequiredCoverage `json:"required"`
+	ComputedAt time.Time          `json:"computed_at"`
+	// 数量已过时（步行速度不同或路网已重新导入）、未计入汇总的样点
+	StaleSamples int `json:"stale_samples"`
+	// 计算后评价标准或分类权重已变化（需重新评分），或有过时样点（需重新计算）
+	Stale bool `json:"stale"`
+}
+
+// RegionCategoryMean 分类得分的面积加权平均
+type RegionCategoryMean struct {
+	Category string  `json:"category"`
+	Name     string  `json:"name"`
+	Mean     float64 `json:"mean"`
+}
+
+// RequiredCoverage 必备设施达标情况：15 分钟圈内数量不少于 min_count_15（至少 1）
+type RequiredCoverage struct {
+	Category string `json:"category"`
+	SubType  string `json:"sub_type"`
+	Name     string `json:"name"`
+	// 达标样点代表的面积占比（0~1）
+	AreaShare float64 `json:"area_share"`
+	// 达标样点代表的人口占比，部分样点缺少人口时为空
+	PopulationShare *float64 `json:"population_share"`
+}
+
+// RegionSummary 区域及其评分汇总，未计算时 Summary 为空
+type RegionSummary struct {
+	Region
+	Summary  *RegionStats    `json:"summary"`
+	Geometry json.RawMessage `json:"geometry,omitempty"`
+}
+
+// RegionRanking 城市内区域排名
+type RegionRanking struct {
+	City  string `json:"city"`
+	Level string `json:"level,omitempty"`
+	// 排序指标：mean / median / population_weighted_mean
+	SortBy string `json:"sort_by"`
+	// asc / desc
+	Order   string         `json:"order"`
+	Regions []RankedRegion `json:"regions"`
+}
+
+// RankedRegion 排名中的区域，未计算汇总的区域排在最后且 Rank 为 0
+type RankedRegion struct {
+	Rank int `json:"rank"`
+	RegionSummary
+}
+
+// RegionRescoreResult 重新评分结果
+type RegionRescoreResult struct {
+	City    string `json:"city"`
+	Regions int    `json:"regions"`
+	Samples int    `json:"samples"`
+	// 尚未计算 POI 数量的样点（需运行 cmd/regions compute）
+	Unmeasured int `json:"unmeasured"`
+	// 数量已过时、未参与评分的样点（需重新运行 cmd/regions compute）
+	Stale         int    `json:"stale"`
+	StandardsHash string `json:"standards_hash"`
+}
@@ -0,0 +1,107 @@
+package model
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// usernamePattern 用户名：3-32 位小写字母、数字、下划线、点、短横线
+var usernamePattern = regexp.MustCompile(`^[a-z0-9_.-]{3,32}$`)
+
+// User 用户账号（不含密码摘要）
+type User struct {
+	ID          int        `json:"id"`
+	Username    string     `json:"username"`
+	DisplayName string     `json:"display_name,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// RegisterRequest 注册请求
+type RegisterRequest struct {
+	Username    string `json:"username" binding:"required"`
+	Password    string `json:"password" binding:"required"`
+	DisplayName string `json:"display_name"`
+}
+
+// Validate 验证注册参数，用户名统一转为小写
+func (r *RegisterRequest) Validate() error {
+	r.Username = strings.ToLower(strings.TrimSpace(r.Username))
+	if !usernamePattern.MatchString(r.Username) {
+		return errors.New("username must be 3-32 characters of a-z, 0-9, '_', '.', '-'")
+	}
+	// bcrypt 只使用前 72 字节
+	if len(r.Password) < 8 || len(r.Password) > 72 {
+		return errors.New("password must be 8-72 bytes")
+	}
+	r.DisplayName = strings.TrimSpace(r.DisplayName)
+	if utf8.RuneCountInString(r.DisplayName) > 100 {
+		return errors.New("display_name must be at most 100 characters")
+	}
+	return nil
+}
+
+// LoginRequest 登录请求
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// LoginResult 登录结果，会话令牌只在此返回一次（同时写入 Cookie）
+type LoginResult struct {
+	User      User      `json:"user"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SavedPlace 用户保存的地点
+type SavedPlace struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Lng       float64   `json:"lng"`
+	Lat       float64   `json:"lat"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SavedPlaceRequest 新建或修改常用地点
+type SavedPlaceRequest struct {
+	Name string  `json:"name" binding:"required"`
+	Lng  float64 `json:"lng" binding:"required"`
+	Lat  float64 `json:"lat" binding:"required"`
+	Note string  `json:"note"`
+}
+
+// Validate 验证地点参数
+func (r *SavedPlaceRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" || utf8.RuneCountInString(r.Name) > 100 {
+		return errors.New("name must be 1-100 characters")
+	}
+	if r.Lng < -180 || r.Lng > 180 || r.Lat < -90 || r.Lat > 90 {
+		return errors.New("lng/lat out of range")
+	}
+	return nil
+}
+
+// PlaceAnalyzeRequest 评价常用地点，参数同 EvaluationRequest（坐标取自地点）
+type PlaceAnalyzeRequest struct {
+	TimeThreshold int        `json:"time_threshold"`
+	WalkSpeed     float64    `json:"walk_speed"`
+	At            *time.Time `json:"at,omitempty"`
+	Profile       bool       `json:"profile"`
+}
+
+// AnalysisRerun 以当前数据重新评价历史记录的结果
+type AnalysisRerun struct {
+	// 原记录
+	Previous AnalysisRecord `json:"previous"`
+	// 新的评价结果（已写入历史，AnalysisID 为新记录）
+	Result *EvaluationResult `json:"result"`
+	// 总分变化（新 - 旧）
+	ScoreDelta float64 `json:"score_delta"`
+}
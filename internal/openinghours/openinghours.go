@@ -0,0 +1,336 @@
+// Package openinghours 解析 OSM opening_hours 营业时间
+//
+// 支持最常见的子集：24/7、星期选择（Mo-Fr、Sa,Su）、时间段
+// （08:00-18:00、22:00-02:00 跨午夜、18:00+ 营业至深夜）、off/closed，
+// 以 ; 分隔的规则按顺序覆盖前面规则中相同星期的时间，以 , 连接的附加规则则叠加。
+// 同时兼容高德营业时间的中文写法（周一至周五 09:00-17:00、24小时营业）。
+//
+// 月份、日期、周数等选择器不支持，返回 ErrUnsupported，调用方应将此类设施视为营业时间未知。
+// 节假日 PH/SH 无法按日期判断：与星期同时出现时只按星期计，只有 PH/SH 的规则（如 PH off）忽略；
+// 全部规则都只针对节假日时返回 ErrUnsupported。
+package openinghours
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrUnsupported 营业时间包含不支持的语法
+var ErrUnsupported = errors.New("unsupported opening_hours")
+
+const (
+	minutesPerDay = 24 * 60
+	// 18:00+ 等开放结束时间按营业至午夜计
+	openEndMinutes = minutesPerDay
+)
+
+// span 一天中的营业时段（分钟），End 可超过 1440 表示跨午夜
+type span struct {
+	Start, End int
+}
+
+// Schedule 解析后的每周营业时间，下标 0 为周一
+type Schedule struct {
+	days [7][]span
+}
+
+// 星期缩写，下标与 Schedule.days 一致
+var weekdays = []string{"Mo", "Tu", "We", "Th", "Fr", "Sa", "Su"}
+
+// 高德营业时间中的中文写法
+var chineseReplacer = strings.NewReplacer(
+	"24小时营业", "24/7",
+	"24小时", "24/7",
+	"全天", "24/7",
+	"周一", "Mo", "周二", "Tu", "周三", "We", "周四", "Th", "周五", "Fr", "周六", "Sa", "周日", "Su", "周天", "Su",
+	"星期一", "Mo", "星期二", "Tu", "星期三", "We", "星期四", "Th", "星期五", "Fr", "星期六", "Sa", "星期日", "Su", "星期天", "Su",
+	"至", "-", "~", "-", "－", "-", "—", "-", "–", "-",
+	"；", ";", "，", ",", "、", ",", "：", ":",
+	"休息", "off", "不营业", "off",
+)
+
+// Parse 解析营业时间
+func Parse(s string) (*Schedule, error) {
+	s = strings.TrimSpace(chineseReplacer.Replace(s))
+	if s == "" {
+		return nil, fmt.Errorf("%w: empty", ErrUnsupported)
+	}
+
+	sch := &Schedule{}
+	selected := false
+	for _, rule := range strings.FieldsFunc(s, func(r rune) bool { return r == ';' }) {
+		rule = strings.TrimSpace(strings.ReplaceAll(rule, "||", ""))
+		if rule == "" {
+			continue
+		}
+		ok, err := sch.apply(rule)
+		if err != nil {
+			return nil, err
+		}
+		selected = selected || ok
+	}
+	if !selected {
+		return nil, fmt.Errorf("%w: holiday rules only: %q", ErrUnsupported, s)
+	}
+	return sch, nil
+}
+
+// OpenAt 给定时刻是否营业（按 t 所在时区的本地时间判断）
+func (s *Schedule) OpenAt(t time.Time) bool {
+	day := (int(t.Weekday()) + 6) % 7 // 周一为 0
+	m := t.Hour()*60 + t.Minute()
+	for _, sp := range s.days[day] {
+		if m >= sp.Start && m < sp.End {
+			return true
+		}
+	}
+	// 前一天跨午夜的时段
+	prev := (day + 6) % 7
+	for _, sp := range s.days[prev] {
+		if m+minutesPerDay >= sp.Start && m+minutesPerDay < sp.End {
+			return true
+		}
+	}
+	return false
+}
+
+// apply 应用一条规则，规则中以 , 连接的附加规则叠加到同一组星期上；
+// 返回是否选中了星期（只有 PH/SH 的规则不选中任何星期，不影响结果）
+func (s *Schedule) apply(rule string) (bool, error) {
+	toks, err := tokenize(rule)
+	if err != nil {
+		return false, err
+	}
+
+	p := &parser{toks: toks}
+	first, selected := true, false
+	for {
+		days, spans, err := p.rule()
+		if err != nil {
+			return false, fmt.Errorf("%w: %q", err, rule)
+		}
+		for d, ok := range days {
+			if !ok {
+				continue
+			}
+			if first {
+				// 普通规则覆盖此前规则中相同星期的时段
+				s.days[d] = nil
+			}
+			s.days[d] = append(s.days[d], spans...)
+			selected = true
+		}
+		first = false
+
+		if p.done() {
+			return selected, nil
+		}
+		if !p.accept(",") {
+			return false, fmt.Errorf("%w: %q", ErrUnsupported, rule)
+		}
+	}
+}
+
+// parser 单条规则的递归下降解析
+type parser struct {
+	toks []string
+	pos  int
+}
+
+func (p *parser) done() bool { return p.pos >= len(p.toks) }
+
+func (p *parser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.toks[p.pos]
+}
+
+func (p *parser) accept(tok string) bool {
+	if p.peek() == tok {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// rule 解析 [星期选择] [时间段 | 24/7] [off]
+func (p *parser) rule() ([7]bool, []span, error) {
+	var days [7]bool
+
+	if p.accept("24/7") {
+		for i := range days {
+			days[i] = true
+		}
+		return days, []span{{0, minutesPerDay}}, nil
+	}
+
+	hasDays, err := p.daySelectors(&days)
+	if err != nil {
+		return days, nil, err
+	}
+	if !hasDays {
+		for i := range days {
+			days[i] = true
+		}
+	}
+
+	var spans []span
+	if isTime(p.peek()) {
+		for {
+			sp, err := p.timeSpan()
+			if err != nil {
+				return days, nil, err
+			}
+			spans = append(spans, sp)
+			// 逗号后仍是时间时属于同一规则，否则为附加规则
+			if p.peek() == "," && p.pos+1 < len(p.toks) && isTime(p.toks[p.pos+1]) {
+				p.pos++
+				continue
+			}
+			break
+		}
+	} else if p.accept("24/7") {
+		spans = []span{{0, minutesPerDay}}
+	} else if !hasDays {
+		return days, nil, ErrUnsupported
+	} else {
+		// 只有星期没有时间：全天营业
+		spans = []span{{0, minutesPerDay}}
+	}
+
+	if p.accept("off") || p.accept("closed") {
+		spans = nil
+	}
+	return days, spans, nil
+}
+
+// daySelectors 解析 Mo-Fr,Su 形式的星期选择，返回是否出现过选择器；
+// PH/SH（节假日）不参与判断，只有 PH/SH 时不选中任何星期
+func (p *parser) daySelectors(days *[7]bool) (bool, error) {
+	found := false
+	for {
+		tok := p.peek()
+		if tok == "PH" || tok == "SH" {
+			p.pos++
+			found = true
+		} else if d := weekdayIndex(tok); d >= 0 {
+			p.pos++
+			found = true
+			end := d
+			if p.accept("-") {
+				end = weekdayIndex(p.peek())
+				if end < 0 {
+					return found, ErrUnsupported
+				}
+				p.pos++
+			}
+			for i := d; ; i = (i + 1) % 7 {
+				days[i] = true
+				if i == end {
+					break
+				}
+			}
+		} else {
+			return found, nil
+		}
+
+		// 逗号后仍是星期时继续，否则交给时间段或附加规则处理
+		if p.peek() == "," && p.pos+1 < len(p.toks) && isDaySelector(p.toks[p.pos+1]) {
+			p.pos++
+			continue
+		}
+		return found, nil
+	}
+}
+
+// timeSpan 解析 HH:MM-HH:MM 或 HH:MM+
+func (p *parser) timeSpan() (span, error) {
+	start, err := parseClock(p.peek())
+	if err != nil {
+		return span{}, err
+	}
+	p.pos++
+
+	if p.accept("+") {
+		return span{start, max(openEndMinutes, start+1)}, nil
+	}
+	if !p.accept("-") {
+		return span{}, ErrUnsupported
+	}
+	end, err := parseClock(p.peek())
+	if err != nil {
+		return span{}, err
+	}
+	p.pos++
+	p.accept("+")
+
+	if end <= start {
+		end += minutesPerDay
+	}
+	return span{start, end}, nil
+}
+
+// tokenize 将规则切分为星期、时间、符号等记号
+func tokenize(rule string) ([]string, error) {
+	var toks []string
+	for i := 0; i < len(rule); {
+		c := rule[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == ',' || c == '-' || c == '+':
+			toks = append(toks, string(c))
+			i++
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(rule) && (rule[j] >= '0' && rule[j] <= '9' || rule[j] == ':' || rule[j] == '/') {
+				j++
+			}
+			toks = append(toks, rule[i:j])
+			i = j
+		case c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
+			j := i
+			for j < len(rule) && (rule[j] >= 'A' && rule[j] <= 'Z' || rule[j] >= 'a' && rule[j] <= 'z') {
+				j++
+			}
+			toks = append(toks, rule[i:j])
+			i = j
+		default:
+			return nil, fmt.Errorf("%w: unexpected %q", ErrUnsupported, rule[i:])
+		}
+	}
+	return toks, nil
+}
+
+func weekdayIndex(tok string) int {
+	for i, wd := range weekdays {
+		if tok == wd {
+			return i
+		}
+	}
+	return -1
+}
+
+func isDaySelector(tok string) bool {
+	return weekdayIndex(tok) >= 0 || tok == "PH" || tok == "SH"
+}
+
+func isTime(tok string) bool {
+	_, err := parseClock(tok)
+	return err == nil
+}
+
+// parseClock 解析 HH:MM，允许 24:00 及以上（跨午夜写法）
+func parseClock(tok string) (int, error) {
+	var h, m int
+	if len(tok) < 4 || strings.Count(tok, ":") != 1 {
+		return 0, ErrUnsupported
+	}
+	if _, err := fmt.Sscanf(tok, "%d:%d", &h, &m); err != nil || h < 0 || h > 48 || m < 0 || m > 59 {
+		return 0, ErrUnsupported
+	}
+	return h*60 + m, nil
+}
@@ -0,0 +1,140 @@
+package openinghours
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// at 2026-10-19（周一）所在一周的本地时刻，day 0 为周一
+func at(day, hour, minute int) time.Time {
+	return time.Date(2026, 10, 19+day, hour, minute, 0, 0, time.UTC)
+}
+
+const (
+	mo = iota
+	tu
+	we
+	th
+	fr
+	sa
+	su
+)
+
+func TestParse(t *testing.T) {
+	type check struct {
+		at   time.Time
+		open bool
+	}
+	tests := []struct {
+		name   string
+		hours  string
+		checks []check
+	}{
+		{"24/7", "24/7", []check{
+			{at(mo, 3, 0), true},
+			{at(su, 23, 59), true},
+		}},
+		{"weekdays", "Mo-Fr 08:00-18:00", []check{
+			{at(we, 10, 0), true},
+			{at(we, 7, 59), false},
+			{at(fr, 18, 0), false},
+			{at(sa, 10, 0), false},
+		}},
+		{"multiple spans", "Mo-Sa 08:00-12:00,14:00-18:00", []check{
+			{at(tu, 11, 59), true},
+			{at(tu, 13, 0), false},
+			{at(sa, 15, 0), true},
+			{at(su, 15, 0), false},
+		}},
+		{"past midnight", "Fr,Sa 22:00-02:00", []check{
+			{at(fr, 23, 0), true},
+			{at(sa, 1, 0), true},
+			{at(su, 1, 30), true},
+			{at(sa, 2, 0), false},
+			{at(fr, 1, 0), false},
+		}},
+		{"past midnight wraps to monday", "Su 20:00-01:00", []check{
+			{at(mo, 0, 30), true},
+			{at(mo, 1, 0), false},
+		}},
+		{"open end", "18:00+", []check{
+			{at(th, 23, 0), true},
+			{at(th, 17, 0), false},
+		}},
+		{"later rule overrides", "Mo-Su 08:00-22:00; Su off", []check{
+			{at(mo, 10, 0), true},
+			{at(su, 10, 0), false},
+		}},
+		{"closed", "Mo-Fr 09:00-17:00; Sa,Su closed", []check{
+			{at(sa, 10, 0), false},
+			{at(fr, 10, 0), true},
+		}},
+		{"additional rule", "Mo-Fr 09:00-12:00, Sa 10:00-11:00", []check{
+			{at(mo, 9, 30), true},
+			{at(sa, 10, 30), true},
+			{at(sa, 11, 30), false},
+		}},
+		{"public holiday with weekdays", "Mo-Fr,PH 09:00-17:00", []check{
+			{at(we, 10, 0), true},
+			{at(sa, 10, 0), false},
+		}},
+		// 只针对节假日的规则忽略，其余规则照常
+		{"public holiday rule", "Mo-Fr 09:00-17:00; PH off", []check{
+			{at(we, 10, 0), true},
+			{at(we, 18, 0), false},
+			{at(sa, 10, 0), false},
+		}},
+		{"public holiday rule first", "PH closed; Mo-Sa 08:00-20:00, SH 09:00-12:00", []check{
+			{at(mo, 8, 0), true},
+			{at(sa, 19, 59), true},
+			{at(su, 10, 0), false},
+		}},
+		{"chinese", "周一至周五 09:00-17:00；周六 10:00-12:00", []check{
+			{at(mo, 10, 0), true},
+			{at(sa, 11, 0), true},
+			{at(su, 11, 0), false},
+		}},
+		{"chinese all day", "24小时营业", []check{
+			{at(we, 4, 0), true},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sch, err := Parse(tt.hours)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.hours, err)
+			}
+			for _, c := range tt.checks {
+				if got := sch.OpenAt(c.at); got != c.open {
+					t.Errorf("OpenAt(%s %s) = %v, want %v", c.at.Weekday(), c.at.Format("15:04"), got, c.open)
+				}
+			}
+		})
+	}
+}
+
+func TestParseUnsupported(t *testing.T) {
+	tests := []struct {
+		name  string
+		hours string
+	}{
+		{"empty", ""},
+		{"public holiday only", "PH off"},
+		{"holiday rules only", "PH off; SH 10:00-12:00"},
+		{"school holiday only", "SH 10:00-12:00"},
+		{"month selector", "Jan-Mar 10:00-12:00"},
+		{"missing end", "Mo-Fr 08:00"},
+		{"bare hours", "Mo-Fr 8-18"},
+		{"bad minute", "Mo 08:75-10:00"},
+		{"unexpected character", "Mo-Fr 08:00-18:00 @"},
+		{"bad day range", "Mo-Xx 08:00-18:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.hours); !errors.Is(err, ErrUnsupported) {
+				t.Errorf("Parse(%q) error = %v, want ErrUnsupported", tt.hours, err)
+			}
+		})
+	}
+}
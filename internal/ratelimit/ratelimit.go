@@ -0,0 +1,125 @@
+// Package ratelimit 按客户端的内存令牌桶限速
+//
+// 每个客户端（API Key 或匿名 IP）一个桶，按每分钟速率补充、容量为 burst。
+// 限速在各实例内独立进行，多实例部署时总速率约为单实例的实例数倍。
+// DailyCounter 为匿名客户端的每日配额计数。
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// idleTTL 超过该时长未使用且已补满的桶会被清理
+const idleTTL = 10 * time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter 令牌桶集合
+type Limiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// New 创建限速器
+func New() *Limiter {
+	return &Limiter{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// Result 一次限速判断的结果
+type Result struct {
+	Allowed bool
+	// 剩余可立即发出的请求数
+	Remaining int
+	// 被拒绝时距下一个令牌的时长
+	RetryAfter time.Duration
+}
+
+// Allow 从 key 的桶中取一个令牌；ratePerMinute、burst 可随配置变化，按最新值计算
+func (l *Limiter) Allow(key string, ratePerMinute, burst int) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	perSec := float64(ratePerMinute) / 60
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*perSec)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / perSec * float64(time.Second))
+		return Result{RetryAfter: wait}
+	}
+	b.tokens--
+	return Result{Allowed: true, Remaining: int(b.tokens)}
+}
+
+// sweep 每分钟清理一次长时间未使用的桶，这些桶重新创建时同样是满的
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > idleTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// overflowKey 计数器已满时新客户端共用的计数键
+const overflowKey = "\x00overflow"
+
+// DailyCounter 按客户端的每日计数（本地日期，次日清零）
+//
+// 最多记录 maxKeys 个客户端，已满时当日新出现的客户端共用一个计数，
+// 内存有界；大量伪造来源时只会耗尽共用额度，不影响已记录的客户端。
+type DailyCounter struct {
+	mu      sync.Mutex
+	day     string
+	used    map[string]int
+	maxKeys int
+	now     func() time.Time
+}
+
+// NewDailyCounter 创建每日计数器，maxKeys 为每日最多记录的客户端数
+func NewDailyCounter(maxKeys int) *DailyCounter {
+	return &DailyCounter{used: make(map[string]int), maxKeys: maxKeys, now: time.Now}
+}
+
+// Take 占用 key 当日的一次额度，返回占用后的已用次数；已达 quota 时 ok 为 false
+func (d *DailyCounter) Take(key string, quota int) (used int, ok bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if day := d.now().Format(time.DateOnly); day != d.day {
+		d.day, d.used = day, make(map[string]int)
+	}
+	if _, seen := d.used[key]; !seen && len(d.used) >= d.maxKeys {
+		key = overflowKey
+	}
+	if d.used[key] >= quota {
+		return quota, false
+	}
+	d.used[key]++
+	return d.used[key], true
+}
+
+// Len 当日已记录的客户端数（含共用计数）
+func (d *DailyCounter) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.used)
+}
@@ -0,0 +1,160 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// clock 可手动推进的时钟
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestLimiter() (*Limiter, *clock) {
+	c := &clock{t: time.Date(2026, 1, 1, 12, 0, 0, 0, time.Local)}
+	l := New()
+	l.now = c.now
+	return l, c
+}
+
+func TestLimiterBurst(t *testing.T) {
+	l, _ := newTestLimiter()
+	for i := 1; i <= 5; i++ {
+		res := l.Allow("a", 60, 5)
+		if !res.Allowed {
+			t.Fatalf("request %d rejected within burst", i)
+		}
+		if res.Remaining != 5-i {
+			t.Errorf("request %d: remaining = %d, want %d", i, res.Remaining, 5-i)
+		}
+	}
+	res := l.Allow("a", 60, 5)
+	if res.Allowed {
+		t.Fatal("request beyond burst allowed")
+	}
+	// 60 次/分钟，下一个令牌在 1 秒后
+	if res.RetryAfter <= 0 || res.RetryAfter > time.Second {
+		t.Errorf("retry after = %s, want (0, 1s]", res.RetryAfter)
+	}
+
+	// 其他客户端的桶不受影响
+	if !l.Allow("b", 60, 5).Allowed {
+		t.Error("independent bucket rejected")
+	}
+}
+
+func TestLimiterRefill(t *testing.T) {
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		// 耗尽后经过 elapsed 可立即发出的请求数
+		want int
+	}{
+		{"no time", 0, 0},
+		{"half token", 500 * time.Millisecond, 0},
+		{"one token", time.Second, 1},
+		{"three tokens", 3 * time.Second, 3},
+		{"capped at burst", time.Hour, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c := newTestLimiter()
+			for l.Allow("a", 60, 5).Allowed {
+			}
+			c.advance(tt.elapsed)
+
+			got := 0
+			for l.Allow("a", 60, 5).Allowed {
+				got++
+			}
+			if got != tt.want {
+				t.Errorf("allowed %d after %s, want %d", got, tt.elapsed, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimiterSweep(t *testing.T) {
+	l, c := newTestLimiter()
+	l.Allow("idle", 60, 5)
+	c.advance(idleTTL + time.Minute)
+	l.Allow("active", 60, 5)
+
+	if _, ok := l.buckets["idle"]; ok {
+		t.Error("idle bucket not swept")
+	}
+	if _, ok := l.buckets["active"]; !ok {
+		t.Error("active bucket missing")
+	}
+}
+
+func newTestCounter(maxKeys int) (*DailyCounter, *clock) {
+	c := &clock{t: time.Date(2026, 1, 1, 23, 0, 0, 0, time.Local)}
+	d := NewDailyCounter(maxKeys)
+	d.now = c.now
+	return d, c
+}
+
+func TestDailyCounterQuota(t *testing.T) {
+	d, _ := newTestCounter(10)
+	for i := 1; i <= 3; i++ {
+		used, ok := d.Take("1.2.3.4", 3)
+		if !ok || used != i {
+			t.Fatalf("take %d = (%d, %v), want (%d, true)", i, used, ok, i)
+		}
+	}
+	if used, ok := d.Take("1.2.3.4", 3); ok || used != 3 {
+		t.Errorf("take beyond quota = (%d, %v), want (3, false)", used, ok)
+	}
+	if _, ok := d.Take("5.6.7.8", 3); !ok {
+		t.Error("other client rejected")
+	}
+}
+
+func TestDailyCounterReset(t *testing.T) {
+	d, c := newTestCounter(10)
+	d.Take("1.2.3.4", 1)
+	if _, ok := d.Take("1.2.3.4", 1); ok {
+		t.Fatal("quota not enforced")
+	}
+
+	// 同一天内不清零
+	c.advance(30 * time.Minute)
+	if _, ok := d.Take("1.2.3.4", 1); ok {
+		t.Error("quota reset before midnight")
+	}
+
+	// 次日清零
+	c.advance(time.Hour)
+	if used, ok := d.Take("1.2.3.4", 1); !ok || used != 1 {
+		t.Errorf("take next day = (%d, %v), want (1, true)", used, ok)
+	}
+	if d.Len() != 1 {
+		t.Errorf("len = %d after reset, want 1", d.Len())
+	}
+}
+
+func TestDailyCounterBounded(t *testing.T) {
+	d, _ := newTestCounter(2)
+	d.Take("a", 2)
+	d.Take("b", 2)
+
+	// 已满：新客户端共用一份额度
+	if _, ok := d.Take("c", 2); !ok {
+		t.Fatal("first overflow client rejected")
+	}
+	if _, ok := d.Take("d", 2); !ok {
+		t.Fatal("second overflow client rejected")
+	}
+	if _, ok := d.Take("e", 2); ok {
+		t.Error("overflow quota not shared")
+	}
+	// 已记录的客户端不受影响
+	if _, ok := d.Take("a", 2); !ok {
+		t.Error("known client rejected")
+	}
+	if d.Len() != 3 {
+		t.Errorf("len = %d, want 3 (2 clients + overflow)", d.Len())
+	}
+}
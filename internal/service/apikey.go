@@ -0,0 +1,416 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yourname/15min-life-circle/internal/config"
+	"github.com/yourname/15min-life-circle/internal/database"
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/ratelimit"
+)
+
+var (
+	// ErrAPIKeyNotFound API Key 不存在
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrInvalidAPIKey 未知或已吊销的 API Key
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	// ErrInvalidAPIKeyRequest 签发参数不合法
+	ErrInvalidAPIKeyRequest = errors.New("invalid api key request")
+)
+
+// apiKeyPrefix 明文 Key 的固定前缀，便于在代码和日志中识别泄露的 Key
+const apiKeyPrefix = "lc_"
+
+// authCacheTTL 认证结果缓存时长；其他实例吊销的 Key 最多在该时长后失效
+const authCacheTTL = 30 * time.Second
+
+// APIKeyService API Key 签发、认证、配额与用量
+type APIKeyService struct {
+	db    *database.DB
+	tiers config.TiersConfig
+
+	mu    sync.Mutex
+	cache map[string]authEntry
+	// 待写入 api_key_usage 的计数
+	usage map[usageKey]*usageCount
+	// 匿名客户端（按 IP）的每日配额，仅在本实例内计数
+	anonQuota *ratelimit.DailyCounter
+}
+
+// maxAnonymousClients 每日最多单独计数的匿名 IP 数
+const maxAnonymousClients = 100000
+
+type authEntry struct {
+	key     *model.APIKey
+	expires time.Time
+}
+
+type usageKey struct {
+	keyID int
+	day   string
+	route string
+}
+
+type usageCount struct {
+	requests, rejected, errors int
+}
+
+// NewAPIKeyService 创建 API Key 服务，tiers 为各等级默认限额
+func NewAPIKeyService(db *database.DB, tiers config.TiersConfig) *APIKeyService {
+	return &APIKeyService{
+		db:        db,
+		tiers:     tiers,
+		cache:     make(map[string]authEntry),
+		usage:     make(map[usageKey]*usageCount),
+		anonQuota: ratelimit.NewDailyCounter(maxAnonymousClients),
+	}
+}
+
+// hashAPIKey 明文 Key 的 SHA-256 摘要；Key 为 24 字节随机数，无需慢哈希
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// today 本地日期，配额与用量按此分日
+func today() string {
+	return time.Now().Format(time.DateOnly)
+}
+
+const apiKeyColumns = `
+	id, name, prefix, tier, rate_per_minute, burst, daily_quota,
+	created_by, created_at, revoked_at, COALESCE(revoked_by, ''), last_used_at`
+
+func scanAPIKey(row pgx.Row) (*model.APIKey, error) {
+	var k model.APIKey
+	err := row.Scan(
+		&k.ID, &k.Name, &k.Prefix, &k.Tier, &k.RatePerMinute, &k.Burst, &k.DailyQuota,
+		&k.CreatedBy, &k.CreatedAt, &k.RevokedAt, &k.RevokedBy, &k.LastUsedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// Issue 签发 API Key，返回的明文不会保存
+func (s *APIKeyService) Issue(ctx context.Context, actor string, req *model.APIKeyCreateRequest) (*model.APIKeyIssued, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAPIKeyRequest, err)
+	}
+
+	var raw [24]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		return nil, fmt.Errorf("generate api key: %w", err)
+	}
+	secret := hex.EncodeToString(raw[:])
+	plain := apiKeyPrefix + secret
+
+	k, err := scanAPIKey(s.db.Pool.QueryRow(ctx, `
+		INSERT INTO api_key (name, prefix, key_hash, tier, rate_per_minute, burst, daily_quota, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+apiKeyColumns,
+		req.Name, secret[:8], hashAPIKey(plain), req.Tier, req.RatePerMinute, req.Burst, req.DailyQuota, actor,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("insert api key: %w", err)
+	}
+	return &model.APIKeyIssued{APIKey: *k, Key: plain}, nil
+}
+
+// List 全部 API Key（含已吊销）
+func (s *APIKeyService) List(ctx context.Context) ([]model.APIKey, error) {
+	rows, err := s.db.Pool.Query(ctx, `SELECT `+apiKeyColumns+` FROM api_key ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("query api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]model.APIKey, 0)
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan api key: %w", err)
+		}
+		keys = append(keys, *k)
+	}
+	return keys, rows.Err()
+}
+
+// Get 按 ID 获取 API Key
+func (s *APIKeyService) Get(ctx context.Context, id int) (*model.APIKey, error) {
+	k, err := scanAPIKey(s.db.Pool.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_key WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query api key: %w", err)
+	}
+	return k, nil
+}
+
+// Revoke 吊销 API Key；已吊销的 Key 保持原吊销记录
+func (s *APIKeyService) Revoke(ctx context.Context, actor string, id int) (*model.APIKey, error) {
+	k, err := scanAPIKey(s.db.Pool.QueryRow(ctx, `
+		UPDATE api_key SET revoked_at = NOW(), revoked_by = $2
+		WHERE id = $1 AND revoked_at IS NULL
+		RETURNING `+apiKeyColumns, id, actor))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s.Get(ctx, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("revoke api key: %w", err)
+	}
+
+	// 本实例立即失效
+	s.mu.Lock()
+	for hash, e := range s.cache {
+		if e.key != nil && e.key.ID == id {
+			delete(s.cache, hash)
+		}
+	}
+	s.mu.Unlock()
+	return k, nil
+}
+
+// Authenticate 校验明文 Key，未知或已吊销时返回 ErrInvalidAPIKey
+func (s *APIKeyService) Authenticate(ctx context.Context, plain string) (*model.APIKey, error) {
+	hash := hashAPIKey(plain)
+	now := time.Now()
+
+	s.mu.Lock()
+	e, ok := s.cache[hash]
+	s.mu.Unlock()
+	if !ok || now.After(e.expires) {
+		k, err := scanAPIKey(s.db.Pool.QueryRow(ctx,
+			`SELECT `+apiKeyColumns+` FROM api_key WHERE key_hash = $1`, hash))
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("query api key: %w", err)
+		}
+		e = authEntry{key: k, expires: now.Add(authCacheTTL)}
+
+		s.mu.Lock()
+		// 无效 Key 也缓存，避免以随机 Key 反复查询数据库；条目过多时整体清空
+		if len(s.cache) > 10000 {
+			s.cache = make(map[string]authEntry)
+		}
+		s.cache[hash] = e
+		s.mu.Unlock()
+	}
+
+	if e.key == nil || e.key.Revoked() {
+		return nil, ErrInvalidAPIKey
+	}
+	return e.key, nil
+}
+
+// Limits Key 生效的限额：Key 覆盖值优先，其次为等级默认值；k 为 nil 时为匿名等级
+func (s *APIKeyService) Limits(k *model.APIKey) config.TierConfig {
+	if k == nil {
+		return s.tiers.Anonymous
+	}
+	limits, ok := s.tiers.Tier(k.Tier)
+	if !ok {
+		limits = s.tiers.Public
+	}
+	if k.RatePerMinute != nil {
+		limits.RatePerMinute = *k.RatePerMinute
+	}
+	if k.Burst != nil {
+		limits.Burst = *k.Burst
+	}
+	if k.DailyQuota != nil {
+		limits.DailyQuota = *k.DailyQuota
+	}
+	return limits
+}
+
+// ConsumeQuota 占用一次当日高开销接口额度，返回占用后的已用次数；
+// 额度用尽时 ok 为 false。quota 为 0 表示不限制，此时不计数
+func (s *APIKeyService) ConsumeQuota(ctx context.Context, keyID, quota int) (used int, ok bool, err error) {
+	if quota <= 0 {
+		return 0, true, nil
+	}
+	// 条件更新保证多实例并发时不超额
+	err = s.db.Pool.QueryRow(ctx, `
+		INSERT INTO api_key_quota (key_id, day, used) VALUES ($1, $2::date, 1)
+		ON CONFLICT (key_id, day) DO UPDATE SET used = api_key_quota.used + 1
+		WHERE api_key_quota.used < $3
+		RETURNING used`, keyID, today(), quota).Scan(&used)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return quota, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("consume quota: %w", err)
+	}
+	return used, true, nil
+}
+
+// ConsumeAnonymousQuota 匿名客户端按 IP 占用当日额度（本实例内计数）
+// IPv6 地址按 /64 计数，同一网段内轮换地址不能绕过配额
+func (s *APIKeyService) ConsumeAnonymousQuota(ip string, quota int) (used int, ok bool) {
+	if quota <= 0 {
+		return 0, true
+	}
+	return s.anonQuota.Take(ClientNetwork(ip), quota)
+}
+
+// ClientNetwork 限速与匿名配额使用的客户端标识：IPv4 为地址本身，IPv6 为所在 /64 网段
+func ClientNetwork(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || addr.Unmap().Is4() {
+		return ip
+	}
+	prefix, err := addr.Prefix(64)
+	if err != nil {
+		return ip
+	}
+	return prefix.String()
+}
+
+// RecordUsage 记录一次请求，定期由 Flush 批量写入 api_key_usage
+func (s *APIKeyService) RecordUsage(keyID int, route string, status int, rejected bool) {
+	k := usageKey{keyID: keyID, day: today(), route: route}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.usage[k]
+	if !ok {
+		c = &usageCount{}
+		s.usage[k] = c
+	}
+	c.requests++
+	if rejected {
+		c.rejected++
+	}
+	if status >= 500 {
+		c.errors++
+	}
+}
+
+// Flush 将内存中的用量计数写入数据库，并更新 Key 的最近使用时间
+func (s *APIKeyService) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	pending := s.usage
+	s.usage = make(map[usageKey]*usageCount)
+	s.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+	used := make(map[int]bool)
+	for k, c := range pending {
+		batch.Queue(`
+			INSERT INTO api_key_usage (key_id, day, route, requests, rejected, errors)
+			VALUES ($1, $2::date, $3, $4, $5, $6)
+			ON CONFLICT (key_id, day, route) DO UPDATE SET
+				requests = api_key_usage.requests + EXCLUDED.requests,
+				rejected = api_key_usage.rejected + EXCLUDED.rejected,
+				errors = api_key_usage.errors + EXCLUDED.errors`,
+			k.keyID, k.day, k.route, c.requests, c.rejected, c.errors)
+		used[k.keyID] = true
+	}
+	ids := make([]int, 0, len(used))
+	for id := range used {
+		ids = append(ids, id)
+	}
+	batch.Queue(`UPDATE api_key SET last_used_at = NOW() WHERE id = ANY($1)`, ids)
+
+	if err := s.db.Pool.SendBatch(ctx, batch).Close(); err != nil {
+		// 写入失败时放回，下次重试
+		s.mu.Lock()
+		for k, c := range pending {
+			if cur, ok := s.usage[k]; ok {
+				cur.requests += c.requests
+				cur.rejected += c.rejected
+				cur.errors += c.errors
+			} else {
+				s.usage[k] = c
+			}
+		}
+		s.mu.Unlock()
+		return fmt.Errorf("flush api key usage: %w", err)
+	}
+	return nil
+}
+
+// Run 每隔 interval 写入一次用量，ctx 结束时做最后一次写入
+func (s *APIKeyService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Flush(ctx); err != nil {
+				slog.WarnContext(ctx, "flush api key usage failed", "error", err)
+			}
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := s.Flush(flushCtx); err != nil {
+				slog.Warn("flush api key usage failed", "error", err)
+			}
+			cancel()
+			return
+		}
+	}
+}
+
+// Usage 最近 days 天的用量（含今日已用配额）
+func (s *APIKeyService) Usage(ctx context.Context, id, days int) (*model.APIKeyUsageReport, error) {
+	if days < 1 || days > 366 {
+		return nil, fmt.Errorf("%w: days must be between 1 and 366", ErrInvalidAPIKeyRequest)
+	}
+	k, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// 先写入本实例尚未落库的计数
+	if err := s.Flush(ctx); err != nil {
+		slog.WarnContext(ctx, "flush api key usage failed", "error", err)
+	}
+
+	limits := s.Limits(k)
+	report := &model.APIKeyUsageReport{
+		Key:           *k,
+		RatePerMinute: limits.RatePerMinute,
+		Burst:         limits.Burst,
+		DailyQuota:    limits.DailyQuota,
+		Days:          days,
+		Usage:         make([]model.APIKeyUsage, 0),
+	}
+
+	day := today()
+	if err := s.db.Pool.QueryRow(ctx, `
+		SELECT COALESCE((SELECT used FROM api_key_quota WHERE key_id = $1 AND day = $2::date), 0)`,
+		id, day).Scan(&report.QuotaUsedToday); err != nil {
+		return nil, fmt.Errorf("query quota: %w", err)
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT to_char(day, 'YYYY-MM-DD'), route, requests, rejected, errors
+		FROM api_key_usage
+		WHERE key_id = $1 AND day > $2::date - $3::int
+		ORDER BY day DESC, route`, id, day, days)
+	if err != nil {
+		return nil, fmt.Errorf("query usage: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var u model.APIKeyUsage
+		if err := rows.Scan(&u.Day, &u.Route, &u.Requests, &u.Rejected, &u.Errors); err != nil {
+			return nil, fmt.Errorf("scan usage: %w", err)
+		}
+		report.Usage = append(report.Usage, u)
+	}
+	return report, rows.Err()
+}
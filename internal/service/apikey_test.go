@@ -0,0 +1,21 @@
+package service
+
+import "testing"
+
+func TestClientNetwork(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"203.0.113.7", "203.0.113.7"},
+		{"::ffff:203.0.113.7", "::ffff:203.0.113.7"},
+		{"2001:db8:1:2:3:4:5:6", "2001:db8:1:2::/64"},
+		{"2001:db8:1:2::ffff", "2001:db8:1:2::/64"},
+		{"not-an-ip", "not-an-ip"},
+	}
+	for _, tt := range tests {
+		if got := ClientNetwork(tt.ip); got != tt.want {
+			t.Errorf("ClientNetwork(%q) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+}
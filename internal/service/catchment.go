@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yourname/15min-life-circle/internal/metrics"
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrInvalidCatchment 服务范围请求参数无效
+var ErrInvalidCatchment = errors.New("invalid catchment request")
+
+// Catchment 计算设施服务范围（反向等时圈）：哪些地方能在 N 分钟内到达该设施
+func (s *IsochroneService) Catchment(ctx context.Context, req *model.CatchmentRequest) (_ *model.CatchmentResult, err error) {
+	ctx, span := tracing.Start(ctx, "IsochroneService.Catchment")
+	defer func() { tracing.End(span, err) }()
+
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCatchment, err)
+	}
+
+	result := &model.CatchmentResult{
+		POIID:    req.POIID,
+		Mode:     req.Mode,
+		Speed:    req.Speed,
+		Polygons: make([]model.CatchmentPolygon, 0, len(req.TimeThresholds)),
+	}
+
+	if req.POIID != nil {
+		err := s.db.Pool.QueryRow(ctx, `
+			SELECT ST_X(geom), ST_Y(geom), COALESCE(name, '')
+			FROM poi
+			WHERE id = $1 AND deleted_at IS NULL`, *req.POIID,
+		).Scan(&result.Origin[0], &result.Origin[1], &result.POIName)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrPOINotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("query poi: %w", err)
+		}
+	} else {
+		result.Origin = model.Point{*req.Lng, *req.Lat}
+	}
+
+	query := `
+		SELECT
+			minutes,
+			geojson,
+			COALESCE(fallback, ''),
+			population
+		FROM calculate_catchment($1, $2, $3, $4, $5)
+		ORDER BY minutes
+	`
+
+	defer metrics.Stage("catchment", metrics.StagePolygon)()
+	rows, err := s.db.Pool.Query(ctx, query,
+		result.Origin.Lng(),
+		result.Origin.Lat(),
+		req.TimeThresholds,
+		req.Speed,
+		req.Mode,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("calculate catchment: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			p          model.CatchmentPolygon
+			geojsonStr string
+		)
+		if err := rows.Scan(&p.Minutes, &geojsonStr, &p.Fallback, &p.Population); err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(geojsonStr), &p.Geometry); err != nil {
+			return nil, fmt.Errorf("parse geojson: %w", err)
+		}
+		if p.Population != nil {
+			result.PopulationAvailable = true
+		}
+		result.Polygons = append(result.Polygons, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("calculate catchment: %w", err)
+	}
+
+	return result, nil
+}
+
+// Reachable 按出行方式计算单个时间阈值的正向等时圈
+// 步行使用 calculate_isochrones；骑行使用 calculate_reachability 以遵守单行道
+func (s *IsochroneService) Reachable(ctx context.Context, lng, lat float64, minutes int, speed float64, mode string) (_ *model.IsochronePolygon, _ *model.CoverageInfo, err error) {
+	ctx, span := tracing.Start(ctx, "IsochroneService.Reachable", attribute.String("mode", mode), attribute.Int("minutes", minutes))
+	defer func() { tracing.End(span, err) }()
+
+	if mode != model.ModeBike {
+		result, err := s.Calculate(ctx, &model.IsochroneRequest{
+			Lng:            lng,
+			Lat:            lat,
+			TimeThresholds: []int{minutes},
+			WalkSpeed:      speed,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(result.Polygons) == 0 {
+			return nil, nil, fmt.Errorf("no isochrone for %d minutes", minutes)
+		}
+		return &result.Polygons[0], result.Coverage, nil
+	}
+
+	coverage, err := s.CheckCoverage(ctx, lng, lat)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		poly       = &model.IsochronePolygon{Minutes: minutes, Distance: speed * float64(minutes) * 1000.0 / 60.0}
+		geojsonStr string
+	)
+	done := metrics.Stage("isochrone", metrics.StagePolygon)
+	err = s.db.Pool.QueryRow(ctx, `
+		SELECT geojson, COALESCE(fallback, '')
+		FROM calculate_reachability($1, $2, ARRAY[$3]::int[], $4, $5, FALSE)`,
+		lng, lat, minutes, speed, mode,
+	).Scan(&geojsonStr, &poly.Fallback)
+	done()
+	if err != nil {
+		return nil, nil, fmt.Errorf("calculate reachability: %w", err)
+	}
+	if err := json.Unmarshal([]byte(geojsonStr), &poly.Geometry); err != nil {
+		return nil, nil, fmt.Errorf("parse geojson: %w", err)
+	}
+	return poly, coverage, nil
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/yourname/15min-life-circle/internal/database"
+	"github.com/yourname/15min-life-circle/internal/model"
+)
+
+// CityService 城市注册表服务
+type CityService struct {
+	db *database.DB
+}
+
+// NewCityService 创建城市服务
+func NewCityService(db *database.DB) *CityService {
+	return &CityService{db: db}
+}
+
+// List 获取所有已登记城市
+func (s *CityService) List(ctx context.Context) ([]model.City, error) {
+	query := `
+		SELECT
+			code,
+			name,
+			COALESCE(description, name),
+			ST_X(center),
+			ST_Y(center),
+			zoom,
+			ST_XMin(bounds),
+			ST_YMin(bounds),
+			ST_XMax(bounds),
+			ST_YMax(bounds),
+			data_imported_at,
+			COALESCE(poi_counts, '{}'::jsonb),
+			node_count,
+			edge_count,
+			network_km
+		FROM city
+		ORDER BY sort_order, code
+	`
+
+	rows, err := s.db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query cities: %w", err)
+	}
+	defer rows.Close()
+
+	cities := make([]model.City, 0)
+	for rows.Next() {
+		var (
+			city                     model.City
+			west, south, east, north float64
+			poiCountsJSON            []byte
+		)
+		if err := rows.Scan(
+			&city.Code,
+			&city.Name,
+			&city.Description,
+			&city.Center[0],
+			&city.Center[1],
+			&city.Zoom,
+			&west,
+			&south,
+			&east,
+			&north,
+			&city.DataImportedAt,
+			&poiCountsJSON,
+			&city.Network.Nodes,
+			&city.Network.Edges,
+			&city.Network.LengthKm,
+		); err != nil {
+			return nil, fmt.Errorf("scan city: %w", err)
+		}
+		city.Bounds = [2]model.Point{{west, south}, {east, north}}
+		if err := json.Unmarshal(poiCountsJSON, &city.POICounts); err != nil {
+			return nil, fmt.Errorf("parse poi counts: %w", err)
+		}
+		cities = append(cities, city)
+	}
+
+	return cities, rows.Err()
+}
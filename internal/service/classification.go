@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/yourname/15min-life-circle/internal/classify"
+	"github.com/yourname/15min-life-circle/internal/database"
+	"github.com/yourname/15min-life-circle/internal/model"
+)
+
+// ErrInvalidRule 试运行提交的规则无法解析
+var ErrInvalidRule = errors.New("invalid classification rule")
+
+// ClassificationService POI 分类规则服务
+type ClassificationService struct {
+	db *database.DB
+}
+
+// NewClassificationService 创建分类规则服务
+func NewClassificationService(db *database.DB) *ClassificationService {
+	return &ClassificationService{db: db}
+}
+
+// Rules 获取数据库中已启用的规则
+func (s *ClassificationService) Rules(ctx context.Context) ([]model.ClassificationRule, error) {
+	rs, err := classify.Load(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	return toModelRules(rs.Rules()), nil
+}
+
+// DryRun 用给定规则集（为空时使用数据库规则）对样例标签试运行分类
+func (s *ClassificationService) DryRun(ctx context.Context, req *model.ClassificationDryRunRequest) (*model.ClassificationDryRunResult, error) {
+	rs, err := s.ruleSet(ctx, req.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.ClassificationDryRunResult{
+		RuleCount: len(rs.Rules()),
+		Results:   make([]model.ClassificationSampleResult, 0, len(req.Samples)),
+	}
+	for _, tags := range req.Samples {
+		sample := model.ClassificationSampleResult{
+			Tags:    tags,
+			Matches: toModelRules(rs.MatchAll(tags)),
+		}
+		if len(sample.Matches) > 0 {
+			sample.Matched = &sample.Matches[0]
+		} else {
+			result.Unmatched++
+		}
+		result.Results = append(result.Results, sample)
+	}
+
+	return result, nil
+}
+
+// Report 检查评价标准引用的子类型是否都能由规则产生
+func (s *ClassificationService) Report(ctx context.Context, standards []model.EvaluationStandard) (*model.ClassificationReport, error) {
+	rs, err := classify.Load(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	produced := rs.Produces()
+	report := &model.ClassificationReport{
+		RuleCount:  len(rs.Rules()),
+		Produced:   produced,
+		Missing:    []model.ClassificationGap{},
+		Mismatched: []model.ClassificationGap{},
+	}
+	for _, std := range standards {
+		gap := model.ClassificationGap{
+			Category: std.Category,
+			SubType:  std.SubType,
+			Required: std.Required,
+		}
+		category, ok := produced[std.SubType]
+		switch {
+		case !ok:
+			report.Missing = append(report.Missing, gap)
+		case category != std.Category:
+			gap.RuleCategory = category
+			report.Mismatched = append(report.Mismatched, gap)
+		}
+	}
+
+	return report, nil
+}
+
+// CheckStandards 确认评价标准中每项必备设施的子类型都能由已启用的规则产生，
+// 否则返回 classify.ErrUncovered
+func (s *ClassificationService) CheckStandards(ctx context.Context, standards []model.EvaluationStandard) error {
+	rs, err := classify.Load(ctx, s.db)
+	if err != nil {
+		return err
+	}
+	return rs.CheckStandards(standards)
+}
+
+// ruleSet 编译请求中的规则，未提供时加载数据库规则
+func (s *ClassificationService) ruleSet(ctx context.Context, rules []model.ClassificationRule) (*classify.RuleSet, error) {
+	if len(rules) == 0 {
+		return classify.Load(ctx, s.db)
+	}
+
+	compiled := make([]classify.Rule, len(rules))
+	for i, r := range rules {
+		compiled[i] = classify.Rule{
+			ID:         r.ID,
+			Expression: r.Expression,
+			Category:   r.Category,
+			SubType:    r.SubType,
+			Priority:   r.Priority,
+		}
+		// 未编号的临时规则按提交顺序编号，保证同优先级时顺序稳定
+		if compiled[i].ID == 0 {
+			compiled[i].ID = int64(i + 1)
+		}
+	}
+	rs, err := classify.NewRuleSet(compiled)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+	return rs, nil
+}
+
+func toModelRules(rules []*classify.Rule) []model.ClassificationRule {
+	out := make([]model.ClassificationRule, 0, len(rules))
+	for _, r := range rules {
+		out = append(out, model.ClassificationRule{
+			ID:         r.ID,
+			Expression: r.Expression,
+			Category:   r.Category,
+			SubType:    r.SubType,
+			Priority:   r.Priority,
+		})
+	}
+	return out
+}
@@ -0,0 +1,407 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yourname/15min-life-circle/internal/database"
+	"github.com/yourname/15min-life-circle/internal/model"
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	// ErrInvalidEquityRequest 公平性分析参数不合法
+	ErrInvalidEquityRequest = errors.New("invalid equity request")
+	// ErrNoEquitySamples 没有可参与分析的样点（未导入区域或尚未运行 cmd/regions compute）
+	ErrNoEquitySamples = errors.New("no measured samples")
+)
+
+const (
+	// DefaultDesertPoints 报告中列出的服务盲区点数
+	DefaultDesertPoints = 100
+	// MaxDesertPoints 服务盲区点数上限
+	MaxDesertPoints = 5000
+	// equityConcurrency 指定点集同时进行的路网分析数
+	equityConcurrency = 4
+	// lorenzSteps 洛伦兹曲线按 1/lorenzSteps 的权重占比取点
+	lorenzSteps = 20
+)
+
+// EquityService 生活圈评分分布的公平性分析：基尼系数、泰尔指数、洛伦兹曲线、
+// 分位数与服务盲区。格网分析复用区域样点已保存的 POI 数量并按当前标准评分，
+// 不做路网分析；指定点集逐点做路网分析
+type EquityService struct {
+	db         *database.DB
+	evaluation *EvaluationService
+}
+
+// NewEquityService 创建公平性分析服务
+func NewEquityService(db *database.DB, evaluation *EvaluationService) *EquityService {
+	return &EquityService{db: db, evaluation: evaluation}
+}
+
+// equitySample 参与分析的一个点
+type equitySample struct {
+	label      string
+	regionID   *int
+	regionName string
+	lng, lat   float64
+	weight     float64
+	counts     map[string]int
+}
+
+// Grid 城市（或单个区域）格网样点的公平性分析
+// 城市范围只使用一个层级的样点，避免街道与社区样点重复；level 为空时取已导入的最细层级。
+// 默认按样点代表的面积加权，weight 为 population 时按人口加权
+func (s *EquityService) Grid(ctx context.Context, city, level string, regionID *int, weight string, desertLimit int) (*model.EquityReport, error) {
+	if weight != model.EquityWeightDefault && weight != model.EquityWeightPopulation {
+		return nil, fmt.Errorf("%w: weight must be empty or population", ErrInvalidEquityRequest)
+	}
+	if regionID != nil {
+		err := s.db.Pool.QueryRow(ctx,
+			`SELECT city, level FROM admin_region WHERE id = $1`, *regionID).Scan(&city, &level)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRegionNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("query region: %w", err)
+		}
+	}
+	if city == "" {
+		return nil, fmt.Errorf("%w: city or region_id is required", ErrInvalidEquityRequest)
+	}
+	switch level {
+	case model.RegionSubdistrict, model.RegionCommunity:
+	case "":
+		// 已导入社区时取社区，否则取街道
+		err := s.db.Pool.QueryRow(ctx, `
+			SELECT level FROM admin_region WHERE city = $1
+			ORDER BY level = 'community' DESC LIMIT 1`, city).Scan(&level)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNoEquitySamples
+		}
+		if err != nil {
+			return nil, fmt.Errorf("query region levels: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: level must be %s or %s", ErrInvalidEquityRequest, model.RegionSubdistrict, model.RegionCommunity)
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT s.region_id, r.name, ST_X(s.geom), ST_Y(s.geom), s.area_m2, s.population, s.counts
+		FROM region_sample s
+		JOIN admin_region r ON r.id = s.region_id
+		WHERE r.city = $1 AND r.level = $2 AND ($3::int IS NULL OR r.id = $3)
+		ORDER BY s.id`,
+		city, level, regionID)
+	if err != nil {
+		return nil, fmt.Errorf("query samples: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		samples    []equitySample
+		unmeasured int
+	)
+	for rows.Next() {
+		var (
+			p          equitySample
+			id         int
+			area       float64
+			population *float64
+			raw        []byte
+		)
+		if err := rows.Scan(&id, &p.regionName, &p.lng, &p.lat, &area, &population, &raw); err != nil {
+			return nil, fmt.Errorf("scan sample: %w", err)
+		}
+		if raw == nil {
+			unmeasured++
+			continue
+		}
+		if err := json.Unmarshal(raw, &p.counts); err != nil {
+			return nil, fmt.Errorf("decode sample counts: %w", err)
+		}
+		p.regionID = &id
+		p.weight = area
+		if weight == model.EquityWeightPopulation {
+			p.weight = 0
+			if population != nil {
+				p.weight = *population
+			}
+		}
+		samples = append(samples, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query samples: %w", err)
+	}
+	if len(samples) == 0 {
+		return nil, ErrNoEquitySamples
+	}
+
+	report, err := s.analyze(ctx, samples, weight, desertLimit)
+	if err != nil {
+		return nil, err
+	}
+	report.Source = "grid"
+	report.City, report.Level, report.RegionID = city, level, regionID
+	report.Unmeasured = unmeasured
+	if weight == model.EquityWeightDefault {
+		report.Weight = "area"
+	}
+	return report, nil
+}
+
+// Points 指定居住点集的公平性分析，逐点计算 15 分钟步行圈内 POI 数量
+// 默认各点等权，weight 为 population 时按各点人口加权
+func (s *EquityService) Points(ctx context.Context, req *model.EquityPointsRequest, desertLimit int) (*model.EquityReport, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEquityRequest, err)
+	}
+
+	samples := make([]equitySample, len(req.Points))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(equityConcurrency)
+	for i, p := range req.Points {
+		samples[i] = equitySample{label: p.Label, lng: p.Lng, lat: p.Lat, weight: 1}
+		if req.Weight == model.EquityWeightPopulation {
+			samples[i].weight = *p.Population
+		}
+		g.Go(func() error {
+			counts, err := s.evaluation.ReachableCounts(gctx, p.Lng, p.Lat, req.WalkSpeed)
+			if err != nil {
+				return fmt.Errorf("point %s: %w", p.Label, err)
+			}
+			samples[i].counts = counts
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	report, err := s.analyze(ctx, samples, req.Weight, desertLimit)
+	if err != nil {
+		return nil, err
+	}
+	report.Source = "points"
+	if req.Weight == model.EquityWeightDefault {
+		report.Weight = "equal"
+	}
+	return report, nil
+}
+
+// analyze 按当前评价标准为样点评分，计算总分与各分类得分的分布及服务盲区
+// 权重为 0 的样点不代表任何面积或人口，不参与分布与盲区统计（包括计数）
+func (s *EquityService) analyze(ctx context.Context, samples []equitySample, weight string, desertLimit int) (*model.EquityReport, error) {
+	all := len(samples)
+	samples = slices.DeleteFunc(slices.Clone(samples), func(p equitySample) bool { return p.weight <= 0 })
+
+	var totalWeight float64
+	for _, p := range samples {
+		totalWeight += p.weight
+	}
+	if totalWeight <= 0 {
+		return nil, fmt.Errorf("%w: total weight is zero (no population data?)", ErrInvalidEquityRequest)
+	}
+	if desertLimit <= 0 {
+		desertLimit = DefaultDesertPoints
+	}
+	desertLimit = min(desertLimit, MaxDesertPoints)
+
+	scorer, err := s.evaluation.NewScorer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	categories := scorer.Categories()
+	required := scorer.Required()
+	names := subTypeNames()
+
+	weights := make([]float64, len(samples))
+	totals := make([]float64, len(samples))
+	byCategory := make([][]float64, len(categories))
+	for i := range byCategory {
+		byCategory[i] = make([]float64, len(samples))
+	}
+	gaps := make([]model.FacilityGap, len(required))
+	for i, std := range required {
+		gaps[i] = model.FacilityGap{Category: std.Category, SubType: std.SubType, Name: names[std.SubType]}
+	}
+	gapWeights := make([]float64, len(required))
+
+	var (
+		deserts      []model.DesertPoint
+		desertWeight float64
+	)
+	for i, p := range samples {
+		eval := scorer.Score(p.counts)
+		weights[i], totals[i] = p.weight, eval.TotalScore
+		for j, c := range categories {
+			for _, cs := range eval.CategoryScores {
+				if cs.Category == c.Code {
+					byCategory[j][i] = cs.Score
+					break
+				}
+			}
+		}
+
+		var missing []string
+		for j, std := range required {
+			if !meetsStandard(p.counts, std) {
+				missing = append(missing, std.SubType)
+				gaps[j].Count++
+				gapWeights[j] += p.weight
+			}
+		}
+		if len(missing) > 0 {
+			desertWeight += p.weight
+			deserts = append(deserts, model.DesertPoint{
+				Label:      p.label,
+				RegionID:   p.regionID,
+				RegionName: p.regionName,
+				Lng:        p.lng,
+				Lat:        p.lat,
+				Weight:     p.weight,
+				TotalScore: eval.TotalScore,
+				Missing:    missing,
+			})
+		}
+	}
+
+	report := &model.EquityReport{
+		Weight:        weight,
+		Samples:       len(samples),
+		Unweighted:    all - len(samples),
+		Total:         distribution(totals, weights),
+		Categories:    make([]model.CategoryDistribution, len(categories)),
+		StandardsHash: scorer.Hash(),
+		ComputedAt:    time.Now(),
+	}
+	for j, c := range categories {
+		report.Categories[j] = model.CategoryDistribution{
+			Category:     c.Code,
+			Name:         c.Name,
+			Distribution: distribution(byCategory[j], weights),
+		}
+	}
+
+	for j := range gaps {
+		gaps[j].Share = roundShare(gapWeights[j] / totalWeight)
+	}
+	slices.SortStableFunc(deserts, func(a, b model.DesertPoint) int {
+		return cmp.Or(cmp.Compare(len(b.Missing), len(a.Missing)), cmp.Compare(b.Weight, a.Weight))
+	})
+	report.Deserts = model.DesertSummary{
+		Count:      len(deserts),
+		Share:      roundShare(desertWeight / totalWeight),
+		Facilities: gaps,
+		Points:     make([]model.DesertPoint, 0),
+		Truncated:  len(deserts) > desertLimit,
+	}
+	report.Deserts.Points = append(report.Deserts.Points, deserts[:min(len(deserts), desertLimit)]...)
+	return report, nil
+}
+
+// distribution 加权分布：均值、基尼系数、泰尔指数、分位数与洛伦兹曲线
+//
+// 基尼系数取洛伦兹曲线与均等线之间面积的两倍（按权重累计的梯形面积）；
+// 泰尔指数 T = Σ (w_i/W)·(x_i/μ)·ln(x_i/μ)，0 分项记为 0。全部为 0 分时两者均记为 0。
+// 权重为 0 的样本不参与（包括最小、最大值）
+func distribution(values, weights []float64) model.Distribution {
+	d := model.Distribution{Lorenz: make([]model.LorenzPoint, 0, lorenzSteps+1)}
+	values, weights = positiveWeights(values, weights)
+	if len(values) == 0 {
+		return d
+	}
+
+	idx := make([]int, len(values))
+	for i := range idx {
+		idx[i] = i
+	}
+	slices.SortFunc(idx, func(a, b int) int { return cmp.Compare(values[a], values[b]) })
+
+	var totalWeight, totalScore float64
+	for i, v := range values {
+		totalWeight += weights[i]
+		totalScore += v * weights[i]
+	}
+	d.Min, d.Max = values[idx[0]], values[idx[len(idx)-1]]
+	mean := totalScore / totalWeight
+	d.Mean = round2(mean)
+
+	// 洛伦兹曲线的累计点（从低分到高分）
+	cumPop := make([]float64, 1, len(values)+1)
+	cumScore := make([]float64, 1, len(values)+1)
+	var pop, score float64
+	for _, i := range idx {
+		pop += weights[i]
+		score += values[i] * weights[i]
+		cumPop = append(cumPop, pop/totalWeight)
+		if totalScore > 0 {
+			cumScore = append(cumScore, score/totalScore)
+		} else {
+			cumScore = append(cumScore, pop/totalWeight)
+		}
+	}
+
+	if totalScore > 0 {
+		var area float64
+		for k := 1; k < len(cumPop); k++ {
+			area += (cumPop[k] - cumPop[k-1]) * (cumScore[k] + cumScore[k-1])
+		}
+		d.Gini = roundShare(math.Max(0, 1-area))
+
+		var theil float64
+		for i, v := range values {
+			if v > 0 && weights[i] > 0 {
+				r := v / mean
+				theil += weights[i] / totalWeight * r * math.Log(r)
+			}
+		}
+		d.Theil = roundShare(math.Max(0, theil))
+	}
+
+	d.Percentiles = model.Percentiles{
+		P10: round2(weightedPercentile(values, weights, 0.10)),
+		P25: round2(weightedPercentile(values, weights, 0.25)),
+		P50: round2(weightedPercentile(values, weights, 0.50)),
+		P75: round2(weightedPercentile(values, weights, 0.75)),
+		P90: round2(weightedPercentile(values, weights, 0.90)),
+	}
+
+	// 按权重占比等距取点，在累计点之间线性插值
+	k := 1
+	for step := 0; step <= lorenzSteps; step++ {
+		p := float64(step) / lorenzSteps
+		for k < len(cumPop)-1 && cumPop[k] < p {
+			k++
+		}
+		l := cumScore[k]
+		if span := cumPop[k] - cumPop[k-1]; span > 0 && p < cumPop[k] {
+			l = cumScore[k-1] + (cumScore[k]-cumScore[k-1])*(p-cumPop[k-1])/span
+		}
+		if step == 0 {
+			l = 0
+		}
+		d.Lorenz = append(d.Lorenz, model.LorenzPoint{Population: p, Score: roundShare(l)})
+	}
+	return d
+}
+
+// positiveWeights 去掉权重为 0（或负）的样本
+func positiveWeights(values, weights []float64) ([]float64, []float64) {
+	var vs, ws []float64
+	for i, w := range weights {
+		if w > 0 {
+			vs = append(vs, values[i])
+			ws = append(ws, w)
+		}
+	}
+	return vs, ws
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/store/fixture"
+)
+
+func TestDistribution(t *testing.T) {
+	tests := []struct {
+		name            string
+		values, weights []float64
+		mean, min, max  float64
+		gini, theil     float64
+		p10, p50, p90   float64
+	}{
+		{"empty", nil, nil, 0, 0, 0, 0, 0, 0, 0, 0},
+		{"uniform", []float64{60, 60, 60, 60}, []float64{1, 1, 1, 1}, 60, 60, 60, 0, 0, 60, 60, 60},
+		// 一个点拥有全部得分：基尼系数 1-1/N，泰尔指数 ln N
+		{"one holder", []float64{0, 0, 0, 10}, []float64{1, 1, 1, 1}, 2.5, 0, 10, 0.75, roundShare(math.Log(4)), 0, 0, 10},
+		{"all zero", []float64{0, 0, 0}, []float64{1, 1, 1}, 0, 0, 0, 0, 0, 0, 0, 0},
+		// 权重 3:1 → 洛伦兹曲线 (0.75, 0.6)，基尼系数 1-0.85；泰尔指数 0.75·0.8·ln0.8 + 0.25·1.6·ln1.6
+		{"weighted", []float64{10, 20}, []float64{3, 1}, 12.5, 10, 20, 0.15, 0.0541, 10, 10, 20},
+		// 权重为 0 的样本不影响任何指标（包括最小值）
+		{"zero weight ignored", []float64{0, 50, 50}, []float64{0, 2, 2}, 50, 50, 50, 0, 0, 50, 50, 50},
+		{"all weights zero", []float64{30, 70}, []float64{0, 0}, 0, 0, 0, 0, 0, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := distribution(tt.values, tt.weights)
+			got := []float64{d.Mean, d.Min, d.Max, d.Gini, d.Theil, d.Percentiles.P10, d.Percentiles.P50, d.Percentiles.P90}
+			want := []float64{tt.mean, tt.min, tt.max, tt.gini, tt.theil, tt.p10, tt.p50, tt.p90}
+			names := []string{"mean", "min", "max", "gini", "theil", "p10", "p50", "p90"}
+			for i := range got {
+				if math.Abs(got[i]-want[i]) > 1e-4 {
+					t.Errorf("%s = %v, want %v", names[i], got[i], want[i])
+				}
+			}
+
+			var weighted bool
+			for _, w := range tt.weights {
+				weighted = weighted || w > 0
+			}
+			if !weighted {
+				if len(d.Lorenz) != 0 {
+					t.Errorf("lorenz = %v, want no points without weight", d.Lorenz)
+				}
+				return
+			}
+			if n := len(d.Lorenz); n != lorenzSteps+1 {
+				t.Fatalf("lorenz has %d points, want %d", n, lorenzSteps+1)
+			}
+			if first, last := d.Lorenz[0], d.Lorenz[lorenzSteps]; first.Score != 0 || last.Population != 1 || last.Score != 1 {
+				t.Errorf("lorenz endpoints %v, %v", first, last)
+			}
+			for k, p := range d.Lorenz {
+				if p.Score > p.Population+1e-9 || k > 0 && p.Score < d.Lorenz[k-1].Score {
+					t.Errorf("lorenz point %d %v above the equality line or decreasing", k, p)
+				}
+			}
+		})
+	}
+}
+
+func TestWeightedPercentile(t *testing.T) {
+	tests := []struct {
+		name            string
+		values, weights []float64
+		q               float64
+		want            float64
+	}{
+		{"empty", nil, nil, 0.5, 0},
+		{"median of equal weights", []float64{3, 1, 2}, []float64{1, 1, 1}, 0.5, 2},
+		{"lowest", []float64{3, 1, 2}, []float64{1, 1, 1}, 0, 1},
+		{"highest", []float64{3, 1, 2}, []float64{1, 1, 1}, 1, 3},
+		{"heavy value dominates", []float64{10, 90}, []float64{9, 1}, 0.9, 10},
+		{"past heavy value", []float64{10, 90}, []float64{9, 1}, 0.95, 90},
+		{"zero weight skipped", []float64{0, 10, 20}, []float64{0, 1, 1}, 0.1, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := weightedPercentile(tt.values, tt.weights, tt.q); got != tt.want {
+				t.Errorf("weightedPercentile(%v) = %v, want %v", tt.q, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestEquityAnalyzeUnweighted 按人口加权时缺少人口（权重 0）的样点不计入服务盲区
+func TestEquityAnalyzeUnweighted(t *testing.T) {
+	stores := fixture.NewTown().Memory().Stores()
+	iso := NewIsochroneServiceWithStore(stores.Isochrones)
+	evaluation := NewEvaluationServiceWithStores(iso, NewPOIServiceWithStore(stores.POIs, iso), stores.Standards, nil)
+	equity := NewEquityService(nil, evaluation)
+
+	ctx := context.Background()
+	scorer, err := evaluation.NewScorer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full := make(map[string]int)
+	for _, std := range scorer.Required() {
+		full[std.Category+"/"+std.SubType] = max(std.MinCount15, 1)
+	}
+
+	samples := []equitySample{
+		{label: "no population", weight: 0, counts: map[string]int{}},
+		{label: "desert", weight: 100, counts: map[string]int{}},
+		{label: "served", weight: 300, counts: full},
+	}
+	report, err := equity.analyze(ctx, samples, model.EquityWeightPopulation, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Samples != 2 || report.Unweighted != 1 {
+		t.Errorf("samples = %d, unweighted = %d; want 2, 1", report.Samples, report.Unweighted)
+	}
+	if report.Deserts.Count != 1 || report.Deserts.Share != 0.25 {
+		t.Errorf("deserts count = %d, share = %v; want 1, 0.25", report.Deserts.Count, report.Deserts.Share)
+	}
+	for _, g := range report.Deserts.Facilities {
+		if g.Count != 1 {
+			t.Errorf("facility %s missing count = %d, want 1", g.SubType, g.Count)
+		}
+	}
+	if len(report.Deserts.Points) != 1 || report.Deserts.Points[0].Label != "desert" {
+		t.Errorf("desert points = %+v", report.Deserts.Points)
+	}
+	if report.Total.Min != 0 || report.Total.Max == 0 {
+		t.Errorf("total min/max = %v/%v", report.Total.Min, report.Total.Max)
+	}
+}
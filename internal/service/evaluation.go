@@ -4,179 +4,294 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"math"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/yourname/15min-life-circle/internal/config"
 	"github.com/yourname/15min-life-circle/internal/database"
+	"github.com/yourname/15min-life-circle/internal/metrics"
 	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/store"
+	"github.com/yourname/15min-life-circle/internal/store/postgis"
+	"github.com/yourname/15min-life-circle/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/errgroup"
 )
 
 // EvaluationService 评价服务
 type EvaluationService struct {
-	db          *database.DB
-	poiService  *POIService
-	amapService *AmapPOIService
+	poiService      *POIService
+	amapService     *AmapPOIService
+	facilityService *FacilityService
+	isoService      *IsochroneService
+	standards       store.StandardStore
+	history         store.HistoryStore
+	// 评价标准来源（config.ProfileDatabase / config.ProfileBuiltin），空值按 database 处理
+	profile string
 }
 
 // NewEvaluationService 创建评价服务
 func NewEvaluationService(db *database.DB, poiService *POIService, cfg *config.Config) *EvaluationService {
 	return &EvaluationService{
-		db:          db,
-		poiService:  poiService,
-		amapService: NewAmapPOIService(cfg.Amap),
+		poiService:      poiService,
+		amapService:     NewAmapPOIService(cfg.Amap),
+		facilityService: NewFacilityService(db),
+		isoService:      NewIsochroneService(db),
+		standards:       postgis.NewStandardStore(db),
+		history:         postgis.NewHistoryStore(db),
+		profile:         cfg.Evaluation.Profile,
 	}
 }
 
-// Evaluate 执行综合评价
-func (s *EvaluationService) Evaluate(ctx context.Context, req *model.EvaluationRequest) (*model.EvaluationResult, error) {
-	req.Validate()
-
-	// 调用数据库评价函数（使用用户配置的步行速度）
-	query := `
-		SELECT 
-			total_score,
-			grade,
-			category,
-			category_name,
-			cat_weight,
-			category_score,
-			weighted_score,
-			poi_count,
-			details
-		FROM evaluate_life_circle($1, $2, $3)
-	`
-
-	rows, err := s.db.Pool.Query(ctx, query, req.Lng, req.Lat, req.WalkSpeed)
-	if err != nil {
-		return nil, fmt.Errorf("evaluate: %w", err)
+// NewEvaluationServiceWithStores 使用指定存储创建评价服务
+// 不查询高德 POI 与必备设施最近距离；history 为 nil 时不保存评价记录
+func NewEvaluationServiceWithStores(isoService *IsochroneService, poiService *POIService, standards store.StandardStore, history store.HistoryStore) *EvaluationService {
+	return &EvaluationService{
+		poiService: poiService,
+		isoService: isoService,
+		standards:  standards,
+		history:    history,
 	}
-	defer rows.Close()
+}
 
-	result := &model.EvaluationResult{
-		Origin:         model.Point{req.Lng, req.Lat},
-		CategoryScores: make([]model.CategoryScore, 0),
+// evaluationThresholds 综合评价的圈层（分钟），评分按最后一层（15 分钟）计
+var evaluationThresholds = []int{5, 10, 15}
+
+// circleThresholds 评价圈层加上请求的 POI 展示范围 time_threshold（升序、去重）
+func circleThresholds(timeThreshold int) []int {
+	thresholds := append([]int(nil), evaluationThresholds...)
+	if !slices.Contains(thresholds, timeThreshold) {
+		thresholds = append(thresholds, timeThreshold)
+		slices.Sort(thresholds)
 	}
+	return thresholds
+}
 
-	for rows.Next() {
-		var (
-			totalScore     float64
-			grade          string
-			category       string
-			categoryName   string
-			categoryWeight float64
-			categoryScore  float64
-			weightedScore  float64
-			poiCount       int64
-			detailsJSON    []byte
-		)
-
-		if err := rows.Scan(
-			&totalScore,
-			&grade,
-			&category,
-			&categoryName,
-			&categoryWeight,
-			&categoryScore,
-			&weightedScore,
-			&poiCount,
-			&detailsJSON,
-		); err != nil {
-			return nil, fmt.Errorf("scan result: %w", err)
+// withinMinutes 筛选位于 minutes 分钟圈内的 POI
+func withinMinutes(pois []model.POI, minutes int) []model.POI {
+	out := make([]model.POI, 0, len(pois))
+	for _, p := range pois {
+		if p.WithinMinutes > 0 && p.WithinMinutes <= minutes {
+			out = append(out, p)
 		}
+	}
+	return out
+}
 
-		result.TotalScore = totalScore
-		result.Grade = strings.TrimSpace(grade)
-
-		// 解析详情
-		var details []model.SubTypeScore
-		if err := json.Unmarshal(detailsJSON, &details); err != nil {
-			// 忽略解析错误
-			details = nil
-		}
+// Evaluate 执行综合评价
+//
+// 只吸附、路网分析一次（evaluation_reach），等时圈、各圈层 POI、可达道路和评分
+// 均由同一可达节点集得出（019 迁移）。互不依赖的步骤并发执行：
+//
+//	吸附 + 路网分析 ─┬─ 等时圈 ─ 圈内 POI ─┐
+//	评价标准        ─┼─ 可达道路            ├─ 评分
+//	                 ├─ 高德 POI           ─┤
+//	                 └─ 必备设施最近距离   ─┘
+//
+// Evaluate 不写入评价记录；需要历史与分享链接时使用 EvaluateAndSave。
+func (s *EvaluationService) Evaluate(ctx context.Context, req *model.EvaluationRequest) (*model.EvaluationResult, error) {
+	result, _, err := s.evaluate(ctx, req)
+	return result, err
+}
 
-		catScore := model.CategoryScore{
-			Category:      category,
-			Name:          categoryName,
-			Score:         categoryScore,
-			Weight:        categoryWeight,
-			WeightedScore: weightedScore,
-			POICount:      int(poiCount),
-			Details:       details,
-		}
-		result.CategoryScores = append(result.CategoryScores, catScore)
+// EvaluateAndSave 执行综合评价并写入 analysis_history（用户历史与分享链接），
+// 回填 AnalysisID 与 Permalink；保存失败不影响评价结果
+func (s *EvaluationService) EvaluateAndSave(ctx context.Context, req *model.EvaluationRequest) (*model.EvaluationResult, error) {
+	result, iso, err := s.evaluate(ctx, req)
+	if err != nil {
+		return nil, err
 	}
+	s.saveAnalysis(ctx, req, iso, result)
+	return result, nil
+}
 
-	// 生成评价说明
-	result.Summary = model.GetGradeDescription(result.Grade)
+// evaluate 执行综合评价，同时返回评价所用的等时圈（保存记录时使用）
+func (s *EvaluationService) evaluate(ctx context.Context, req *model.EvaluationRequest) (_ *model.EvaluationResult, _ *model.IsochroneResult, err error) {
+	ctx, span := tracing.Start(ctx, "EvaluationService.Evaluate", attribute.Float64("lng", req.Lng), attribute.Float64("lat", req.Lat))
+	defer func() { tracing.End(span, err) }()
 
-	// 生成改进建议
-	result.Suggestions = s.generateSuggestions(result.CategoryScores)
+	req.Validate()
+	thresholds := circleThresholds(req.TimeThreshold)
+	scoreMinutes := evaluationThresholds[len(evaluationThresholds)-1]
+	maxMinutes := thresholds[len(thresholds)-1]
+
+	// 1. 路网分析与评价标准
+	var (
+		reach *ReachSet
+		sc    *scoring
+	)
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() (err error) {
+		reach, err = s.isoService.Reach(gctx, req.Lng, req.Lat, req.WalkSpeed, maxMinutes)
+		return err
+	})
+	g.Go(func() (err error) {
+		sc, err = s.loadScoring(gctx)
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return nil, nil, fmt.Errorf("evaluate: %w", err)
+	}
 
-	// 获取等时圈 GeoJSON（使用用户配置的步行速度）
-	isoService := NewIsochroneService(s.db)
-	isoReq := &model.IsochroneRequest{
-		Lng:            req.Lng,
-		Lat:            req.Lat,
-		TimeThresholds: []int{5, 10, 15},
-		WalkSpeed:      req.WalkSpeed,
+	// 2. 由可达节点派生各项结果；道路、高德、最近设施失败不影响评价
+	var (
+		iso       *model.IsochroneResult
+		localPOIs []model.POI
+		amapPOIs  []model.POI
+		roadsJSON string
+		nearest   []model.NearestRequired
+	)
+	g, gctx = errgroup.WithContext(ctx)
+	g.Go(func() (err error) {
+		if iso, err = s.isoService.FromReach(gctx, reach, thresholds); err != nil {
+			return err
+		}
+		localPOIs, err = s.poiService.QueryInIsochrones(gctx, req.Lng, req.Lat, req.WalkSpeed, iso.Polygons)
+		return err
+	})
+	g.Go(func() error {
+		var err error
+		if roadsJSON, err = s.isoService.RoadsFromReach(gctx, reach, scoreMinutes); err != nil {
+			slog.WarnContext(gctx, "reachable roads failed", "error", err)
+		}
+		return nil
+	})
+	if s.amapService != nil && s.amapService.IsEnabled() {
+		g.Go(func() error {
+			// 搜索半径为步行速度 * 展示范围
+			radius := int(req.WalkSpeed * 1000 / 60 * float64(req.TimeThreshold))
+			defer metrics.Stage("evaluation", metrics.StageExternal)()
+			var err error
+			if amapPOIs, err = s.amapService.SearchNearby(gctx, req.Lng, req.Lat, radius); err != nil {
+				slog.WarnContext(gctx, "amap poi search failed", "error", err)
+			}
+			return nil
+		})
 	}
-	var iso15GeoJSON string
-	if isoFC, err := isoService.CalculateAsGeoJSON(ctx, isoReq); err == nil {
-		result.Isochrone = isoFC
-		// 获取15分钟等时圈的GeoJSON用于过滤POI
-		if isoResult, err := isoService.Calculate(ctx, isoReq); err == nil {
-			for _, poly := range isoResult.Polygons {
-				if poly.Minutes == 15 {
-					if geojsonBytes, err := json.Marshal(poly.Geometry); err == nil {
-						iso15GeoJSON = string(geojsonBytes)
-					}
-					break
-				}
+	if s.facilityService != nil {
+		g.Go(func() error {
+			var err error
+			if nearest, err = s.facilityService.NearestRequired(gctx, reach, sc.standards); err != nil {
+				slog.WarnContext(gctx, "nearest facilities failed", "error", err)
 			}
-		}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, nil, fmt.Errorf("evaluate: %w", err)
 	}
 
-	// 获取 POI GeoJSON（使用用户配置的步行速度）
-	if pois, err := s.poiService.QueryInIsochrone(ctx, req.Lng, req.Lat, req.TimeThreshold, req.WalkSpeed); err == nil {
-		// 尝试补充高德 POI 数据
-		if s.amapService != nil && s.amapService.IsEnabled() {
-			// 计算搜索半径（步行速度 * 15分钟）
-			radius := int(req.WalkSpeed * 1000 / 60 * 15)
-			if amapPOIs, err := s.amapService.SearchNearby(req.Lng, req.Lat, radius); err == nil {
-				// 过滤高德POI：只保留等时圈内的
-				if iso15GeoJSON != "" {
-					filteredAmapPOIs := s.filterPOIsInIsochrone(ctx, amapPOIs, iso15GeoJSON)
-					log.Printf("高德POI过滤：搜索 %d -> 圈内 %d", len(amapPOIs), len(filteredAmapPOIs))
-					amapPOIs = filteredAmapPOIs
-				}
-				// 合并去重
-				pois = s.mergePOIs(pois, amapPOIs)
-				log.Printf("合并高德POI：总计 %d", len(pois))
-			} else {
-				log.Printf("高德POI查询失败: %v", err)
-			}
+	// 3. 评分（只计 15 分钟圈内的本地 POI，高德 POI 仅用于展示）
+	scoreDone := metrics.Stage("evaluation", metrics.StageScore)
+	scored := withinMinutes(localPOIs, scoreMinutes)
+	evaluation := s.score(sc, scored)
+	scoreDone()
+
+	result := &model.EvaluationResult{
+		Origin:          model.Point{req.Lng, req.Lat},
+		TotalScore:      evaluation.TotalScore,
+		Grade:           evaluation.Grade,
+		CategoryScores:  evaluation.CategoryScores,
+		Summary:         evaluation.Summary,
+		Suggestions:     evaluation.Suggestions,
+		Isochrone:       isochroneFeatures(iso),
+		Coverage:        iso.Coverage,
+		Fallback:        iso.Fallback,
+		NearestRequired: nearest,
+	}
+
+	// 展示 time_threshold 圈内的 POI
+	pois := withinMinutes(localPOIs, req.TimeThreshold)
+	for _, p := range iso.Polygons {
+		if len(amapPOIs) == 0 || p.Minutes != req.TimeThreshold {
+			continue
+		}
+		// 过滤高德POI：只保留展示范围等时圈内的
+		if outer, err := json.Marshal(p.Geometry); err == nil {
+			filtered := s.filterPOIsInIsochrone(ctx, amapPOIs, string(outer))
+			slog.DebugContext(ctx, "amap pois filtered", "searched", len(amapPOIs), "in_isochrone", len(filtered))
+			pois = s.mergePOIs(pois, filtered)
 		}
-		result.POIs = s.poiService.POIsAsGeoJSON(pois)
 	}
+	result.POIs = s.poiService.POIsAsGeoJSON(pois)
 
-	// 获取可达道路网络
-	if roadsJSON, err := isoService.GetReachableRoads(ctx, req.Lng, req.Lat, 15, req.WalkSpeed); err == nil && roadsJSON != "" {
+	if roadsJSON != "" {
 		var roads interface{}
 		if json.Unmarshal([]byte(roadsJSON), &roads) == nil {
 			result.Roads = roads
 		}
 	}
 
-	return result, nil
+	// 按营业时间评价：与默认评分相同的设施中只计入指定时刻营业的，分数可直接比较
+	if req.At != nil {
+		s.evaluateAt(ctx, result, sc, scored, req.At.In(model.LocalZone))
+	}
+	if req.Profile {
+		day := time.Now()
+		if req.At != nil {
+			day = *req.At
+		}
+		result.Profile = s.availabilityProfile(ctx, sc, scored, day)
+	}
+
+	return result, iso, nil
+}
+
+// saveAnalysis 将评价结果写入 analysis_history，并回填 AnalysisID 与分享链接；失败不影响评价
+func (s *EvaluationService) saveAnalysis(ctx context.Context, req *model.EvaluationRequest, iso *model.IsochroneResult, result *model.EvaluationResult) {
+	if s.history == nil {
+		return
+	}
+	raw, err := json.Marshal(result)
+	if err != nil {
+		slog.WarnContext(ctx, "encode analysis failed", "error", err)
+		return
+	}
+	params, err := json.Marshal(req)
+	if err != nil {
+		slog.WarnContext(ctx, "encode analysis params failed", "error", err)
+		return
+	}
+	shareID, err := newShareID()
+	if err != nil {
+		slog.WarnContext(ctx, "generate share id failed", "error", err)
+		return
+	}
+	rec := &model.AnalysisRecord{
+		Lng:            req.Lng,
+		Lat:            req.Lat,
+		TimeThresholds: evaluationThresholds,
+		WalkSpeed:      req.WalkSpeed,
+		TotalScore:     result.TotalScore,
+		Grade:          result.Grade,
+		Result:         raw,
+		Isochrones:     iso.Polygons,
+		UserID:         req.UserID,
+		PlaceID:        req.PlaceID,
+		Params:         params,
+		ShareID:        shareID,
+	}
+	if iso.Coverage != nil {
+		rec.Node = iso.Coverage.Node
+	}
+	id, err := s.history.SaveAnalysis(ctx, rec)
+	if err != nil {
+		slog.WarnContext(ctx, "save analysis failed", "error", err)
+		return
+	}
+	result.AnalysisID = id
+	result.ShareID = shareID
+	result.Permalink = model.Permalink(shareID)
 }
 
 // mergePOIs 合并本地和高德POI（去重）
 func (s *EvaluationService) mergePOIs(localPOIs []model.POI, amapPOIs []model.POI) []model.POI {
 	// 用于去重的集合（基于位置和名称）
 	seen := make(map[string]bool)
-	
+
 	// 先添加本地POI并标记source
 	result := make([]model.POI, len(localPOIs))
 	for i, poi := range localPOIs {
@@ -185,11 +300,11 @@ func (s *EvaluationService) mergePOIs(localPOIs []model.POI, amapPOIs []model.PO
 		poi.Source = "osm"
 		result[i] = poi
 	}
-	
+
 	// 添加不重复的高德POI
 	for _, poi := range amapPOIs {
 		key := fmt.Sprintf("%.5f,%.5f,%s", poi.Lng, poi.Lat, poi.Name)
-		
+
 		// 检查是否已存在类似POI（距离在50米内且名称相似）
 		isDuplicate := seen[key]
 		if !isDuplicate {
@@ -203,13 +318,13 @@ func (s *EvaluationService) mergePOIs(localPOIs []model.POI, amapPOIs []model.PO
 				}
 			}
 		}
-		
+
 		if !isDuplicate {
 			result = append(result, poi)
 			seen[key] = true
 		}
 	}
-	
+
 	return result
 }
 
@@ -223,67 +338,12 @@ func distance(lng1, lat1, lng2, lat2 float64) float64 {
 	return math.Sqrt(dLat*dLat + dLng*dLng)
 }
 
-// filterPOIsInIsochrone 使用数据库空间查询过滤POI是否在等时圈内
+// filterPOIsInIsochrone 过滤位于等时圈内的 POI，出错时返回原始 POI
 func (s *EvaluationService) filterPOIsInIsochrone(ctx context.Context, pois []model.POI, isochroneGeoJSON string) []model.POI {
-	if isochroneGeoJSON == "" || len(pois) == 0 {
-		return pois
-	}
-
-	// 批量查询优化：构建临时表并一次性检查所有点
-	// 构建POI点数组用于批量查询
-	type poiPoint struct {
-		Idx int
-		Lng float64
-		Lat float64
-	}
-	points := make([]poiPoint, len(pois))
-	for i, poi := range pois {
-		points[i] = poiPoint{Idx: i, Lng: poi.Lng, Lat: poi.Lat}
-	}
-
-	// 使用单次批量查询检查所有点
-	query := `
-		WITH poi_points AS (
-			SELECT idx, ST_SetSRID(ST_MakePoint(lng, lat), 4326) as geom
-			FROM unnest($1::int[], $2::float8[], $3::float8[]) AS t(idx, lng, lat)
-		),
-		isochrone AS (
-			SELECT ST_GeomFromGeoJSON($4) as geom
-		)
-		SELECT p.idx 
-		FROM poi_points p, isochrone i
-		WHERE ST_Within(p.geom, i.geom)
-	`
-
-	idxs := make([]int, len(points))
-	lngs := make([]float64, len(points))
-	lats := make([]float64, len(points))
-	for i, p := range points {
-		idxs[i] = p.Idx
-		lngs[i] = p.Lng
-		lats[i] = p.Lat
-	}
-
-	rows, err := s.db.Pool.Query(ctx, query, idxs, lngs, lats, isochroneGeoJSON)
+	filtered, err := s.poiService.FilterInPolygon(ctx, pois, isochroneGeoJSON)
 	if err != nil {
-		log.Printf("批量POI过滤查询失败: %v", err)
-		return pois // 出错时返回原始POI
-	}
-	defer rows.Close()
-
-	validIdxs := make(map[int]bool)
-	for rows.Next() {
-		var idx int
-		if err := rows.Scan(&idx); err == nil {
-			validIdxs[idx] = true
-		}
-	}
-
-	var filtered []model.POI
-	for i, poi := range pois {
-		if validIdxs[i] {
-			filtered = append(filtered, poi)
-		}
+		slog.WarnContext(ctx, "poi isochrone filter failed", "error", err)
+		return pois
 	}
 	return filtered
 }
@@ -320,48 +380,18 @@ func (s *EvaluationService) generateSuggestions(scores []model.CategoryScore) []
 	return suggestions
 }
 
-// GetStandards 获取评价标准
+// GetStandards 获取评价标准；evaluation.profile 为 builtin 时使用内置标准
 func (s *EvaluationService) GetStandards(ctx context.Context) ([]model.EvaluationStandard, error) {
-	query := `
-		SELECT 
-			category,
-			sub_type,
-			min_count_5,
-			min_count_10,
-			min_count_15,
-			is_required,
-			base_score
-		FROM evaluation_standard
-		ORDER BY category, sub_type
-	`
-
-	rows, err := s.db.Pool.Query(ctx, query)
-	if err != nil {
-		// 如果表不存在，返回默认标准
+	if s.profile == config.ProfileBuiltin {
 		return model.GetDefaultStandards(), nil
 	}
-	defer rows.Close()
-
-	var standards []model.EvaluationStandard
-	for rows.Next() {
-		var std model.EvaluationStandard
-		if err := rows.Scan(
-			&std.Category,
-			&std.SubType,
-			&std.MinCount5,
-			&std.MinCount10,
-			&std.MinCount15,
-			&std.Required,
-			&std.BaseScore,
-		); err != nil {
-			return nil, fmt.Errorf("scan standard: %w", err)
+	standards, err := s.standards.Standards(ctx)
+	// 如果表不存在或为空，返回默认标准
+	if err != nil || len(standards) == 0 {
+		if err != nil {
+			slog.WarnContext(ctx, "load standards failed, using defaults", "error", err)
 		}
-		standards = append(standards, std)
-	}
-
-	if len(standards) == 0 {
 		return model.GetDefaultStandards(), nil
 	}
-
 	return standards, nil
 }
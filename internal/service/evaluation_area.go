@@ -0,0 +1,251 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// scoring 评分所需的评价标准与分类，一次评价中只加载一次
+type scoring struct {
+	standards  []model.EvaluationStandard
+	categories []model.POICategory
+}
+
+// loadScoring 加载评价标准与分类
+func (s *EvaluationService) loadScoring(ctx context.Context) (*scoring, error) {
+	standards, err := s.GetStandards(ctx)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.poiService.GetCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &scoring{standards: standards, categories: categories}, nil
+}
+
+// EvaluateArea 按区域内 POI 数量对照评价标准打分
+// 计分方式与 evaluate_life_circle 的 15 分钟圈层一致：
+// 达到 min_count_15 得满分，不足按比例得分，分类得分按权重汇总
+func (s *EvaluationService) EvaluateArea(ctx context.Context, pois []model.POI) (_ *model.AreaEvaluation, err error) {
+	ctx, span := tracing.Start(ctx, "EvaluationService.EvaluateArea", attribute.Int("pois", len(pois)))
+	defer func() { tracing.End(span, err) }()
+
+	sc, err := s.loadScoring(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.score(sc, pois), nil
+}
+
+// poiCounts 各子类型（键为 category/sub_type）的 POI 数量
+type poiCounts struct {
+	// 15 分钟圈（全部）
+	all map[string]int
+	// 5、10 分钟圈，仅用于明细展示
+	within5, within10 map[string]int
+}
+
+// countPOIs 统计 POI 数量；带 WithinMinutes 的 POI 同时计入 5、10 分钟圈层
+func countPOIs(pois []model.POI) poiCounts {
+	c := poiCounts{all: make(map[string]int), within5: make(map[string]int), within10: make(map[string]int)}
+	for _, p := range pois {
+		key := p.Category + "/" + p.SubType
+		c.all[key]++
+		if p.WithinMinutes > 0 && p.WithinMinutes <= 10 {
+			c.within10[key]++
+			if p.WithinMinutes <= 5 {
+				c.within5[key]++
+			}
+		}
+	}
+	return c
+}
+
+// score 按 POI 数量打分，pois 视为全部位于 15 分钟圈内
+func (s *EvaluationService) score(sc *scoring, pois []model.POI) *model.AreaEvaluation {
+	return s.scoreCounts(sc, countPOIs(pois))
+}
+
+// scoreCounts 按各子类型 POI 数量打分
+func (s *EvaluationService) scoreCounts(sc *scoring, c poiCounts) *model.AreaEvaluation {
+	standards, categories := sc.standards, sc.categories
+	counts, counts5, counts10 := c.all, c.within5, c.within10
+
+	categoryCounts := make(map[string]int)
+	for key, n := range counts {
+		category, _, _ := strings.Cut(key, "/")
+		categoryCounts[category] += n
+	}
+
+	subTypeNames := make(map[string]string)
+	for _, c := range model.GetDefaultCategories() {
+		for _, st := range c.SubTypes {
+			subTypeNames[st.Code] = st.Name
+		}
+	}
+
+	type tally struct {
+		raw, max    float64
+		hasRequired bool
+		details     []model.SubTypeScore
+	}
+	tallies := make(map[string]*tally)
+	for _, std := range standards {
+		t := tallies[std.Category]
+		if t == nil {
+			t = &tally{hasRequired: true}
+			tallies[std.Category] = t
+		}
+
+		key := std.Category + "/" + std.SubType
+		count := counts[key]
+		score := std.BaseScore
+		if count < std.MinCount15 && std.MinCount15 > 0 {
+			score = std.BaseScore * float64(count) / float64(std.MinCount15)
+		}
+		if std.Required && count < max(std.MinCount15, 1) {
+			t.hasRequired = false
+		}
+
+		t.raw += score
+		t.max += std.BaseScore
+		t.details = append(t.details, model.SubTypeScore{
+			SubType:  std.SubType,
+			Name:     subTypeNames[std.SubType],
+			Count:    count,
+			Count5:   counts5[key],
+			Count10:  counts10[key],
+			Required: std.MinCount15,
+			Score:    score,
+		})
+	}
+
+	result := &model.AreaEvaluation{CategoryScores: make([]model.CategoryScore, 0, len(categories))}
+	var weighted, totalWeight float64
+	for _, c := range categories {
+		t := tallies[c.Code]
+		if t == nil {
+			continue
+		}
+		score := 0.0
+		if t.max > 0 {
+			score = t.raw / t.max * 100
+		}
+		result.CategoryScores = append(result.CategoryScores, model.CategoryScore{
+			Category:      c.Code,
+			Name:          c.Name,
+			Score:         round2(score),
+			Weight:        c.Weight,
+			WeightedScore: round2(score * c.Weight),
+			POICount:      categoryCounts[c.Code],
+			HasRequired:   t.hasRequired,
+			Details:       t.details,
+		})
+		weighted += score * c.Weight
+		totalWeight += c.Weight
+	}
+
+	if totalWeight > 0 {
+		result.TotalScore = round2(weighted / totalWeight)
+	}
+	result.Grade = model.GetGrade(result.TotalScore)
+	result.Summary = model.GetGradeDescription(result.Grade)
+	result.Suggestions = s.generateSuggestions(result.CategoryScores)
+
+	return result
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// ReachableCounts 起点 15 分钟步行圈内各子类型（category/sub_type）的本地 POI 数量
+// 路网分析、等时圈与 POI 查询同综合评价，不查询高德、不保存评价记录；用于区域样点
+func (s *EvaluationService) ReachableCounts(ctx context.Context, lng, lat, walkSpeed float64) (map[string]int, error) {
+	minutes := evaluationThresholds[len(evaluationThresholds)-1]
+	reach, err := s.isoService.Reach(ctx, lng, lat, walkSpeed, minutes)
+	if err != nil {
+		return nil, err
+	}
+	iso, err := s.isoService.FromReach(ctx, reach, []int{minutes})
+	if err != nil {
+		return nil, err
+	}
+	pois, err := s.poiService.QueryInIsochrones(ctx, lng, lat, walkSpeed, iso.Polygons)
+	if err != nil {
+		return nil, err
+	}
+	return countPOIs(pois).all, nil
+}
+
+// Scorer 以一次加载的评价标准对 POI 数量批量打分
+type Scorer struct {
+	s    *EvaluationService
+	sc   *scoring
+	hash string
+}
+
+// NewScorer 加载当前评价标准与分类
+func (s *EvaluationService) NewScorer(ctx context.Context) (*Scorer, error) {
+	sc, err := s.loadScoring(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Scorer{s: s, sc: sc, hash: standardsHash(sc)}, nil
+}
+
+// Score 按各子类型 15 分钟圈内 POI 数量打分
+func (sc *Scorer) Score(counts map[string]int) *model.AreaEvaluation {
+	return sc.s.scoreCounts(sc.sc, poiCounts{all: counts})
+}
+
+// Required 必备设施标准
+func (sc *Scorer) Required() []model.EvaluationStandard {
+	var required []model.EvaluationStandard
+	for _, std := range sc.sc.standards {
+		if std.Required {
+			required = append(required, std)
+		}
+	}
+	return required
+}
+
+// Categories POI 分类（含权重）
+func (sc *Scorer) Categories() []model.POICategory {
+	return sc.sc.categories
+}
+
+// Hash 评价标准与分类权重的摘要，不同时需重新评分
+func (sc *Scorer) Hash() string {
+	return sc.hash
+}
+
+// standardsHash 计算评价标准（按分类、子类型排序）与分类权重的 SHA-256 摘要
+func standardsHash(sc *scoring) string {
+	standards := slices.Clone(sc.standards)
+	slices.SortFunc(standards, func(a, b model.EvaluationStandard) int {
+		return cmp.Or(cmp.Compare(a.Category, b.Category), cmp.Compare(a.SubType, b.SubType))
+	})
+	weights := make(map[string]float64, len(sc.categories))
+	for _, c := range sc.categories {
+		weights[c.Code] = c.Weight
+	}
+	// map 按键排序编码，结果稳定
+	b, _ := json.Marshal(struct {
+		Standards []model.EvaluationStandard `json:"standards"`
+		Weights   map[string]float64         `json:"weights"`
+	}{standards, weights})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
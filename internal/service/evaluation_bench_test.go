@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/yourname/15min-life-circle/internal/config"
+	"github.com/yourname/15min-life-circle/internal/database"
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/store"
+	"github.com/yourname/15min-life-circle/internal/store/fixture"
+	"github.com/yourname/15min-life-circle/internal/store/postgis"
+)
+
+// BenchmarkEvaluate 在小镇数据（internal/store/fixture）上对比综合评价流程：
+//
+//	legacy    原 /analyze 的步骤序列，每步各自吸附并做路网分析（evaluate_life_circle 的三个圈层、
+//	          CalculateAsGeoJSON 与 Calculate、圈内 POI、可达道路、必备设施最近距离）
+//	pipeline  Evaluate：一次路网分析派生等时圈、POI 圈层、可达道路、评分与最近距离
+//
+// routing/op 为每次评价做路网分析的次数。默认只在内存存储上运行（不含必备设施最近距离）；
+// 设置 SCENARIO_POSTGIS=1 时另在 PostGIS 上运行，写入并在结束后删除小镇数据，请使用测试库：
+//
+//	go test ./internal/service -run '^$' -bench Evaluate
+func BenchmarkEvaluate(b *testing.B) {
+	town := fixture.NewTown()
+	b.Run("memory", func(b *testing.B) {
+		benchmarkEvaluate(b, town, town.Memory().Stores(), nil)
+	})
+
+	if os.Getenv("SCENARIO_POSTGIS") == "" {
+		return
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		b.Fatalf("load config: %v", err)
+	}
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		b.Fatalf("connect database: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := town.LoadPostGIS(ctx, db); err != nil {
+		b.Fatalf("load fixture: %v", err)
+	}
+	defer func() {
+		if err := town.Remove(ctx, db); err != nil {
+			b.Errorf("remove fixture: %v", err)
+		}
+	}()
+	b.Run("postgis", func(b *testing.B) {
+		benchmarkEvaluate(b, town, postgis.New(db), NewFacilityService(db))
+	})
+}
+
+func benchmarkEvaluate(b *testing.B, town *fixture.Town, stores store.Stores, facility *FacilityService) {
+	ctx := context.Background()
+	const speed = 5.0
+
+	var routing atomic.Int64
+	stores.Isochrones = countingIsochrones{stores.Isochrones, &routing}
+	stores.POIs = countingPOIs{stores.POIs, &routing}
+
+	iso := NewIsochroneServiceWithStore(stores.Isochrones)
+	evaluation := NewEvaluationServiceWithStores(iso, NewPOIServiceWithStore(stores.POIs, iso), stores.Standards, nil)
+	evaluation.facilityService = facility
+	standards, err := evaluation.GetStandards(ctx)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	run := func(name string, fn func() error) {
+		b.Run(name, func(b *testing.B) {
+			routing.Store(0)
+			for i := 0; i < b.N; i++ {
+				if err := fn(); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(routing.Load())/float64(b.N), "routing/op")
+		})
+	}
+	run("legacy", func() error {
+		return legacyEvaluate(ctx, stores, facility, standards, town.Lng, town.Lat, speed)
+	})
+	run("pipeline", func() error {
+		_, err := evaluation.Evaluate(ctx, &model.EvaluationRequest{Lng: town.Lng, Lat: town.Lat, WalkSpeed: speed})
+		return err
+	})
+}
+
+// legacyEvaluate 按原 EvaluationService.Evaluate 的顺序调用存储，每次调用各自做一次路网分析
+func legacyEvaluate(ctx context.Context, stores store.Stores, facility *FacilityService, standards []model.EvaluationStandard, lng, lat, speed float64) error {
+	for _, minutes := range evaluationThresholds {
+		if _, err := stores.POIs.CountPOIsByCategory(ctx, lng, lat, minutes, speed); err != nil {
+			return err
+		}
+	}
+	req := &model.IsochroneRequest{Lng: lng, Lat: lat, WalkSpeed: speed}
+	req.Validate()
+	for range 2 {
+		if _, err := stores.Isochrones.Coverage(ctx, lng, lat); err != nil {
+			return err
+		}
+		if _, err := stores.Isochrones.Isochrones(ctx, req); err != nil {
+			return err
+		}
+	}
+	if _, err := stores.POIs.POIsInIsochrone(ctx, lng, lat, 15, speed); err != nil {
+		return err
+	}
+	roads, err := stores.Isochrones.ReachableRoads(ctx, lng, lat, 15, speed)
+	if err != nil {
+		return err
+	}
+	var v any
+	if err := json.Unmarshal([]byte(roads), &v); err != nil {
+		return err
+	}
+	if facility != nil {
+		_, err := facility.Nearest(ctx, &model.NearestFacilityRequest{Lng: lng, Lat: lat, K: 1, WalkSpeed: speed, SkipRoute: true}, standards)
+		return err
+	}
+	return nil
+}
+
+// countingIsochrones 统计做路网分析的调用次数
+type countingIsochrones struct {
+	store.IsochroneStore
+	n *atomic.Int64
+}
+
+func (c countingIsochrones) Isochrones(ctx context.Context, req *model.IsochroneRequest) ([]model.IsochronePolygon, error) {
+	c.n.Add(1)
+	return c.IsochroneStore.Isochrones(ctx, req)
+}
+
+func (c countingIsochrones) Reach(ctx context.Context, lng, lat, walkSpeed float64, maxMinutes int) (*store.ReachSet, error) {
+	c.n.Add(1)
+	return c.IsochroneStore.Reach(ctx, lng, lat, walkSpeed, maxMinutes)
+}
+
+func (c countingIsochrones) ReachableRoads(ctx context.Context, lng, lat float64, minutes int, walkSpeed float64) (string, error) {
+	c.n.Add(1)
+	return c.IsochroneStore.ReachableRoads(ctx, lng, lat, minutes, walkSpeed)
+}
+
+// countingPOIs 统计做路网分析的调用次数
+type countingPOIs struct {
+	store.POIStore
+	n *atomic.Int64
+}
+
+func (c countingPOIs) POIsInIsochrone(ctx context.Context, lng, lat float64, minutes int, walkSpeed float64) ([]model.POI, error) {
+	c.n.Add(1)
+	return c.POIStore.POIsInIsochrone(ctx, lng, lat, minutes, walkSpeed)
+}
+
+func (c countingPOIs) CountPOIsByCategory(ctx context.Context, lng, lat float64, minutes int, walkSpeed float64) ([]model.POIStatistics, error) {
+	c.n.Add(1)
+	return c.POIStore.CountPOIsByCategory(ctx, lng, lat, minutes, walkSpeed)
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/store"
+)
+
+// ErrAnalysisNotFound 评价记录不存在或不属于该用户
+var ErrAnalysisNotFound = store.ErrAnalysisNotFound
+
+// shareIDBytes 分享链接 ID 的随机字节数，base64url 编码后为 12 位
+const shareIDBytes = 9
+
+// newShareID 生成分享链接 ID（72 位随机数，不可猜测）
+func newShareID() (string, error) {
+	var b [shareIDBytes]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b[:]), nil
+}
+
+// validShareID 格式正确的分享链接 ID，其余直接视为不存在
+func validShareID(id string) bool {
+	if len(id) != base64.RawURLEncoding.EncodedLen(shareIDBytes) {
+		return false
+	}
+	_, err := base64.RawURLEncoding.DecodeString(id)
+	return err == nil
+}
+
+// SharedAnalysis 按分享链接读取评价结果、参数与数据版本
+func (s *EvaluationService) SharedAnalysis(ctx context.Context, shareID string) (*model.SharedAnalysis, error) {
+	if s.history == nil || !validShareID(shareID) {
+		return nil, ErrAnalysisNotFound
+	}
+	rec, err := s.history.GetSharedAnalysis(ctx, shareID)
+	if err != nil {
+		return nil, err
+	}
+
+	shared := &model.SharedAnalysis{
+		ID:        rec.ShareID,
+		Permalink: model.Permalink(rec.ShareID),
+		CreatedAt: rec.CreatedAt,
+		Params:    model.EvaluationRequest{Lng: rec.Lng, Lat: rec.Lat, WalkSpeed: rec.WalkSpeed},
+		Result:    rec.Result,
+		DataVersion: model.DataVersion{
+			City:                  rec.City,
+			NetworkVersion:        rec.NetworkVersion,
+			CurrentNetworkVersion: rec.CurrentNetworkVersion,
+			InvalidatedAt:         rec.InvalidatedAt,
+			Stale:                 rec.InvalidatedAt != nil || rec.NetworkVersion != rec.CurrentNetworkVersion,
+		},
+	}
+	if len(rec.Params) > 0 {
+		if err := json.Unmarshal(rec.Params, &shared.Params); err != nil {
+			return nil, fmt.Errorf("decode analysis params: %w", err)
+		}
+	}
+	return shared, nil
+}
+
+// UserAnalyses 用户的评价历史（新到旧，不含完整结果）
+func (s *EvaluationService) UserAnalyses(ctx context.Context, userID int, before *time.Time, limit int) ([]model.AnalysisRecord, error) {
+	if s.history == nil {
+		return []model.AnalysisRecord{}, nil
+	}
+	return s.history.ListAnalyses(ctx, userID, before, limit)
+}
+
+// UserAnalysis 用户的一条评价记录（含完整结果）
+func (s *EvaluationService) UserAnalysis(ctx context.Context, userID int, id string) (*model.AnalysisRecord, error) {
+	if s.history == nil {
+		return nil, ErrAnalysisNotFound
+	}
+	rec, err := s.history.GetAnalysis(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rec.UserID == nil || *rec.UserID != userID {
+		return nil, ErrAnalysisNotFound
+	}
+	return rec, nil
+}
+
+// DeleteUserAnalysis 删除用户的评价记录
+func (s *EvaluationService) DeleteUserAnalysis(ctx context.Context, userID int, id string) error {
+	if s.history == nil {
+		return ErrAnalysisNotFound
+	}
+	return s.history.DeleteAnalysis(ctx, userID, id)
+}
+
+// Rerun 以原请求参数和当前数据重新评价，新结果写入历史
+func (s *EvaluationService) Rerun(ctx context.Context, userID int, id string) (*model.AnalysisRerun, error) {
+	rec, err := s.UserAnalysis(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	req := model.EvaluationRequest{Lng: rec.Lng, Lat: rec.Lat, WalkSpeed: rec.WalkSpeed}
+	if len(rec.Params) > 0 {
+		if err := json.Unmarshal(rec.Params, &req); err != nil {
+			return nil, fmt.Errorf("decode analysis params: %w", err)
+		}
+	}
+	req.UserID = &userID
+	req.PlaceID = rec.PlaceID
+
+	result, err := s.EvaluateAndSave(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+	rec.Result = nil
+	return &model.AnalysisRerun{
+		Previous:   *rec,
+		Result:     result,
+		ScoreDelta: result.TotalScore - rec.TotalScore,
+	}, nil
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/yourname/15min-life-circle/internal/metrics"
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/openinghours"
+	"github.com/yourname/15min-life-circle/internal/tracing"
+)
+
+// hoursCache 同一次评价中相同营业时间字符串只解析一次
+type hoursCache map[string]*openinghours.Schedule
+
+// schedule 解析营业时间，缺失或无法解析时返回 nil
+func (c hoursCache) schedule(hours string) *openinghours.Schedule {
+	if hours == "" {
+		return nil
+	}
+	sch, ok := c[hours]
+	metrics.CacheLookup("opening_hours", ok)
+	if !ok {
+		sch, _ = openinghours.Parse(hours)
+		c[hours] = sch
+	}
+	return sch
+}
+
+// filterOpen 筛选给定时刻营业的 POI，营业时间未知的视为营业
+func (c hoursCache) filterOpen(pois []model.POI, at time.Time) ([]model.POI, model.OpenStatus) {
+	status := model.OpenStatus{At: at}
+	open := make([]model.POI, 0, len(pois))
+	for _, p := range pois {
+		sch := c.schedule(p.OpeningHours)
+		switch {
+		case sch == nil:
+			status.Unknown++
+		case sch.OpenAt(at):
+			status.Open++
+		default:
+			status.Closed++
+			continue
+		}
+		open = append(open, p)
+	}
+	return open, status
+}
+
+// evaluateAt 只计入 at 时刻营业的设施重新评分；pois 为默认评分所用的设施，
+// 展示的 POI（含高德）另按营业时间标记 open
+func (s *EvaluationService) evaluateAt(ctx context.Context, result *model.EvaluationResult, sc *scoring, pois []model.POI, at time.Time) {
+	_, span := tracing.Start(ctx, "EvaluationService.evaluateAt")
+	defer span.End()
+
+	cache := make(hoursCache)
+	open, status := cache.filterOpen(pois, at)
+
+	evaluation := s.score(sc, open)
+	result.TotalScore = evaluation.TotalScore
+	result.Grade = evaluation.Grade
+	result.CategoryScores = evaluation.CategoryScores
+	result.Summary = evaluation.Summary
+	result.Suggestions = evaluation.Suggestions
+	result.OpenStatus = &status
+
+	if result.POIs != nil {
+		for i, f := range result.POIs.Features {
+			if f.Properties == nil {
+				continue
+			}
+			if hours, _ := f.Properties["opening_hours"].(string); hours != "" {
+				if sch := cache.schedule(hours); sch != nil {
+					result.POIs.Features[i].Properties["open"] = sch.OpenAt(at)
+				}
+			}
+		}
+	}
+}
+
+// availabilityProfile 按早间、日间、晚间、夜间各时段的代表时刻评分，pois 同 evaluateAt
+// day 决定星期（周末与工作日营业时间不同）
+func (s *EvaluationService) availabilityProfile(ctx context.Context, sc *scoring, pois []model.POI, day time.Time) []model.AvailabilitySlot {
+	_, span := tracing.Start(ctx, "EvaluationService.availabilityProfile")
+	defer span.End()
+
+	cache := make(hoursCache)
+	day = day.In(model.LocalZone)
+
+	profile := make([]model.AvailabilitySlot, 0, len(model.AvailabilitySlots))
+	for _, slot := range model.AvailabilitySlots {
+		at := time.Date(day.Year(), day.Month(), day.Day(), slot.Hour, slot.Minute, 0, 0, model.LocalZone)
+		open, status := cache.filterOpen(pois, at)
+
+		evaluation := s.score(sc, open)
+		profile = append(profile, model.AvailabilitySlot{
+			Slot:           slot.Slot,
+			Name:           slot.Name,
+			Hours:          slot.Hours,
+			OpenStatus:     status,
+			TotalScore:     evaluation.TotalScore,
+			Grade:          evaluation.Grade,
+			CategoryScores: evaluation.CategoryScores,
+		})
+	}
+	return profile
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/yourname/15min-life-circle/internal/database"
+	"github.com/yourname/15min-life-circle/internal/metrics"
+	"github.com/yourname/15min-life-circle/internal/model"
+)
+
+// FacilityService 最近设施服务
+type FacilityService struct {
+	db *database.DB
+}
+
+// NewFacilityService 创建最近设施服务
+func NewFacilityService(db *database.DB) *FacilityService {
+	return &FacilityService{db: db}
+}
+
+// Nearest 按路网步行时间查找各子类型最近的设施
+// 未指定子类型时使用评价标准中的全部子类型
+func (s *FacilityService) Nearest(ctx context.Context, req *model.NearestFacilityRequest, standards []model.EvaluationStandard) (*model.NearestFacilityResult, error) {
+	defer metrics.Stage("facility", metrics.StageRouting)()
+
+	req.Validate()
+
+	groups := facilityGroups(req.SubTypes, standards)
+	subTypes := make([]string, len(groups))
+	for i, g := range groups {
+		subTypes[i] = g.SubType
+	}
+
+	query := `
+		SELECT
+			sub_type,
+			category,
+			rank,
+			poi_id,
+			COALESCE(name, ''),
+			lng,
+			lat,
+			distance_m,
+			walk_time_min,
+			route
+		FROM nearest_facilities($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	rows, err := s.db.Pool.Query(ctx, query,
+		req.Lng, req.Lat, subTypes, req.K, req.WalkSpeed, req.MaxMinutes, !req.SkipRoute)
+	if err != nil {
+		return nil, fmt.Errorf("nearest facilities: %w", err)
+	}
+	defer rows.Close()
+
+	index := make(map[string]int, len(groups))
+	for i, g := range groups {
+		index[g.SubType] = i
+	}
+	for rows.Next() {
+		var (
+			f        model.NearestFacility
+			subType  string
+			category string
+			route    *string
+		)
+		if err := rows.Scan(
+			&subType,
+			&category,
+			&f.Rank,
+			&f.POIID,
+			&f.Name,
+			&f.Lng,
+			&f.Lat,
+			&f.DistanceM,
+			&f.WalkTimeMin,
+			&route,
+		); err != nil {
+			return nil, fmt.Errorf("scan facility: %w", err)
+		}
+		if route != nil {
+			f.Route = json.RawMessage(*route)
+		}
+		i, ok := index[subType]
+		if !ok {
+			continue
+		}
+		if groups[i].Category == "" {
+			groups[i].Category = category
+		}
+		groups[i].Facilities = append(groups[i].Facilities, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("nearest facilities: %w", err)
+	}
+
+	return &model.NearestFacilityResult{
+		Origin: model.Point{req.Lng, req.Lat},
+		Groups: groups,
+	}, nil
+}
+
+// NearestRequired 各必备设施的最近步行时间（不含路径）
+// 复用综合评价的可达节点（nearest_facilities_from_reach，027 迁移），不再单独做路网分析，
+// 只在 r.MaxMinutes 内查找
+func (s *FacilityService) NearestRequired(ctx context.Context, r *ReachSet, standards []model.EvaluationStandard) ([]model.NearestRequired, error) {
+	defer metrics.Stage("facility", metrics.StageRouting)()
+
+	var required []model.EvaluationStandard
+	for _, std := range standards {
+		if std.Required {
+			required = append(required, std)
+		}
+	}
+	if len(required) == 0 {
+		return nil, nil
+	}
+
+	groups := facilityGroups(nil, required)
+	nearest := make([]model.NearestRequired, len(groups))
+	subTypes := make([]string, len(groups))
+	index := make(map[string]int, len(groups))
+	for i, g := range groups {
+		nearest[i] = model.NearestRequired{Category: g.Category, SubType: g.SubType}
+		subTypes[i] = g.SubType
+		index[g.SubType] = i
+	}
+	if len(r.Nodes) == 0 {
+		return nearest, nil
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT sub_type, category, COALESCE(name, ''), distance_m, walk_time_min
+		FROM nearest_facilities_from_reach($1, $2, $3, $4, $5, $6, $7)`,
+		r.Lng, r.Lat, subTypes, r.WalkSpeed, r.MaxMinutes, r.Nodes, r.Costs)
+	if err != nil {
+		return nil, fmt.Errorf("nearest facilities: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			subType, category string
+			f                 model.NearestRequired
+		)
+		if err := rows.Scan(&subType, &category, &f.Name, &f.DistanceM, &f.WalkTimeMin); err != nil {
+			return nil, fmt.Errorf("scan facility: %w", err)
+		}
+		i, ok := index[subType]
+		if !ok {
+			continue
+		}
+		n := &nearest[i]
+		if n.Category == "" {
+			n.Category = category
+		}
+		n.Found = true
+		n.Name, n.DistanceM, n.WalkTimeMin = f.Name, f.DistanceM, f.WalkTimeMin
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("nearest facilities: %w", err)
+	}
+	return nearest, nil
+}
+
+// facilityGroups 确定查询的子类型及其分类、是否必备
+func facilityGroups(subTypes []string, standards []model.EvaluationStandard) []model.NearestFacilityGroup {
+	byType := make(map[string]model.EvaluationStandard, len(standards))
+	for _, std := range standards {
+		byType[std.SubType] = std
+	}
+
+	var groups []model.NearestFacilityGroup
+	seen := make(map[string]bool)
+	add := func(subType string) {
+		if subType == "" || seen[subType] {
+			return
+		}
+		seen[subType] = true
+		std := byType[subType]
+		groups = append(groups, model.NearestFacilityGroup{
+			Category:   std.Category,
+			SubType:    subType,
+			Required:   std.Required,
+			Facilities: []model.NearestFacility{},
+		})
+	}
+
+	if len(subTypes) > 0 {
+		for _, st := range subTypes {
+			add(st)
+		}
+	} else {
+		for _, std := range standards {
+			add(std.SubType)
+		}
+	}
+	return groups
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/yourname/15min-life-circle/internal/database"
+	"github.com/yourname/15min-life-circle/internal/model"
+)
+
+// ErrInvalidHousehold 家庭等时圈请求参数无效
+var ErrInvalidHousehold = errors.New("invalid household request")
+
+// HouseholdService 多起点家庭等时圈服务
+type HouseholdService struct {
+	db                *database.DB
+	isochroneService  *IsochroneService
+	poiService        *POIService
+	evaluationService *EvaluationService
+}
+
+// NewHouseholdService 创建家庭等时圈服务
+func NewHouseholdService(db *database.DB, isoService *IsochroneService, poiService *POIService, evalService *EvaluationService) *HouseholdService {
+	return &HouseholdService{
+		db:                db,
+		isochroneService:  isoService,
+		poiService:        poiService,
+		evaluationService: evalService,
+	}
+}
+
+// Calculate 计算各起点等时圈及其并集、交集，并评价交集区域
+func (s *HouseholdService) Calculate(ctx context.Context, req *model.HouseholdRequest) (*model.HouseholdResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidHousehold, err)
+	}
+
+	result := &model.HouseholdResult{
+		Origins: make([]model.HouseholdOriginResult, 0, len(req.Origins)),
+		POIs:    model.NewFeatureCollection(),
+	}
+
+	geojsons := make([]string, 0, len(req.Origins))
+	for _, o := range req.Origins {
+		poly, coverage, err := s.isochroneService.Reachable(ctx, o.Lng, o.Lat, o.Minutes, o.Speed, o.Mode)
+		if err != nil {
+			return nil, fmt.Errorf("origin %s: %w", o.Label, err)
+		}
+		b, err := json.Marshal(poly.Geometry)
+		if err != nil {
+			return nil, fmt.Errorf("origin %s: %w", o.Label, err)
+		}
+		geojsons = append(geojsons, string(b))
+
+		result.Origins = append(result.Origins, model.HouseholdOriginResult{
+			Label:    o.Label,
+			Origin:   model.Point{o.Lng, o.Lat},
+			Mode:     o.Mode,
+			Speed:    o.Speed,
+			Minutes:  o.Minutes,
+			Geometry: poly.Geometry,
+			Fallback: poly.Fallback,
+			Coverage: coverage,
+		})
+	}
+
+	var (
+		unionJSON        string
+		intersectionJSON *string
+	)
+	result.Union = &model.HouseholdArea{}
+	result.Intersection = &model.HouseholdArea{}
+	query := `
+		SELECT
+			union_geojson,
+			union_area_m2,
+			union_population,
+			intersection_geojson,
+			intersection_area_m2,
+			intersection_population
+		FROM household_areas($1)
+	`
+	if err := s.db.Pool.QueryRow(ctx, query, geojsons).Scan(
+		&unionJSON,
+		&result.Union.AreaM2,
+		&result.Union.Population,
+		&intersectionJSON,
+		&result.Intersection.AreaM2,
+		&result.Intersection.Population,
+	); err != nil {
+		return nil, fmt.Errorf("household areas: %w", err)
+	}
+
+	var union model.Geometry
+	if err := json.Unmarshal([]byte(unionJSON), &union); err != nil {
+		return nil, fmt.Errorf("parse geojson: %w", err)
+	}
+	result.Union.Geometry = &union
+
+	// 交集为空：没有所有成员都能到达的区域
+	if intersectionJSON == nil {
+		return result, nil
+	}
+
+	var intersection model.Geometry
+	if err := json.Unmarshal([]byte(*intersectionJSON), &intersection); err != nil {
+		return nil, fmt.Errorf("parse geojson: %w", err)
+	}
+	result.Intersection.Geometry = &intersection
+
+	pois, err := s.poiService.QueryInPolygon(ctx, *intersectionJSON)
+	if err != nil {
+		return nil, err
+	}
+	result.POIs = s.poiService.POIsAsGeoJSON(pois)
+
+	evaluation, err := s.evaluationService.EvaluateArea(ctx, pois)
+	if err != nil {
+		return nil, err
+	}
+	result.Evaluation = evaluation
+
+	return result, nil
+}
@@ -2,86 +2,152 @@ package service
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/yourname/15min-life-circle/internal/database"
+	"github.com/yourname/15min-life-circle/internal/metrics"
 	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/store"
+	"github.com/yourname/15min-life-circle/internal/store/postgis"
+	"github.com/yourname/15min-life-circle/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// ErrOutsideCoverage 起点不在已导入城市的数据覆盖范围内
+var ErrOutsideCoverage = errors.New("origin outside coverage area")
+
+// ErrInvalidPolygonMethod 不支持的多边形生成算法
+var ErrInvalidPolygonMethod = errors.New("invalid polygon method")
+
+// ErrInvalidTerrainModel 不支持的地形模型
+var ErrInvalidTerrainModel = errors.New("invalid terrain model")
+
 // IsochroneService 等时圈计算服务
 type IsochroneService struct {
-	db *database.DB
+	// Catchment、Reachable 仍直接查询 PostGIS
+	db    *database.DB
+	store store.IsochroneStore
 }
 
 // NewIsochroneService 创建等时圈服务
 func NewIsochroneService(db *database.DB) *IsochroneService {
-	return &IsochroneService{db: db}
+	return &IsochroneService{db: db, store: postgis.NewIsochroneStore(db)}
+}
+
+// NewIsochroneServiceWithStore 使用指定存储（如 internal/store/memory）创建等时圈服务
+// 不连接数据库，Catchment、Reachable 不可用
+func NewIsochroneServiceWithStore(st store.IsochroneStore) *IsochroneService {
+	return &IsochroneService{store: st}
 }
 
 // Calculate 计算等时圈
-func (s *IsochroneService) Calculate(ctx context.Context, req *model.IsochroneRequest) (*model.IsochroneResult, error) {
+func (s *IsochroneService) Calculate(ctx context.Context, req *model.IsochroneRequest) (_ *model.IsochroneResult, err error) {
+	ctx, span := tracing.Start(ctx, "IsochroneService.Calculate", attribute.Float64("lng", req.Lng), attribute.Float64("lat", req.Lat))
+	defer func() { tracing.End(span, err) }()
+
 	req.Validate()
+	if !model.ValidPolygonMethod(req.PolygonMethod) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidPolygonMethod, req.PolygonMethod)
+	}
+	if req.Terrain && !model.ValidTerrainModel(req.TerrainModel) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTerrainModel, req.TerrainModel)
+	}
+
+	// 默认参数由可达节点生成：节点可达性已预计算（node_reach）时跳过路网分析，
+	// 多边形仍按点击位置生成（起点参与凹壳、起点缓冲、屏障裁剪保留起点所在部分）
+	if reachVariant(req) {
+		return s.calculateFromReach(ctx, req)
+	}
+
+	coverage, err := s.CheckCoverage(ctx, req.Lng, req.Lat)
+	if err != nil {
+		return nil, err
+	}
+	if req.RequireCoverage && (!coverage.InCoverage || !coverage.Snapped) {
+		return nil, fmt.Errorf("%w: snap distance %.0fm", ErrOutsideCoverage, coverage.SnapDistance)
+	}
 
 	result := &model.IsochroneResult{
 		Origin:   model.Point{req.Lng, req.Lat},
 		Polygons: make([]model.IsochronePolygon, 0, len(req.TimeThresholds)),
+		Coverage: coverage,
+		Method:   req.PolygonMethod,
+	}
+	if req.Terrain {
+		result.Terrain = req.TerrainModel
 	}
 
-	// 调用数据库函数计算各时间阈值的等时圈
-	query := `
-		SELECT 
-			minutes,
-			distance_m,
-			geojson
-		FROM calculate_isochrones($1, $2, $3, $4)
-		ORDER BY minutes
-	`
-
-	rows, err := s.db.Pool.Query(ctx, query,
-		req.Lng,
-		req.Lat,
-		req.TimeThresholds,
-		req.WalkSpeed,
-	)
+	// 路网分析与多边形生成由存储完成，整体计入 polygon 阶段
+	polygonDone := metrics.Stage("isochrone", metrics.StagePolygon)
+	polygons, err := s.store.Isochrones(ctx, req)
+	polygonDone()
 	if err != nil {
-		return nil, fmt.Errorf("calculate isochrones: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var (
-			minutes   int
-			distance  float64
-			geojsonStr string
-		)
-		if err := rows.Scan(&minutes, &distance, &geojsonStr); err != nil {
-			return nil, fmt.Errorf("scan row: %w", err)
+	for _, poly := range polygons {
+		result.Polygons = append(result.Polygons, poly)
+		if poly.Fallback != "" {
+			result.Fallback = poly.Fallback
 		}
-
-		// 解析 GeoJSON
-		var geom model.Geometry
-		if err := json.Unmarshal([]byte(geojsonStr), &geom); err != nil {
-			return nil, fmt.Errorf("parse geojson: %w", err)
+		if poly.Clipped {
+			result.Clipped = true
 		}
+	}
 
-		result.Polygons = append(result.Polygons, model.IsochronePolygon{
-			Minutes:  minutes,
-			Distance: distance,
-			Geometry: geom,
-		})
+	return result, nil
+}
+
+// reachVariant 请求是否为默认参数（凹壳、屏障裁剪、不按坡度），可由可达节点生成
+func reachVariant(req *model.IsochroneRequest) bool {
+	return req.PolygonMethod == model.PolygonConcave && req.PolygonParam <= 0 && !req.Terrain && !req.IgnoreBarriers
+}
+
+// calculateFromReach 吸附并做一次路网分析（或读取节点可达性预计算），再按起点生成等时圈
+func (s *IsochroneService) calculateFromReach(ctx context.Context, req *model.IsochroneRequest) (*model.IsochroneResult, error) {
+	thresholds := slices.Sorted(slices.Values(req.TimeThresholds))
+	r, err := s.Reach(ctx, req.Lng, req.Lat, req.WalkSpeed, thresholds[len(thresholds)-1])
+	if err != nil {
+		return nil, err
+	}
+	if req.RequireCoverage && (!r.Coverage.InCoverage || !r.Coverage.Snapped) {
+		return nil, fmt.Errorf("%w: snap distance %.0fm", ErrOutsideCoverage, r.Coverage.SnapDistance)
 	}
 
+	result, err := s.FromReach(ctx, r, thresholds)
+	if err != nil {
+		return nil, err
+	}
+	result.Cached = r.Cached
 	return result, nil
 }
 
+// CheckCoverage 检查起点是否在数据覆盖范围内，并报告吸附距离
+func (s *IsochroneService) CheckCoverage(ctx context.Context, lng, lat float64) (_ *model.CoverageInfo, err error) {
+	ctx, span := tracing.Start(ctx, "IsochroneService.CheckCoverage")
+	defer func() { tracing.End(span, err) }()
+
+	defer metrics.Stage("isochrone", metrics.StageSnap)()
+
+	return s.store.Coverage(ctx, lng, lat)
+}
+
 // CalculateAsGeoJSON 计算等时圈并返回 FeatureCollection
-func (s *IsochroneService) CalculateAsGeoJSON(ctx context.Context, req *model.IsochroneRequest) (*model.FeatureCollection, error) {
+func (s *IsochroneService) CalculateAsGeoJSON(ctx context.Context, req *model.IsochroneRequest) (_ *model.FeatureCollection, err error) {
+	ctx, span := tracing.Start(ctx, "IsochroneService.CalculateAsGeoJSON")
+	defer func() { tracing.End(span, err) }()
+
 	result, err := s.Calculate(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
+	return isochroneFeatures(result), nil
+}
+
+// isochroneFeatures 将等时圈结果转换为 FeatureCollection（含起点）
+func isochroneFeatures(result *model.IsochroneResult) *model.FeatureCollection {
 	fc := model.NewFeatureCollection()
 
 	// 按时间从大到小排序，便于前端渲染（大的在底层）
@@ -94,6 +160,9 @@ func (s *IsochroneService) CalculateAsGeoJSON(ctx context.Context, req *model.Is
 				"minutes":  p.Minutes,
 				"distance": p.Distance,
 				"type":     "isochrone",
+				"fallback": p.Fallback,
+				"method":   p.Method,
+				"clipped":  p.Clipped,
 			},
 		}
 		fc.AddFeature(feature)
@@ -101,22 +170,23 @@ func (s *IsochroneService) CalculateAsGeoJSON(ctx context.Context, req *model.Is
 
 	// 添加起点
 	originFeature := model.NewPointFeature(result.Origin.Lng(), result.Origin.Lat(), map[string]interface{}{
-		"type": "origin",
+		"type":     "origin",
+		"coverage": result.Coverage,
+		"fallback": result.Fallback,
+		"method":   result.Method,
+		"terrain":  result.Terrain,
 	})
 	fc.AddFeature(originFeature)
 
-	return fc, nil
+	return fc
 }
 
 // GetReachableRoads 获取可达道路网络
-func (s *IsochroneService) GetReachableRoads(ctx context.Context, lng, lat float64, minutes int, walkSpeed float64) (string, error) {
-	query := `SELECT road_geojson FROM get_reachable_roads($1, $2, $3, $4)`
-	
-	var geojson string
-	err := s.db.Pool.QueryRow(ctx, query, lng, lat, minutes, walkSpeed).Scan(&geojson)
-	if err != nil {
-		return "", fmt.Errorf("get reachable roads: %w", err)
-	}
-	
-	return geojson, nil
+func (s *IsochroneService) GetReachableRoads(ctx context.Context, lng, lat float64, minutes int, walkSpeed float64) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "IsochroneService.GetReachableRoads", attribute.Int("minutes", minutes))
+	defer func() { tracing.End(span, err) }()
+
+	defer metrics.Stage("isochrone", metrics.StageRoads)()
+
+	return s.store.ReachableRoads(ctx, lng, lat, minutes, walkSpeed)
 }
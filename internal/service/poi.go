@@ -2,93 +2,63 @@ package service
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/yourname/15min-life-circle/internal/database"
+	"github.com/yourname/15min-life-circle/internal/metrics"
 	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/store"
+	"github.com/yourname/15min-life-circle/internal/store/postgis"
+	"github.com/yourname/15min-life-circle/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // POIService POI 服务
 type POIService struct {
-	db *database.DB
+	store store.POIStore
+	// 搜索 minutes 过滤时做路网分析
+	isoService *IsochroneService
 }
 
 // NewPOIService 创建 POI 服务
 func NewPOIService(db *database.DB) *POIService {
-	return &POIService{db: db}
+	return &POIService{store: postgis.NewPOIStore(db), isoService: NewIsochroneService(db)}
+}
+
+// NewPOIServiceWithStore 使用指定存储（如 internal/store/memory）创建 POI 服务
+func NewPOIServiceWithStore(st store.POIStore, isoService *IsochroneService) *POIService {
+	return &POIService{store: st, isoService: isoService}
 }
 
 // QueryInIsochrone 查询等时圈内的 POI
-func (s *POIService) QueryInIsochrone(ctx context.Context, lng, lat float64, minutes int, walkSpeed float64) ([]model.POI, error) {
-	query := `
-		SELECT 
-			id,
-			COALESCE(name, '') AS name,
-			category,
-			sub_type,
-			lng,
-			lat,
-			distance_m,
-			walk_time_min
-		FROM query_pois_in_isochrone($1, $2, $3, $4, NULL)
-	`
-
-	rows, err := s.db.Pool.Query(ctx, query, lng, lat, minutes, walkSpeed)
-	if err != nil {
-		return nil, fmt.Errorf("query pois: %w", err)
-	}
-	defer rows.Close()
-
-	var pois []model.POI
-	for rows.Next() {
-		var poi model.POI
-		var distanceM, walkTimeMin float64
-		if err := rows.Scan(
-			&poi.ID,
-			&poi.Name,
-			&poi.Category,
-			&poi.SubType,
-			&poi.Lng,
-			&poi.Lat,
-			&distanceM,
-			&walkTimeMin,
-		); err != nil {
-			return nil, fmt.Errorf("scan poi: %w", err)
-		}
-		pois = append(pois, poi)
-	}
+func (s *POIService) QueryInIsochrone(ctx context.Context, lng, lat float64, minutes int, walkSpeed float64) (_ []model.POI, err error) {
+	ctx, span := tracing.Start(ctx, "POIService.QueryInIsochrone", attribute.Int("minutes", minutes))
+	defer func() { tracing.End(span, err) }()
+
+	defer metrics.Stage("poi", metrics.StagePOIQuery)()
 
-	return pois, nil
+	return s.store.POIsInIsochrone(ctx, lng, lat, minutes, walkSpeed)
 }
 
-// CountByCategory 统计各分类的 POI 数量
-func (s *POIService) CountByCategory(ctx context.Context, lng, lat float64, minutes int, walkSpeed float64) ([]model.POIStatistics, error) {
-	query := `
-		SELECT 
-			category,
-			sub_type,
-			poi_count
-		FROM count_pois_in_isochrone($1, $2, $3, $4)
-	`
-
-	rows, err := s.db.Pool.Query(ctx, query, lng, lat, minutes, walkSpeed)
-	if err != nil {
-		return nil, fmt.Errorf("count pois: %w", err)
-	}
-	defer rows.Close()
-
-	var stats []model.POIStatistics
-	for rows.Next() {
-		var stat model.POIStatistics
-		var count int64
-		if err := rows.Scan(&stat.Category, &stat.SubType, &count); err != nil {
-			return nil, fmt.Errorf("scan stat: %w", err)
-		}
-		stat.Count = int(count)
-		stats = append(stats, stat)
+// QueryInIsochrones 查询最大等时圈内的 POI，并标记各 POI 所在的最小圈层（WithinMinutes）
+// 等时圈已由调用方计算，不再重复路网分析
+func (s *POIService) QueryInIsochrones(ctx context.Context, lng, lat, walkSpeed float64, polygons []model.IsochronePolygon) (_ []model.POI, err error) {
+	ctx, span := tracing.Start(ctx, "POIService.QueryInIsochrones", attribute.Int("polygons", len(polygons)))
+	defer func() { tracing.End(span, err) }()
+
+	defer metrics.Stage("poi", metrics.StagePOIQuery)()
+
+	if len(polygons) == 0 {
+		return nil, nil
 	}
+	return s.store.POIsInIsochrones(ctx, lng, lat, walkSpeed, polygons)
+}
 
-	return stats, nil
+// CountByCategory 统计各分类的 POI 数量
+func (s *POIService) CountByCategory(ctx context.Context, lng, lat float64, minutes int, walkSpeed float64) (_ []model.POIStatistics, err error) {
+	ctx, span := tracing.Start(ctx, "POIService.CountByCategory")
+	defer func() { tracing.End(span, err) }()
+
+	return s.store.CountPOIsByCategory(ctx, lng, lat, minutes, walkSpeed)
 }
 
 // POIsAsGeoJSON 将 POI 转换为 GeoJSON
@@ -96,14 +66,21 @@ func (s *POIService) POIsAsGeoJSON(pois []model.POI) *model.FeatureCollection {
 	fc := model.NewFeatureCollection()
 
 	for _, poi := range pois {
-		feature := model.NewPointFeature(poi.Lng, poi.Lat, map[string]interface{}{
+		props := map[string]interface{}{
 			"id":       poi.ID,
 			"name":     poi.Name,
 			"category": poi.Category,
 			"sub_type": poi.SubType,
 			"type":     "poi",
 			"source":   poi.Source,
-		})
+		}
+		if poi.OpeningHours != "" {
+			props["opening_hours"] = poi.OpeningHours
+		}
+		if poi.WithinMinutes > 0 {
+			props["within_minutes"] = poi.WithinMinutes
+		}
+		feature := model.NewPointFeature(poi.Lng, poi.Lat, props)
 		fc.AddFeature(feature)
 	}
 
@@ -112,50 +89,31 @@ func (s *POIService) POIsAsGeoJSON(pois []model.POI) *model.FeatureCollection {
 
 // GetCategories 获取所有 POI 分类
 func (s *POIService) GetCategories(ctx context.Context) ([]model.POICategory, error) {
-	query := `
-		SELECT 
-			c.code,
-			c.name,
-			COALESCE(c.description, '') AS description,
-			c.weight,
-			COALESCE(
-				JSON_AGG(
-					JSON_BUILD_OBJECT(
-						'code', st.code,
-						'name', st.name,
-						'osm_tag', COALESCE(st.osm_tags[1], '')
-					) ORDER BY st.sort_order
-				) FILTER (WHERE st.code IS NOT NULL),
-				'[]'::json
-			) AS sub_types
-		FROM poi_category c
-		LEFT JOIN poi_sub_type st ON st.category_code = c.code
-		GROUP BY c.code, c.name, c.description, c.weight, c.sort_order
-		ORDER BY c.sort_order
-	`
-
-	rows, err := s.db.Pool.Query(ctx, query)
-	if err != nil {
-		// 如果表不存在，返回默认分类
+	categories, err := s.store.Categories(ctx)
+	// 如果表不存在或为空，返回默认分类
+	if err != nil || len(categories) == 0 {
 		return model.GetDefaultCategories(), nil
 	}
-	defer rows.Close()
-
-	var categories []model.POICategory
-	for rows.Next() {
-		var cat model.POICategory
-		var subTypesJSON []byte
-		if err := rows.Scan(&cat.Code, &cat.Name, &cat.Description, &cat.Weight, &subTypesJSON); err != nil {
-			return nil, fmt.Errorf("scan category: %w", err)
-		}
-		// 解析子类型
-		// 这里简化处理，实际项目中应该解析 JSON
-		categories = append(categories, cat)
-	}
+	return categories, nil
+}
 
-	if len(categories) == 0 {
-		return model.GetDefaultCategories(), nil
-	}
+// QueryInPolygon 查询 GeoJSON 多边形内的全部 POI
+func (s *POIService) QueryInPolygon(ctx context.Context, geojson string) (_ []model.POI, err error) {
+	ctx, span := tracing.Start(ctx, "POIService.QueryInPolygon")
+	defer func() { tracing.End(span, err) }()
 
-	return categories, nil
+	defer metrics.Stage("poi", metrics.StagePOIQuery)()
+
+	return s.store.POIsInPolygon(ctx, geojson)
+}
+
+// FilterInPolygon 保留位于 GeoJSON 多边形内的 POI
+func (s *POIService) FilterInPolygon(ctx context.Context, pois []model.POI, geojson string) (_ []model.POI, err error) {
+	ctx, span := tracing.Start(ctx, "POIService.FilterInPolygon", attribute.Int("pois", len(pois)))
+	defer func() { tracing.End(span, err) }()
+
+	if geojson == "" || len(pois) == 0 {
+		return pois, nil
+	}
+	return s.store.FilterInPolygon(ctx, pois, geojson)
 }
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/store"
+)
+
+var (
+	// ErrPOINotFound POI 不存在或已删除
+	ErrPOINotFound = store.ErrPOINotFound
+	// ErrUnknownSubType 子类型不在 poi_sub_type 中
+	ErrUnknownSubType = store.ErrUnknownSubType
+)
+
+// Get 获取单个 POI（含已删除）
+func (s *POIService) Get(ctx context.Context, id int64) (*model.POIRecord, error) {
+	return s.store.GetPOI(ctx, id)
+}
+
+// Create 人工新增 POI
+func (s *POIService) Create(ctx context.Context, actor string, req *model.POICreateRequest) (*model.POIEditResult, error) {
+	return s.store.CreatePOI(ctx, actor, req)
+}
+
+// Update 人工修改 POI 名称、位置、地址或标签
+// 修改后 data_source 置为 manual，重新导入时不会被覆盖
+func (s *POIService) Update(ctx context.Context, actor string, id int64, req *model.POIUpdateRequest) (*model.POIEditResult, error) {
+	return s.store.UpdatePOI(ctx, actor, id, req)
+}
+
+// Reclassify 人工修改 POI 子类型（分类随之变化）
+func (s *POIService) Reclassify(ctx context.Context, actor string, id int64, req *model.POIReclassifyRequest) (*model.POIEditResult, error) {
+	return s.store.ReclassifyPOI(ctx, actor, id, req)
+}
+
+// Delete 软删除 POI
+// 记录保留并标记为 manual，避免重新导入时恢复
+func (s *POIService) Delete(ctx context.Context, actor string, id int64, comment string) (*model.POIEditResult, error) {
+	return s.store.DeletePOI(ctx, actor, id, comment)
+}
+
+// History 获取 POI 审计记录（新到旧）
+func (s *POIService) History(ctx context.Context, id int64) ([]model.POIAudit, error) {
+	return s.store.POIHistory(ctx, id)
+}
@@ -1,31 +1,49 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/yourname/15min-life-circle/internal/config"
+	"github.com/yourname/15min-life-circle/internal/metrics"
 	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ErrAmapQuotaExhausted 本进程当日高德调用次数已达 amap.daily_quota
+var ErrAmapQuotaExhausted = errors.New("amap daily quota exhausted")
+
 // AmapPOIService 高德地图POI服务
 type AmapPOIService struct {
 	apiKey  string
 	enabled bool
 	client  *http.Client
+
+	// 每日调用上限，0 表示不限制
+	dailyQuota int
+	mu         sync.Mutex
+	quotaDay   string
+	quotaUsed  int
 }
 
 // AmapPOIResponse 高德API响应
 type AmapPOIResponse struct {
-	Status     string     `json:"status"`
-	Info       string     `json:"info"`
-	Count      string     `json:"count"`
-	POIs       []AmapPOI  `json:"pois"`
+	Status   string    `json:"status"`
+	Info     string    `json:"info"`
+	Infocode string    `json:"infocode"`
+	Count    string    `json:"count"`
+	POIs     []AmapPOI `json:"pois"`
 }
 
 // FlexibleString 处理高德API返回的灵活类型字段（可能是字符串或数组）
@@ -38,7 +56,7 @@ func (f *FlexibleString) UnmarshalJSON(data []byte) error {
 		*f = FlexibleString(s)
 		return nil
 	}
-	
+
 	// 如果失败，尝试解析为字符串数组
 	var arr []string
 	if err := json.Unmarshal(data, &arr); err == nil {
@@ -49,7 +67,7 @@ func (f *FlexibleString) UnmarshalJSON(data []byte) error {
 		}
 		return nil
 	}
-	
+
 	// 都失败则设为空
 	*f = ""
 	return nil
@@ -64,6 +82,32 @@ type AmapPOI struct {
 	Address  FlexibleString `json:"address"`
 	Location string         `json:"location"` // 经度,纬度
 	Distance string         `json:"distance"`
+	// 扩展信息（extensions=all 时返回）
+	BizExt AmapBizExt `json:"biz_ext"`
+}
+
+// AmapBizExt 高德POI扩展信息
+type AmapBizExt struct {
+	// 营业时间，如 "08:00-22:00"
+	OpenTime2 FlexibleString `json:"opentime2"`
+	// 营业时间描述，如 "周一至周日 08:00-22:00"
+	OpenTime FlexibleString `json:"open_time"`
+}
+
+// UnmarshalJSON 高德在无扩展信息时返回空数组 []，其他无法解析的内容返回错误
+func (b *AmapBizExt) UnmarshalJSON(data []byte) error {
+	var arr []json.RawMessage
+	if json.Unmarshal(data, &arr) == nil && len(arr) == 0 {
+		*b = AmapBizExt{}
+		return nil
+	}
+	type plain AmapBizExt
+	var v plain
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("decode biz_ext: %w", err)
+	}
+	*b = AmapBizExt(v)
+	return nil
 }
 
 // NewAmapPOIService 创建高德POI服务
@@ -72,9 +116,27 @@ func NewAmapPOIService(cfg config.AmapConfig) *AmapPOIService {
 		apiKey:  cfg.Key,
 		enabled: cfg.Enabled,
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout: cfg.Timeout.D(),
 		},
+		dailyQuota: cfg.DailyQuota,
+	}
+}
+
+// reserve 占用一次当日调用额度，额度用尽时返回 false
+func (s *AmapPOIService) reserve() bool {
+	if s.dailyQuota <= 0 {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if day := time.Now().Format(time.DateOnly); day != s.quotaDay {
+		s.quotaDay, s.quotaUsed = day, 0
+	}
+	if s.quotaUsed >= s.dailyQuota {
+		return false
 	}
+	s.quotaUsed++
+	return true
 }
 
 // IsEnabled 是否启用
@@ -89,76 +151,76 @@ var amapTypeMapping = map[string]struct {
 	SubType  string
 }{
 	// 医疗卫生
-	"090100": {Category: "medical", SubType: "community_health"},  // 综合医院
-	"090200": {Category: "medical", SubType: "community_health"},  // 专科医院
-	"090300": {Category: "medical", SubType: "community_health"},  // 诊所
-	"090400": {Category: "medical", SubType: "community_health"},  // 卫生站
-	"090500": {Category: "medical", SubType: "pharmacy"},          // 药房药店
-	"090600": {Category: "medical", SubType: "community_health"},  // 医疗保健服务
-	"090700": {Category: "medical", SubType: "hospital"},          // 疾控中心
-	
+	"090100": {Category: "medical", SubType: "community_health"}, // 综合医院
+	"090200": {Category: "medical", SubType: "community_health"}, // 专科医院
+	"090300": {Category: "medical", SubType: "community_health"}, // 诊所
+	"090400": {Category: "medical", SubType: "community_health"}, // 卫生站
+	"090500": {Category: "medical", SubType: "pharmacy"},         // 药房药店
+	"090600": {Category: "medical", SubType: "community_health"}, // 医疗保健服务
+	"090700": {Category: "medical", SubType: "hospital"},         // 疾控中心
+
 	// 教育
-	"141200": {Category: "education", SubType: "kindergarten"},    // 幼儿园
-	"141201": {Category: "education", SubType: "kindergarten"},    // 幼儿园
-	"141202": {Category: "child", SubType: "nursery"},             // 亲子园
-	"141300": {Category: "education", SubType: "primary"},         // 小学
-	"141301": {Category: "education", SubType: "primary"},         // 小学
-	"141400": {Category: "education", SubType: "secondary"},       // 中学
-	"141401": {Category: "education", SubType: "secondary"},       // 初级中学
-	"141402": {Category: "education", SubType: "secondary"},       // 高级中学
-	
+	"141200": {Category: "education", SubType: "kindergarten"}, // 幼儿园
+	"141201": {Category: "education", SubType: "kindergarten"}, // 幼儿园
+	"141202": {Category: "child", SubType: "nursery"},          // 亲子园
+	"141300": {Category: "education", SubType: "primary"},      // 小学
+	"141301": {Category: "education", SubType: "primary"},      // 小学
+	"141400": {Category: "education", SubType: "secondary"},    // 中学
+	"141401": {Category: "education", SubType: "secondary"},    // 初级中学
+	"141402": {Category: "education", SubType: "secondary"},    // 高级中学
+
 	// 养老服务
-	"100105": {Category: "elderly", SubType: "elderly_center"},    // 福利院
-	"100106": {Category: "elderly", SubType: "elderly_center"},    // 敬老院
-	"100107": {Category: "elderly", SubType: "elderly_center"},    // 养老院
-	
+	"100105": {Category: "elderly", SubType: "elderly_center"}, // 福利院
+	"100106": {Category: "elderly", SubType: "elderly_center"}, // 敬老院
+	"100107": {Category: "elderly", SubType: "elderly_center"}, // 养老院
+
 	// 商业服务
-	"060100": {Category: "commerce", SubType: "supermarket"},      // 购物中心
-	"060400": {Category: "commerce", SubType: "supermarket"},      // 超级市场
-	"060401": {Category: "commerce", SubType: "supermarket"},      // 超市
-	"060402": {Category: "commerce", SubType: "convenience"},      // 便利店
-	"060500": {Category: "commerce", SubType: "market"},           // 农副产品市场
-	"060501": {Category: "commerce", SubType: "market"},           // 菜市场
-	"050100": {Category: "commerce", SubType: "restaurant"},       // 中餐厅
-	"050200": {Category: "commerce", SubType: "restaurant"},       // 外国餐厅
-	"050300": {Category: "commerce", SubType: "restaurant"},       // 快餐厅
-	
+	"060100": {Category: "commerce", SubType: "supermarket"}, // 购物中心
+	"060400": {Category: "commerce", SubType: "supermarket"}, // 超级市场
+	"060401": {Category: "commerce", SubType: "supermarket"}, // 超市
+	"060402": {Category: "commerce", SubType: "convenience"}, // 便利店
+	"060500": {Category: "commerce", SubType: "market"},      // 农副产品市场
+	"060501": {Category: "commerce", SubType: "market"},      // 菜市场
+	"050100": {Category: "commerce", SubType: "restaurant"},  // 中餐厅
+	"050200": {Category: "commerce", SubType: "restaurant"},  // 外国餐厅
+	"050300": {Category: "commerce", SubType: "restaurant"},  // 快餐厅
+
 	// 文化体育
-	"080100": {Category: "culture", SubType: "culture_center"},    // 博物馆
-	"080300": {Category: "culture", SubType: "library"},           // 图书馆
-	"080400": {Category: "culture", SubType: "culture_center"},    // 科技馆
-	"080500": {Category: "culture", SubType: "culture_center"},    // 文化宫
-	"080600": {Category: "culture", SubType: "culture_center"},    // 美术馆
-	"080700": {Category: "culture", SubType: "culture_center"},    // 展览馆
-	"110000": {Category: "culture", SubType: "park"},              // 公园
-	"110100": {Category: "culture", SubType: "park"},              // 公园
-	"110101": {Category: "culture", SubType: "park"},              // 公园
-	"110102": {Category: "culture", SubType: "park"},              // 动物园
-	"110103": {Category: "culture", SubType: "park"},              // 植物园
-	"080101": {Category: "culture", SubType: "sports_field"},      // 体育馆
-	"080102": {Category: "culture", SubType: "sports_field"},      // 体育场
-	"080103": {Category: "culture", SubType: "sports_field"},      // 运动场
-	"080104": {Category: "culture", SubType: "sports_field"},      // 健身中心
-	
+	"080100": {Category: "culture", SubType: "culture_center"}, // 博物馆
+	"080300": {Category: "culture", SubType: "library"},        // 图书馆
+	"080400": {Category: "culture", SubType: "culture_center"}, // 科技馆
+	"080500": {Category: "culture", SubType: "culture_center"}, // 文化宫
+	"080600": {Category: "culture", SubType: "culture_center"}, // 美术馆
+	"080700": {Category: "culture", SubType: "culture_center"}, // 展览馆
+	"110000": {Category: "culture", SubType: "park"},           // 公园
+	"110100": {Category: "culture", SubType: "park"},           // 公园
+	"110101": {Category: "culture", SubType: "park"},           // 公园
+	"110102": {Category: "culture", SubType: "park"},           // 动物园
+	"110103": {Category: "culture", SubType: "park"},           // 植物园
+	"080101": {Category: "culture", SubType: "sports_field"},   // 体育馆
+	"080102": {Category: "culture", SubType: "sports_field"},   // 体育场
+	"080103": {Category: "culture", SubType: "sports_field"},   // 运动场
+	"080104": {Category: "culture", SubType: "sports_field"},   // 健身中心
+
 	// 公共管理
-	"130100": {Category: "public", SubType: "community_service"},  // 政府机构
-	"130105": {Category: "public", SubType: "community_service"},  // 社区服务中心
-	"130300": {Category: "public", SubType: "police"},             // 公安局
-	"130301": {Category: "public", SubType: "police"},             // 派出所
-	"160100": {Category: "public", SubType: "bank"},               // 银行
-	"160300": {Category: "public", SubType: "post"},               // 邮局
-	
+	"130100": {Category: "public", SubType: "community_service"}, // 政府机构
+	"130105": {Category: "public", SubType: "community_service"}, // 社区服务中心
+	"130300": {Category: "public", SubType: "police"},            // 公安局
+	"130301": {Category: "public", SubType: "police"},            // 派出所
+	"160100": {Category: "public", SubType: "bank"},              // 银行
+	"160300": {Category: "public", SubType: "post"},              // 邮局
+
 	// 交通设施
-	"150200": {Category: "transport", SubType: "bus_stop"},        // 公交车站
-	"150201": {Category: "transport", SubType: "bus_stop"},        // 公交站
-	"150500": {Category: "transport", SubType: "metro"},           // 地铁站
-	"150501": {Category: "transport", SubType: "metro"},           // 轨道交通站
-	"150900": {Category: "transport", SubType: "parking"},         // 停车场
-	"150904": {Category: "transport", SubType: "bike_parking"},    // 停车场入口
-	
+	"150200": {Category: "transport", SubType: "bus_stop"},     // 公交车站
+	"150201": {Category: "transport", SubType: "bus_stop"},     // 公交站
+	"150500": {Category: "transport", SubType: "metro"},        // 地铁站
+	"150501": {Category: "transport", SubType: "metro"},        // 轨道交通站
+	"150900": {Category: "transport", SubType: "parking"},      // 停车场
+	"150904": {Category: "transport", SubType: "bike_parking"}, // 停车场入口
+
 	// 托幼托育
-	"141203": {Category: "child", SubType: "nursery"},             // 托儿所
-	"141204": {Category: "child", SubType: "nursery"},             // 早教中心
+	"141203": {Category: "child", SubType: "nursery"}, // 托儿所
+	"141204": {Category: "child", SubType: "nursery"}, // 早教中心
 }
 
 // 高德POI类型代码（用于搜索）
@@ -182,17 +244,17 @@ var amapSearchTypes = []string{
 }
 
 // SearchNearby 周边搜索POI
-func (s *AmapPOIService) SearchNearby(lng, lat float64, radius int) ([]model.POI, error) {
+func (s *AmapPOIService) SearchNearby(ctx context.Context, lng, lat float64, radius int) ([]model.POI, error) {
 	if !s.IsEnabled() {
 		return nil, nil
 	}
 
 	var allPOIs []model.POI
-	
+
 	// 搜索多个类型
 	types := "090000|141200|141300|141400|100100|060400|050000|080000|110000|130000|150200|150500|160100"
-	
-	pois, err := s.searchByType(lng, lat, radius, types)
+
+	pois, err := s.searchByType(ctx, lng, lat, radius, types)
 	if err != nil {
 		return nil, err
 	}
@@ -202,40 +264,67 @@ func (s *AmapPOIService) SearchNearby(lng, lat float64, radius int) ([]model.POI
 }
 
 // searchByType 按类型搜索
-func (s *AmapPOIService) searchByType(lng, lat float64, radius int, types string) ([]model.POI, error) {
+func (s *AmapPOIService) searchByType(ctx context.Context, lng, lat float64, radius int, types string) (_ []model.POI, err error) {
+	ctx, span := tracing.StartKind(ctx, trace.SpanKindClient, "amap place/around",
+		attribute.String("amap.types", types),
+		attribute.Int("amap.radius", radius),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	if !s.reserve() {
+		return nil, ErrAmapQuotaExhausted
+	}
+
 	baseURL := "https://restapi.amap.com/v3/place/around"
-	
+
 	params := url.Values{}
 	params.Set("key", s.apiKey)
 	params.Set("location", fmt.Sprintf("%.6f,%.6f", lng, lat))
 	params.Set("radius", strconv.Itoa(radius))
 	params.Set("types", types)
-	params.Set("offset", "50")  // 每页50条
+	params.Set("offset", "50") // 每页50条
 	params.Set("page", "1")
-	params.Set("extensions", "base")
-	
+	params.Set("extensions", "all") // 含 biz_ext 营业时间
+
 	reqURL := baseURL + "?" + params.Encode()
-	
-	resp, err := s.client.Get(reqURL)
+
+	start := time.Now()
+	outcome := metrics.OutcomeError
+	defer func() {
+		metrics.ObserveExternal("amap", outcome, time.Since(start))
+		span.SetAttributes(attribute.String("amap.outcome", outcome))
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build amap request: %w", err)
+	}
+	resp, err := s.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("amap API request failed: %w", err)
 	}
 	defer resp.Body.Close()
-	
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("read response failed: %w", err)
 	}
-	
+
 	var result AmapPOIResponse
 	if err := json.Unmarshal(body, &result); err != nil {
 		return nil, fmt.Errorf("parse response failed: %w", err)
 	}
-	
+
+	span.SetAttributes(attribute.String("amap.infocode", result.Infocode))
 	if result.Status != "1" {
+		if amapQuotaExceeded(result) {
+			outcome = metrics.OutcomeQuota
+		}
 		return nil, fmt.Errorf("amap API error: %s", result.Info)
 	}
-	
+	outcome = metrics.OutcomeOK
+	span.SetAttributes(attribute.Int("amap.pois", len(result.POIs)))
+
 	// 转换为内部POI格式
 	var pois []model.POI
 	for _, ap := range result.POIs {
@@ -244,10 +333,20 @@ func (s *AmapPOIService) searchByType(lng, lat float64, radius int, types string
 			pois = append(pois, *poi)
 		}
 	}
-	
+
 	return pois, nil
 }
 
+// amapQuotaExceeded 判断是否为配额耗尽或调用过于频繁
+// 10003 日访问量超限，10004 单位时间访问过于频繁，10044 账号日调用量超限
+func amapQuotaExceeded(r AmapPOIResponse) bool {
+	switch r.Infocode {
+	case "10003", "10004", "10044":
+		return true
+	}
+	return strings.Contains(r.Info, "OVER_LIMIT") || strings.Contains(r.Info, "TOO_FREQUENT")
+}
+
 // convertToPOI 转换高德POI为内部格式
 func (s *AmapPOIService) convertToPOI(ap AmapPOI) *model.POI {
 	// 解析坐标
@@ -255,13 +354,13 @@ func (s *AmapPOIService) convertToPOI(ap AmapPOI) *model.POI {
 	if _, err := fmt.Sscanf(ap.Location, "%f,%f", &lng, &lat); err != nil {
 		return nil
 	}
-	
+
 	// 获取类型映射（使用前6位类型码）
 	typeCode := ap.TypeCode
 	if len(typeCode) > 6 {
 		typeCode = typeCode[:6]
 	}
-	
+
 	mapping, ok := amapTypeMapping[typeCode]
 	if !ok {
 		// 尝试使用前4位
@@ -276,7 +375,7 @@ func (s *AmapPOIService) convertToPOI(ap AmapPOI) *model.POI {
 			}{Category: "public", SubType: "community_service"}
 		}
 	}
-	
+
 	return &model.POI{
 		Name:     ap.Name,
 		Category: mapping.Category,
@@ -284,5 +383,16 @@ func (s *AmapPOIService) convertToPOI(ap AmapPOI) *model.POI {
 		Lng:      lng,
 		Lat:      lat,
 		Source:   "amap",
+		// 优先使用描述更完整的 open_time
+		OpeningHours: firstNonEmpty(string(ap.BizExt.OpenTime), string(ap.BizExt.OpenTime2)),
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
 	}
+	return ""
 }
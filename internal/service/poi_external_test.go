@@ -0,0 +1,34 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAmapBizExtUnmarshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantErr  bool
+		wantOpen string
+	}{
+		{"object", `{"opentime2":"08:00-22:00","open_time":"周一至周日 08:00-22:00"}`, false, "08:00-22:00"},
+		{"empty array", `[]`, false, ""},
+		{"null", `null`, false, ""},
+		{"array field", `{"opentime2":[]}`, false, ""},
+		{"non-empty array", `[{"opentime2":"08:00-22:00"}]`, true, ""},
+		{"string", `"08:00-22:00"`, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var poi AmapPOI
+			err := json.Unmarshal([]byte(`{"id":"B0","biz_ext":`+tt.data+`}`), &poi)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && string(poi.BizExt.OpenTime2) != tt.wantOpen {
+				t.Errorf("opentime2 = %q, want %q", poi.BizExt.OpenTime2, tt.wantOpen)
+			}
+		})
+	}
+}
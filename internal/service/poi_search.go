@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yourname/15min-life-circle/internal/metrics"
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/store"
+	"github.com/yourname/15min-life-circle/internal/tracing"
+)
+
+// ErrInvalidSearch 搜索参数或游标无效
+var ErrInvalidSearch = store.ErrInvalidSearch
+
+// Search 按空间范围与属性过滤搜索 POI，游标分页
+// 提供参考点时按距离排序并返回 distance_m，否则按 ID 排序。
+// minutes 过滤与综合评价相同：一次路网分析（Reach）生成等时圈，取圈内 POI，
+// 并按路网时间返回 walk_time_min
+func (s *POIService) Search(ctx context.Context, req *model.POISearchRequest) (_ *model.POISearchResult, err error) {
+	ctx, span := tracing.Start(ctx, "POIService.Search")
+	defer func() { tracing.End(span, err) }()
+
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSearch, err)
+	}
+
+	var reach *store.SearchReach
+	if req.Mode() == model.SearchMinutes {
+		r, err := s.isoService.Reach(ctx, *req.Lng, *req.Lat, req.WalkSpeed, req.Minutes)
+		if err != nil {
+			return nil, err
+		}
+		iso, err := s.isoService.FromReach(ctx, r, []int{req.Minutes})
+		if err != nil {
+			return nil, err
+		}
+		if len(iso.Polygons) == 0 {
+			return nil, fmt.Errorf("no %d-minute isochrone", req.Minutes)
+		}
+		reach = &store.SearchReach{Reach: r, Polygon: iso.Polygons[0].Geometry}
+	}
+
+	defer metrics.Stage("poi", metrics.StagePOIQuery)()
+	return s.store.SearchPOIs(ctx, req, reach)
+}
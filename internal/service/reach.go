@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+
+	"github.com/yourname/15min-life-circle/internal/metrics"
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/store"
+	"github.com/yourname/15min-life-circle/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ReachSet 一次路网分析的结果，见 store.ReachSet
+type ReachSet = store.ReachSet
+
+// Reach 吸附起点并做一次路网分析，节点已预计算（node_reach，020 迁移）时直接读取
+// 吸附距离超过 MaxSnapDistanceMeters 时不做路网分析，Nodes 为空
+func (s *IsochroneService) Reach(ctx context.Context, lng, lat, walkSpeed float64, maxMinutes int) (_ *ReachSet, err error) {
+	ctx, span := tracing.Start(ctx, "IsochroneService.Reach", attribute.Int("minutes", maxMinutes))
+	defer func() { tracing.End(span, err) }()
+
+	defer metrics.Stage("isochrone", metrics.StageRouting)()
+
+	r, err := s.store.Reach(ctx, lng, lat, walkSpeed, maxMinutes)
+	if err != nil {
+		return nil, err
+	}
+	if r.Coverage.Snapped {
+		metrics.CacheLookup("node_reach", r.Cached)
+	}
+	span.SetAttributes(attribute.Int("nodes", len(r.Nodes)), attribute.Bool("cached", r.Cached))
+
+	return r, nil
+}
+
+// FromReach 由可达节点生成各时间阈值的等时圈（默认凹壳算法，按屏障裁剪）
+// thresholds 不应超过 r.MaxMinutes
+func (s *IsochroneService) FromReach(ctx context.Context, r *ReachSet, thresholds []int) (_ *model.IsochroneResult, err error) {
+	ctx, span := tracing.Start(ctx, "IsochroneService.FromReach")
+	defer func() { tracing.End(span, err) }()
+
+	defer metrics.Stage("isochrone", metrics.StagePolygon)()
+
+	polygons, err := s.store.IsochronesFromReach(ctx, r, thresholds)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.IsochroneResult{
+		Origin:   model.Point{r.Lng, r.Lat},
+		Polygons: make([]model.IsochronePolygon, 0, len(thresholds)),
+		Coverage: r.Coverage,
+		Method:   model.PolygonConcave,
+	}
+	for _, poly := range polygons {
+		result.Polygons = append(result.Polygons, poly)
+		if poly.Fallback != "" {
+			result.Fallback = poly.Fallback
+		}
+		if poly.Clipped {
+			result.Clipped = true
+		}
+	}
+
+	return result, nil
+}
+
+// RoadsFromReach 由可达节点得到 minutes 内两端均可达的道路（FeatureCollection JSON）
+func (s *IsochroneService) RoadsFromReach(ctx context.Context, r *ReachSet, minutes int) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "IsochroneService.RoadsFromReach", attribute.Int("minutes", minutes))
+	defer func() { tracing.End(span, err) }()
+
+	defer metrics.Stage("isochrone", metrics.StageRoads)()
+
+	return s.store.RoadsFromReach(ctx, r, minutes)
+}
+
+// PrecomputeReach 预计算单个节点的可达性（node_reach），返回可达节点数
+func (s *IsochroneService) PrecomputeReach(ctx context.Context, node int64, walkSpeed float64, maxMinutes int) (int, error) {
+	return s.store.PrecomputeReach(ctx, node, walkSpeed, maxMinutes)
+}
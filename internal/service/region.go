@@ -0,0 +1,617 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yourname/15min-life-circle/internal/database"
+	"github.com/yourname/15min-life-circle/internal/model"
+)
+
+var (
+	// ErrRegionNotFound 区域不存在
+	ErrRegionNotFound = errors.New("region not found")
+	// ErrInvalidRegion 区域导入或查询参数不合法
+	ErrInvalidRegion = errors.New("invalid region request")
+)
+
+// 区域排名可用的排序指标及对应的 region_summary 列
+var regionSortColumns = map[string]string{
+	"mean":                     "s.mean_score",
+	"median":                   "s.median_score",
+	"population_weighted_mean": "s.weighted_mean_score",
+}
+
+// RegionService 行政区划导入、格网采样与评分汇总
+//
+// 每个区域按格网布置样点，样点保存 15 分钟步行圈内各子类型 POI 数量（Measure，开销大，
+// 由 cmd/regions 批量运行）；评分与汇总只依赖这些数量和评价标准（Rescore），
+// 标准变化后重新评分即可。
+type RegionService struct {
+	db         *database.DB
+	evaluation *EvaluationService
+}
+
+// NewRegionService 创建区域服务
+func NewRegionService(db *database.DB, evaluation *EvaluationService) *RegionService {
+	return &RegionService{db: db, evaluation: evaluation}
+}
+
+// Import 导入（更新）城市某一层级的区域边界，按 (city, code) 匹配已有区域；
+// 边界变化的区域删除原有样点。父级按 ParentCode 在同城市已导入的区域中查找，
+// 返回导入数与未找到父级的区域数
+func (s *RegionService) Import(ctx context.Context, city, level, source string, regions []model.RegionImport) (imported, orphans int, err error) {
+	if city == "" {
+		return 0, 0, fmt.Errorf("%w: city is required", ErrInvalidRegion)
+	}
+	if level != model.RegionSubdistrict && level != model.RegionCommunity {
+		return 0, 0, fmt.Errorf("%w: level must be %s or %s", ErrInvalidRegion, model.RegionSubdistrict, model.RegionCommunity)
+	}
+	for i, r := range regions {
+		if r.Code == "" || r.Name == "" || len(r.Geometry) == 0 {
+			return 0, 0, fmt.Errorf("%w: region #%d: code, name and geometry are required", ErrInvalidRegion, i+1)
+		}
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	const geom = `ST_Multi(ST_CollectionExtract(ST_MakeValid(ST_SetSRID(ST_GeomFromGeoJSON($3), 4326)), 3))`
+	for _, r := range regions {
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM region_sample s
+			USING admin_region r
+			WHERE s.region_id = r.id AND r.city = $1 AND r.code = $2
+			  AND NOT ST_Equals(r.geom, `+geom+`)`,
+			city, r.Code, string(r.Geometry)); err != nil {
+			return 0, 0, fmt.Errorf("region %s: clear samples: %w", r.Code, err)
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO admin_region (city, code, name, level, geom, area_m2, source)
+			SELECT $1, $2, $4, $5, g, ST_Area(g::geography), NULLIF($6, '')
+			FROM (SELECT `+geom+` AS g) t
+			ON CONFLICT (city, code) DO UPDATE SET
+				name = EXCLUDED.name,
+				level = EXCLUDED.level,
+				geom = EXCLUDED.geom,
+				area_m2 = EXCLUDED.area_m2,
+				source = EXCLUDED.source,
+				imported_at = NOW()`,
+			city, r.Code, string(r.Geometry), r.Name, level, source); err != nil {
+			return 0, 0, fmt.Errorf("region %s: %w", r.Code, err)
+		}
+
+		tag, err := tx.Exec(ctx, `
+			UPDATE admin_region c SET parent_id = p.id
+			FROM admin_region p
+			WHERE c.city = $1 AND c.code = $2 AND p.city = $1 AND p.code = $3 AND p.id <> c.id`,
+			city, r.Code, r.ParentCode)
+		if err != nil {
+			return 0, 0, fmt.Errorf("region %s: set parent: %w", r.Code, err)
+		}
+		if r.ParentCode != "" && tag.RowsAffected() == 0 {
+			orphans++
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, 0, fmt.Errorf("commit: %w", err)
+	}
+	return len(regions), orphans, nil
+}
+
+// Sample 为城市中尚无样点的区域（all 时为全部区域）按 spacing 米的格网布置样点
+// 样点取格网单元与区域交集的内点，代表交集面积及其中的人口；
+// 不足 10% 单元面积的边角不单独布点，区域内没有样点时以区域内点作为唯一样点。
+// 返回新增样点数
+func (s *RegionService) Sample(ctx context.Context, city string, spacing float64, all bool) (int64, error) {
+	if spacing < 50 {
+		return 0, fmt.Errorf("%w: spacing must be at least 50 m", ErrInvalidRegion)
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if all {
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM region_sample s USING admin_region r
+			WHERE s.region_id = r.id AND r.city = $1`, city); err != nil {
+			return 0, fmt.Errorf("clear samples: %w", err)
+		}
+	}
+
+	// 按纬度换算格网的经纬度步长
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO region_sample (region_id, geom, area_m2, population)
+		SELECT r.id, ST_PointOnSurface(p.piece), p.area, population_within(p.piece)
+		FROM admin_region r
+		CROSS JOIN LATERAL (
+			SELECT $2 / 111320.0 AS dy,
+			       $2 / (111320.0 * cos(radians(ST_Y(ST_Centroid(r.geom))))) AS dx
+		) g
+		CROSS JOIN LATERAL generate_series(0, floor((ST_XMax(r.geom) - ST_XMin(r.geom)) / g.dx)::int) i
+		CROSS JOIN LATERAL generate_series(0, floor((ST_YMax(r.geom) - ST_YMin(r.geom)) / g.dy)::int) j
+		CROSS JOIN LATERAL (
+			SELECT ST_MakeEnvelope(
+				ST_XMin(r.geom) + i * g.dx, ST_YMin(r.geom) + j * g.dy,
+				ST_XMin(r.geom) + (i + 1) * g.dx, ST_YMin(r.geom) + (j + 1) * g.dy, 4326) AS cell
+		) c
+		CROSS JOIN LATERAL (
+			SELECT x.piece, ST_Area(x.piece::geography) AS area
+			FROM (SELECT ST_CollectionExtract(ST_Intersection(c.cell, r.geom), 3) AS piece) x
+		) p
+		WHERE r.city = $1
+		  AND NOT EXISTS (SELECT 1 FROM region_sample s WHERE s.region_id = r.id)
+		  AND c.cell && r.geom
+		  AND NOT ST_IsEmpty(p.piece)
+		  AND p.area >= 0.1 * $2 * $2`,
+		city, spacing)
+	if err != nil {
+		return 0, fmt.Errorf("insert grid samples: %w", err)
+	}
+	added := tag.RowsAffected()
+
+	tag, err = tx.Exec(ctx, `
+		INSERT INTO region_sample (region_id, geom, area_m2, population)
+		SELECT r.id, ST_PointOnSurface(r.geom), r.area_m2, population_within(r.geom)
+		FROM admin_region r
+		WHERE r.city = $1
+		  AND NOT EXISTS (SELECT 1 FROM region_sample s WHERE s.region_id = r.id)`, city)
+	if err != nil {
+		return 0, fmt.Errorf("insert fallback samples: %w", err)
+	}
+	added += tag.RowsAffected()
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+	return added, nil
+}
+
+// RegionSample 待计算 POI 数量的样点
+type RegionSample struct {
+	ID       int64
+	City     string
+	Lng, Lat float64
+}
+
+// staleSample 已保存的 POI 数量按不同步行速度（$2）或旧路网计算，需重新计算；
+// 查询需 LEFT JOIN network_version nv
+const staleSample = `(s.walk_speed <> $2 OR s.network_version <> COALESCE(nv.version, 0))`
+
+// PendingSamples 城市中需要计算 POI 数量的样点：尚未计算、步行速度不同或路网已重新导入；
+// all 时为全部样点
+func (s *RegionService) PendingSamples(ctx context.Context, city string, walkSpeed float64, all bool) ([]RegionSample, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT s.id, r.city, ST_X(s.geom), ST_Y(s.geom)
+		FROM region_sample s
+		JOIN admin_region r ON r.id = s.region_id
+		LEFT JOIN network_version nv ON nv.city = r.city
+		WHERE r.city = $1
+		  AND ($3 OR s.counts IS NULL OR `+staleSample+`)
+		ORDER BY s.id`,
+		city, walkSpeed, all)
+	if err != nil {
+		return nil, fmt.Errorf("query samples: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []RegionSample
+	for rows.Next() {
+		var p RegionSample
+		if err := rows.Scan(&p.ID, &p.City, &p.Lng, &p.Lat); err != nil {
+			return nil, fmt.Errorf("scan sample: %w", err)
+		}
+		samples = append(samples, p)
+	}
+	return samples, rows.Err()
+}
+
+// Measure 计算样点 15 分钟步行圈内各子类型 POI 数量，记录当时的路网版本
+func (s *RegionService) Measure(ctx context.Context, p RegionSample, walkSpeed float64) error {
+	counts, err := s.evaluation.ReachableCounts(ctx, p.Lng, p.Lat, walkSpeed)
+	if err != nil {
+		return fmt.Errorf("sample %d: %w", p.ID, err)
+	}
+	if _, err := s.db.Pool.Exec(ctx, `
+		UPDATE region_sample SET
+			counts = $2,
+			walk_speed = $3,
+			network_version = COALESCE((SELECT version FROM network_version WHERE city = $4), 0),
+			measured_at = NOW()
+		WHERE id = $1`,
+		p.ID, counts, walkSpeed, p.City); err != nil {
+		return fmt.Errorf("sample %d: save counts: %w", p.ID, err)
+	}
+	return nil
+}
+
+// measuredSample 已计算 POI 数量的样点
+type measuredSample struct {
+	id         int64
+	regionID   int
+	area       float64
+	population *float64
+	counts     map[string]int
+	eval       *model.AreaEvaluation
+}
+
+// Rescore 按当前评价标准重新计算城市全部样点评分与区域汇总
+// 只使用已保存的 POI 数量，不做路网分析；没有已计算样点的区域不生成汇总。
+// 按其他步行速度或旧路网计算的样点（PendingSamples 会重新计算）不参与评分，
+// 计入区域汇总的 stale_samples，汇总标记为过时
+func (s *RegionService) Rescore(ctx context.Context, city string, walkSpeed float64) (*model.RegionRescoreResult, error) {
+	if walkSpeed <= 0 {
+		return nil, fmt.Errorf("%w: walk_speed must be positive", ErrInvalidRegion)
+	}
+	scorer, err := s.evaluation.NewScorer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT s.id, s.region_id, s.area_m2, s.population, s.counts, `+staleSample+`
+		FROM region_sample s
+		JOIN admin_region r ON r.id = s.region_id
+		LEFT JOIN network_version nv ON nv.city = r.city
+		WHERE r.city = $1
+		ORDER BY s.region_id, s.id`, city, walkSpeed)
+	if err != nil {
+		return nil, fmt.Errorf("query samples: %w", err)
+	}
+	result := &model.RegionRescoreResult{City: city, StandardsHash: scorer.Hash()}
+	byRegion := make(map[int][]*measuredSample)
+	staleByRegion := make(map[int]int)
+	var regionIDs []int
+	for rows.Next() {
+		var (
+			m     measuredSample
+			raw   []byte
+			stale *bool
+		)
+		if err := rows.Scan(&m.id, &m.regionID, &m.area, &m.population, &raw, &stale); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan sample: %w", err)
+		}
+		if raw == nil {
+			result.Unmeasured++
+			continue
+		}
+		if stale != nil && *stale {
+			result.Stale++
+			staleByRegion[m.regionID]++
+			continue
+		}
+		if err := json.Unmarshal(raw, &m.counts); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("sample %d: decode counts: %w", m.id, err)
+		}
+		m.eval = scorer.Score(m.counts)
+		if _, ok := byRegion[m.regionID]; !ok {
+			regionIDs = append(regionIDs, m.regionID)
+		}
+		byRegion[m.regionID] = append(byRegion[m.regionID], &m)
+		result.Samples++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query samples: %w", err)
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	for _, id := range regionIDs {
+		for _, m := range byRegion[id] {
+			categories := make(map[string]float64, len(m.eval.CategoryScores))
+			for _, c := range m.eval.CategoryScores {
+				categories[c.Category] = c.Score
+			}
+			missing := make([]string, 0)
+			for _, std := range scorer.Required() {
+				if !meetsStandard(m.counts, std) {
+					missing = append(missing, std.SubType)
+				}
+			}
+			batch.Queue(`
+				UPDATE region_sample
+				SET total_score = $2, category_scores = $3, missing_required = $4
+				WHERE id = $1`, m.id, m.eval.TotalScore, categories, missing)
+		}
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return nil, fmt.Errorf("update sample scores: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM region_summary s USING admin_region r
+		WHERE s.region_id = r.id AND r.city = $1`, city); err != nil {
+		return nil, fmt.Errorf("clear summaries: %w", err)
+	}
+	for _, id := range regionIDs {
+		st := summarize(scorer, byRegion[id])
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO region_summary (
+				region_id, sample_count, sampled_area_m2, mean_score, median_score,
+				weighted_mean_score, population, grade, category_means, required, standards_hash,
+				stale_samples
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+			id, st.SampleCount, st.SampledAreaM2, st.Mean, st.Median,
+			st.WeightedMean, st.Population, st.Grade, st.CategoryMeans, st.Required, scorer.Hash(),
+			staleByRegion[id],
+		); err != nil {
+			return nil, fmt.Errorf("region %d: save summary: %w", id, err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
+	result.Regions = len(regionIDs)
+	return result, nil
+}
+
+// meetsStandard 15 分钟圈内数量不少于 min_count_15（至少 1），与评分中的必备设施判断一致
+func meetsStandard(counts map[string]int, std model.EvaluationStandard) bool {
+	return counts[std.Category+"/"+std.SubType] >= max(std.MinCount15, 1)
+}
+
+// summarize 汇总区域样点：均值、中位数按面积加权，另计人口加权平均与必备设施达标占比
+// 人口指标只在全部样点都有人口时计算，部分样点缺少人口格网时只代表区域的一部分，留空
+func summarize(scorer *Scorer, samples []*measuredSample) *model.RegionStats {
+	st := &model.RegionStats{SampleCount: len(samples)}
+
+	var (
+		popTotal, popScore float64
+		popSamples         int
+	)
+	scores := make([]float64, len(samples))
+	areas := make([]float64, len(samples))
+	categorySums := make(map[string]float64)
+	for i, m := range samples {
+		scores[i], areas[i] = m.eval.TotalScore, m.area
+		st.SampledAreaM2 += m.area
+		st.Mean += m.eval.TotalScore * m.area
+		for _, c := range m.eval.CategoryScores {
+			categorySums[c.Category] += c.Score * m.area
+		}
+		if m.population != nil {
+			popSamples++
+			popTotal += *m.population
+			popScore += m.eval.TotalScore * *m.population
+		}
+	}
+	if st.SampledAreaM2 > 0 {
+		st.Mean = round2(st.Mean / st.SampledAreaM2)
+	}
+	st.Median = round2(weightedPercentile(scores, areas, 0.5))
+	st.Grade = model.GetGrade(st.Mean)
+	hasPop := len(samples) > 0 && popSamples == len(samples)
+	if hasPop {
+		st.Population = &popTotal
+		if popTotal > 0 {
+			mean := round2(popScore / popTotal)
+			st.WeightedMean = &mean
+		}
+	}
+
+	st.CategoryMeans = make([]model.RegionCategoryMean, 0, len(categorySums))
+	for _, c := range scorer.Categories() {
+		sum, ok := categorySums[c.Code]
+		if !ok || st.SampledAreaM2 == 0 {
+			continue
+		}
+		st.CategoryMeans = append(st.CategoryMeans, model.RegionCategoryMean{
+			Category: c.Code,
+			Name:     c.Name,
+			Mean:     round2(sum / st.SampledAreaM2),
+		})
+	}
+
+	names := subTypeNames()
+	st.Required = make([]model.RequiredCoverage, 0)
+	for _, std := range scorer.Required() {
+		var area, pop float64
+		for _, m := range samples {
+			if !meetsStandard(m.counts, std) {
+				continue
+			}
+			area += m.area
+			if m.population != nil {
+				pop += *m.population
+			}
+		}
+		cov := model.RequiredCoverage{Category: std.Category, SubType: std.SubType, Name: names[std.SubType]}
+		if st.SampledAreaM2 > 0 {
+			cov.AreaShare = roundShare(area / st.SampledAreaM2)
+		}
+		if hasPop && popTotal > 0 {
+			share := roundShare(pop / popTotal)
+			cov.PopulationShare = &share
+		}
+		st.Required = append(st.Required, cov)
+	}
+	return st
+}
+
+// weightedPercentile 加权分位数：按值排序后累计权重首次达到 q 处的值
+func weightedPercentile(values, weights []float64, q float64) float64 {
+	values, weights = positiveWeights(values, weights)
+	if len(values) == 0 {
+		return 0
+	}
+	idx := make([]int, len(values))
+	var total float64
+	for i := range idx {
+		idx[i] = i
+		total += weights[i]
+	}
+	slices.SortFunc(idx, func(a, b int) int { return cmp.Compare(values[a], values[b]) })
+	var acc float64
+	for _, i := range idx {
+		acc += weights[i]
+		if acc >= total*q {
+			return values[i]
+		}
+	}
+	return values[idx[len(idx)-1]]
+}
+
+// roundShare 占比保留 4 位小数
+func roundShare(v float64) float64 {
+	return math.Round(v*1e4) / 1e4
+}
+
+// subTypeNames 子类型代码到名称
+func subTypeNames() map[string]string {
+	names := make(map[string]string)
+	for _, c := range model.GetDefaultCategories() {
+		for _, st := range c.SubTypes {
+			names[st.Code] = st.Name
+		}
+	}
+	return names
+}
+
+const regionSummaryColumns = `
+	r.id, r.city, r.code, r.name, r.level, r.parent_id, COALESCE(p.name, ''), r.area_m2,
+	s.sample_count, s.sampled_area_m2, s.mean_score, s.median_score, s.weighted_mean_score,
+	s.population, s.grade, s.category_means, s.required, s.standards_hash, s.computed_at,
+	s.stale_samples`
+
+const regionSummaryFrom = `
+	FROM admin_region r
+	LEFT JOIN admin_region p ON p.id = r.parent_id
+	LEFT JOIN region_summary s ON s.region_id = r.id`
+
+// scanRegionSummary 扫描 regionSummaryColumns（及 extra），汇总未计算时 Summary 为空；
+// standards_hash 与 hash 不同或有样点需重新计算时标记 Stale
+func scanRegionSummary(row pgx.Row, hash string, extra ...any) (*model.RegionSummary, error) {
+	var (
+		r           model.RegionSummary
+		sampleCount *int
+		st          model.RegionStats
+		grade       *string
+		stdHash     *string
+		sampledArea *float64
+		mean        *float64
+		median      *float64
+		computedAt  *time.Time
+		stale       *int
+	)
+	dest := []any{
+		&r.ID, &r.City, &r.Code, &r.Name, &r.Level, &r.ParentID, &r.ParentName, &r.AreaM2,
+		&sampleCount, &sampledArea, &mean, &median, &st.WeightedMean,
+		&st.Population, &grade, &st.CategoryMeans, &st.Required, &stdHash, &computedAt,
+		&stale,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if sampleCount != nil {
+		st.SampleCount = *sampleCount
+		st.SampledAreaM2, st.Mean, st.Median = *sampledArea, *mean, *median
+		st.Grade = *grade
+		st.ComputedAt = *computedAt
+		st.StaleSamples = *stale
+		st.Stale = *stdHash != hash || st.StaleSamples > 0
+		r.Summary = &st
+	}
+	return &r, nil
+}
+
+// Summary 区域评分汇总与边界（GeoJSON）
+func (s *RegionService) Summary(ctx context.Context, id int) (*model.RegionSummary, error) {
+	scorer, err := s.evaluation.NewScorer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var geometry string
+	r, err := scanRegionSummary(s.db.Pool.QueryRow(ctx,
+		`SELECT `+regionSummaryColumns+`, ST_AsGeoJSON(r.geom, 6)`+regionSummaryFrom+` WHERE r.id = $1`, id),
+		scorer.Hash(), &geometry)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRegionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query region: %w", err)
+	}
+	r.Geometry = json.RawMessage(geometry)
+	return r, nil
+}
+
+// Ranking 城市内区域按汇总指标排名（level 为空时不限层级），
+// order 为 asc 时从低到高，便于找出短板区域
+func (s *RegionService) Ranking(ctx context.Context, city, level, sortBy, order string) (*model.RegionRanking, error) {
+	if city == "" {
+		return nil, fmt.Errorf("%w: city is required", ErrInvalidRegion)
+	}
+	if level != "" && level != model.RegionSubdistrict && level != model.RegionCommunity {
+		return nil, fmt.Errorf("%w: level must be %s or %s", ErrInvalidRegion, model.RegionSubdistrict, model.RegionCommunity)
+	}
+	if sortBy == "" {
+		sortBy = "mean"
+	}
+	column, ok := regionSortColumns[sortBy]
+	if !ok {
+		return nil, fmt.Errorf("%w: sort must be mean, median or population_weighted_mean", ErrInvalidRegion)
+	}
+	order = strings.ToLower(order)
+	direction := "DESC"
+	switch order {
+	case "", "desc":
+		order = "desc"
+	case "asc":
+		direction = "ASC"
+	default:
+		return nil, fmt.Errorf("%w: order must be asc or desc", ErrInvalidRegion)
+	}
+
+	scorer, err := s.evaluation.NewScorer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Pool.Query(ctx, `SELECT `+regionSummaryColumns+regionSummaryFrom+`
+		WHERE r.city = $1 AND ($2 = '' OR r.level = $2)
+		ORDER BY `+column+` `+direction+` NULLS LAST, r.code`,
+		city, level)
+	if err != nil {
+		return nil, fmt.Errorf("query regions: %w", err)
+	}
+	defer rows.Close()
+
+	ranking := &model.RegionRanking{City: city, Level: level, SortBy: sortBy, Order: order, Regions: make([]model.RankedRegion, 0)}
+	rank := 0
+	for rows.Next() {
+		r, err := scanRegionSummary(rows, scorer.Hash())
+		if err != nil {
+			return nil, fmt.Errorf("scan region: %w", err)
+		}
+		ranked := model.RankedRegion{RegionSummary: *r}
+		if r.Summary != nil && (sortBy != "population_weighted_mean" || r.Summary.WeightedMean != nil) {
+			rank++
+			ranked.Rank = rank
+		}
+		ranking.Regions = append(ranking.Regions, ranked)
+	}
+	return ranking, rows.Err()
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/yourname/15min-life-circle/internal/store/fixture"
+)
+
+func TestSummarize(t *testing.T) {
+	stores := fixture.NewTown().Memory().Stores()
+	iso := NewIsochroneServiceWithStore(stores.Isochrones)
+	evaluation := NewEvaluationServiceWithStores(iso, NewPOIServiceWithStore(stores.POIs, iso), stores.Standards, nil)
+	scorer, err := evaluation.NewScorer(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	full := make(map[string]int)
+	for _, std := range scorer.Required() {
+		full[std.Category+"/"+std.SubType] = max(std.MinCount15, 1)
+	}
+	served, desert := scorer.Score(full).TotalScore, scorer.Score(map[string]int{}).TotalScore
+	if served <= desert {
+		t.Fatalf("served score %v not above desert score %v", served, desert)
+	}
+
+	pop := func(v float64) *float64 { return &v }
+	type sample struct {
+		served     bool
+		area       float64
+		population *float64
+	}
+	tests := []struct {
+		name    string
+		samples []sample
+		mean    float64
+		// 人口加权平均分与必备设施人口占比，nil 表示不计算
+		weighted, share *float64
+	}{
+		{"area weighted", []sample{{true, 1, nil}, {false, 3, nil}}, (served + 3*desert) / 4, nil, nil},
+		{"population weighted", []sample{{true, 1, pop(300)}, {false, 3, pop(100)}}, (served + 3*desert) / 4,
+			pop((3*served + desert) / 4), pop(0.75)},
+		{"zero population", []sample{{true, 1, pop(0)}, {false, 1, pop(0)}}, (served + desert) / 2, nil, nil},
+		// 只有部分样点有人口时人口指标只代表区域的一部分
+		{"partial population", []sample{{true, 1, pop(300)}, {false, 3, nil}}, (served + 3*desert) / 4, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var samples []*measuredSample
+			for i, s := range tt.samples {
+				counts := map[string]int{}
+				if s.served {
+					counts = full
+				}
+				samples = append(samples, &measuredSample{id: int64(i), area: s.area, population: s.population, counts: counts, eval: scorer.Score(counts)})
+			}
+			st := summarize(scorer, samples)
+
+			if st.SampleCount != len(samples) || math.Abs(st.Mean-round2(tt.mean)) > 0.01 {
+				t.Errorf("samples = %d, mean = %v; want %d, %v", st.SampleCount, st.Mean, len(samples), round2(tt.mean))
+			}
+			if !nearPtr(st.WeightedMean, tt.weighted) {
+				t.Errorf("weighted mean = %v, want %v", deref(st.WeightedMean), deref(tt.weighted))
+			}
+			if len(st.Required) != len(scorer.Required()) {
+				t.Fatalf("required = %d entries, want %d", len(st.Required), len(scorer.Required()))
+			}
+			for _, cov := range st.Required {
+				if !nearPtr(cov.PopulationShare, tt.share) {
+					t.Errorf("%s population share = %v, want %v", cov.SubType, deref(cov.PopulationShare), deref(tt.share))
+				}
+			}
+		})
+	}
+}
+
+func nearPtr(got, want *float64) bool {
+	if got == nil || want == nil {
+		return got == want
+	}
+	return math.Abs(*got-round2(*want)) <= 0.01
+}
+
+func deref(v *float64) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
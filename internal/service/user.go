@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/yourname/15min-life-circle/internal/config"
+	"github.com/yourname/15min-life-circle/internal/database"
+	"github.com/yourname/15min-life-circle/internal/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrInvalidUserRequest 注册或地点参数不合法
+	ErrInvalidUserRequest = errors.New("invalid user request")
+	// ErrRegistrationClosed 未开放注册
+	ErrRegistrationClosed = errors.New("registration closed")
+	// ErrUserExists 用户名已被使用
+	ErrUserExists = errors.New("username already taken")
+	// ErrInvalidCredentials 用户名或密码错误
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrInvalidSession 会话不存在或已过期
+	ErrInvalidSession = errors.New("invalid session")
+	// ErrPlaceNotFound 地点不存在或不属于该用户
+	ErrPlaceNotFound = errors.New("place not found")
+	// ErrPlaceExists 同名地点已存在
+	ErrPlaceExists = errors.New("place name already used")
+)
+
+// sessionPrefix 会话令牌的固定前缀，与 API Key（lc_）区分
+const sessionPrefix = "lcs_"
+
+// UserService 用户账号、登录会话与常用地点
+type UserService struct {
+	db  *database.DB
+	cfg config.AuthConfig
+
+	// dummyHash 用户不存在时同样做一次 bcrypt 比较，避免按响应时间枚举用户名
+	dummyHash []byte
+}
+
+// NewUserService 创建用户服务
+func NewUserService(db *database.DB, cfg config.AuthConfig) *UserService {
+	dummy, _ := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	return &UserService{db: db, cfg: cfg, dummyHash: dummy}
+}
+
+// hashSession 会话令牌的 SHA-256 摘要；令牌为 32 字节随机数，无需慢哈希
+func hashSession(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// isUniqueViolation 违反唯一约束
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+const userColumns = `id, username, COALESCE(display_name, ''), created_at, last_login_at`
+
+func scanUser(row pgx.Row) (*model.User, error) {
+	var u model.User
+	if err := row.Scan(&u.ID, &u.Username, &u.DisplayName, &u.CreatedAt, &u.LastLoginAt); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// Register 注册账号
+func (s *UserService) Register(ctx context.Context, req *model.RegisterRequest) (*model.User, error) {
+	if !s.cfg.AllowRegistration {
+		return nil, ErrRegistrationClosed
+	}
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidUserRequest, err)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("hash password: %w", err)
+	}
+	u, err := scanUser(s.db.Pool.QueryRow(ctx, `
+		INSERT INTO app_user (username, password_hash, display_name)
+		VALUES ($1, $2, NULLIF($3, ''))
+		RETURNING `+userColumns,
+		req.Username, string(hash), req.DisplayName))
+	if isUniqueViolation(err) {
+		return nil, ErrUserExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("insert user: %w", err)
+	}
+	return u, nil
+}
+
+// Login 校验密码并创建会话，同时清理该用户已过期的会话
+func (s *UserService) Login(ctx context.Context, req *model.LoginRequest, userAgent string) (*model.LoginResult, error) {
+	var (
+		id   int
+		hash string
+	)
+	err := s.db.Pool.QueryRow(ctx,
+		`SELECT id, password_hash FROM app_user WHERE username = lower($1)`, req.Username,
+	).Scan(&id, &hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		bcrypt.CompareHashAndPassword(s.dummyHash, []byte(req.Password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query user: %w", err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(req.Password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	var raw [32]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		return nil, fmt.Errorf("generate session token: %w", err)
+	}
+	token := sessionPrefix + hex.EncodeToString(raw[:])
+	expires := time.Now().Add(s.cfg.SessionTTL.D())
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM user_session WHERE user_id = $1 AND expires_at < NOW()`, id); err != nil {
+		return nil, fmt.Errorf("clean sessions: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO user_session (token_hash, user_id, expires_at, user_agent)
+		VALUES ($1, $2, $3, NULLIF($4, ''))`,
+		hashSession(token), id, expires, userAgent); err != nil {
+		return nil, fmt.Errorf("insert session: %w", err)
+	}
+	u, err := scanUser(tx.QueryRow(ctx,
+		`UPDATE app_user SET last_login_at = NOW() WHERE id = $1 RETURNING `+userColumns, id))
+	if err != nil {
+		return nil, fmt.Errorf("update user: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return &model.LoginResult{User: *u, Token: token, ExpiresAt: expires}, nil
+}
+
+// Logout 删除会话，令牌无效时不报错
+func (s *UserService) Logout(ctx context.Context, token string) error {
+	if _, err := s.db.Pool.Exec(ctx, `DELETE FROM user_session WHERE token_hash = $1`, hashSession(token)); err != nil {
+		return fmt.Errorf("delete session: %w", err)
+	}
+	return nil
+}
+
+// Authenticate 按会话令牌获取用户，不存在或已过期时返回 ErrInvalidSession
+func (s *UserService) Authenticate(ctx context.Context, token string) (*model.User, error) {
+	u, err := scanUser(s.db.Pool.QueryRow(ctx, `
+		SELECT u.id, u.username, COALESCE(u.display_name, ''), u.created_at, u.last_login_at
+		FROM user_session s
+		JOIN app_user u ON u.id = s.user_id
+		WHERE s.token_hash = $1 AND s.expires_at > NOW()`, hashSession(token)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvalidSession
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query session: %w", err)
+	}
+	return u, nil
+}
+
+const placeColumns = `id, name, ST_X(geom), ST_Y(geom), COALESCE(note, ''), created_at, updated_at`
+
+func scanPlace(row pgx.Row) (*model.SavedPlace, error) {
+	var p model.SavedPlace
+	if err := row.Scan(&p.ID, &p.Name, &p.Lng, &p.Lat, &p.Note, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Places 用户的常用地点（按名称）
+func (s *UserService) Places(ctx context.Context, userID int) ([]model.SavedPlace, error) {
+	rows, err := s.db.Pool.Query(ctx,
+		`SELECT `+placeColumns+` FROM saved_place WHERE user_id = $1 ORDER BY name`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("query places: %w", err)
+	}
+	defer rows.Close()
+
+	places := make([]model.SavedPlace, 0)
+	for rows.Next() {
+		p, err := scanPlace(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan place: %w", err)
+		}
+		places = append(places, *p)
+	}
+	return places, rows.Err()
+}
+
+// Place 获取用户的常用地点
+func (s *UserService) Place(ctx context.Context, userID, id int) (*model.SavedPlace, error) {
+	p, err := scanPlace(s.db.Pool.QueryRow(ctx,
+		`SELECT `+placeColumns+` FROM saved_place WHERE id = $1 AND user_id = $2`, id, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPlaceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query place: %w", err)
+	}
+	return p, nil
+}
+
+// CreatePlace 保存地点，同一用户的地点名称不能重复
+func (s *UserService) CreatePlace(ctx context.Context, userID int, req *model.SavedPlaceRequest) (*model.SavedPlace, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidUserRequest, err)
+	}
+	p, err := scanPlace(s.db.Pool.QueryRow(ctx, `
+		INSERT INTO saved_place (user_id, name, geom, note)
+		VALUES ($1, $2, ST_SetSRID(ST_MakePoint($3, $4), 4326), NULLIF($5, ''))
+		RETURNING `+placeColumns,
+		userID, req.Name, req.Lng, req.Lat, req.Note))
+	if isUniqueViolation(err) {
+		return nil, ErrPlaceExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("insert place: %w", err)
+	}
+	return p, nil
+}
+
+// UpdatePlace 修改地点名称、位置或备注
+func (s *UserService) UpdatePlace(ctx context.Context, userID, id int, req *model.SavedPlaceRequest) (*model.SavedPlace, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidUserRequest, err)
+	}
+	p, err := scanPlace(s.db.Pool.QueryRow(ctx, `
+		UPDATE saved_place
+		SET name = $3, geom = ST_SetSRID(ST_MakePoint($4, $5), 4326), note = NULLIF($6, ''), updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING `+placeColumns,
+		id, userID, req.Name, req.Lng, req.Lat, req.Note))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPlaceNotFound
+	}
+	if isUniqueViolation(err) {
+		return nil, ErrPlaceExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update place: %w", err)
+	}
+	return p, nil
+}
+
+// DeletePlace 删除地点，历史评价记录保留（place_id 置空）
+func (s *UserService) DeletePlace(ctx context.Context, userID, id int) error {
+	tag, err := s.db.Pool.Exec(ctx, `DELETE FROM saved_place WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("delete place: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrPlaceNotFound
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package store
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseBBox 解析 "west,south,east,north"
+func ParseBBox(s string) ([4]float64, error) {
+	var bbox [4]float64
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return bbox, fmt.Errorf("%w: bbox must be west,south,east,north", ErrInvalidSearch)
+	}
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return bbox, fmt.Errorf("%w: bbox: %v", ErrInvalidSearch, err)
+		}
+		bbox[i] = v
+	}
+	if bbox[0] >= bbox[2] || bbox[1] >= bbox[3] {
+		return bbox, fmt.Errorf("%w: bbox is empty", ErrInvalidSearch)
+	}
+	return bbox, nil
+}
+
+// EncodeCursor 游标编码最后一条记录的排序键（距离, ID）
+func EncodeCursor(distance float64, id int64) string {
+	raw := strconv.FormatFloat(distance, 'g', -1, 64) + ":" + strconv.FormatInt(id, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor 解析 EncodeCursor 生成的游标
+func DecodeCursor(cursor string) (float64, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: malformed cursor", ErrInvalidSearch)
+	}
+	d, i, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("%w: malformed cursor", ErrInvalidSearch)
+	}
+	distance, err := strconv.ParseFloat(d, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: malformed cursor", ErrInvalidSearch)
+	}
+	id, err := strconv.ParseInt(i, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: malformed cursor", ErrInvalidSearch)
+	}
+	return distance, id, nil
+}
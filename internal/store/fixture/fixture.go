@@ -0,0 +1,191 @@
+// Package fixture 小镇测试数据：网格路网、POI 与评价标准
+//
+// 同一份数据可加载到内存存储（Memory）或 PostGIS（LoadPostGIS），
+// internal/store/scenario 中的场景在两种存储上运行同一组检查。
+// 小镇位于南太平洋（城市代码 fixturetown，节点 ID 为负数），不与 OSM 数据冲突。
+package fixture
+
+import (
+	"math"
+
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/store/memory"
+)
+
+// City 小镇的城市代码，POI 的 city 与 data_source 标记
+const City = "fixturetown"
+
+// Node 路网节点
+type Node struct {
+	ID       int64
+	Lng, Lat float64
+}
+
+// Edge 双向步行道路
+type Edge struct {
+	ID             int64
+	Source, Target int64
+	LengthM        float64
+}
+
+// POI 相对小镇中心的位置（米，东、北为正）
+type POI struct {
+	Name     string
+	Category string
+	SubType  string
+	DX, DY   float64
+}
+
+// Town 小镇数据
+type Town struct {
+	City string
+	// 中心节点坐标
+	Lng, Lat float64
+	// 每边节点数与节点间距（米）
+	Size    int
+	Spacing float64
+
+	Nodes     []Node
+	Edges     []Edge
+	POIs      []POI
+	Standards []model.EvaluationStandard
+}
+
+// NewTown 21×21 网格、间距 100 米的小镇，中心节点为 (10, 10)
+//
+// 按 5 km/h 步行，各 POI 沿路网到中心的距离（曼哈顿距离）：
+//
+//	5 分钟内   社区卫生服务站、药店、幼儿园、两处便利店（≤ 300 米）
+//	10 分钟内  小学、菜市场、综合超市（600–700 米）
+//	15 分钟内  文化活动中心（1000 米）
+//	圈外       另一处综合超市（1800 米）
+//
+// 没有养老设施，评价应给出养老服务的改进建议。
+func NewTown() *Town {
+	t := &Town{
+		City:      City,
+		Lng:       -139.5,
+		Lat:       -41.0,
+		Size:      21,
+		Spacing:   100,
+		Standards: Standards(),
+		POIs: []POI{
+			{"Fixture Health Station", "medical", "community_health", 150, 50},
+			{"Fixture Pharmacy", "medical", "pharmacy", -100, 100},
+			{"Fixture Kindergarten", "education", "kindergarten", 0, -200},
+			{"Fixture Corner Shop", "commerce", "convenience", 50, 0},
+			{"Fixture West Shop", "commerce", "convenience", -250, -50},
+			{"Fixture Primary School", "education", "primary", 400, 300},
+			{"Fixture Market", "commerce", "market", -300, 400},
+			{"Fixture Supermarket", "commerce", "supermarket", 500, -100},
+			{"Fixture Culture Center", "culture", "culture_center", -500, -500},
+			{"Fixture Far Supermarket", "commerce", "supermarket", 900, 900},
+		},
+	}
+
+	half := t.Size / 2
+	var edgeID int64
+	for i := 0; i < t.Size; i++ {
+		for j := 0; j < t.Size; j++ {
+			lng, lat := t.At(float64(j-half)*t.Spacing, float64(i-half)*t.Spacing)
+			t.Nodes = append(t.Nodes, Node{ID: t.nodeID(i, j), Lng: lng, Lat: lat})
+			if j+1 < t.Size {
+				edgeID--
+				t.Edges = append(t.Edges, Edge{ID: edgeID, Source: t.nodeID(i, j), Target: t.nodeID(i, j+1), LengthM: t.Spacing})
+			}
+			if i+1 < t.Size {
+				edgeID--
+				t.Edges = append(t.Edges, Edge{ID: edgeID, Source: t.nodeID(i, j), Target: t.nodeID(i+1, j), LengthM: t.Spacing})
+			}
+		}
+	}
+	return t
+}
+
+// nodeID 第 i 行第 j 列节点的 ID（负数）
+func (t *Town) nodeID(i, j int) int64 {
+	return -int64(i*t.Size + j + 1)
+}
+
+// At 相对中心 (dx, dy) 米处的坐标
+func (t *Town) At(dx, dy float64) (lng, lat float64) {
+	lat = t.Lat + dy/metersPerDegree
+	lng = t.Lng + dx/(metersPerDegree*math.Cos(t.Lat*math.Pi/180))
+	return lng, lat
+}
+
+// Bounds 路网外包矩形 west, south, east, north
+func (t *Town) Bounds() (west, south, east, north float64) {
+	r := float64(t.Size/2) * t.Spacing
+	west, south = t.At(-r, -r)
+	east, north = t.At(r, r)
+	return west, south, east, north
+}
+
+// metersPerDegree 纬度 1 度对应的距离（米）
+const metersPerDegree = 111320.0
+
+// Memory 加载到新的内存存储
+func (t *Town) Memory() *memory.Store {
+	s := memory.New()
+	for _, n := range t.Nodes {
+		s.AddNode(n.ID, n.Lng, n.Lat, t.City)
+	}
+	for _, e := range t.Edges {
+		s.AddEdge(e.ID, e.Source, e.Target, e.LengthM)
+	}
+	for _, p := range t.POIs {
+		lng, lat := t.At(p.DX, p.DY)
+		s.AddPOI(model.POIRecord{
+			Name:     p.Name,
+			Category: p.Category,
+			SubType:  p.SubType,
+			Lng:      lng,
+			Lat:      lat,
+			Source:   model.SourceOSM,
+			City:     t.City,
+		})
+	}
+	s.SetStandards(t.Standards)
+	return s
+}
+
+// Standards 评价标准，与 001_init_schema.sql 中 evaluation_standard 的初始数据一致
+func Standards() []model.EvaluationStandard {
+	std := func(category, subType string, min5, min10, min15 int, required bool, base float64) model.EvaluationStandard {
+		return model.EvaluationStandard{
+			Category: category, SubType: subType,
+			MinCount5: min5, MinCount10: min10, MinCount15: min15,
+			Required: required, BaseScore: base,
+		}
+	}
+	return []model.EvaluationStandard{
+		std("child", "nursery", 0, 0, 1, false, 40),
+		std("child", "playground", 0, 1, 2, false, 30),
+		std("commerce", "convenience", 1, 2, 4, false, 15),
+		std("commerce", "market", 0, 1, 1, true, 30),
+		std("commerce", "restaurant", 0, 2, 4, false, 10),
+		std("commerce", "supermarket", 0, 1, 2, false, 20),
+		std("culture", "culture_center", 0, 0, 1, true, 25),
+		std("culture", "library", 0, 0, 1, false, 15),
+		std("culture", "park", 0, 1, 1, true, 25),
+		std("culture", "sports_field", 0, 1, 2, true, 25),
+		std("education", "kindergarten", 0, 1, 1, true, 35),
+		std("education", "primary", 0, 0, 1, true, 35),
+		std("education", "secondary", 0, 0, 1, false, 15),
+		std("elderly", "daycare", 0, 1, 1, false, 25),
+		std("elderly", "elderly_activity", 0, 1, 2, false, 15),
+		std("elderly", "elderly_center", 0, 0, 1, true, 35),
+		std("medical", "community_health", 0, 1, 1, true, 40),
+		std("medical", "hospital", 0, 0, 1, false, 15),
+		std("medical", "pharmacy", 1, 2, 3, false, 15),
+		std("public", "bank", 0, 1, 2, false, 15),
+		std("public", "community_service", 0, 0, 1, true, 30),
+		std("public", "police", 0, 0, 1, false, 20),
+		std("public", "post", 0, 0, 1, false, 10),
+		std("transport", "bike_parking", 1, 2, 4, false, 10),
+		std("transport", "bus_stop", 1, 2, 3, true, 35),
+		std("transport", "metro", 0, 0, 1, false, 25),
+		std("transport", "parking", 0, 1, 2, false, 15),
+	}
+}
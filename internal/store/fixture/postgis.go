@@ -0,0 +1,98 @@
+package fixture
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yourname/15min-life-circle/internal/database"
+)
+
+// LoadPostGIS 将小镇路网与 POI 写入数据库并登记城市（覆盖范围、路网版本）
+// 评价标准使用数据库中的 evaluation_standard，与 Standards 一致时两种存储的评分相同。
+// 已加载的同名数据会先删除。请在测试库上运行。
+func (t *Town) LoadPostGIS(ctx context.Context, db *database.DB) error {
+	if err := t.Remove(ctx, db); err != nil {
+		return err
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, n := range t.Nodes {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO ways_vertices_pgr (id, the_geom, city)
+			VALUES ($1, ST_SetSRID(ST_MakePoint($2, $3), 4326), $4)`,
+			n.ID, n.Lng, n.Lat, t.City,
+		); err != nil {
+			return fmt.Errorf("insert vertex %d: %w", n.ID, err)
+		}
+	}
+	for _, e := range t.Edges {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO ways (source, target, length_m, name, highway, city, the_geom)
+			SELECT $1, $2, $3, 'Fixture Rd ' || $1, 'residential', $4, ST_MakeLine(a.the_geom, b.the_geom)
+			FROM ways_vertices_pgr a, ways_vertices_pgr b
+			WHERE a.id = $1 AND b.id = $2`,
+			e.Source, e.Target, e.LengthM, t.City,
+		); err != nil {
+			return fmt.Errorf("insert edge %d: %w", e.ID, err)
+		}
+	}
+	for _, p := range t.POIs {
+		lng, lat := t.At(p.DX, p.DY)
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO poi (name, category, sub_type, geom, data_source, city)
+			VALUES ($1, $2, $3, ST_SetSRID(ST_MakePoint($4, $5), 4326), 'osm', $6)`,
+			p.Name, p.Category, p.SubType, lng, lat, t.City,
+		); err != nil {
+			return fmt.Errorf("insert poi %s: %w", p.Name, err)
+		}
+	}
+
+	west, south, east, north := t.Bounds()
+	if _, err := tx.Exec(ctx, `SELECT register_city($1, 'Fixture Town', NULL, $2, $3, $4, $5)`,
+		t.City, west, south, east, north); err != nil {
+		return fmt.Errorf("register city: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `SELECT bump_network_version($1)`, t.City); err != nil {
+		return fmt.Errorf("bump network version: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	for _, table := range []string{"ways", "ways_vertices_pgr", "poi"} {
+		if _, err := db.Pool.Exec(ctx, "ANALYZE "+table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove 删除小镇数据，包括场景运行中产生的 POI、审计、缓存与评价记录
+func (t *Town) Remove(ctx context.Context, db *database.DB) error {
+	west, south, east, north := t.Bounds()
+	for _, step := range []struct {
+		name string
+		sql  string
+		args []any
+	}{
+		{"audits", `DELETE FROM poi_audit WHERE poi_id IN (SELECT id FROM poi WHERE city = $1)`, []any{t.City}},
+		{"pois", `DELETE FROM poi WHERE city = $1`, []any{t.City}},
+		{"analyses", `DELETE FROM analysis_history WHERE origin && ST_MakeEnvelope($1, $2, $3, $4, 4326)`, []any{west, south, east, north}},
+		{"node reach", `DELETE FROM node_reach WHERE city = $1`, []any{t.City}},
+		{"edges", `DELETE FROM ways WHERE city = $1`, []any{t.City}},
+		{"vertices", `DELETE FROM ways_vertices_pgr WHERE city = $1`, []any{t.City}},
+		{"coverage", `DELETE FROM coverage WHERE city = $1`, []any{t.City}},
+		{"city", `DELETE FROM city WHERE code = $1`, []any{t.City}},
+	} {
+		if _, err := db.Pool.Exec(ctx, step.sql, step.args...); err != nil {
+			return fmt.Errorf("remove %s: %w", step.name, err)
+		}
+	}
+	return nil
+}
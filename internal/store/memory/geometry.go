@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/yourname/15min-life-circle/internal/model"
+)
+
+// polygonSet 多边形集合，每个多边形为外环加若干内环
+type polygonSet [][][][2]float64
+
+// parseGeometry 解析 GeoJSON Polygon / MultiPolygon
+func parseGeometry(raw []byte) (polygonSet, error) {
+	var g struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}
+	if err := json.Unmarshal(raw, &g); err != nil {
+		return nil, fmt.Errorf("parse geojson: %w", err)
+	}
+	switch g.Type {
+	case "Polygon":
+		var p [][][2]float64
+		if err := json.Unmarshal(g.Coordinates, &p); err != nil {
+			return nil, fmt.Errorf("parse polygon: %w", err)
+		}
+		return polygonSet{p}, nil
+	case "MultiPolygon":
+		var mp polygonSet
+		if err := json.Unmarshal(g.Coordinates, &mp); err != nil {
+			return nil, fmt.Errorf("parse multipolygon: %w", err)
+		}
+		return mp, nil
+	}
+	return nil, fmt.Errorf("unsupported geometry type %q", g.Type)
+}
+
+// toPolygonSet 将 model.Geometry 转为多边形集合
+func toPolygonSet(g model.Geometry) (polygonSet, error) {
+	raw, err := json.Marshal(g)
+	if err != nil {
+		return nil, err
+	}
+	return parseGeometry(raw)
+}
+
+// contains 点是否位于任一多边形内（在外环内且不在内环内）
+func (ps polygonSet) contains(lng, lat float64) bool {
+	for _, poly := range ps {
+		if len(poly) == 0 || !ringContains(poly[0], lng, lat) {
+			continue
+		}
+		inHole := false
+		for _, hole := range poly[1:] {
+			if ringContains(hole, lng, lat) {
+				inHole = true
+				break
+			}
+		}
+		if !inHole {
+			return true
+		}
+	}
+	return false
+}
+
+// ringContains 射线法判断点是否位于环内
+func ringContains(ring [][2]float64, lng, lat float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > lat) != (yj > lat) && lng < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// convexHull 单调链算法求凸包，返回闭合外环；点数不足 3 或共线时返回 nil
+func convexHull(points [][2]float64) [][2]float64 {
+	pts := append([][2]float64(nil), points...)
+	sort.Slice(pts, func(i, j int) bool {
+		if pts[i][0] != pts[j][0] {
+			return pts[i][0] < pts[j][0]
+		}
+		return pts[i][1] < pts[j][1]
+	})
+	cross := func(o, a, b [2]float64) float64 {
+		return (a[0]-o[0])*(b[1]-o[1]) - (a[1]-o[1])*(b[0]-o[0])
+	}
+
+	var hull [][2]float64
+	for _, p := range pts {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	lower := len(hull) + 1
+	for i := len(pts) - 2; i >= 0; i-- {
+		p := pts[i]
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	// 单调链结果首尾相同，即为闭合环
+	if len(hull) < 4 {
+		return nil
+	}
+	return hull
+}
+
+// circle 以起点为圆心、radius 米为半径的近似圆（缓冲区回退）
+func circle(lng, lat, radius float64) [][2]float64 {
+	const segments = 32
+	ring := make([][2]float64, 0, segments+1)
+	dLat := radius / metersPerDegree
+	dLng := radius / (metersPerDegree * math.Cos(lat*math.Pi/180))
+	for i := 0; i < segments; i++ {
+		a := 2 * math.Pi * float64(i) / segments
+		ring = append(ring, [2]float64{lng + dLng*math.Cos(a), lat + dLat*math.Sin(a)})
+	}
+	return append(ring, ring[0])
+}
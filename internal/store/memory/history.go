@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/store"
+)
+
+// Standards 评价标准
+func (s *Store) Standards(ctx context.Context) ([]model.EvaluationStandard, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]model.EvaluationStandard(nil), s.standards...), nil
+}
+
+// SaveAnalysis 保存评价记录
+func (s *Store) SaveAnalysis(ctx context.Context, rec *model.AnalysisRecord) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := *rec
+	s.nextHistory++
+	c.ID = strconv.Itoa(s.nextHistory)
+	c.CreatedAt = time.Now()
+	c.InvalidatedAt = nil
+	if n, ok := s.nodes[rec.Node]; ok {
+		c.City, c.NetworkVersion = n.city, s.versions[n.city]
+	}
+	s.history = append(s.history, &c)
+	return c.ID, nil
+}
+
+// GetAnalysis 读取评价记录
+func (s *Store) GetAnalysis(ctx context.Context, id string) (*model.AnalysisRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, rec := range s.history {
+		if rec.ID == id {
+			c := *rec
+			return &c, nil
+		}
+	}
+	return nil, store.ErrAnalysisNotFound
+}
+
+// GetSharedAnalysis 按分享链接 ID 读取评价记录
+func (s *Store) GetSharedAnalysis(ctx context.Context, shareID string) (*model.AnalysisRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, rec := range s.history {
+		if rec.ShareID != "" && rec.ShareID == shareID {
+			c := *rec
+			c.CurrentNetworkVersion = s.versions[c.City]
+			return &c, nil
+		}
+	}
+	return nil, store.ErrAnalysisNotFound
+}
+
+// ListAnalyses 用户的评价记录（新到旧，不含 Result）
+func (s *Store) ListAnalyses(ctx context.Context, userID int, before *time.Time, limit int) ([]model.AnalysisRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := make([]model.AnalysisRecord, 0)
+	for i := len(s.history) - 1; i >= 0 && len(records) < limit; i-- {
+		rec := s.history[i]
+		if rec.UserID == nil || *rec.UserID != userID || before != nil && !rec.CreatedAt.Before(*before) {
+			continue
+		}
+		c := *rec
+		c.Result = nil
+		records = append(records, c)
+	}
+	return records, nil
+}
+
+// DeleteAnalysis 删除用户的评价记录
+func (s *Store) DeleteAnalysis(ctx context.Context, userID int, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, rec := range s.history {
+		if rec.ID == id && rec.UserID != nil && *rec.UserID == userID {
+			s.history = append(s.history[:i], s.history[i+1:]...)
+			return nil
+		}
+	}
+	return store.ErrAnalysisNotFound
+}
+
+// invalidateAnalyses 使等时圈包含任一给定点的评价记录失效，返回失效条数
+// 对应数据库函数 invalidate_analyses_at，调用方需持有锁
+func (s *Store) invalidateAnalyses(points [][2]float64) int {
+	now := time.Now()
+	n := 0
+	for _, rec := range s.history {
+		if rec.InvalidatedAt != nil || !recordContains(rec, points) {
+			continue
+		}
+		rec.InvalidatedAt = &now
+		n++
+	}
+	return n
+}
+
+// recordContains 与 analysis_history 一致，只保存 5/10/15 分钟等时圈
+func recordContains(rec *model.AnalysisRecord, points [][2]float64) bool {
+	for _, iso := range rec.Isochrones {
+		if iso.Minutes != 5 && iso.Minutes != 10 && iso.Minutes != 15 {
+			continue
+		}
+		ps, err := toPolygonSet(iso.Geometry)
+		if err != nil {
+			continue
+		}
+		for _, p := range points {
+			if ps.contains(p[0], p[1]) {
+				return true
+			}
+		}
+	}
+	return false
+}
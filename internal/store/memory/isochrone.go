@@ -0,0 +1,251 @@
+package memory
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/store"
+)
+
+// Coverage 最近节点吸附；起点位于路网节点外包矩形内视为在覆盖范围内
+func (s *Store) Coverage(ctx context.Context, lng, lat float64) (*model.CoverageInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.coverage(lng, lat), nil
+}
+
+func (s *Store) coverage(lng, lat float64) *model.CoverageInfo {
+	info := &model.CoverageInfo{SnapDistance: -1}
+	if len(s.nodes) == 0 {
+		return info
+	}
+
+	west, south, east, north := 180.0, 90.0, -180.0, -90.0
+	for id, n := range s.nodes {
+		d := distanceMeters(lng, lat, n.lng, n.lat)
+		if info.SnapDistance < 0 || d < info.SnapDistance || (d == info.SnapDistance && id < info.Node) {
+			info.SnapDistance, info.Node, info.City = d, id, n.city
+		}
+		west, east = min(west, n.lng), max(east, n.lng)
+		south, north = min(south, n.lat), max(north, n.lat)
+	}
+	info.InCoverage = lng >= west && lng <= east && lat >= south && lat <= north
+	info.Snapped = info.SnapDistance <= model.MaxSnapDistanceMeters
+	if !info.InCoverage {
+		info.City = ""
+	}
+	return info
+}
+
+// Isochrones 计算各时间阈值的等时圈；多边形算法、地形与屏障参数不影响结果（均为凸包）
+func (s *Store) Isochrones(ctx context.Context, req *model.IsochroneRequest) ([]model.IsochronePolygon, error) {
+	maxMinutes := 0
+	for _, t := range req.TimeThresholds {
+		maxMinutes = max(maxMinutes, t)
+	}
+	r, err := s.Reach(ctx, req.Lng, req.Lat, req.WalkSpeed, maxMinutes)
+	if err != nil {
+		return nil, err
+	}
+	polygons, err := s.IsochronesFromReach(ctx, r, req.TimeThresholds)
+	if err != nil {
+		return nil, err
+	}
+	for i := range polygons {
+		polygons[i].Method = req.PolygonMethod
+	}
+	return polygons, nil
+}
+
+// Reach 吸附起点并做 Dijkstra，节点已预计算时直接读取
+func (s *Store) Reach(ctx context.Context, lng, lat, walkSpeed float64, maxMinutes int) (*store.ReachSet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r := &store.ReachSet{
+		Lng:        lng,
+		Lat:        lat,
+		WalkSpeed:  walkSpeed,
+		MaxMinutes: maxMinutes,
+		Coverage:   s.coverage(lng, lat),
+	}
+	if !r.Coverage.Snapped {
+		return r, nil
+	}
+
+	if e := s.reach[reachKey{r.Coverage.Node, speedBucket(walkSpeed)}]; e != nil && e.maxMinutes >= maxMinutes {
+		for i, c := range e.costs {
+			if c > float64(maxMinutes) {
+				break
+			}
+			r.Nodes = append(r.Nodes, e.nodes[i])
+			r.Costs = append(r.Costs, c)
+		}
+		r.Cached = true
+		return r, nil
+	}
+
+	r.Nodes, r.Costs = s.dijkstra(r.Coverage.Node, walkSpeed, float64(maxMinutes))
+	return r, nil
+}
+
+// IsochronesFromReach 取 minutes 内可达节点的凸包；可达节点不足时以起点缓冲区回退
+func (s *Store) IsochronesFromReach(ctx context.Context, r *store.ReachSet, thresholds []int) ([]model.IsochronePolygon, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sorted := append([]int(nil), thresholds...)
+	sort.Ints(sorted)
+
+	polygons := make([]model.IsochronePolygon, 0, len(sorted))
+	for _, minutes := range sorted {
+		distance := float64(minutes) * metersPerMinute(r.WalkSpeed)
+		poly := model.IsochronePolygon{
+			Minutes:  minutes,
+			Distance: distance,
+			Method:   model.PolygonConcave,
+		}
+
+		points := [][2]float64{{r.Lng, r.Lat}}
+		for i, id := range r.Nodes {
+			if r.Costs[i] > float64(minutes) {
+				break
+			}
+			n := s.nodes[id]
+			points = append(points, [2]float64{n.lng, n.lat})
+		}
+		ring := convexHull(points)
+		if ring == nil {
+			ring = circle(r.Lng, r.Lat, distance)
+			poly.Fallback = model.FallbackBuffer
+		}
+		poly.Geometry = model.Geometry{Type: "Polygon", Coordinates: [][][2]float64{ring}}
+		polygons = append(polygons, poly)
+	}
+	return polygons, nil
+}
+
+// RoadsFromReach 两端均在 minutes 内可达的道路
+func (s *Store) RoadsFromReach(ctx context.Context, r *store.ReachSet, minutes int) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cost := make(map[int64]float64, len(r.Nodes))
+	for i, id := range r.Nodes {
+		if r.Costs[i] <= float64(minutes) {
+			cost[id] = r.Costs[i]
+		}
+	}
+
+	fc := model.NewFeatureCollection()
+	for _, e := range s.edges {
+		cs, ok1 := cost[e.source]
+		ct, ok2 := cost[e.target]
+		if !ok1 || !ok2 {
+			continue
+		}
+		a, b := s.nodes[e.source], s.nodes[e.target]
+		fc.AddFeature(model.Feature{
+			Type: "Feature",
+			Geometry: model.Geometry{
+				Type:        "LineString",
+				Coordinates: [][2]float64{{a.lng, a.lat}, {b.lng, b.lat}},
+			},
+			Properties: map[string]interface{}{
+				"id":        e.id,
+				"length_m":  e.length,
+				"walk_time": max(cs, ct),
+			},
+		})
+	}
+
+	raw, err := json.Marshal(fc)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+// ReachableRoads 独立做一次路网分析得到可达道路
+func (s *Store) ReachableRoads(ctx context.Context, lng, lat float64, minutes int, walkSpeed float64) (string, error) {
+	r, err := s.Reach(ctx, lng, lat, walkSpeed, minutes)
+	if err != nil {
+		return "", err
+	}
+	return s.RoadsFromReach(ctx, r, minutes)
+}
+
+// PrecomputeReach 预计算单个节点的可达性
+func (s *Store) PrecomputeReach(ctx context.Context, node int64, walkSpeed float64, maxMinutes int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.nodes[node]; !ok {
+		return 0, fmt.Errorf("precompute node %d: node not found", node)
+	}
+	speed := speedBucket(walkSpeed)
+	nodes, costs := s.dijkstra(node, speed, float64(maxMinutes))
+	s.reach[reachKey{node, speed}] = &reachEntry{maxMinutes: maxMinutes, nodes: nodes, costs: costs}
+	return len(nodes), nil
+}
+
+// dijkstra 从 source 出发，返回 maxMinutes 内可达的节点及步行时间（按时间升序）
+// 调用方需持有锁
+func (s *Store) dijkstra(source int64, walkSpeed, maxMinutes float64) ([]int64, []float64) {
+	speed := metersPerMinute(walkSpeed)
+	best := map[int64]float64{source: 0}
+	done := make(map[int64]bool)
+	pq := &queue{{node: source}}
+
+	var nodes []int64
+	var costs []float64
+	for pq.Len() > 0 {
+		cur := heap.Pop(pq).(item)
+		if done[cur.node] {
+			continue
+		}
+		done[cur.node] = true
+		nodes = append(nodes, cur.node)
+		costs = append(costs, cur.cost)
+
+		for _, a := range s.adj[cur.node] {
+			c := cur.cost + a.length/speed
+			if c > maxMinutes || done[a.to] {
+				continue
+			}
+			if old, ok := best[a.to]; !ok || c < old {
+				best[a.to] = c
+				heap.Push(pq, item{node: a.to, cost: c})
+			}
+		}
+	}
+	return nodes, costs
+}
+
+type item struct {
+	node int64
+	cost float64
+}
+
+// queue 按累计时间排序的优先队列，时间相同按节点 ID，保证结果稳定
+type queue []item
+
+func (q queue) Len() int { return len(q) }
+func (q queue) Less(i, j int) bool {
+	if q[i].cost != q[j].cost {
+		return q[i].cost < q[j].cost
+	}
+	return q[i].node < q[j].node
+}
+func (q queue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *queue) Push(x any)   { *q = append(*q, x.(item)) }
+func (q *queue) Pop() any {
+	old := *q
+	it := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return it
+}
@@ -0,0 +1,187 @@
+// Package memory 以内存数据实现 internal/store 中的存储接口
+//
+// 路网分析按边长做 Dijkstra，等时圈取可达节点的凸包，空间判断用点在多边形内，
+// 足以在不依赖数据库的情况下验证服务层逻辑；结果形状与 PostGIS 凹壳并不完全一致。
+package memory
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/store"
+)
+
+// Store 内存存储，同时实现 IsochroneStore、POIStore、StandardStore、HistoryStore
+type Store struct {
+	mu sync.RWMutex
+
+	nodes map[int64]node
+	adj   map[int64][]arc
+	edges []edge
+
+	pois    map[int64]*model.POIRecord
+	nextPOI int64
+	audits  []model.POIAudit
+
+	categories []model.POICategory
+	subTypes   map[string]string
+	standards  []model.EvaluationStandard
+
+	versions map[string]int64
+	reach    map[reachKey]*reachEntry
+
+	history     []*model.AnalysisRecord
+	nextHistory int
+}
+
+type node struct {
+	lng, lat float64
+	city     string
+}
+
+type arc struct {
+	to     int64
+	length float64
+}
+
+type edge struct {
+	id             int64
+	source, target int64
+	length         float64
+}
+
+type reachKey struct {
+	node  int64
+	speed float64
+}
+
+type reachEntry struct {
+	maxMinutes int
+	nodes      []int64
+	costs      []float64
+}
+
+// New 创建空的内存存储，POI 分类使用 model.GetDefaultCategories
+func New() *Store {
+	s := &Store{
+		nodes:    make(map[int64]node),
+		adj:      make(map[int64][]arc),
+		pois:     make(map[int64]*model.POIRecord),
+		versions: make(map[string]int64),
+		reach:    make(map[reachKey]*reachEntry),
+	}
+	s.SetCategories(model.GetDefaultCategories())
+	return s
+}
+
+// Stores 以同一内存存储提供全部接口
+func (s *Store) Stores() store.Stores {
+	return store.Stores{Isochrones: s, POIs: s, Standards: s, History: s}
+}
+
+// AddNode 添加路网节点
+func (s *Store) AddNode(id int64, lng, lat float64, city string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nodes[id] = node{lng: lng, lat: lat, city: city}
+}
+
+// AddEdge 添加双向步行道路，length 为空时按节点间直线距离计
+func (s *Store) AddEdge(id, source, target int64, length float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if length <= 0 {
+		a, b := s.nodes[source], s.nodes[target]
+		length = distanceMeters(a.lng, a.lat, b.lng, b.lat)
+	}
+	s.edges = append(s.edges, edge{id: id, source: source, target: target, length: length})
+	s.adj[source] = append(s.adj[source], arc{to: target, length: length})
+	s.adj[target] = append(s.adj[target], arc{to: source, length: length})
+}
+
+// AddPOI 添加 POI，ID 为 0 时自动分配
+func (s *Store) AddPOI(p model.POIRecord) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p.ID == 0 {
+		s.nextPOI++
+		p.ID = s.nextPOI
+	} else if p.ID > s.nextPOI {
+		s.nextPOI = p.ID
+	}
+	if p.Source == "" {
+		p.Source = model.SourceOSM
+	}
+	if p.UpdatedAt.IsZero() {
+		p.UpdatedAt = time.Now()
+	}
+	s.pois[p.ID] = &p
+	return p.ID
+}
+
+// SetCategories 设置 POI 分类，子类型用于新增、重新分类时推导分类
+func (s *Store) SetCategories(categories []model.POICategory) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.categories = categories
+	s.subTypes = make(map[string]string)
+	for _, c := range categories {
+		for _, st := range c.SubTypes {
+			s.subTypes[st.Code] = c.Code
+		}
+	}
+}
+
+// SetStandards 设置评价标准，为空时 Standards 返回空（服务层回退默认标准）
+func (s *Store) SetStandards(standards []model.EvaluationStandard) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.standards = standards
+}
+
+// BumpNetworkVersion 路网更新后递增城市路网版本并清除该城市的节点可达性预计算
+// 对应数据库函数 bump_network_version
+func (s *Store) BumpNetworkVersion(city string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.versions[city]++
+	for k := range s.reach {
+		if s.nodes[k.node].city == city {
+			delete(s.reach, k)
+		}
+	}
+	return s.versions[city]
+}
+
+// metersPerDegree 纬度 1 度对应的距离（米）
+const metersPerDegree = 111320.0
+
+// distanceMeters 等距圆柱投影下两点间距离（米），适用于城市尺度
+func distanceMeters(lng1, lat1, lng2, lat2 float64) float64 {
+	x := (lng2 - lng1) * metersPerDegree * math.Cos((lat1+lat2)/2*math.Pi/180)
+	y := (lat2 - lat1) * metersPerDegree
+	return math.Hypot(x, y)
+}
+
+// metersPerMinute 步行速度 km/h 换算为 m/min
+func metersPerMinute(walkSpeed float64) float64 {
+	return walkSpeed * 1000 / 60
+}
+
+// speedBucket 速度档（0.1 km/h），与数据库函数 speed_bucket 一致
+func speedBucket(speed float64) float64 {
+	b := math.Round(speed*10) / 10
+	if b <= 0 {
+		b = 0.1
+	}
+	return b
+}
+
+var (
+	_ store.IsochroneStore = (*Store)(nil)
+	_ store.POIStore       = (*Store)(nil)
+	_ store.StandardStore  = (*Store)(nil)
+	_ store.HistoryStore   = (*Store)(nil)
+)
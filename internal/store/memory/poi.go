@@ -0,0 +1,499 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/store"
+)
+
+// POIsInIsochrone 查询起点 minutes 分钟等时圈内的 POI（按距离排序）
+func (s *Store) POIsInIsochrone(ctx context.Context, lng, lat float64, minutes int, walkSpeed float64) ([]model.POI, error) {
+	ps, err := s.isochrone(ctx, lng, lat, minutes, walkSpeed)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var pois []model.POI
+	for _, p := range s.sortedPOIs() {
+		if ps.contains(p.Lng, p.Lat) {
+			pois = append(pois, toPOI(p, lng, lat, walkSpeed))
+		}
+	}
+	sortByDistance(pois)
+	return pois, nil
+}
+
+// POIsInIsochrones 查询最大等时圈内的 POI，并标记各 POI 所在的最小圈层
+func (s *Store) POIsInIsochrones(ctx context.Context, lng, lat, walkSpeed float64, polygons []model.IsochronePolygon) ([]model.POI, error) {
+	sorted := append([]model.IsochronePolygon(nil), polygons...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Minutes < sorted[j].Minutes })
+	sets := make([]polygonSet, len(sorted))
+	for i, p := range sorted {
+		ps, err := toPolygonSet(p.Geometry)
+		if err != nil {
+			return nil, fmt.Errorf("isochrone %d: %w", p.Minutes, err)
+		}
+		sets[i] = ps
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var pois []model.POI
+	for _, p := range s.sortedPOIs() {
+		for i, ps := range sets {
+			if ps.contains(p.Lng, p.Lat) {
+				poi := toPOI(p, lng, lat, walkSpeed)
+				poi.WithinMinutes = sorted[i].Minutes
+				pois = append(pois, poi)
+				break
+			}
+		}
+	}
+	sortByDistance(pois)
+	return pois, nil
+}
+
+// CountPOIsByCategory 统计等时圈内各子类型的 POI 数量
+func (s *Store) CountPOIsByCategory(ctx context.Context, lng, lat float64, minutes int, walkSpeed float64) ([]model.POIStatistics, error) {
+	pois, err := s.POIsInIsochrone(ctx, lng, lat, minutes, walkSpeed)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[[2]string]int)
+	for _, p := range pois {
+		counts[[2]string{p.Category, p.SubType}]++
+	}
+	stats := make([]model.POIStatistics, 0, len(counts))
+	for k, n := range counts {
+		stats = append(stats, model.POIStatistics{Category: k[0], SubType: k[1], Count: n})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Category != stats[j].Category {
+			return stats[i].Category < stats[j].Category
+		}
+		return stats[i].SubType < stats[j].SubType
+	})
+	return stats, nil
+}
+
+// POIsInPolygon 查询 GeoJSON 多边形内未删除的 POI
+func (s *Store) POIsInPolygon(ctx context.Context, geojson string) ([]model.POI, error) {
+	ps, err := parseGeometry([]byte(geojson))
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var pois []model.POI
+	for _, p := range s.sortedPOIs() {
+		if ps.contains(p.Lng, p.Lat) {
+			poi := toPOI(p, 0, 0, 0)
+			pois = append(pois, poi)
+		}
+	}
+	sort.SliceStable(pois, func(i, j int) bool {
+		if pois[i].Category != pois[j].Category {
+			return pois[i].Category < pois[j].Category
+		}
+		return pois[i].SubType < pois[j].SubType
+	})
+	return pois, nil
+}
+
+// FilterInPolygon 保留位于 GeoJSON 多边形内的 POI
+func (s *Store) FilterInPolygon(ctx context.Context, pois []model.POI, geojson string) ([]model.POI, error) {
+	ps, err := parseGeometry([]byte(geojson))
+	if err != nil {
+		return nil, err
+	}
+	var filtered []model.POI
+	for _, p := range pois {
+		if ps.contains(p.Lng, p.Lat) {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered, nil
+}
+
+// SearchPOIs 按空间范围与属性过滤搜索 POI，游标分页
+// minutes 过滤取 reach 等时圈内的 POI，walk_time_min 为最近节点的路网时间加节点到 POI 的步行时间
+func (s *Store) SearchPOIs(ctx context.Context, req *model.POISearchRequest, reach *store.SearchReach) (*model.POISearchResult, error) {
+	byDistance := req.Lng != nil
+	var (
+		area    polygonSet
+		bbox    [4]float64
+		afterD  float64
+		afterID int64
+		err     error
+	)
+	switch req.Mode() {
+	case model.SearchBBox:
+		if bbox, err = store.ParseBBox(req.BBox); err != nil {
+			return nil, err
+		}
+	case model.SearchPolygon:
+		if area, err = parseGeometry([]byte(req.Polygon)); err != nil {
+			return nil, fmt.Errorf("%w: %v", store.ErrInvalidSearch, err)
+		}
+	case model.SearchMinutes:
+		if reach == nil {
+			return nil, fmt.Errorf("%w: minutes search without reach", store.ErrInvalidSearch)
+		}
+		if area, err = toPolygonSet(reach.Polygon); err != nil {
+			return nil, err
+		}
+	}
+	if req.Cursor != "" {
+		if afterD, afterID, err = store.DecodeCursor(req.Cursor); err != nil {
+			return nil, err
+		}
+	}
+	categories := model.SplitList(req.Category)
+	subTypes := model.SplitList(req.SubType)
+	sources := model.SplitList(req.Source)
+	name := strings.ToLower(req.Name)
+
+	var cost map[int64]float64
+	if reach != nil {
+		cost = make(map[int64]float64, len(reach.Reach.Nodes))
+		for i, id := range reach.Reach.Nodes {
+			cost[id] = reach.Reach.Costs[i]
+		}
+	}
+
+	s.mu.RLock()
+	var pois []model.POI
+	for _, p := range s.sortedPOIs() {
+		poi := toPOI(p, 0, 0, 0)
+		if byDistance {
+			poi = toPOI(p, *req.Lng, *req.Lat, req.WalkSpeed)
+			poi.WalkTimeMin = 0
+		}
+		switch req.Mode() {
+		case model.SearchBBox:
+			if p.Lng < bbox[0] || p.Lat < bbox[1] || p.Lng > bbox[2] || p.Lat > bbox[3] {
+				continue
+			}
+		case model.SearchRadius:
+			if poi.DistanceM > req.Radius {
+				continue
+			}
+		case model.SearchPolygon, model.SearchMinutes:
+			if !area.contains(p.Lng, p.Lat) {
+				continue
+			}
+		}
+		if !matches(categories, p.Category) || !matches(subTypes, p.SubType) || !matches(sources, poi.Source) {
+			continue
+		}
+		if name != "" && !strings.Contains(strings.ToLower(p.Name), name) {
+			continue
+		}
+		if req.Cursor != "" {
+			if byDistance && (poi.DistanceM < afterD || (poi.DistanceM == afterD && p.ID <= afterID)) {
+				continue
+			}
+			if !byDistance && p.ID <= afterID {
+				continue
+			}
+		}
+		// 最近节点的路网时间加吸附距离的步行时间（同 PostGIS 实现）
+		if cost != nil {
+			snap := s.coverage(p.Lng, p.Lat)
+			if c, ok := cost[snap.Node]; ok {
+				poi.WalkTimeMin = c + snap.SnapDistance/metersPerMinute(reach.Reach.WalkSpeed)
+			}
+		}
+		pois = append(pois, poi)
+	}
+	s.mu.RUnlock()
+
+	if byDistance {
+		sortByDistance(pois)
+	}
+	result := &model.POISearchResult{}
+	if len(pois) > req.Limit {
+		pois = pois[:req.Limit]
+		last := pois[len(pois)-1]
+		result.NextCursor = store.EncodeCursor(last.DistanceM, last.ID)
+	}
+	if pois == nil {
+		pois = []model.POI{}
+	}
+	result.POIs = pois
+	result.Count = len(pois)
+	return result, nil
+}
+
+// Categories POI 分类
+func (s *Store) Categories(ctx context.Context) ([]model.POICategory, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]model.POICategory(nil), s.categories...), nil
+}
+
+// GetPOI 获取单个 POI（含已删除）
+func (s *Store) GetPOI(ctx context.Context, id int64) (*model.POIRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.pois[id]
+	if !ok {
+		return nil, store.ErrPOINotFound
+	}
+	return clonePOI(p), nil
+}
+
+// CreatePOI 人工新增 POI，城市为空时按覆盖范围推断
+func (s *Store) CreatePOI(ctx context.Context, actor string, req *model.POICreateRequest) (*model.POIEditResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	category, err := s.subTypeCategory(req.SubType)
+	if err != nil {
+		return nil, err
+	}
+	city := req.City
+	if city == "" {
+		city = s.coverage(req.Lng, req.Lat).City
+	}
+	s.nextPOI++
+	p := &model.POIRecord{
+		ID:        s.nextPOI,
+		Name:      req.Name,
+		Category:  category,
+		SubType:   req.SubType,
+		Lng:       req.Lng,
+		Lat:       req.Lat,
+		Address:   req.Address,
+		Tags:      cloneTags(req.Tags),
+		Source:    model.SourceManual,
+		City:      city,
+		UpdatedBy: actor,
+		UpdatedAt: time.Now(),
+	}
+	s.pois[p.ID] = p
+	return s.audit(nil, p, model.AuditCreate, actor, req.Comment), nil
+}
+
+// UpdatePOI 人工修改 POI 名称、位置、地址或标签
+func (s *Store) UpdatePOI(ctx context.Context, actor string, id int64, req *model.POIUpdateRequest) (*model.POIEditResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, err := s.livePOI(id)
+	if err != nil {
+		return nil, err
+	}
+	before := clonePOI(p)
+	if req.Name != nil {
+		p.Name = *req.Name
+	}
+	if req.Lng != nil {
+		p.Lng = *req.Lng
+	}
+	if req.Lat != nil {
+		p.Lat = *req.Lat
+	}
+	if req.Address != nil {
+		p.Address = *req.Address
+	}
+	// 与 PostGIS 存储一致：按键合并，值为空的键删除
+	for k, v := range req.Tags {
+		if p.Tags == nil {
+			p.Tags = make(map[string]string)
+		}
+		if v == "" {
+			delete(p.Tags, k)
+		} else {
+			p.Tags[k] = v
+		}
+	}
+	s.touch(p, actor)
+	return s.audit(before, p, model.AuditUpdate, actor, req.Comment), nil
+}
+
+// ReclassifyPOI 人工修改 POI 子类型
+func (s *Store) ReclassifyPOI(ctx context.Context, actor string, id int64, req *model.POIReclassifyRequest) (*model.POIEditResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, err := s.livePOI(id)
+	if err != nil {
+		return nil, err
+	}
+	category, err := s.subTypeCategory(req.SubType)
+	if err != nil {
+		return nil, err
+	}
+	before := clonePOI(p)
+	p.Category, p.SubType = category, req.SubType
+	s.touch(p, actor)
+	return s.audit(before, p, model.AuditReclassify, actor, req.Comment), nil
+}
+
+// DeletePOI 软删除 POI
+func (s *Store) DeletePOI(ctx context.Context, actor string, id int64, comment string) (*model.POIEditResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, err := s.livePOI(id)
+	if err != nil {
+		return nil, err
+	}
+	before := clonePOI(p)
+	s.touch(p, actor)
+	deleted := p.UpdatedAt
+	p.DeletedAt = &deleted
+	return s.audit(before, p, model.AuditDelete, actor, comment), nil
+}
+
+// POIHistory POI 审计记录（新到旧）
+func (s *Store) POIHistory(ctx context.Context, id int64) ([]model.POIAudit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var audits []model.POIAudit
+	for i := len(s.audits) - 1; i >= 0; i-- {
+		if s.audits[i].POIID == id {
+			audits = append(audits, s.audits[i])
+		}
+	}
+	return audits, nil
+}
+
+// isochrone 计算起点 minutes 分钟等时圈
+func (s *Store) isochrone(ctx context.Context, lng, lat float64, minutes int, walkSpeed float64) (polygonSet, error) {
+	r, err := s.Reach(ctx, lng, lat, walkSpeed, minutes)
+	if err != nil {
+		return nil, err
+	}
+	polygons, err := s.IsochronesFromReach(ctx, r, []int{minutes})
+	if err != nil {
+		return nil, err
+	}
+	return toPolygonSet(polygons[0].Geometry)
+}
+
+// sortedPOIs 未删除的 POI（按 ID 排序），调用方需持有锁
+func (s *Store) sortedPOIs() []*model.POIRecord {
+	out := make([]*model.POIRecord, 0, len(s.pois))
+	for _, p := range s.pois {
+		if p.DeletedAt == nil {
+			out = append(out, p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// livePOI 读取未删除的 POI，调用方需持有锁
+func (s *Store) livePOI(id int64) (*model.POIRecord, error) {
+	p, ok := s.pois[id]
+	if !ok || p.DeletedAt != nil {
+		return nil, store.ErrPOINotFound
+	}
+	return p, nil
+}
+
+func (s *Store) subTypeCategory(subType string) (string, error) {
+	category, ok := s.subTypes[subType]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", store.ErrUnknownSubType, subType)
+	}
+	return category, nil
+}
+
+// touch 标记为人工维护，重新导入时不覆盖
+func (s *Store) touch(p *model.POIRecord, actor string) {
+	p.Source = model.SourceManual
+	p.UpdatedBy = actor
+	p.UpdatedAt = time.Now()
+}
+
+// audit 写入审计记录并使原位置、新位置所在的评价记录失效，调用方需持有锁
+func (s *Store) audit(before, after *model.POIRecord, action, actor, comment string) *model.POIEditResult {
+	s.audits = append(s.audits, model.POIAudit{
+		ID:        int64(len(s.audits) + 1),
+		POIID:     after.ID,
+		Action:    action,
+		Actor:     actor,
+		Before:    before,
+		After:     clonePOI(after),
+		Comment:   comment,
+		CreatedAt: after.UpdatedAt,
+	})
+
+	points := [][2]float64{{after.Lng, after.Lat}}
+	if before != nil {
+		points = append(points, [2]float64{before.Lng, before.Lat})
+	}
+	return &model.POIEditResult{POI: clonePOI(after), InvalidatedAnalyses: s.invalidateAnalyses(points)}
+}
+
+func toPOI(p *model.POIRecord, lng, lat, walkSpeed float64) model.POI {
+	poi := model.POI{
+		ID:           p.ID,
+		Name:         p.Name,
+		Category:     p.Category,
+		SubType:      p.SubType,
+		Lng:          p.Lng,
+		Lat:          p.Lat,
+		Address:      p.Address,
+		Source:       p.Source,
+		OpeningHours: p.Tags["opening_hours"],
+	}
+	if walkSpeed > 0 {
+		poi.DistanceM = distanceMeters(lng, lat, p.Lng, p.Lat)
+		poi.WalkTimeMin = poi.DistanceM / metersPerMinute(walkSpeed)
+	}
+	return poi
+}
+
+func sortByDistance(pois []model.POI) {
+	sort.SliceStable(pois, func(i, j int) bool {
+		if pois[i].DistanceM != pois[j].DistanceM {
+			return pois[i].DistanceM < pois[j].DistanceM
+		}
+		return pois[i].ID < pois[j].ID
+	})
+}
+
+func matches(values []string, v string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// clonePOI 深拷贝，经 JSON 往返与审计快照保持一致
+func clonePOI(p *model.POIRecord) *model.POIRecord {
+	raw, _ := json.Marshal(p)
+	var c model.POIRecord
+	_ = json.Unmarshal(raw, &c)
+	return &c
+}
+
+func cloneTags(tags map[string]string) map[string]string {
+	if tags == nil {
+		return nil
+	}
+	c := make(map[string]string, len(tags))
+	for k, v := range tags {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,153 @@
+package postgis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/store"
+)
+
+// historyIsochrone GeoJSON 转为 analysis_history 的 MultiPolygon 列（021 迁移）
+const historyIsochrone = `ST_Multi(ST_CollectionExtract(ST_SetSRID(ST_GeomFromGeoJSON(%s), 4326), 3))`
+
+// SaveAnalysis 保存评价记录，5/10/15 分钟等时圈用于 POI 变更时使记录失效
+func (s *HistoryStore) SaveAnalysis(ctx context.Context, rec *model.AnalysisRecord) (string, error) {
+	isochrones := make(map[int]*string)
+	for _, p := range rec.Isochrones {
+		b, err := json.Marshal(p.Geometry)
+		if err != nil {
+			return "", fmt.Errorf("encode isochrone: %w", err)
+		}
+		geojson := string(b)
+		isochrones[p.Minutes] = &geojson
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO analysis_history (
+			origin, lng, lat, time_thresholds, walk_speed, total_score, grade, result_json,
+			isochrone_5, isochrone_10, isochrone_15, user_id, place_id, params,
+			share_id, city, network_version
+		)
+		VALUES (
+			ST_SetSRID(ST_MakePoint($1, $2), 4326), $1, $2, $3, $4, $5, NULLIF($6, ''), $7,
+			%s, %s, %s, $11, $12, $13,
+			NULLIF($14, ''), (SELECT city FROM ways_vertices_pgr WHERE id = $15), node_network_version($15)
+		)
+		RETURNING id::text`,
+		fmt.Sprintf(historyIsochrone, "$8"), fmt.Sprintf(historyIsochrone, "$9"), fmt.Sprintf(historyIsochrone, "$10"))
+
+	var id string
+	err := s.db.Pool.QueryRow(ctx, query,
+		rec.Lng, rec.Lat, rec.TimeThresholds, rec.WalkSpeed, rec.TotalScore, rec.Grade, rec.Result,
+		isochrones[5], isochrones[10], isochrones[15],
+		rec.UserID, rec.PlaceID, rec.Params,
+		rec.ShareID, rec.Node,
+	).Scan(&id)
+	if err != nil {
+		return "", fmt.Errorf("save analysis: %w", err)
+	}
+	return id, nil
+}
+
+// GetAnalysis 读取评价记录
+func (s *HistoryStore) GetAnalysis(ctx context.Context, id string) (*model.AnalysisRecord, error) {
+	rec := &model.AnalysisRecord{}
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT `+analysisColumns+`, a.result_json
+		FROM analysis_history a
+		LEFT JOIN saved_place p ON p.id = a.place_id
+		WHERE a.id = $1::uuid`, id,
+	).Scan(append(analysisFields(rec), &rec.Result)...)
+	if errors.Is(err, pgx.ErrNoRows) || isInvalidText(err) {
+		return nil, store.ErrAnalysisNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get analysis: %w", err)
+	}
+	return rec, nil
+}
+
+// GetSharedAnalysis 按分享链接 ID 读取评价记录
+func (s *HistoryStore) GetSharedAnalysis(ctx context.Context, shareID string) (*model.AnalysisRecord, error) {
+	rec := &model.AnalysisRecord{}
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT `+analysisColumns+`, a.result_json, COALESCE(nv.version, 0)
+		FROM analysis_history a
+		LEFT JOIN saved_place p ON p.id = a.place_id
+		LEFT JOIN network_version nv ON nv.city = COALESCE(a.city, '')
+		WHERE a.share_id = $1`, shareID,
+	).Scan(append(analysisFields(rec), &rec.Result, &rec.CurrentNetworkVersion)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, store.ErrAnalysisNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get shared analysis: %w", err)
+	}
+	return rec, nil
+}
+
+// analysisColumns 评价记录列表字段（不含 result_json），需 JOIN saved_place p
+const analysisColumns = `
+	a.id::text, a.lng::float8, a.lat::float8, COALESCE(a.time_thresholds, '{}'), COALESCE(a.walk_speed, 0)::float8,
+	COALESCE(a.total_score, 0)::float8, COALESCE(a.grade, ''), a.created_at, a.invalidated_at,
+	a.user_id, a.place_id, COALESCE(p.name, ''), a.params,
+	COALESCE(a.share_id, ''), COALESCE(a.city, ''), COALESCE(a.network_version, 0)`
+
+func analysisFields(rec *model.AnalysisRecord) []any {
+	return []any{&rec.ID, &rec.Lng, &rec.Lat, &rec.TimeThresholds, &rec.WalkSpeed,
+		&rec.TotalScore, &rec.Grade, &rec.CreatedAt, &rec.InvalidatedAt,
+		&rec.UserID, &rec.PlaceID, &rec.PlaceName, &rec.Params,
+		&rec.ShareID, &rec.City, &rec.NetworkVersion}
+}
+
+// ListAnalyses 用户的评价记录（新到旧，不含 Result）
+func (s *HistoryStore) ListAnalyses(ctx context.Context, userID int, before *time.Time, limit int) ([]model.AnalysisRecord, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT `+analysisColumns+`
+		FROM analysis_history a
+		LEFT JOIN saved_place p ON p.id = a.place_id
+		WHERE a.user_id = $1 AND ($2::timestamp IS NULL OR a.created_at < $2)
+		ORDER BY a.created_at DESC
+		LIMIT $3`, userID, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list analyses: %w", err)
+	}
+	defer rows.Close()
+
+	records := make([]model.AnalysisRecord, 0)
+	for rows.Next() {
+		var rec model.AnalysisRecord
+		if err := rows.Scan(analysisFields(&rec)...); err != nil {
+			return nil, fmt.Errorf("scan analysis: %w", err)
+		}
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
+
+// DeleteAnalysis 删除用户的评价记录
+func (s *HistoryStore) DeleteAnalysis(ctx context.Context, userID int, id string) error {
+	tag, err := s.db.Pool.Exec(ctx, `DELETE FROM analysis_history WHERE id = $1::uuid AND user_id = $2`, id, userID)
+	if isInvalidText(err) {
+		return store.ErrAnalysisNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("delete analysis: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return store.ErrAnalysisNotFound
+	}
+	return nil
+}
+
+// isInvalidText 参数格式错误（如非法 UUID）
+func isInvalidText(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "22P02"
+}
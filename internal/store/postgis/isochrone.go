@@ -0,0 +1,199 @@
+package postgis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/store"
+)
+
+// Coverage 检查起点是否在数据覆盖范围内，并报告最近节点与吸附距离
+func (s *IsochroneStore) Coverage(ctx context.Context, lng, lat float64) (*model.CoverageInfo, error) {
+	query := `
+		SELECT
+			in_coverage,
+			COALESCE(city, ''),
+			COALESCE(snap_distance_m, -1),
+			COALESCE(nearest_node, 0)
+		FROM check_origin_coverage($1, $2)
+	`
+
+	info := &model.CoverageInfo{}
+	if err := s.db.Pool.QueryRow(ctx, query, lng, lat).Scan(
+		&info.InCoverage,
+		&info.City,
+		&info.SnapDistance,
+		&info.Node,
+	); err != nil {
+		return nil, fmt.Errorf("check coverage: %w", err)
+	}
+	info.Snapped = info.SnapDistance >= 0 && info.SnapDistance <= model.MaxSnapDistanceMeters
+
+	return info, nil
+}
+
+// Isochrones 调用数据库函数计算各时间阈值的等时圈
+// 默认凹壳算法沿用 calculate_isochrones（含道路中点采样），其余算法见 015 迁移，
+// 按坡度计算步行时间（terrain）见 018 迁移；
+// 结果再按水面、铁路、高速公路屏障裁剪（016 迁移），ignore_barriers 时跳过
+func (s *IsochroneStore) Isochrones(ctx context.Context, req *model.IsochroneRequest) ([]model.IsochronePolygon, error) {
+	var method, terrain *string
+	var param, penalty *float64
+	if req.PolygonMethod != model.PolygonConcave || req.PolygonParam > 0 {
+		method = &req.PolygonMethod
+		if req.PolygonParam > 0 {
+			param = &req.PolygonParam
+		}
+	}
+	if req.Terrain {
+		terrain = &req.TerrainModel
+		if req.SlopePenalty > 0 {
+			penalty = &req.SlopePenalty
+		}
+	}
+
+	query := `
+		SELECT
+			minutes,
+			distance_m,
+			geojson,
+			COALESCE(fallback, ''),
+			clipped
+		FROM calculate_isochrones_barrier($1, $2, $3, $4, $5, $6, $7, $8)
+		ORDER BY minutes
+	`
+	args := []any{req.Lng, req.Lat, req.TimeThresholds, req.WalkSpeed, method, param, terrain, penalty}
+	if req.IgnoreBarriers {
+		query = `
+			SELECT
+				minutes,
+				distance_m,
+				geojson,
+				COALESCE(fallback, ''),
+				FALSE
+			FROM calculate_isochrones($1, $2, $3, $4)
+			ORDER BY minutes
+		`
+		args = args[:4]
+		if method != nil || terrain != nil {
+			query = `
+				SELECT
+					minutes,
+					distance_m,
+					geojson,
+					COALESCE(fallback, ''),
+					FALSE
+				FROM calculate_isochrones_method($1, $2, $3, $4, $5, $6, $7, $8)
+				ORDER BY minutes
+			`
+			args = append(args, req.PolygonMethod, param, terrain, penalty)
+		}
+	}
+
+	rows, err := s.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("calculate isochrones: %w", err)
+	}
+	polygons, err := scanPolygons(rows, req.PolygonMethod)
+	if err != nil {
+		return nil, fmt.Errorf("calculate isochrones: %w", err)
+	}
+	return polygons, nil
+}
+
+// Reach 吸附起点并做一次路网分析，节点已预计算（node_reach，020 迁移）时直接读取
+func (s *IsochroneStore) Reach(ctx context.Context, lng, lat, walkSpeed float64, maxMinutes int) (*store.ReachSet, error) {
+	r := &store.ReachSet{
+		Lng:        lng,
+		Lat:        lat,
+		WalkSpeed:  walkSpeed,
+		MaxMinutes: maxMinutes,
+		Coverage:   &model.CoverageInfo{},
+	}
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT in_coverage, COALESCE(city, ''), COALESCE(snap_distance_m, -1), COALESCE(source_node, 0), nodes, costs, cached
+		FROM evaluation_reach($1, $2, $3, $4, $5)`,
+		lng, lat, walkSpeed, maxMinutes, float64(model.MaxSnapDistanceMeters),
+	).Scan(&r.Coverage.InCoverage, &r.Coverage.City, &r.Coverage.SnapDistance, &r.Coverage.Node, &r.Nodes, &r.Costs, &r.Cached)
+	if err != nil {
+		return nil, fmt.Errorf("evaluation reach: %w", err)
+	}
+	r.Coverage.Snapped = r.Coverage.SnapDistance >= 0 && r.Coverage.SnapDistance <= model.MaxSnapDistanceMeters
+
+	return r, nil
+}
+
+// IsochronesFromReach 由可达节点生成各时间阈值的等时圈（isochrones_from_reach）
+func (s *IsochroneStore) IsochronesFromReach(ctx context.Context, r *store.ReachSet, thresholds []int) ([]model.IsochronePolygon, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT minutes, distance_m, geojson, COALESCE(fallback, ''), clipped
+		FROM isochrones_from_reach($1, $2, $3, $4, $5, $6)
+		ORDER BY minutes`,
+		r.Lng, r.Lat, thresholds, r.WalkSpeed, r.Nodes, r.Costs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("isochrones from reach: %w", err)
+	}
+	polygons, err := scanPolygons(rows, model.PolygonConcave)
+	if err != nil {
+		return nil, fmt.Errorf("isochrones from reach: %w", err)
+	}
+	return polygons, nil
+}
+
+// RoadsFromReach 由可达节点得到 minutes 内两端均可达的道路
+func (s *IsochroneStore) RoadsFromReach(ctx context.Context, r *store.ReachSet, minutes int) (string, error) {
+	var geojson string
+	err := s.db.Pool.QueryRow(ctx, `SELECT roads_from_reach($1, $2, $3)`, minutes, r.Nodes, r.Costs).Scan(&geojson)
+	if err != nil {
+		return "", fmt.Errorf("roads from reach: %w", err)
+	}
+	return geojson, nil
+}
+
+// ReachableRoads 获取可达道路网络
+func (s *IsochroneStore) ReachableRoads(ctx context.Context, lng, lat float64, minutes int, walkSpeed float64) (string, error) {
+	query := `SELECT road_geojson FROM get_reachable_roads($1, $2, $3, $4)`
+
+	var geojson string
+	err := s.db.Pool.QueryRow(ctx, query, lng, lat, minutes, walkSpeed).Scan(&geojson)
+	if err != nil {
+		return "", fmt.Errorf("get reachable roads: %w", err)
+	}
+
+	return geojson, nil
+}
+
+// scanPolygons 读取 (minutes, distance_m, geojson, fallback, clipped) 结果行
+func scanPolygons(rows pgx.Rows, method string) ([]model.IsochronePolygon, error) {
+	defer rows.Close()
+
+	var polygons []model.IsochronePolygon
+	for rows.Next() {
+		var (
+			poly       = model.IsochronePolygon{Method: method}
+			geojsonStr string
+		)
+		if err := rows.Scan(&poly.Minutes, &poly.Distance, &geojsonStr, &poly.Fallback, &poly.Clipped); err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(geojsonStr), &poly.Geometry); err != nil {
+			return nil, fmt.Errorf("parse geojson: %w", err)
+		}
+		polygons = append(polygons, poly)
+	}
+	return polygons, rows.Err()
+}
+
+// PrecomputeReach 预计算单个节点的可达性（node_reach）
+func (s *IsochroneStore) PrecomputeReach(ctx context.Context, node int64, walkSpeed float64, maxMinutes int) (int, error) {
+	var n int
+	err := s.db.Pool.QueryRow(ctx, `SELECT precompute_node_reach($1, $2, $3)`, node, walkSpeed, maxMinutes).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("precompute node %d: %w", node, err)
+	}
+	return n, nil
+}
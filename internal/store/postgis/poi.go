@@ -0,0 +1,284 @@
+package postgis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yourname/15min-life-circle/internal/model"
+)
+
+// POIsInIsochrone 查询等时圈内的 POI（query_pois_in_isochrone）
+func (s *POIStore) POIsInIsochrone(ctx context.Context, lng, lat float64, minutes int, walkSpeed float64) ([]model.POI, error) {
+	query := `
+		SELECT
+			id,
+			COALESCE(name, '') AS name,
+			category,
+			sub_type,
+			lng,
+			lat,
+			distance_m,
+			walk_time_min,
+			COALESCE(opening_hours, '')
+		FROM query_pois_in_isochrone($1, $2, $3, $4, NULL)
+	`
+
+	rows, err := s.db.Pool.Query(ctx, query, lng, lat, minutes, walkSpeed)
+	if err != nil {
+		return nil, fmt.Errorf("query pois: %w", err)
+	}
+	defer rows.Close()
+
+	var pois []model.POI
+	for rows.Next() {
+		var poi model.POI
+		if err := rows.Scan(
+			&poi.ID,
+			&poi.Name,
+			&poi.Category,
+			&poi.SubType,
+			&poi.Lng,
+			&poi.Lat,
+			&poi.DistanceM,
+			&poi.WalkTimeMin,
+			&poi.OpeningHours,
+		); err != nil {
+			return nil, fmt.Errorf("scan poi: %w", err)
+		}
+		pois = append(pois, poi)
+	}
+
+	return pois, rows.Err()
+}
+
+// POIsInIsochrones 查询最大等时圈内的 POI 及其所在的最小圈层（pois_in_isochrones）
+func (s *POIStore) POIsInIsochrones(ctx context.Context, lng, lat, walkSpeed float64, polygons []model.IsochronePolygon) ([]model.POI, error) {
+	minutes := make([]int, len(polygons))
+	geojsons := make([]string, len(polygons))
+	for i, p := range polygons {
+		b, err := json.Marshal(p.Geometry)
+		if err != nil {
+			return nil, fmt.Errorf("encode isochrone: %w", err)
+		}
+		minutes[i] = p.Minutes
+		geojsons[i] = string(b)
+	}
+
+	query := `
+		SELECT
+			id,
+			COALESCE(name, ''),
+			category,
+			sub_type,
+			lng,
+			lat,
+			distance_m,
+			walk_time_min,
+			COALESCE(opening_hours, ''),
+			COALESCE(within_minutes, 0)
+		FROM pois_in_isochrones($1, $2, $3, $4, $5)
+	`
+
+	rows, err := s.db.Pool.Query(ctx, query, lng, lat, walkSpeed, minutes, geojsons)
+	if err != nil {
+		return nil, fmt.Errorf("query pois: %w", err)
+	}
+	defer rows.Close()
+
+	var pois []model.POI
+	for rows.Next() {
+		var poi model.POI
+		if err := rows.Scan(
+			&poi.ID,
+			&poi.Name,
+			&poi.Category,
+			&poi.SubType,
+			&poi.Lng,
+			&poi.Lat,
+			&poi.DistanceM,
+			&poi.WalkTimeMin,
+			&poi.OpeningHours,
+			&poi.WithinMinutes,
+		); err != nil {
+			return nil, fmt.Errorf("scan poi: %w", err)
+		}
+		pois = append(pois, poi)
+	}
+
+	return pois, rows.Err()
+}
+
+// CountPOIsByCategory 统计各分类的 POI 数量（count_pois_in_isochrone）
+func (s *POIStore) CountPOIsByCategory(ctx context.Context, lng, lat float64, minutes int, walkSpeed float64) ([]model.POIStatistics, error) {
+	query := `
+		SELECT
+			category,
+			sub_type,
+			poi_count
+		FROM count_pois_in_isochrone($1, $2, $3, $4)
+	`
+
+	rows, err := s.db.Pool.Query(ctx, query, lng, lat, minutes, walkSpeed)
+	if err != nil {
+		return nil, fmt.Errorf("count pois: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []model.POIStatistics
+	for rows.Next() {
+		var stat model.POIStatistics
+		var count int64
+		if err := rows.Scan(&stat.Category, &stat.SubType, &count); err != nil {
+			return nil, fmt.Errorf("scan stat: %w", err)
+		}
+		stat.Count = int(count)
+		stats = append(stats, stat)
+	}
+
+	return stats, rows.Err()
+}
+
+// Categories 获取所有 POI 分类
+func (s *POIStore) Categories(ctx context.Context) ([]model.POICategory, error) {
+	query := `
+		SELECT
+			c.code,
+			c.name,
+			COALESCE(c.description, '') AS description,
+			c.weight,
+			COALESCE(
+				JSON_AGG(
+					JSON_BUILD_OBJECT(
+						'code', st.code,
+						'name', st.name,
+						'osm_tag', COALESCE(r.expression, '')
+					) ORDER BY st.sort_order
+				) FILTER (WHERE st.code IS NOT NULL),
+				'[]'::json
+			) AS sub_types
+		FROM poi_category c
+		LEFT JOIN poi_sub_type st ON st.category_code = c.code
+		LEFT JOIN LATERAL (
+			-- 以优先级最高的分类规则作为子类型的代表标签
+			SELECT expression FROM poi_rule
+			WHERE sub_type = st.code AND enabled
+			ORDER BY priority DESC, id
+			LIMIT 1
+		) r ON TRUE
+		GROUP BY c.code, c.name, c.description, c.weight, c.sort_order
+		ORDER BY c.sort_order
+	`
+
+	rows, err := s.db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query categories: %w", err)
+	}
+	defer rows.Close()
+
+	var categories []model.POICategory
+	for rows.Next() {
+		var cat model.POICategory
+		var subTypesJSON []byte
+		if err := rows.Scan(&cat.Code, &cat.Name, &cat.Description, &cat.Weight, &subTypesJSON); err != nil {
+			return nil, fmt.Errorf("scan category: %w", err)
+		}
+		// 解析子类型
+		// 这里简化处理，实际项目中应该解析 JSON
+		categories = append(categories, cat)
+	}
+
+	return categories, rows.Err()
+}
+
+// POIsInPolygon 查询 GeoJSON 多边形内的全部 POI
+func (s *POIStore) POIsInPolygon(ctx context.Context, geojson string) ([]model.POI, error) {
+	query := `
+		SELECT
+			p.id,
+			COALESCE(p.name, ''),
+			p.category,
+			p.sub_type,
+			ST_X(p.geom),
+			ST_Y(p.geom),
+			COALESCE(p.data_source, 'osm'),
+			COALESCE(p.tags->'opening_hours', '')
+		FROM poi p
+		WHERE ST_Within(p.geom, ST_SetSRID(ST_GeomFromGeoJSON($1), 4326))
+		  AND p.deleted_at IS NULL
+		ORDER BY p.category, p.sub_type, p.id
+	`
+
+	rows, err := s.db.Pool.Query(ctx, query, geojson)
+	if err != nil {
+		return nil, fmt.Errorf("query pois: %w", err)
+	}
+	defer rows.Close()
+
+	var pois []model.POI
+	for rows.Next() {
+		var poi model.POI
+		if err := rows.Scan(
+			&poi.ID,
+			&poi.Name,
+			&poi.Category,
+			&poi.SubType,
+			&poi.Lng,
+			&poi.Lat,
+			&poi.Source,
+			&poi.OpeningHours,
+		); err != nil {
+			return nil, fmt.Errorf("scan poi: %w", err)
+		}
+		pois = append(pois, poi)
+	}
+
+	return pois, rows.Err()
+}
+
+// FilterInPolygon 批量检查点是否位于多边形内，一次查询完成
+func (s *POIStore) FilterInPolygon(ctx context.Context, pois []model.POI, geojson string) ([]model.POI, error) {
+	query := `
+		WITH poi_points AS (
+			SELECT idx, ST_SetSRID(ST_MakePoint(lng, lat), 4326) as geom
+			FROM unnest($1::int[], $2::float8[], $3::float8[]) AS t(idx, lng, lat)
+		),
+		isochrone AS (
+			SELECT ST_GeomFromGeoJSON($4) as geom
+		)
+		SELECT p.idx
+		FROM poi_points p, isochrone i
+		WHERE ST_Within(p.geom, i.geom)
+	`
+
+	idxs := make([]int, len(pois))
+	lngs := make([]float64, len(pois))
+	lats := make([]float64, len(pois))
+	for i, p := range pois {
+		idxs[i] = i
+		lngs[i] = p.Lng
+		lats[i] = p.Lat
+	}
+
+	rows, err := s.db.Pool.Query(ctx, query, idxs, lngs, lats, geojson)
+	if err != nil {
+		return nil, fmt.Errorf("filter pois: %w", err)
+	}
+	valid, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return nil, fmt.Errorf("filter pois: %w", err)
+	}
+
+	inside := make(map[int]bool, len(valid))
+	for _, i := range valid {
+		inside[i] = true
+	}
+	var filtered []model.POI
+	for i, poi := range pois {
+		if inside[i] {
+			filtered = append(filtered, poi)
+		}
+	}
+	return filtered, nil
+}
@@ -0,0 +1,303 @@
+package postgis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/store"
+)
+
+// poiRecordColumns 读取 POIRecord 的列，与 scanPOIRecord 对应
+const poiRecordColumns = `
+	id, osm_id, COALESCE(name, ''), category, sub_type,
+	ST_X(geom), ST_Y(geom), COALESCE(address, ''),
+	COALESCE(hstore_to_json(tags), '{}'::json),
+	COALESCE(data_source, ''), COALESCE(city, ''), COALESCE(updated_by, ''),
+	updated_at, deleted_at
+`
+
+func scanPOIRecord(row pgx.Row) (*model.POIRecord, error) {
+	var p model.POIRecord
+	if err := row.Scan(
+		&p.ID, &p.OSMID, &p.Name, &p.Category, &p.SubType,
+		&p.Lng, &p.Lat, &p.Address,
+		&p.Tags,
+		&p.Source, &p.City, &p.UpdatedBy,
+		&p.UpdatedAt, &p.DeletedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrPOINotFound
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetPOI 获取单个 POI（含已删除）
+func (s *POIStore) GetPOI(ctx context.Context, id int64) (*model.POIRecord, error) {
+	return scanPOIRecord(s.db.Pool.QueryRow(ctx,
+		`SELECT `+poiRecordColumns+` FROM poi WHERE id = $1`, id))
+}
+
+// CreatePOI 人工新增 POI
+func (s *POIStore) CreatePOI(ctx context.Context, actor string, req *model.POICreateRequest) (*model.POIEditResult, error) {
+	return s.edit(ctx, func(tx pgx.Tx) (*model.POIRecord, *model.POIRecord, string, string, error) {
+		category, err := subTypeCategory(ctx, tx, req.SubType)
+		if err != nil {
+			return nil, nil, "", "", err
+		}
+		keys, values := tagArrays(req.Tags)
+
+		after, err := scanPOIRecord(tx.QueryRow(ctx, `
+			WITH pt AS (SELECT ST_SetSRID(ST_MakePoint($4, $5), 4326) AS geom)
+			INSERT INTO poi (name, category, sub_type, geom, address, tags, data_source, city, updated_by)
+			SELECT $1, $2, $3, pt.geom, NULLIF($6, ''), hstore($7::text[], $8::text[]), 'manual',
+			       COALESCE(NULLIF($9, ''), (SELECT c.city FROM coverage c WHERE ST_Contains(c.geom, pt.geom) LIMIT 1)),
+			       $10
+			FROM pt
+			RETURNING `+poiRecordColumns,
+			req.Name, category, req.SubType, req.Lng, req.Lat, req.Address, keys, values, req.City, actor,
+		))
+		if err != nil {
+			return nil, nil, "", "", fmt.Errorf("insert poi: %w", err)
+		}
+		return nil, after, model.AuditCreate, req.Comment, nil
+	}, actor)
+}
+
+// UpdatePOI 人工修改 POI 名称、位置、地址或标签
+// 修改后 data_source 置为 manual，重新导入时不会被覆盖
+func (s *POIStore) UpdatePOI(ctx context.Context, actor string, id int64, req *model.POIUpdateRequest) (*model.POIEditResult, error) {
+	return s.edit(ctx, func(tx pgx.Tx) (*model.POIRecord, *model.POIRecord, string, string, error) {
+		before, err := lockPOI(ctx, tx, id)
+		if err != nil {
+			return nil, nil, "", "", err
+		}
+
+		name, lng, lat, address := before.Name, before.Lng, before.Lat, before.Address
+		if req.Name != nil {
+			name = *req.Name
+		}
+		if req.Lng != nil {
+			lng = *req.Lng
+		}
+		if req.Lat != nil {
+			lat = *req.Lat
+		}
+		if req.Address != nil {
+			address = *req.Address
+		}
+		// 标签按键合并，保留 osm_type 等导入时写入的标签
+		keys, values, removed := tagPatch(req.Tags)
+
+		after, err := scanPOIRecord(tx.QueryRow(ctx, `
+			UPDATE poi SET
+				name = NULLIF($2, ''),
+				geom = ST_SetSRID(ST_MakePoint($3, $4), 4326),
+				address = NULLIF($5, ''),
+				tags = (COALESCE(tags, ''::hstore) || hstore($6::text[], $7::text[])) - $9::text[],
+				data_source = 'manual',
+				updated_by = $8,
+				updated_at = NOW()
+			WHERE id = $1
+			RETURNING `+poiRecordColumns,
+			id, name, lng, lat, address, keys, values, actor, removed,
+		))
+		if err != nil {
+			return nil, nil, "", "", fmt.Errorf("update poi: %w", err)
+		}
+		return before, after, model.AuditUpdate, req.Comment, nil
+	}, actor)
+}
+
+// ReclassifyPOI 人工修改 POI 子类型（分类随之变化）
+func (s *POIStore) ReclassifyPOI(ctx context.Context, actor string, id int64, req *model.POIReclassifyRequest) (*model.POIEditResult, error) {
+	return s.edit(ctx, func(tx pgx.Tx) (*model.POIRecord, *model.POIRecord, string, string, error) {
+		before, err := lockPOI(ctx, tx, id)
+		if err != nil {
+			return nil, nil, "", "", err
+		}
+		category, err := subTypeCategory(ctx, tx, req.SubType)
+		if err != nil {
+			return nil, nil, "", "", err
+		}
+
+		after, err := scanPOIRecord(tx.QueryRow(ctx, `
+			UPDATE poi SET
+				category = $2,
+				sub_type = $3,
+				data_source = 'manual',
+				updated_by = $4,
+				updated_at = NOW()
+			WHERE id = $1
+			RETURNING `+poiRecordColumns,
+			id, category, req.SubType, actor,
+		))
+		if err != nil {
+			return nil, nil, "", "", fmt.Errorf("reclassify poi: %w", err)
+		}
+		return before, after, model.AuditReclassify, req.Comment, nil
+	}, actor)
+}
+
+// DeletePOI 软删除 POI
+// 记录保留并标记为 manual，避免重新导入时恢复
+func (s *POIStore) DeletePOI(ctx context.Context, actor string, id int64, comment string) (*model.POIEditResult, error) {
+	return s.edit(ctx, func(tx pgx.Tx) (*model.POIRecord, *model.POIRecord, string, string, error) {
+		before, err := lockPOI(ctx, tx, id)
+		if err != nil {
+			return nil, nil, "", "", err
+		}
+
+		after, err := scanPOIRecord(tx.QueryRow(ctx, `
+			UPDATE poi SET
+				deleted_at = NOW(),
+				data_source = 'manual',
+				updated_by = $2,
+				updated_at = NOW()
+			WHERE id = $1
+			RETURNING `+poiRecordColumns,
+			id, actor,
+		))
+		if err != nil {
+			return nil, nil, "", "", fmt.Errorf("delete poi: %w", err)
+		}
+		return before, after, model.AuditDelete, comment, nil
+	}, actor)
+}
+
+// POIHistory 获取 POI 审计记录（新到旧）
+func (s *POIStore) POIHistory(ctx context.Context, id int64) ([]model.POIAudit, error) {
+	query := `
+		SELECT id, poi_id, action, actor, before, after, COALESCE(comment, ''), created_at
+		FROM poi_audit
+		WHERE poi_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+
+	rows, err := s.db.Pool.Query(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("query audit: %w", err)
+	}
+	defer rows.Close()
+
+	audits := make([]model.POIAudit, 0)
+	for rows.Next() {
+		var a model.POIAudit
+		if err := rows.Scan(&a.ID, &a.POIID, &a.Action, &a.Actor, &a.Before, &a.After, &a.Comment, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan audit: %w", err)
+		}
+		audits = append(audits, a)
+	}
+	return audits, rows.Err()
+}
+
+// editFunc 在事务中执行修改，返回修改前后快照、审计动作和说明
+type editFunc func(tx pgx.Tx) (before, after *model.POIRecord, action, comment string, err error)
+
+// edit 执行修改并在同一事务中写入审计记录、使相关缓存分析失效
+func (s *POIStore) edit(ctx context.Context, fn editFunc, actor string) (*model.POIEditResult, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	before, after, action, comment, err := fn(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	beforeJSON, err := auditSnapshot(before)
+	if err != nil {
+		return nil, err
+	}
+	afterJSON, err := auditSnapshot(after)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO poi_audit (poi_id, action, actor, before, after, comment)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))`,
+		after.ID, action, actor, beforeJSON, afterJSON, comment,
+	); err != nil {
+		return nil, fmt.Errorf("insert audit: %w", err)
+	}
+
+	// 原位置和新位置都可能落在缓存的等时圈内
+	oldLng, oldLat := after.Lng, after.Lat
+	if before != nil {
+		oldLng, oldLat = before.Lng, before.Lat
+	}
+	var invalidated int
+	if err := tx.QueryRow(ctx, `
+		SELECT invalidate_analyses_at(ST_SetSRID(ST_Collect(ST_MakePoint($1, $2), ST_MakePoint($3, $4)), 4326))`,
+		after.Lng, after.Lat, oldLng, oldLat,
+	).Scan(&invalidated); err != nil {
+		return nil, fmt.Errorf("invalidate analyses: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
+	return &model.POIEditResult{POI: after, InvalidatedAnalyses: invalidated}, nil
+}
+
+// lockPOI 锁定并读取未删除的 POI
+func lockPOI(ctx context.Context, tx pgx.Tx, id int64) (*model.POIRecord, error) {
+	return scanPOIRecord(tx.QueryRow(ctx,
+		`SELECT `+poiRecordColumns+` FROM poi WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id))
+}
+
+// subTypeCategory 查询子类型所属分类
+func subTypeCategory(ctx context.Context, tx pgx.Tx, subType string) (string, error) {
+	var category string
+	err := tx.QueryRow(ctx, `SELECT category_code FROM poi_sub_type WHERE code = $1`, subType).Scan(&category)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("%w: %s", store.ErrUnknownSubType, subType)
+	}
+	if err != nil {
+		return "", fmt.Errorf("query sub_type: %w", err)
+	}
+	return category, nil
+}
+
+func auditSnapshot(p *model.POIRecord) ([]byte, error) {
+	if p == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("marshal snapshot: %w", err)
+	}
+	return b, nil
+}
+
+// tagPatch 拆分标签修改：值非空的键写入，值为空的键删除
+func tagPatch(tags map[string]string) (keys, values, removed []string) {
+	keys, values, removed = []string{}, []string{}, []string{}
+	for k, v := range tags {
+		if v == "" {
+			removed = append(removed, k)
+			continue
+		}
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+	return keys, values, removed
+}
+
+func tagArrays(tags map[string]string) ([]string, []string) {
+	keys := make([]string, 0, len(tags))
+	values := make([]string, 0, len(tags))
+	for k, v := range tags {
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+	return keys, values
+}
@@ -0,0 +1,169 @@
+package postgis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/store"
+)
+
+// SearchPOIs 按空间范围与属性过滤搜索 POI，游标分页
+// 提供参考点时按距离排序并返回 distance_m，否则按 ID 排序；
+// minutes 过滤取 reach 等时圈内的 POI，walk_time_min 为吸附节点的路网时间加节点到 POI 的步行时间，
+// 吸附节点不可达时为空
+func (s *POIStore) SearchPOIs(ctx context.Context, req *model.POISearchRequest, reach *store.SearchReach) (*model.POISearchResult, error) {
+	var (
+		args  []any
+		where = []string{"p.deleted_at IS NULL"}
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	byDistance := req.Lng != nil
+	distance := "0::float8"
+	walkTime := "NULL::float8"
+	if byDistance {
+		origin := fmt.Sprintf("ST_SetSRID(ST_MakePoint(%s, %s), 4326)", arg(*req.Lng), arg(*req.Lat))
+		distance = fmt.Sprintf("ST_Distance(p.geom::geography, %s::geography)", origin)
+
+		if req.Mode() == model.SearchRadius {
+			where = append(where, fmt.Sprintf("ST_DWithin(p.geom::geography, %s::geography, %s)", origin, arg(req.Radius)))
+		}
+	}
+	if req.Mode() == model.SearchMinutes {
+		if reach == nil {
+			return nil, fmt.Errorf("%w: minutes search without reach", store.ErrInvalidSearch)
+		}
+		polygon, err := json.Marshal(reach.Polygon)
+		if err != nil {
+			return nil, fmt.Errorf("encode isochrone: %w", err)
+		}
+		where = append(where, fmt.Sprintf("ST_Within(p.geom, ST_SetSRID(ST_GeomFromGeoJSON(%s), 4326))", arg(string(polygon))))
+		// 同 nearest_facilities_from_reach：POI 吸附到最近的节点，路网时间加吸附距离的步行时间
+		walkTime = fmt.Sprintf(`(
+			SELECT r.agg_cost + snap.snap_m / (%[3]s * 1000.0 / 60.0)
+			FROM (
+				SELECT v.id, ST_Distance(v.the_geom::geography, p.geom::geography) AS snap_m
+				FROM ways_vertices_pgr v
+				ORDER BY v.the_geom <-> p.geom
+				LIMIT 1
+			) snap
+			JOIN unnest(%[1]s::bigint[], %[2]s::float8[]) AS r(node, agg_cost) ON r.node = snap.id
+		)`, arg(reach.Reach.Nodes), arg(reach.Reach.Costs), arg(reach.Reach.WalkSpeed))
+	}
+
+	switch req.Mode() {
+	case model.SearchBBox:
+		bbox, err := store.ParseBBox(req.BBox)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, fmt.Sprintf("p.geom && ST_MakeEnvelope(%s, %s, %s, %s, 4326)",
+			arg(bbox[0]), arg(bbox[1]), arg(bbox[2]), arg(bbox[3])))
+	case model.SearchPolygon:
+		where = append(where, fmt.Sprintf("ST_Intersects(p.geom, ST_SetSRID(ST_GeomFromGeoJSON(%s), 4326))", arg(req.Polygon)))
+	}
+
+	if v := model.SplitList(req.Category); len(v) > 0 {
+		where = append(where, "p.category = ANY("+arg(v)+"::text[])")
+	}
+	if v := model.SplitList(req.SubType); len(v) > 0 {
+		where = append(where, "p.sub_type = ANY("+arg(v)+"::text[])")
+	}
+	if v := model.SplitList(req.Source); len(v) > 0 {
+		where = append(where, "COALESCE(p.data_source, 'osm') = ANY("+arg(v)+"::text[])")
+	}
+	if req.Name != "" {
+		where = append(where, "p.name ILIKE '%' || "+arg(escapeLike(req.Name))+" || '%'")
+	}
+
+	if req.Cursor != "" {
+		dist, id, err := store.DecodeCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if byDistance {
+			where = append(where, fmt.Sprintf("(%s, p.id) > (%s, %s)", distance, arg(dist), arg(id)))
+		} else {
+			where = append(where, "p.id > "+arg(id))
+		}
+	}
+
+	order := "p.id"
+	if byDistance {
+		order = "distance_m, p.id"
+	}
+	query := fmt.Sprintf(`
+		SELECT
+			p.id,
+			COALESCE(p.name, ''),
+			p.category,
+			p.sub_type,
+			ST_X(p.geom),
+			ST_Y(p.geom),
+			COALESCE(p.data_source, 'osm'),
+			%s AS distance_m,
+			%s AS walk_time_min
+		FROM poi p
+		WHERE %s
+		ORDER BY %s
+		LIMIT %s
+	`, distance, walkTime, strings.Join(where, "\n\t\t  AND "), order, arg(req.Limit+1))
+
+	rows, err := s.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("search pois: %w", err)
+	}
+	defer rows.Close()
+
+	pois := make([]model.POI, 0, req.Limit)
+	for rows.Next() {
+		var (
+			poi      model.POI
+			walkTime *float64
+		)
+		if err := rows.Scan(
+			&poi.ID,
+			&poi.Name,
+			&poi.Category,
+			&poi.SubType,
+			&poi.Lng,
+			&poi.Lat,
+			&poi.Source,
+			&poi.DistanceM,
+			&walkTime,
+		); err != nil {
+			return nil, fmt.Errorf("scan poi: %w", err)
+		}
+		if walkTime != nil {
+			poi.WalkTimeMin = *walkTime
+		}
+		pois = append(pois, poi)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("search pois: %w", err)
+	}
+
+	result := &model.POISearchResult{}
+	// 多取一条用于判断是否还有下一页
+	if len(pois) > req.Limit {
+		pois = pois[:req.Limit]
+		last := pois[len(pois)-1]
+		result.NextCursor = store.EncodeCursor(last.DistanceM, last.ID)
+	}
+	result.POIs = pois
+	result.Count = len(pois)
+
+	return result, nil
+}
+
+// escapeLike 转义 LIKE 通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
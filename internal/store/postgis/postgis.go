@@ -0,0 +1,66 @@
+// Package postgis 以 PostGIS + pgRouting 实现 internal/store 中的存储接口
+//
+// 空间查询与路网分析由 migrations 中的数据库函数完成，这里只负责调用与结果映射。
+package postgis
+
+import (
+	"github.com/yourname/15min-life-circle/internal/database"
+	"github.com/yourname/15min-life-circle/internal/store"
+)
+
+// New 创建全部存储实现
+func New(db *database.DB) store.Stores {
+	return store.Stores{
+		Isochrones: NewIsochroneStore(db),
+		POIs:       NewPOIStore(db),
+		Standards:  NewStandardStore(db),
+		History:    NewHistoryStore(db),
+	}
+}
+
+// IsochroneStore 等时圈存储
+type IsochroneStore struct {
+	db *database.DB
+}
+
+// NewIsochroneStore 创建等时圈存储
+func NewIsochroneStore(db *database.DB) *IsochroneStore {
+	return &IsochroneStore{db: db}
+}
+
+// POIStore POI 存储
+type POIStore struct {
+	db *database.DB
+}
+
+// NewPOIStore 创建 POI 存储
+func NewPOIStore(db *database.DB) *POIStore {
+	return &POIStore{db: db}
+}
+
+// StandardStore 评价标准存储
+type StandardStore struct {
+	db *database.DB
+}
+
+// NewStandardStore 创建评价标准存储
+func NewStandardStore(db *database.DB) *StandardStore {
+	return &StandardStore{db: db}
+}
+
+// HistoryStore 评价记录存储
+type HistoryStore struct {
+	db *database.DB
+}
+
+// NewHistoryStore 创建评价记录存储
+func NewHistoryStore(db *database.DB) *HistoryStore {
+	return &HistoryStore{db: db}
+}
+
+var (
+	_ store.IsochroneStore = (*IsochroneStore)(nil)
+	_ store.POIStore       = (*POIStore)(nil)
+	_ store.StandardStore  = (*StandardStore)(nil)
+	_ store.HistoryStore   = (*HistoryStore)(nil)
+)
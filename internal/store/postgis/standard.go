@@ -0,0 +1,49 @@
+package postgis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yourname/15min-life-circle/internal/model"
+)
+
+// Standards 获取评价标准
+func (s *StandardStore) Standards(ctx context.Context) ([]model.EvaluationStandard, error) {
+	query := `
+		SELECT
+			category,
+			sub_type,
+			min_count_5,
+			min_count_10,
+			min_count_15,
+			is_required,
+			base_score
+		FROM evaluation_standard
+		ORDER BY category, sub_type
+	`
+
+	rows, err := s.db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query standards: %w", err)
+	}
+	defer rows.Close()
+
+	var standards []model.EvaluationStandard
+	for rows.Next() {
+		var std model.EvaluationStandard
+		if err := rows.Scan(
+			&std.Category,
+			&std.SubType,
+			&std.MinCount5,
+			&std.MinCount10,
+			&std.MinCount15,
+			&std.Required,
+			&std.BaseScore,
+		); err != nil {
+			return nil, fmt.Errorf("scan standard: %w", err)
+		}
+		standards = append(standards, std)
+	}
+
+	return standards, rows.Err()
+}
@@ -0,0 +1,39 @@
+// Package scenario 在小镇数据（internal/store/fixture）上验证服务层行为的场景
+//
+// 同一组场景既可运行在内存存储上（不需要数据库），也可运行在 PostGIS 上，
+// 用于确认两种存储实现对服务层给出一致的结果。场景见 scenarios_test.go：
+//
+//	go test ./internal/store/scenario                       # 内存存储
+//	SCENARIO_POSTGIS=1 go test ./internal/store/scenario    # 另在 PostGIS 上运行（请使用测试库）
+package scenario
+
+import (
+	"github.com/yourname/15min-life-circle/internal/service"
+	"github.com/yourname/15min-life-circle/internal/store"
+	"github.com/yourname/15min-life-circle/internal/store/fixture"
+)
+
+// WalkSpeed 场景使用的步行速度（km/h），小镇 POI 的圈层按此速度布置
+const WalkSpeed = 5.0
+
+// Services 场景使用的服务，均基于同一组存储
+type Services struct {
+	Town       *fixture.Town
+	Stores     store.Stores
+	Isochrones *service.IsochroneService
+	POIs       *service.POIService
+	Evaluation *service.EvaluationService
+}
+
+// NewServices 以指定存储创建服务；不调用高德 POI
+func NewServices(town *fixture.Town, stores store.Stores) *Services {
+	iso := service.NewIsochroneServiceWithStore(stores.Isochrones)
+	poi := service.NewPOIServiceWithStore(stores.POIs, iso)
+	return &Services{
+		Town:       town,
+		Stores:     stores,
+		Isochrones: iso,
+		POIs:       poi,
+		Evaluation: service.NewEvaluationServiceWithStores(iso, poi, stores.Standards, stores.History),
+	}
+}
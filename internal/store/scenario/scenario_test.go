@@ -0,0 +1,65 @@
+package scenario
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/yourname/15min-life-circle/internal/config"
+	"github.com/yourname/15min-life-circle/internal/database"
+	"github.com/yourname/15min-life-circle/internal/store/fixture"
+	"github.com/yourname/15min-life-circle/internal/store/postgis"
+)
+
+// Scenario 一个场景，Run 返回 nil 表示通过
+type Scenario struct {
+	Name string
+	Run  func(ctx context.Context, s *Services) error
+}
+
+// runScenarios 依次运行全部场景；场景之间共享数据，各场景自行清理新增的 POI
+func runScenarios(t *testing.T, s *Services) {
+	ctx := context.Background()
+	for _, sc := range Scenarios {
+		t.Run(sc.Name, func(t *testing.T) {
+			if err := sc.Run(ctx, s); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestScenariosMemory(t *testing.T) {
+	town := fixture.NewTown()
+	runScenarios(t, NewServices(town, town.Memory().Stores()))
+}
+
+// TestScenariosPostGIS 在 PostGIS 上运行同一组场景，需设置 SCENARIO_POSTGIS=1；
+// 连接参数取自配置（CONFIG_FILE 与 DB_* 环境变量），会写入并在结束后删除小镇数据，请使用测试库
+func TestScenariosPostGIS(t *testing.T) {
+	if os.Getenv("SCENARIO_POSTGIS") == "" {
+		t.Skip("set SCENARIO_POSTGIS=1 to run scenarios against PostGIS")
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		t.Fatalf("connect database: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	ctx := context.Background()
+	town := fixture.NewTown()
+	if err := town.LoadPostGIS(ctx, db); err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := town.Remove(context.Background(), db); err != nil {
+			t.Errorf("remove fixture: %v", err)
+		}
+	})
+
+	runScenarios(t, NewServices(town, postgis.New(db)))
+}
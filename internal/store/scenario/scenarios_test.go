@@ -0,0 +1,448 @@
+package scenario
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/yourname/15min-life-circle/internal/model"
+	"github.com/yourname/15min-life-circle/internal/service"
+)
+
+// Scenarios 全部场景，按顺序运行
+var Scenarios = []Scenario{
+	{"isochrones nest", isochronesNest},
+	{"poi within_minutes bands", poiBands},
+	{"centre scores higher than outskirts", centreBeatsOutskirts},
+	{"missing category yields suggestion", missingCategorySuggestion},
+	{"evaluation geojson ordering", evaluationGeoJSON},
+	{"outside coverage is rejected", outsideCoverage},
+	{"precomputed reach is reused per origin", reachPrecomputed},
+	{"poi create and delete", poiEdits},
+	{"search pages by distance", searchPages},
+	{"minutes search matches evaluation", searchMinutes},
+	{"standards fall back to defaults", standardsFallback},
+}
+
+// bands 小镇各 POI 所在的最小圈层，0 表示 15 分钟圈外（见 fixture.NewTown）
+var bands = map[string]int{
+	"Fixture Health Station":  5,
+	"Fixture Pharmacy":        5,
+	"Fixture Kindergarten":    5,
+	"Fixture Corner Shop":     5,
+	"Fixture West Shop":       5,
+	"Fixture Primary School":  10,
+	"Fixture Market":          10,
+	"Fixture Supermarket":     10,
+	"Fixture Culture Center":  15,
+	"Fixture Far Supermarket": 0,
+}
+
+// centre 小镇中心的等时圈
+func centre(ctx context.Context, s *Services, thresholds ...int) (*model.IsochroneResult, error) {
+	return s.Isochrones.Calculate(ctx, &model.IsochroneRequest{
+		Lng:             s.Town.Lng,
+		Lat:             s.Town.Lat,
+		TimeThresholds:  thresholds,
+		WalkSpeed:       WalkSpeed,
+		RequireCoverage: true,
+	})
+}
+
+// evaluateCentre 小镇中心的综合评价
+func evaluateCentre(ctx context.Context, s *Services) (*model.EvaluationResult, error) {
+	return s.Evaluation.Evaluate(ctx, &model.EvaluationRequest{Lng: s.Town.Lng, Lat: s.Town.Lat, WalkSpeed: WalkSpeed})
+}
+
+// saveCentre 小镇中心的综合评价，并写入评价记录
+func saveCentre(ctx context.Context, s *Services) (*model.EvaluationResult, error) {
+	return s.Evaluation.EvaluateAndSave(ctx, &model.EvaluationRequest{Lng: s.Town.Lng, Lat: s.Town.Lat, WalkSpeed: WalkSpeed})
+}
+
+// namesIn 多边形内的 POI 名称
+func namesIn(ctx context.Context, s *Services, p model.IsochronePolygon) ([]string, error) {
+	geojson, err := json.Marshal(p.Geometry)
+	if err != nil {
+		return nil, err
+	}
+	pois, err := s.POIs.QueryInPolygon(ctx, string(geojson))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(pois))
+	for _, poi := range pois {
+		names = append(names, poi.Name)
+	}
+	return names, nil
+}
+
+// isochronesNest 等时圈按分钟升序、已吸附且无回退，小圈内的 POI 均在大圈内
+func isochronesNest(ctx context.Context, s *Services) error {
+	iso, err := centre(ctx, s, 15, 5, 10)
+	if err != nil {
+		return err
+	}
+	if !iso.Coverage.Snapped || iso.Fallback != "" {
+		return fmt.Errorf("expected snapped origin without fallback, got snapped=%v fallback=%q", iso.Coverage.Snapped, iso.Fallback)
+	}
+	if len(iso.Polygons) != 3 {
+		return fmt.Errorf("expected 3 polygons, got %d", len(iso.Polygons))
+	}
+
+	var inner []string
+	for i, p := range iso.Polygons {
+		if want := []int{5, 10, 15}[i]; p.Minutes != want {
+			return fmt.Errorf("polygon %d: expected %d minutes, got %d", i, want, p.Minutes)
+		}
+		names, err := namesIn(ctx, s, p)
+		if err != nil {
+			return err
+		}
+		for _, n := range inner {
+			if !slices.Contains(names, n) {
+				return fmt.Errorf("%s inside a smaller isochrone but not the %d-minute one", n, p.Minutes)
+			}
+		}
+		if len(names) <= len(inner) {
+			return fmt.Errorf("%d-minute isochrone adds no POIs (%d)", p.Minutes, len(names))
+		}
+		inner = names
+	}
+	return nil
+}
+
+// poiBands 每个 POI 标记为所在的最小圈层
+func poiBands(ctx context.Context, s *Services) error {
+	iso, err := centre(ctx, s, 5, 10, 15)
+	if err != nil {
+		return err
+	}
+	pois, err := s.POIs.QueryInIsochrones(ctx, s.Town.Lng, s.Town.Lat, WalkSpeed, iso.Polygons)
+	if err != nil {
+		return err
+	}
+
+	got := make(map[string]int)
+	for _, p := range pois {
+		got[p.Name] = p.WithinMinutes
+	}
+	for name, want := range bands {
+		if got[name] != want {
+			return fmt.Errorf("%s: expected within_minutes %d, got %d", name, want, got[name])
+		}
+	}
+	return nil
+}
+
+// centreBeatsOutskirts 中心的得分高于小镇角落；EvaluateAndSave 保存评价记录，Evaluate 不保存
+func centreBeatsOutskirts(ctx context.Context, s *Services) error {
+	c, err := saveCentre(ctx, s)
+	if err != nil {
+		return err
+	}
+	lng, lat := s.Town.At(900, -900)
+	o, err := s.Evaluation.Evaluate(ctx, &model.EvaluationRequest{Lng: lng, Lat: lat, WalkSpeed: WalkSpeed})
+	if err != nil {
+		return err
+	}
+	if c.TotalScore <= o.TotalScore {
+		return fmt.Errorf("expected centre (%.2f) to score higher than outskirts (%.2f)", c.TotalScore, o.TotalScore)
+	}
+	if c.AnalysisID == "" {
+		return errors.New("expected analysis_id on saved evaluation result")
+	}
+	if o.AnalysisID != "" {
+		return fmt.Errorf("expected Evaluate not to save, got analysis_id %s", o.AnalysisID)
+	}
+	rec, err := s.Stores.History.GetAnalysis(ctx, c.AnalysisID)
+	if err != nil {
+		return fmt.Errorf("get analysis %s: %w", c.AnalysisID, err)
+	}
+	if rec.Grade != c.Grade {
+		return fmt.Errorf("stored grade %q, evaluation grade %q", rec.Grade, c.Grade)
+	}
+	return nil
+}
+
+// missingCategorySuggestion 小镇没有养老设施：养老服务得 0 分、缺必备设施并给出建议
+func missingCategorySuggestion(ctx context.Context, s *Services) error {
+	r, err := evaluateCentre(ctx, s)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(r.CategoryScores, func(cs model.CategoryScore) bool { return cs.Category == "elderly" })
+	if i < 0 {
+		return errors.New("no elderly category score")
+	}
+	if cs := r.CategoryScores[i]; cs.Score != 0 || cs.HasRequired {
+		return fmt.Errorf("elderly: expected score 0 without required facilities, got %.2f has_required=%v", cs.Score, cs.HasRequired)
+	}
+	if !slices.ContainsFunc(r.Suggestions, func(s string) bool { return strings.Contains(s, "养老服务") }) {
+		return fmt.Errorf("expected an elderly suggestion, got %v", r.Suggestions)
+	}
+
+	// 有社区卫生服务站，医疗不应缺必备设施
+	i = slices.IndexFunc(r.CategoryScores, func(cs model.CategoryScore) bool { return cs.Category == "medical" })
+	if i < 0 || !r.CategoryScores[i].HasRequired {
+		return errors.New("medical: expected required facilities present")
+	}
+	return nil
+}
+
+// evaluationGeoJSON 等时圈要素按分钟降序、起点在最后；POI 要素带 within_minutes
+func evaluationGeoJSON(ctx context.Context, s *Services) error {
+	r, err := evaluateCentre(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	var kinds []string
+	for _, f := range r.Isochrone.Features {
+		kind, _ := f.Properties["type"].(string)
+		if kind == "isochrone" {
+			kind = fmt.Sprintf("%v", f.Properties["minutes"])
+		}
+		kinds = append(kinds, kind)
+	}
+	if want := []string{"15", "10", "5", "origin"}; !slices.Equal(kinds, want) {
+		return fmt.Errorf("expected features %v, got %v", want, kinds)
+	}
+
+	want := 0
+	for _, b := range bands {
+		if b > 0 {
+			want++
+		}
+	}
+	if len(r.POIs.Features) != want {
+		return fmt.Errorf("expected %d poi features, got %d", want, len(r.POIs.Features))
+	}
+	for _, f := range r.POIs.Features {
+		m, ok := f.Properties["within_minutes"].(int)
+		if !ok || m != bands[f.Properties["name"].(string)] {
+			return fmt.Errorf("%v: unexpected within_minutes %v", f.Properties["name"], f.Properties["within_minutes"])
+		}
+	}
+	return nil
+}
+
+// outsideCoverage 覆盖范围外且要求覆盖时返回 ErrOutsideCoverage
+func outsideCoverage(ctx context.Context, s *Services) error {
+	lng, lat := s.Town.At(5000, 5000)
+	_, err := s.Isochrones.Calculate(ctx, &model.IsochroneRequest{Lng: lng, Lat: lat, RequireCoverage: true})
+	if !errors.Is(err, service.ErrOutsideCoverage) {
+		return fmt.Errorf("expected ErrOutsideCoverage, got %v", err)
+	}
+	return nil
+}
+
+// reachPrecomputed 预计算节点可达性后，吸附到该节点的请求跳过路网分析，多边形仍按各自的起点生成
+func reachPrecomputed(ctx context.Context, s *Services) error {
+	first, err := centre(ctx, s, 7, 12)
+	if err != nil {
+		return err
+	}
+	if _, err := s.Isochrones.PrecomputeReach(ctx, first.Coverage.Node, WalkSpeed, 15); err != nil {
+		return err
+	}
+
+	second, err := centre(ctx, s, 12, 7)
+	if err != nil {
+		return err
+	}
+	if !second.Cached {
+		return errors.New("expected second calculation to use the precomputed reach")
+	}
+	if len(second.Polygons) != len(first.Polygons) || second.Polygons[0].Minutes != 7 || second.Coverage == nil {
+		return fmt.Errorf("precomputed result differs: %d polygons (first %d), coverage %v",
+			len(second.Polygons), len(first.Polygons), second.Coverage)
+	}
+
+	// 附近的点击位置吸附到同一节点，起点仍为点击位置
+	lng, lat := s.Town.At(15, 15)
+	near, err := s.Isochrones.Calculate(ctx, &model.IsochroneRequest{
+		Lng: lng, Lat: lat, TimeThresholds: []int{7, 12}, WalkSpeed: WalkSpeed, RequireCoverage: true,
+	})
+	if err != nil {
+		return err
+	}
+	if near.Coverage.Node != first.Coverage.Node || !near.Cached {
+		return fmt.Errorf("expected nearby click to reuse node %d, got node %d cached %v",
+			first.Coverage.Node, near.Coverage.Node, near.Cached)
+	}
+	if near.Origin != (model.Point{lng, lat}) {
+		return fmt.Errorf("origin = %v, want the clicked point %v", near.Origin, model.Point{lng, lat})
+	}
+	return nil
+}
+
+// poiEdits 新增的 POI 立即出现在等时圈查询中并使评价记录失效，删除后消失，审计按新到旧
+func poiEdits(ctx context.Context, s *Services) error {
+	if _, err := saveCentre(ctx, s); err != nil {
+		return err
+	}
+
+	lng, lat := s.Town.At(-50, -50)
+	created, err := s.POIs.Create(ctx, "scenario", &model.POICreateRequest{
+		Name: "Fixture New Pharmacy", SubType: "pharmacy", Lng: lng, Lat: lat, City: s.Town.City,
+		Tags: map[string]string{"opening_hours": "Mo-Su 08:00-20:00", "source": "survey"},
+	})
+	if err != nil {
+		return err
+	}
+	id := created.POI.ID
+	if created.POI.Category != "medical" || created.POI.Source != model.SourceManual {
+		return fmt.Errorf("created poi: category %q source %q", created.POI.Category, created.POI.Source)
+	}
+	if created.InvalidatedAnalyses < 1 {
+		return errors.New("expected the centre evaluation to be invalidated")
+	}
+
+	contains := func() (bool, error) {
+		pois, err := s.POIs.QueryInIsochrone(ctx, s.Town.Lng, s.Town.Lat, 5, WalkSpeed)
+		if err != nil {
+			return false, err
+		}
+		return slices.ContainsFunc(pois, func(p model.POI) bool { return p.ID == id }), nil
+	}
+	if ok, err := contains(); err != nil || !ok {
+		return fmt.Errorf("new poi not in 5-minute isochrone (err %v)", err)
+	}
+
+	// 修改标签只影响提及的键
+	updated, err := s.POIs.Update(ctx, "scenario", id, &model.POIUpdateRequest{
+		Tags: map[string]string{"phone": "0571-1234", "source": ""},
+	})
+	if err != nil {
+		return err
+	}
+	if want := map[string]string{"opening_hours": "Mo-Su 08:00-20:00", "phone": "0571-1234"}; !maps.Equal(updated.POI.Tags, want) {
+		return fmt.Errorf("expected merged tags %v, got %v", want, updated.POI.Tags)
+	}
+
+	if _, err := s.POIs.Delete(ctx, "scenario", id, "scenario cleanup"); err != nil {
+		return err
+	}
+	if ok, err := contains(); err != nil || ok {
+		return fmt.Errorf("deleted poi still in 5-minute isochrone (err %v)", err)
+	}
+	if _, err := s.POIs.Delete(ctx, "scenario", id, ""); !errors.Is(err, service.ErrPOINotFound) {
+		return fmt.Errorf("expected ErrPOINotFound deleting twice, got %v", err)
+	}
+	if _, err := s.POIs.Reclassify(ctx, "scenario", id, &model.POIReclassifyRequest{SubType: "no_such_type"}); !errors.Is(err, service.ErrPOINotFound) {
+		return fmt.Errorf("expected ErrPOINotFound reclassifying deleted poi, got %v", err)
+	}
+
+	audits, err := s.POIs.History(ctx, id)
+	if err != nil {
+		return err
+	}
+	var actions []string
+	for _, a := range audits {
+		actions = append(actions, a.Action)
+	}
+	if want := []string{model.AuditDelete, model.AuditUpdate, model.AuditCreate}; !slices.Equal(actions, want) {
+		return fmt.Errorf("expected audits %v, got %v", want, actions)
+	}
+	return nil
+}
+
+// searchPages 按距离排序的半径搜索分页：各页不重复、整体按距离升序
+func searchPages(ctx context.Context, s *Services) error {
+	lng, lat := s.Town.Lng, s.Town.Lat
+	var (
+		cursor string
+		pois   []model.POI
+	)
+	for page := 0; ; page++ {
+		if page > 10 {
+			return errors.New("too many pages")
+		}
+		r, err := s.POIs.Search(ctx, &model.POISearchRequest{Lng: &lng, Lat: &lat, Radius: 300, Limit: 2, Cursor: cursor})
+		if err != nil {
+			return err
+		}
+		pois = append(pois, r.POIs...)
+		if r.NextCursor == "" {
+			break
+		}
+		cursor = r.NextCursor
+	}
+
+	if len(pois) != 5 {
+		return fmt.Errorf("expected 5 pois within 300m, got %d", len(pois))
+	}
+	for i := 1; i < len(pois); i++ {
+		if pois[i].DistanceM < pois[i-1].DistanceM || pois[i].ID == pois[i-1].ID {
+			return fmt.Errorf("results out of order at %d: %v", i, pois)
+		}
+	}
+	if _, err := s.POIs.Search(ctx, &model.POISearchRequest{Cursor: "%%%"}); !errors.Is(err, service.ErrInvalidSearch) {
+		return fmt.Errorf("expected ErrInvalidSearch for a malformed cursor, got %v", err)
+	}
+	return nil
+}
+
+// searchMinutes minutes 搜索与综合评价使用同一路网分析与等时圈：圈内 POI 相同，
+// walk_time_min 为路网时间
+func searchMinutes(ctx context.Context, s *Services) error {
+	lng, lat := s.Town.Lng, s.Town.Lat
+	const speed = 5.0
+	reach, err := s.Isochrones.Reach(ctx, lng, lat, speed, 15)
+	if err != nil {
+		return err
+	}
+	iso, err := s.Isochrones.FromReach(ctx, reach, []int{15})
+	if err != nil {
+		return err
+	}
+	inside, err := s.POIs.QueryInIsochrones(ctx, lng, lat, speed, iso.Polygons)
+	if err != nil {
+		return err
+	}
+
+	r, err := s.POIs.Search(ctx, &model.POISearchRequest{Lng: &lng, Lat: &lat, Minutes: 15, WalkSpeed: speed, Limit: 100})
+	if err != nil {
+		return err
+	}
+	var want, got []int64
+	for _, p := range inside {
+		want = append(want, p.ID)
+	}
+	for _, p := range r.POIs {
+		got = append(got, p.ID)
+		if p.WalkTimeMin <= 0 || p.WalkTimeMin > 30 {
+			return fmt.Errorf("%s: walk_time_min %.2f is not a routed time", p.Name, p.WalkTimeMin)
+		}
+	}
+	slices.Sort(want)
+	slices.Sort(got)
+	if len(got) == 0 || !slices.Equal(got, want) {
+		return fmt.Errorf("minutes search returned %v, evaluation isochrone contains %v", got, want)
+	}
+	return nil
+}
+
+// standardsFallback 评价标准读取失败时使用默认标准
+func standardsFallback(ctx context.Context, s *Services) error {
+	ev := service.NewEvaluationServiceWithStores(s.Isochrones, s.POIs, failingStandards{}, nil)
+	standards, err := ev.GetStandards(ctx)
+	if err != nil {
+		return err
+	}
+	if len(standards) != len(model.GetDefaultStandards()) {
+		return fmt.Errorf("expected %d default standards, got %d", len(model.GetDefaultStandards()), len(standards))
+	}
+	return nil
+}
+
+// failingStandards 读取总是失败的评价标准存储
+type failingStandards struct{}
+
+func (failingStandards) Standards(context.Context) ([]model.EvaluationStandard, error) {
+	return nil, errors.New("standards unavailable")
+}
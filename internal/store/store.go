@@ -0,0 +1,128 @@
+// Package store 定义服务层使用的存储接口
+//
+// 业务逻辑（合并、评分、建议、标准回退、GeoJSON 组装等）在 internal/service 中，
+// 空间查询与路网分析由存储实现：
+//
+//	postgis  PostGIS + pgRouting 实现，生产环境使用
+//	memory   内存实现，按小型路网做 Dijkstra 与凸包，用于不依赖数据库的场景验证
+//
+// internal/store/fixture 提供可同时加载到两种实现的小镇路网与 POI，
+// internal/store/scenario 中的场景对两种实现运行同一组检查。
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yourname/15min-life-circle/internal/model"
+)
+
+var (
+	// ErrPOINotFound POI 不存在或已删除
+	ErrPOINotFound = errors.New("poi not found")
+	// ErrUnknownSubType 子类型不在 poi_sub_type 中
+	ErrUnknownSubType = errors.New("unknown sub_type")
+	// ErrInvalidSearch 搜索参数或游标无效
+	ErrInvalidSearch = errors.New("invalid search")
+	// ErrAnalysisNotFound 分析记录不存在
+	ErrAnalysisNotFound = errors.New("analysis not found")
+)
+
+// ReachSet 一次路网分析的结果：起点吸附后在 MaxMinutes 内可达的路网节点及累计步行时间
+// 等时圈、可达道路等均可由同一 ReachSet 得出，无需重复路网分析（019 迁移）
+type ReachSet struct {
+	Lng        float64
+	Lat        float64
+	WalkSpeed  float64
+	MaxMinutes int
+	Coverage   *model.CoverageInfo
+	// 按累计时间升序，Costs[i] 为到达 Nodes[i] 的步行时间（分钟）
+	Nodes []int64
+	Costs []float64
+	// 是否读取自节点可达性预计算（020 迁移）
+	Cached bool
+}
+
+// SearchReach POI 搜索 minutes 过滤使用的路网分析结果，与综合评价相同：
+// Polygon 为由 Reach 生成的 minutes 分钟等时圈，walk_time_min 按 Reach 的路网时间计算
+type SearchReach struct {
+	Reach   *ReachSet
+	Polygon model.Geometry
+}
+
+// IsochroneStore 吸附、路网分析与等时圈生成
+type IsochroneStore interface {
+	// Coverage 检查起点是否在覆盖范围内，并返回最近节点与吸附距离
+	Coverage(ctx context.Context, lng, lat float64) (*model.CoverageInfo, error)
+	// Isochrones 按请求的算法、地形与屏障参数计算各时间阈值的等时圈（请求已校验）
+	Isochrones(ctx context.Context, req *model.IsochroneRequest) ([]model.IsochronePolygon, error)
+	// Reach 吸附起点并做一次路网分析，吸附距离超过 MaxSnapDistanceMeters 时 Nodes 为空
+	Reach(ctx context.Context, lng, lat, walkSpeed float64, maxMinutes int) (*ReachSet, error)
+	// IsochronesFromReach 由可达节点生成各时间阈值的等时圈（凹壳，按屏障裁剪）
+	IsochronesFromReach(ctx context.Context, r *ReachSet, thresholds []int) ([]model.IsochronePolygon, error)
+	// RoadsFromReach 由可达节点得到 minutes 内两端均可达的道路（FeatureCollection JSON）
+	RoadsFromReach(ctx context.Context, r *ReachSet, minutes int) (string, error)
+	// ReachableRoads 独立做一次路网分析得到可达道路（FeatureCollection JSON）
+	ReachableRoads(ctx context.Context, lng, lat float64, minutes int, walkSpeed float64) (string, error)
+
+	// PrecomputeReach 预计算单个节点的可达性，返回可达节点数
+	PrecomputeReach(ctx context.Context, node int64, walkSpeed float64, maxMinutes int) (int, error)
+}
+
+// POIStore POI 查询与人工维护
+type POIStore interface {
+	// POIsInIsochrone 查询起点 minutes 分钟等时圈内的 POI
+	POIsInIsochrone(ctx context.Context, lng, lat float64, minutes int, walkSpeed float64) ([]model.POI, error)
+	// POIsInIsochrones 查询最大等时圈内的 POI，并标记各 POI 所在的最小圈层（WithinMinutes）
+	POIsInIsochrones(ctx context.Context, lng, lat, walkSpeed float64, polygons []model.IsochronePolygon) ([]model.POI, error)
+	// CountPOIsByCategory 统计等时圈内各子类型的 POI 数量
+	CountPOIsByCategory(ctx context.Context, lng, lat float64, minutes int, walkSpeed float64) ([]model.POIStatistics, error)
+	// POIsInPolygon 查询 GeoJSON 多边形内未删除的 POI
+	POIsInPolygon(ctx context.Context, geojson string) ([]model.POI, error)
+	// FilterInPolygon 保留位于 GeoJSON 多边形内的 POI（如高德 POI）
+	FilterInPolygon(ctx context.Context, pois []model.POI, geojson string) ([]model.POI, error)
+	// SearchPOIs 按空间范围与属性过滤搜索 POI（请求已校验），游标分页；
+	// minutes 过滤时 reach 为起点的路网分析结果，其他过滤方式为 nil
+	SearchPOIs(ctx context.Context, req *model.POISearchRequest, reach *SearchReach) (*model.POISearchResult, error)
+	// Categories POI 分类
+	Categories(ctx context.Context) ([]model.POICategory, error)
+
+	// GetPOI 获取单个 POI（含已删除）
+	GetPOI(ctx context.Context, id int64) (*model.POIRecord, error)
+	// CreatePOI、UpdatePOI、ReclassifyPOI、DeletePOI 修改 POI，
+	// 同时写入审计记录并使相关缓存分析失效
+	CreatePOI(ctx context.Context, actor string, req *model.POICreateRequest) (*model.POIEditResult, error)
+	UpdatePOI(ctx context.Context, actor string, id int64, req *model.POIUpdateRequest) (*model.POIEditResult, error)
+	ReclassifyPOI(ctx context.Context, actor string, id int64, req *model.POIReclassifyRequest) (*model.POIEditResult, error)
+	DeletePOI(ctx context.Context, actor string, id int64, comment string) (*model.POIEditResult, error)
+	// POIHistory POI 审计记录（新到旧）
+	POIHistory(ctx context.Context, id int64) ([]model.POIAudit, error)
+}
+
+// StandardStore 评价标准
+type StandardStore interface {
+	Standards(ctx context.Context) ([]model.EvaluationStandard, error)
+}
+
+// HistoryStore 综合评价记录（analysis_history）
+type HistoryStore interface {
+	// SaveAnalysis 保存评价记录，返回记录 ID；城市与路网版本按 rec.Node 确定
+	SaveAnalysis(ctx context.Context, rec *model.AnalysisRecord) (string, error)
+	// GetAnalysis 读取评价记录
+	GetAnalysis(ctx context.Context, id string) (*model.AnalysisRecord, error)
+	// GetSharedAnalysis 按分享链接 ID 读取评价记录（含当前路网版本），不存在时返回 ErrAnalysisNotFound
+	GetSharedAnalysis(ctx context.Context, shareID string) (*model.AnalysisRecord, error)
+	// ListAnalyses 用户的评价记录（新到旧，不含 Result），before 非空时只返回更早的记录
+	ListAnalyses(ctx context.Context, userID int, before *time.Time, limit int) ([]model.AnalysisRecord, error)
+	// DeleteAnalysis 删除用户的评价记录，不存在或不属于该用户时返回 ErrAnalysisNotFound
+	DeleteAnalysis(ctx context.Context, userID int, id string) error
+}
+
+// Stores 一组存储实现
+type Stores struct {
+	Isochrones IsochroneStore
+	POIs       POIStore
+	Standards  StandardStore
+	History    HistoryStore
+}
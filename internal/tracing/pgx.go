@@ -0,0 +1,73 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxStatementLen db.statement 属性的最大长度，超出截断
+const maxStatementLen = 4096
+
+// QueryTracer pgx 查询钩子，为每条 SQL 创建子 span
+// 设置到 pgxpool.Config.ConnConfig.Tracer 后对 Query / QueryRow / Exec 生效
+type QueryTracer struct{}
+
+var _ pgx.QueryTracer = QueryTracer{}
+
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = StartKind(ctx, trace.SpanKindClient, sqlSpanName(data.SQL),
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.statement", compactSQL(data.SQL)),
+		attribute.Int("db.args", len(data.Args)),
+	)
+	return ctx
+}
+
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if data.Err != nil && !errors.Is(data.Err, pgx.ErrNoRows) {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+	} else {
+		span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+	}
+	span.End()
+}
+
+var (
+	// 数据库函数调用，如 FROM evaluate_life_circle($1, $2, $3)
+	sqlFuncPattern = regexp.MustCompile(`(?i)\bFROM\s+([a-z_][a-z0-9_]*)\s*\(`)
+	// 普通表，如 FROM poi / INSERT INTO poi_audit / UPDATE poi
+	sqlTablePattern = regexp.MustCompile(`(?i)\b(?:FROM|INTO|UPDATE)\s+([a-z_][a-z0-9_.]*)`)
+)
+
+// sqlSpanName 以调用的数据库函数或首个表命名 span，便于在链路中区分各条 SQL
+func sqlSpanName(sql string) string {
+	op := "SQL"
+	if fields := strings.Fields(sql); len(fields) > 0 {
+		op = strings.ToUpper(fields[0])
+	}
+	if m := sqlFuncPattern.FindStringSubmatch(sql); m != nil {
+		return op + " " + m[1]
+	}
+	if m := sqlTablePattern.FindStringSubmatch(sql); m != nil {
+		return op + " " + m[1]
+	}
+	return op
+}
+
+// compactSQL 折叠空白并截断
+func compactSQL(sql string) string {
+	s := strings.Join(strings.Fields(sql), " ")
+	if len(s) > maxStatementLen {
+		s = s[:maxStatementLen] + "..."
+	}
+	return s
+}
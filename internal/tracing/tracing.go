@@ -0,0 +1,99 @@
+// Package tracing OpenTelemetry 链路追踪
+//
+// Setup 按配置安装全局 TracerProvider：
+//   - otlp：OTLP/HTTP 导出到采集器（Jaeger、Tempo、otel-collector 等）
+//   - stdout：打印到标准输出，便于本地调试
+//   - none：不导出（默认），Start 返回的 span 为空操作
+//
+// 一次请求的链路为：HTTP 路由 span -> 服务方法 span -> SQL / 高德 API span。
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/yourname/15min-life-circle/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentation = "github.com/yourname/15min-life-circle"
+
+// 导出方式
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// ErrInvalidExporter 不支持的导出方式
+var ErrInvalidExporter = errors.New("invalid tracing exporter")
+
+// Setup 安装全局 TracerProvider 和 W3C traceparent 传播器
+// 返回的 shutdown 在退出前调用，确保缓冲中的 span 导出
+func Setup(ctx context.Context, cfg config.TracingConfig) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			// http:// 前缀自动使用非 TLS 连接
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("%w: %q (none / stdout / otlp)", ErrInvalidExporter, cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create %s exporter: %w", cfg.Exporter, err)
+	}
+
+	res := resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName))
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter, sdktrace.WithBatchTimeout(2*time.Second)),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start 开始一个内部 span，调用方负责 End
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return StartKind(ctx, trace.SpanKindInternal, name, attrs...)
+}
+
+// StartKind 开始指定类型的 span：HTTP 入口为 Server，SQL 和外部 API 为 Client
+func StartKind(ctx context.Context, kind trace.SpanKind, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+}
+
+// End 结束 span，err 非空时记录错误并标记失败，配合命名返回值使用：
+//
+//	ctx, span := tracing.Start(ctx, "POIService.Search")
+//	defer func() { tracing.End(span, err) }()
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,289 @@
+-- ============================================================
+-- v2.3 覆盖范围感知
+-- 1. coverage 表记录已导入城市的数据范围（由导入脚本写入）
+-- 2. check_origin_coverage() 报告起点是否在覆盖范围内及吸附距离
+-- 3. 等时圈结果增加 fallback 列，标记非路网（缓冲区）结果
+-- ============================================================
+
+-- ============================================================
+-- 1. 覆盖范围表
+-- ============================================================
+
+CREATE TABLE IF NOT EXISTS coverage (
+    id SERIAL PRIMARY KEY,
+    city VARCHAR(50) NOT NULL UNIQUE,       -- 城市代码，如 hangzhou
+    name VARCHAR(100),                      -- 显示名称
+    geom GEOMETRY(Polygon, 4326) NOT NULL,  -- 路网覆盖范围
+    node_count INT DEFAULT 0,               -- 范围内路网节点数
+    imported_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_coverage_geom ON coverage USING GIST (geom);
+
+COMMENT ON TABLE coverage IS '已导入城市的路网覆盖范围，用于判断起点是否可做路网分析';
+
+-- ============================================================
+-- 2. 刷新覆盖范围（导入脚本调用）
+-- 以边界框内路网节点的凸包作为覆盖范围
+-- ============================================================
+
+CREATE OR REPLACE FUNCTION refresh_coverage(
+    p_city VARCHAR,
+    p_name VARCHAR DEFAULT NULL,
+    p_west DOUBLE PRECISION DEFAULT -180,
+    p_south DOUBLE PRECISION DEFAULT -90,
+    p_east DOUBLE PRECISION DEFAULT 180,
+    p_north DOUBLE PRECISION DEFAULT 90
+)
+RETURNS INTEGER AS $$
+DECLARE
+    v_hull GEOMETRY;
+    v_cnt INTEGER;
+BEGIN
+    SELECT ST_ConvexHull(ST_Collect(v.the_geom)), COUNT(*)
+    INTO v_hull, v_cnt
+    FROM ways_vertices_pgr v
+    WHERE v.the_geom && ST_MakeEnvelope(p_west, p_south, p_east, p_north, 4326);
+
+    -- 节点太少无法构成多边形
+    IF v_cnt < 3 OR GeometryType(v_hull) <> 'POLYGON' THEN
+        RETURN 0;
+    END IF;
+
+    INSERT INTO coverage (city, name, geom, node_count, imported_at)
+    VALUES (p_city, COALESCE(p_name, p_city), v_hull, v_cnt, CURRENT_TIMESTAMP)
+    ON CONFLICT (city) DO UPDATE SET
+        name = COALESCE(EXCLUDED.name, coverage.name),
+        geom = EXCLUDED.geom,
+        node_count = EXCLUDED.node_count,
+        imported_at = EXCLUDED.imported_at;
+
+    RETURN v_cnt;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION refresh_coverage IS '根据路网节点刷新城市覆盖范围';
+
+-- ============================================================
+-- 3. 起点覆盖检查
+-- 覆盖表为空时视为全部覆盖（兼容未登记覆盖范围的旧数据）
+-- ============================================================
+
+DROP FUNCTION IF EXISTS check_origin_coverage(DOUBLE PRECISION, DOUBLE PRECISION);
+
+CREATE OR REPLACE FUNCTION check_origin_coverage(
+    p_lng DOUBLE PRECISION,
+    p_lat DOUBLE PRECISION
+)
+RETURNS TABLE (
+    in_coverage BOOLEAN,
+    city VARCHAR,
+    nearest_node BIGINT,
+    snap_distance_m DOUBLE PRECISION
+) AS $$
+DECLARE
+    v_origin GEOMETRY;
+BEGIN
+    v_origin := ST_SetSRID(ST_MakePoint(p_lng, p_lat), 4326);
+
+    SELECT c.city INTO city
+    FROM coverage c
+    WHERE ST_Contains(c.geom, v_origin)
+    LIMIT 1;
+
+    in_coverage := city IS NOT NULL OR NOT EXISTS (SELECT 1 FROM coverage);
+
+    -- 不限距离的最近节点，用于报告吸附距离
+    SELECT v.id, ST_Distance(v.the_geom::geography, v_origin::geography)
+    INTO nearest_node, snap_distance_m
+    FROM ways_vertices_pgr v
+    ORDER BY v.the_geom <-> v_origin
+    LIMIT 1;
+
+    RETURN NEXT;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+COMMENT ON FUNCTION check_origin_coverage IS '检查起点是否在覆盖范围内并返回最近节点吸附距离';
+
+-- ============================================================
+-- 4. 等时圈结果增加 fallback 列
+-- fallback = NULL     路网分析结果
+-- fallback = 'buffer' 找不到路网节点或可达点过少，退化为圆形缓冲区
+-- ============================================================
+
+DROP FUNCTION IF EXISTS calculate_isochrones(DOUBLE PRECISION, DOUBLE PRECISION, INTEGER[], DOUBLE PRECISION);
+DROP FUNCTION IF EXISTS calculate_isochrones_optimized(DOUBLE PRECISION, DOUBLE PRECISION, INTEGER[], DOUBLE PRECISION);
+
+CREATE OR REPLACE FUNCTION calculate_isochrones_optimized(
+    p_lng DOUBLE PRECISION,
+    p_lat DOUBLE PRECISION,
+    p_time_thresholds INTEGER[] DEFAULT ARRAY[5, 10, 15],
+    p_walk_speed_kmh DOUBLE PRECISION DEFAULT 5.0
+)
+RETURNS TABLE (
+    minutes INTEGER,
+    distance_m DOUBLE PRECISION,
+    geom GEOMETRY,
+    geojson TEXT,
+    fallback TEXT
+) AS $$
+DECLARE
+    v_source_id BIGINT;
+    v_max_cost DOUBLE PRECISION;
+    v_origin GEOMETRY;
+    v_threshold INTEGER;
+    v_result GEOMETRY;
+    v_collected GEOMETRY;
+    v_cnt INTEGER;
+BEGIN
+    v_origin := ST_SetSRID(ST_MakePoint(p_lng, p_lat), 4326);
+
+    -- 查找最近节点
+    v_source_id := find_nearest_node(p_lng, p_lat);
+
+    IF v_source_id IS NULL THEN
+        -- 如果找不到路网节点，返回简单的缓冲区
+        FOREACH v_threshold IN ARRAY p_time_thresholds
+        LOOP
+            v_result := ST_Transform(
+                ST_Buffer(
+                    ST_Transform(v_origin, 3857),
+                    p_walk_speed_kmh * v_threshold / 60.0 * 1000
+                ),
+                4326
+            );
+            minutes := v_threshold;
+            distance_m := p_walk_speed_kmh * v_threshold / 60.0 * 1000;
+            geom := v_result;
+            geojson := ST_AsGeoJSON(v_result);
+            fallback := 'buffer';
+            RETURN NEXT;
+        END LOOP;
+        RETURN;
+    END IF;
+
+    -- 计算最大时间阈值
+    SELECT MAX(t) INTO v_max_cost FROM unnest(p_time_thresholds) AS t;
+
+    -- 创建临时表存储所有可达节点（只做一次路网分析）
+    DROP TABLE IF EXISTS temp_reachable_nodes;
+    CREATE TEMP TABLE temp_reachable_nodes (
+        node BIGINT,
+        agg_cost DOUBLE PRECISION
+    );
+
+    INSERT INTO temp_reachable_nodes (node, agg_cost)
+    SELECT dd.node, dd.agg_cost
+    FROM pgr_drivingDistance(
+        'SELECT gid AS id,
+                source,
+                target,
+                length_m / (' || p_walk_speed_kmh || ' * 1000.0 / 60.0) AS cost,
+                length_m / (' || p_walk_speed_kmh || ' * 1000.0 / 60.0) AS reverse_cost
+         FROM ways',
+        v_source_id,
+        v_max_cost,
+        FALSE
+    ) AS dd;
+
+    -- 为每个时间阈值生成等时圈（复用可达节点数据）
+    FOREACH v_threshold IN ARRAY p_time_thresholds
+    LOOP
+        -- 收集所有点到变量（包括原点！）
+        SELECT ST_Collect(pt.the_geom), COUNT(*)
+        INTO v_collected, v_cnt
+        FROM (
+            -- 【关键】添加原点，确保原点一定在等时圈内
+            SELECT v_origin AS the_geom
+            UNION ALL
+            -- 节点几何
+            SELECT v.the_geom
+            FROM temp_reachable_nodes trn
+            JOIN ways_vertices_pgr v ON trn.node = v.id
+            WHERE trn.agg_cost <= v_threshold
+            UNION ALL
+            -- 可达道路的起点/终点/中点
+            SELECT ST_StartPoint(w.the_geom)
+            FROM ways w
+            WHERE EXISTS (SELECT 1 FROM temp_reachable_nodes t1 WHERE t1.node = w.source AND t1.agg_cost <= v_threshold)
+              AND EXISTS (SELECT 1 FROM temp_reachable_nodes t2 WHERE t2.node = w.target AND t2.agg_cost <= v_threshold)
+            UNION ALL
+            SELECT ST_EndPoint(w.the_geom)
+            FROM ways w
+            WHERE EXISTS (SELECT 1 FROM temp_reachable_nodes t1 WHERE t1.node = w.source AND t1.agg_cost <= v_threshold)
+              AND EXISTS (SELECT 1 FROM temp_reachable_nodes t2 WHERE t2.node = w.target AND t2.agg_cost <= v_threshold)
+            UNION ALL
+            SELECT ST_LineInterpolatePoint(w.the_geom, 0.5)
+            FROM ways w
+            WHERE EXISTS (SELECT 1 FROM temp_reachable_nodes t1 WHERE t1.node = w.source AND t1.agg_cost <= v_threshold)
+              AND EXISTS (SELECT 1 FROM temp_reachable_nodes t2 WHERE t2.node = w.target AND t2.agg_cost <= v_threshold)
+              AND ST_Length(w.the_geom) > 0.0001
+        ) AS pt;
+
+        fallback := NULL;
+
+        -- 根据点数量选择算法
+        IF v_cnt IS NULL OR v_cnt < 10 THEN
+            v_result := ST_Transform(
+                ST_Buffer(ST_Transform(v_origin, 3857), p_walk_speed_kmh * v_threshold / 60.0 * 1000),
+                4326
+            );
+            fallback := 'buffer';
+        ELSE
+            -- 使用凹壳，并确保结果包含原点
+            v_result := COALESCE(
+                ST_ConcaveHull(v_collected, 0.5),
+                ST_ConvexHull(v_collected)
+            );
+
+            IF v_result IS NULL THEN
+                v_result := ST_Transform(
+                    ST_Buffer(ST_Transform(v_origin, 3857), p_walk_speed_kmh * v_threshold / 60.0 * 1000),
+                    4326
+                );
+                fallback := 'buffer';
+            ELSIF NOT ST_Within(v_origin, v_result) THEN
+                -- 如果结果仍不包含原点，与原点缓冲区合并
+                v_result := ST_Union(
+                    v_result,
+                    ST_Transform(
+                        ST_Buffer(ST_Transform(v_origin, 3857), 50), -- 50米缓冲
+                        4326
+                    )
+                );
+            END IF;
+        END IF;
+
+        -- 返回结果
+        minutes := v_threshold;
+        distance_m := p_walk_speed_kmh * v_threshold / 60.0 * 1000;
+        geom := v_result;
+        geojson := ST_AsGeoJSON(v_result);
+        RETURN NEXT;
+    END LOOP;
+
+    -- 清理临时表
+    DROP TABLE IF EXISTS temp_reachable_nodes;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION calculate_isochrones_optimized IS '优化版批量等时圈计算 - 确保包含原点，标记缓冲区回退';
+
+CREATE OR REPLACE FUNCTION calculate_isochrones(
+    p_lng DOUBLE PRECISION,
+    p_lat DOUBLE PRECISION,
+    p_time_thresholds INTEGER[] DEFAULT ARRAY[5, 10, 15],
+    p_walk_speed_kmh DOUBLE PRECISION DEFAULT 5.0
+)
+RETURNS TABLE (
+    minutes INTEGER,
+    distance_m DOUBLE PRECISION,
+    geom GEOMETRY,
+    geojson TEXT,
+    fallback TEXT
+) AS $$
+BEGIN
+    RETURN QUERY SELECT * FROM calculate_isochrones_optimized(p_lng, p_lat, p_time_thresholds, p_walk_speed_kmh);
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,143 @@
+-- ============================================================
+-- v2.4 城市注册表
+-- 城市列表、中心点、边界及数据统计统一存储在数据库中，
+-- 由导入脚本调用 register_city() 登记，前端通过 /api/v1/cities 读取
+-- ============================================================
+
+-- ============================================================
+-- 1. 城市表
+-- ============================================================
+
+CREATE TABLE IF NOT EXISTS city (
+    code VARCHAR(50) PRIMARY KEY,            -- 城市代码，如 hangzhou
+    name VARCHAR(100) NOT NULL,              -- 显示名称
+    description VARCHAR(200),                -- 描述，如 浙江省杭州市
+    center GEOMETRY(Point, 4326) NOT NULL,   -- 地图默认中心
+    zoom INT DEFAULT 14,                     -- 地图默认缩放级别
+    bounds GEOMETRY(Polygon, 4326) NOT NULL, -- 数据边界框
+
+    -- 数据统计（导入时刷新）
+    poi_counts JSONB DEFAULT '{}'::jsonb,    -- 各分类 POI 数量
+    node_count INT DEFAULT 0,                -- 路网节点数
+    edge_count INT DEFAULT 0,                -- 路网边数
+    network_km DOUBLE PRECISION DEFAULT 0,   -- 路网总长度（公里）
+    data_imported_at TIMESTAMP,              -- 数据导入时间
+
+    sort_order INT DEFAULT 0
+);
+
+COMMENT ON TABLE city IS '城市注册表，由导入脚本维护';
+
+-- 已有城市（与原前端配置一致），重新导入时会被 register_city 更新
+INSERT INTO city (code, name, description, center, zoom, bounds, sort_order) VALUES
+    ('hangzhou', '杭州', '浙江省杭州市', ST_SetSRID(ST_MakePoint(120.1551, 30.2741), 4326), 14,
+        ST_MakeEnvelope(119.9, 30.1, 120.5, 30.5, 4326), 1),
+    ('zhuji', '诸暨', '浙江省诸暨市', ST_SetSRID(ST_MakePoint(120.08, 29.85), 4326), 14,
+        ST_MakeEnvelope(120.0, 29.6, 120.4, 29.9, 4326), 2),
+    ('shenyang', '沈阳', '辽宁省沈阳市', ST_SetSRID(ST_MakePoint(123.43, 41.80), 4326), 13,
+        ST_MakeEnvelope(123.2, 41.65, 123.6, 41.95, 4326), 3)
+ON CONFLICT (code) DO NOTHING;
+
+-- ============================================================
+-- 2. 刷新城市数据统计
+-- ============================================================
+
+CREATE OR REPLACE FUNCTION refresh_city_stats(p_code VARCHAR)
+RETURNS VOID AS $$
+DECLARE
+    v_bounds GEOMETRY;
+BEGIN
+    SELECT c.bounds INTO v_bounds FROM city c WHERE c.code = p_code;
+    IF v_bounds IS NULL THEN
+        RETURN;
+    END IF;
+
+    UPDATE city SET
+        poi_counts = COALESCE((
+            SELECT JSONB_OBJECT_AGG(pc.category, pc.cnt)
+            FROM (
+                SELECT p.category, COUNT(*) AS cnt
+                FROM poi p
+                WHERE p.geom && v_bounds
+                GROUP BY p.category
+            ) pc
+        ), '{}'::jsonb),
+        node_count = (
+            SELECT COUNT(*) FROM ways_vertices_pgr v WHERE v.the_geom && v_bounds
+        ),
+        edge_count = (
+            SELECT COUNT(*) FROM ways w WHERE w.the_geom && v_bounds
+        ),
+        network_km = (
+            SELECT COALESCE(ROUND((SUM(w.length_m) / 1000)::numeric, 2), 0)
+            FROM ways w WHERE w.the_geom && v_bounds
+        )
+    WHERE code = p_code;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION refresh_city_stats IS '刷新城市 POI 与路网统计';
+
+-- ============================================================
+-- 3. 登记城市（导入脚本调用）
+-- 未提供边界框时使用全部路网节点的外包框
+-- ============================================================
+
+CREATE OR REPLACE FUNCTION register_city(
+    p_code VARCHAR,
+    p_name VARCHAR DEFAULT NULL,
+    p_description VARCHAR DEFAULT NULL,
+    p_west DOUBLE PRECISION DEFAULT NULL,
+    p_south DOUBLE PRECISION DEFAULT NULL,
+    p_east DOUBLE PRECISION DEFAULT NULL,
+    p_north DOUBLE PRECISION DEFAULT NULL,
+    p_zoom INTEGER DEFAULT 14
+)
+RETURNS VOID AS $$
+DECLARE
+    v_bounds GEOMETRY;
+    v_center GEOMETRY;
+BEGIN
+    IF p_west IS NULL OR p_south IS NULL OR p_east IS NULL OR p_north IS NULL THEN
+        SELECT ST_SetSRID(ST_Extent(v.the_geom)::geometry, 4326) INTO v_bounds
+        FROM ways_vertices_pgr v;
+    ELSE
+        v_bounds := ST_MakeEnvelope(p_west, p_south, p_east, p_north, 4326);
+    END IF;
+
+    IF v_bounds IS NULL OR GeometryType(v_bounds) <> 'POLYGON' THEN
+        RAISE EXCEPTION 'register_city(%): 无法确定城市边界', p_code;
+    END IF;
+
+    -- 覆盖范围与城市边界同步刷新
+    PERFORM refresh_coverage(p_code, p_name,
+        ST_XMin(v_bounds), ST_YMin(v_bounds), ST_XMax(v_bounds), ST_YMax(v_bounds));
+
+    -- 中心点优先取路网覆盖范围的质心
+    SELECT ST_Centroid(c.geom) INTO v_center FROM coverage c WHERE c.city = p_code;
+    v_center := COALESCE(v_center, ST_Centroid(v_bounds));
+
+    INSERT INTO city (code, name, description, center, zoom, bounds, data_imported_at, sort_order)
+    VALUES (
+        p_code,
+        COALESCE(p_name, p_code),
+        COALESCE(p_description, p_name, p_code),
+        v_center,
+        p_zoom,
+        v_bounds,
+        CURRENT_TIMESTAMP,
+        (SELECT COALESCE(MAX(sort_order), 0) + 1 FROM city)
+    )
+    ON CONFLICT (code) DO UPDATE SET
+        name = COALESCE(p_name, city.name),
+        description = COALESCE(p_description, city.description),
+        center = EXCLUDED.center,
+        zoom = EXCLUDED.zoom,
+        bounds = EXCLUDED.bounds,
+        data_imported_at = EXCLUDED.data_imported_at;
+
+    PERFORM refresh_city_stats(p_code);
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION register_city IS '登记/更新城市并刷新覆盖范围与统计';
@@ -0,0 +1,54 @@
+-- ============================================================
+-- v2.5 Go 原生导入器（cmd/importer）所需表结构
+-- 路网表兼容 osm2pgrouting 的列名（gid/source/target/the_geom），
+-- 并增加城市与导入版本标记，支持按城市重新导入
+-- ============================================================
+
+-- ============================================================
+-- 1. 路网表（如已由 osm2pgrouting 创建则只补充列）
+-- ============================================================
+
+CREATE TABLE IF NOT EXISTS ways_vertices_pgr (
+    id BIGINT PRIMARY KEY,                   -- 导入器使用 OSM 节点 ID
+    osm_id BIGINT,
+    the_geom GEOMETRY(Point, 4326)
+);
+
+CREATE TABLE IF NOT EXISTS ways (
+    gid BIGSERIAL PRIMARY KEY,
+    osm_id BIGINT,
+    source BIGINT,
+    target BIGINT,
+    length_m DOUBLE PRECISION,
+    name TEXT,
+    one_way INT DEFAULT 0,                   -- 1: 正向单行, -1: 反向单行, 0: 双向
+    the_geom GEOMETRY(LineString, 4326)
+);
+
+ALTER TABLE ways ADD COLUMN IF NOT EXISTS length_m DOUBLE PRECISION;
+ALTER TABLE ways ADD COLUMN IF NOT EXISTS highway VARCHAR(50);
+ALTER TABLE ways ADD COLUMN IF NOT EXISTS tags HSTORE;
+ALTER TABLE ways ADD COLUMN IF NOT EXISTS city VARCHAR(50);
+ALTER TABLE ways ADD COLUMN IF NOT EXISTS import_version VARCHAR(50);
+
+ALTER TABLE ways_vertices_pgr ADD COLUMN IF NOT EXISTS city VARCHAR(50);
+ALTER TABLE ways_vertices_pgr ADD COLUMN IF NOT EXISTS import_version VARCHAR(50);
+
+CREATE INDEX IF NOT EXISTS idx_ways_source ON ways (source);
+CREATE INDEX IF NOT EXISTS idx_ways_target ON ways (target);
+CREATE INDEX IF NOT EXISTS idx_ways_geom ON ways USING GIST (the_geom);
+CREATE INDEX IF NOT EXISTS idx_ways_city ON ways (city);
+CREATE INDEX IF NOT EXISTS idx_ways_vertices_geom ON ways_vertices_pgr USING GIST (the_geom);
+CREATE INDEX IF NOT EXISTS idx_ways_vertices_city ON ways_vertices_pgr (city);
+
+-- ============================================================
+-- 2. POI 表增加城市与导入版本
+-- ============================================================
+
+ALTER TABLE poi ADD COLUMN IF NOT EXISTS city VARCHAR(50);
+ALTER TABLE poi ADD COLUMN IF NOT EXISTS import_version VARCHAR(50);
+
+CREATE INDEX IF NOT EXISTS idx_poi_city ON poi (city);
+
+COMMENT ON COLUMN poi.city IS '所属城市代码（city.code）';
+COMMENT ON COLUMN poi.import_version IS '导入批次版本，由导入器写入';
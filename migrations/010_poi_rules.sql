@@ -0,0 +1,94 @@
+-- ============================================================
+-- v2.6 数据驱动的 POI 分类规则
+-- 统一取代 003_import_osm_poi.sql 的 CASE 语句和 poi_sub_type.osm_tags，
+-- 由 cmd/importer（含 -osm2pgsql 流程）和服务层（internal/classify）共同加载
+--
+-- 表达式语法（条件以 & 连接，全部满足才匹配）：
+--   amenity=school            键等于值
+--   amenity=clinic|doctors    键等于任一值
+--   amenity!=parking          键不等于值
+--   shop=*                    键存在
+--   !disused                  键不存在
+--   name~中学                  值匹配正则
+-- 多条规则同时匹配时 priority 高者优先
+-- ============================================================
+
+CREATE TABLE IF NOT EXISTS poi_rule (
+    id SERIAL PRIMARY KEY,
+    expression TEXT NOT NULL,
+    category VARCHAR(50) NOT NULL REFERENCES poi_category(code),
+    sub_type VARCHAR(50) NOT NULL REFERENCES poi_sub_type(code),
+    priority INT DEFAULT 10,
+    enabled BOOLEAN DEFAULT TRUE,
+    description VARCHAR(200),
+    UNIQUE (expression, sub_type)
+);
+
+CREATE INDEX IF NOT EXISTS idx_poi_rule_sub_type ON poi_rule (sub_type);
+
+COMMENT ON TABLE poi_rule IS 'OSM 标签到分类/子类型的映射规则';
+
+-- ============================================================
+-- 初始规则
+-- priority 20: 需要组合条件区分的特例（如按名称区分中小学）
+-- priority 10: 主要标签
+-- priority 5:  次要/兜底标签
+-- ============================================================
+
+INSERT INTO poi_rule (expression, category, sub_type, priority, description) VALUES
+    -- 医疗卫生
+    ('amenity=clinic|doctors',                         'medical',   'community_health', 10, '诊所、社区卫生服务站'),
+    ('healthcare=centre|clinic|doctor',                'medical',   'community_health', 5,  NULL),
+    ('amenity=hospital',                               'medical',   'hospital',         10, NULL),
+    ('healthcare=hospital',                            'medical',   'hospital',         5,  NULL),
+    ('amenity=pharmacy',                               'medical',   'pharmacy',         10, NULL),
+    ('healthcare=pharmacy',                            'medical',   'pharmacy',         5,  NULL),
+
+    -- 教育设施（OSM 中学校很少标注学段，按名称区分）
+    ('amenity=kindergarten',                           'education', 'kindergarten',     10, NULL),
+    ('amenity=school & name~中学',                      'education', 'secondary',        20, '名称含“中学”'),
+    ('amenity=school & isced:level~^[23]',             'education', 'secondary',        20, 'ISCED 2/3 级'),
+    ('amenity=school',                                 'education', 'primary',          10, '未区分学段的学校默认归为小学'),
+
+    -- 养老服务
+    ('amenity=social_facility & social_facility=day_care', 'elderly', 'daycare',        20, NULL),
+    ('amenity=community_centre & name~老年|老人',        'elderly',   'elderly_activity', 20, NULL),
+    ('amenity=nursing_home',                           'elderly',   'elderly_center',   10, NULL),
+    ('amenity=social_facility',                        'elderly',   'elderly_center',   5,  NULL),
+
+    -- 商业服务
+    ('shop=supermarket & name~生鲜|菜',                  'commerce',  'market',           20, '生鲜超市'),
+    ('amenity=marketplace',                            'commerce',  'market',           10, NULL),
+    ('shop=greengrocer',                               'commerce',  'market',           10, NULL),
+    ('shop=supermarket|department_store',              'commerce',  'supermarket',      10, NULL),
+    ('shop=convenience',                               'commerce',  'convenience',      10, NULL),
+    ('amenity=restaurant|cafe|fast_food',              'commerce',  'restaurant',       10, NULL),
+
+    -- 文化体育
+    ('amenity=community_centre|arts_centre',           'culture',   'culture_center',   10, NULL),
+    ('leisure=pitch|sports_centre|fitness_centre|stadium|fitness_station', 'culture', 'sports_field', 10, NULL),
+    ('leisure=park|garden',                            'culture',   'park',             10, NULL),
+    ('amenity=library',                                'culture',   'library',          10, NULL),
+
+    -- 公共管理
+    ('amenity=townhall',                               'public',    'community_service', 10, NULL),
+    ('office=government',                              'public',    'community_service', 5,  NULL),
+    ('amenity=police',                                 'public',    'police',           10, NULL),
+    ('amenity=bank|atm',                               'public',    'bank',             10, NULL),
+    ('amenity=post_office|post_box',                   'public',    'post',             10, NULL),
+
+    -- 交通设施
+    ('highway=bus_stop',                               'transport', 'bus_stop',         10, NULL),
+    ('public_transport=platform|stop_position & !railway', 'transport', 'bus_stop',     5,  NULL),
+    ('railway=station|subway_entrance|halt',           'transport', 'metro',            10, NULL),
+    ('amenity=parking',                                'transport', 'parking',          10, NULL),
+    ('amenity=bicycle_parking',                        'transport', 'bike_parking',     10, NULL),
+
+    -- 托幼托育
+    ('amenity=childcare|nursery',                      'child',     'nursery',          10, NULL),
+    ('leisure=playground',                             'child',     'playground',       10, NULL)
+ON CONFLICT (expression, sub_type) DO NOTHING;
+
+-- poi_sub_type.osm_tags 与规则重复且早已不一致（如 amenity=school 同时对应小学和初中），
+-- OSM 标签映射只保留 poi_rule 一处；/api/v1/poi/categories 的 osm_tag 取优先级最高的规则
+ALTER TABLE poi_sub_type DROP COLUMN IF EXISTS osm_tags;
@@ -0,0 +1,327 @@
+-- ============================================================
+-- v2.7 POI 人工维护
+-- 软删除、审计记录、手工数据保留与分析缓存失效
+-- ============================================================
+
+-- ============================================================
+-- 1. POI 表增加软删除与维护人
+-- data_source = 'manual' 的记录不会被导入器删除或覆盖：
+-- 导入器跳过已被人工修改（含软删除）的 OSM 要素
+-- ============================================================
+
+ALTER TABLE poi ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE poi ADD COLUMN IF NOT EXISTS updated_by VARCHAR(100);
+
+CREATE INDEX IF NOT EXISTS idx_poi_manual_osm ON poi (osm_id) WHERE data_source = 'manual';
+
+COMMENT ON COLUMN poi.deleted_at IS '软删除时间，非空时不参与查询与评价';
+COMMENT ON COLUMN poi.updated_by IS '最后一次人工修改的操作人';
+
+-- ============================================================
+-- 2. 审计记录
+-- ============================================================
+
+CREATE TABLE IF NOT EXISTS poi_audit (
+    id BIGSERIAL PRIMARY KEY,
+    poi_id BIGINT NOT NULL,
+    action VARCHAR(20) NOT NULL,             -- create / update / delete / reclassify
+    actor VARCHAR(100) NOT NULL,
+    before JSONB,                            -- 修改前快照，新建时为空
+    after JSONB,                             -- 修改后快照
+    comment TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_poi_audit_poi ON poi_audit (poi_id, created_at DESC);
+
+COMMENT ON TABLE poi_audit IS 'POI 人工维护审计记录';
+
+-- ============================================================
+-- 3. 分析缓存失效标记
+-- POI 变更时，等时圈包含该点的历史分析结果标记为失效
+-- ============================================================
+
+ALTER TABLE analysis_history ADD COLUMN IF NOT EXISTS invalidated_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_analysis_isochrone_15 ON analysis_history USING GIST (isochrone_15);
+
+CREATE OR REPLACE FUNCTION invalidate_analyses_at(p_points GEOMETRY)
+RETURNS INTEGER AS $$
+DECLARE
+    v_count INTEGER;
+BEGIN
+    UPDATE analysis_history a
+    SET invalidated_at = NOW()
+    WHERE a.invalidated_at IS NULL
+      AND (ST_Intersects(a.isochrone_15, p_points)
+           OR ST_Intersects(a.isochrone_10, p_points)
+           OR ST_Intersects(a.isochrone_5, p_points));
+    GET DIAGNOSTICS v_count = ROW_COUNT;
+    RETURN v_count;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION invalidate_analyses_at IS '使等时圈包含给定点的缓存分析失效，返回失效条数';
+
+-- ============================================================
+-- 4. 查询与评价函数排除软删除的 POI
+-- ============================================================
+
+CREATE OR REPLACE FUNCTION query_pois_in_isochrone(
+    p_lng DOUBLE PRECISION,
+    p_lat DOUBLE PRECISION,
+    p_time_minutes INTEGER DEFAULT 15,
+    p_walk_speed_kmh DOUBLE PRECISION DEFAULT 5.0,
+    p_category VARCHAR DEFAULT NULL
+)
+RETURNS TABLE (
+    id BIGINT,
+    name VARCHAR,
+    category VARCHAR,
+    sub_type VARCHAR,
+    lng DOUBLE PRECISION,
+    lat DOUBLE PRECISION,
+    distance_m DOUBLE PRECISION,
+    walk_time_min DOUBLE PRECISION
+) AS $$
+DECLARE
+    v_isochrone GEOMETRY;
+    v_origin GEOMETRY;
+BEGIN
+    -- 使用优化版等时圈计算（获取指定分钟数的等时圈）
+    SELECT geom INTO v_isochrone
+    FROM calculate_isochrones_optimized(p_lng, p_lat, ARRAY[p_time_minutes], p_walk_speed_kmh)
+    WHERE minutes = p_time_minutes
+    LIMIT 1;
+    
+    v_origin := ST_SetSRID(ST_MakePoint(p_lng, p_lat), 4326);
+    
+    -- 如果等时圈为空，返回空结果
+    IF v_isochrone IS NULL THEN
+        RETURN;
+    END IF;
+    
+    RETURN QUERY
+    SELECT 
+        p.id,
+        p.name,
+        p.category,
+        p.sub_type,
+        ST_X(p.geom) AS lng,
+        ST_Y(p.geom) AS lat,
+        ST_Distance(p.geom::geography, v_origin::geography) AS distance_m,
+        ST_Distance(p.geom::geography, v_origin::geography) / (p_walk_speed_kmh * 1000 / 60) AS walk_time_min
+    FROM poi p
+    WHERE ST_Within(p.geom, v_isochrone)
+      AND p.deleted_at IS NULL
+      AND (p_category IS NULL OR p.category = p_category)
+    ORDER BY distance_m;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+CREATE OR REPLACE FUNCTION count_pois_in_isochrone(
+    p_lng DOUBLE PRECISION,
+    p_lat DOUBLE PRECISION,
+    p_time_minutes INTEGER DEFAULT 15,
+    p_walk_speed_kmh DOUBLE PRECISION DEFAULT 5.0
+)
+RETURNS TABLE (
+    category VARCHAR,
+    sub_type VARCHAR,
+    poi_count BIGINT
+) AS $$
+DECLARE
+    v_isochrone GEOMETRY;
+BEGIN
+    -- 使用优化版等时圈计算
+    SELECT geom INTO v_isochrone
+    FROM calculate_isochrones_optimized(p_lng, p_lat, ARRAY[p_time_minutes], p_walk_speed_kmh)
+    WHERE minutes = p_time_minutes
+    LIMIT 1;
+    
+    -- 如果等时圈为空，返回空结果
+    IF v_isochrone IS NULL THEN
+        RETURN;
+    END IF;
+    
+    RETURN QUERY
+    SELECT 
+        p.category,
+        p.sub_type,
+        COUNT(*)::BIGINT AS poi_count
+    FROM poi p
+    WHERE ST_Within(p.geom, v_isochrone)
+      AND p.deleted_at IS NULL
+    GROUP BY p.category, p.sub_type
+    ORDER BY p.category, poi_count DESC;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+CREATE OR REPLACE FUNCTION evaluate_life_circle(
+    p_lng DOUBLE PRECISION,
+    p_lat DOUBLE PRECISION,
+    p_walk_speed_kmh DOUBLE PRECISION DEFAULT 5.0
+)
+RETURNS TABLE (
+    total_score DECIMAL,
+    grade CHAR(1),
+    category VARCHAR,
+    category_name VARCHAR,
+    category_weight DECIMAL,
+    category_score DECIMAL,
+    weighted_score DECIMAL,
+    poi_count BIGINT,
+    details JSONB
+) AS $$
+BEGIN
+    RETURN QUERY
+    WITH 
+    -- 计算各时间阈值的等时圈
+    isochrones AS (
+        SELECT 
+            5 AS minutes, calculate_isochrone(p_lng, p_lat, 5, p_walk_speed_kmh) AS geom
+        UNION ALL
+        SELECT 
+            10, calculate_isochrone(p_lng, p_lat, 10, p_walk_speed_kmh)
+        UNION ALL
+        SELECT 
+            15, calculate_isochrone(p_lng, p_lat, 15, p_walk_speed_kmh)
+    ),
+    -- 统计各等时圈内的 POI
+    poi_counts AS (
+        SELECT 
+            p.category,
+            p.sub_type,
+            i.minutes,
+            COUNT(*)::INT AS cnt
+        FROM poi p
+        CROSS JOIN isochrones i
+        WHERE ST_Within(p.geom, i.geom)
+          AND p.deleted_at IS NULL
+        GROUP BY p.category, p.sub_type, i.minutes
+    ),
+    -- 计算子类型得分
+    subtype_scores AS (
+        SELECT 
+            es.category,
+            es.sub_type,
+            COALESCE(pc5.cnt, 0) AS count_5,
+            COALESCE(pc10.cnt, 0) AS count_10,
+            COALESCE(pc15.cnt, 0) AS count_15,
+            es.min_count_5,
+            es.min_count_10,
+            es.min_count_15,
+            es.is_required,
+            es.base_score,
+            -- 计算得分：满足要求得满分，部分满足按比例
+            CASE 
+                WHEN COALESCE(pc15.cnt, 0) >= es.min_count_15 THEN es.base_score
+                WHEN es.min_count_15 > 0 THEN 
+                    es.base_score * COALESCE(pc15.cnt, 0)::DECIMAL / es.min_count_15
+                ELSE es.base_score
+            END AS score
+        FROM evaluation_standard es
+        LEFT JOIN poi_counts pc5 ON pc5.category = es.category 
+            AND pc5.sub_type = es.sub_type AND pc5.minutes = 5
+        LEFT JOIN poi_counts pc10 ON pc10.category = es.category 
+            AND pc10.sub_type = es.sub_type AND pc10.minutes = 10
+        LEFT JOIN poi_counts pc15 ON pc15.category = es.category 
+            AND pc15.sub_type = es.sub_type AND pc15.minutes = 15
+    ),
+    -- 按分类汇总
+    category_summary AS (
+        SELECT 
+            ss.category,
+            c.name AS category_name,
+            c.weight AS category_weight,
+            SUM(ss.score) AS raw_score,
+            SUM(ss.base_score) AS max_score,
+            SUM(ss.count_15) AS total_poi_count,
+            JSONB_AGG(
+                JSONB_BUILD_OBJECT(
+                    'sub_type', ss.sub_type,
+                    'count_5', ss.count_5,
+                    'count_10', ss.count_10,
+                    'count_15', ss.count_15,
+                    'required', ss.min_count_15,
+                    'score', ss.score,
+                    'max_score', ss.base_score,
+                    'is_required', ss.is_required
+                )
+            ) AS sub_details
+        FROM subtype_scores ss
+        JOIN poi_category c ON c.code = ss.category
+        GROUP BY ss.category, c.name, c.weight
+    ),
+    -- 计算总分
+    total AS (
+        SELECT 
+            -- 归一化到 100 分制
+            ROUND(SUM(
+                CASE 
+                    WHEN max_score > 0 THEN (raw_score / max_score) * 100 * category_weight
+                    ELSE 0
+                END
+            ) / SUM(category_weight), 2) AS total_score
+        FROM category_summary
+    )
+    SELECT 
+        t.total_score,
+        CASE 
+            WHEN t.total_score >= 90 THEN 'A'
+            WHEN t.total_score >= 75 THEN 'B'
+            WHEN t.total_score >= 60 THEN 'C'
+            WHEN t.total_score >= 45 THEN 'D'
+            ELSE 'E'
+        END::CHAR(1) AS grade,
+        cs.category,
+        cs.category_name,
+        cs.category_weight,
+        ROUND(CASE WHEN cs.max_score > 0 THEN cs.raw_score / cs.max_score * 100 ELSE 0 END, 2) AS category_score,
+        ROUND(CASE WHEN cs.max_score > 0 THEN cs.raw_score / cs.max_score * 100 * cs.category_weight ELSE 0 END, 2) AS weighted_score,
+        cs.total_poi_count,
+        cs.sub_details
+    FROM category_summary cs
+    CROSS JOIN total t
+    ORDER BY cs.category;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+-- ============================================================
+-- 5. 城市统计排除软删除的 POI
+-- ============================================================
+
+CREATE OR REPLACE FUNCTION refresh_city_stats(p_code VARCHAR)
+RETURNS VOID AS $$
+DECLARE
+    v_bounds GEOMETRY;
+BEGIN
+    SELECT c.bounds INTO v_bounds FROM city c WHERE c.code = p_code;
+    IF v_bounds IS NULL THEN
+        RETURN;
+    END IF;
+
+    UPDATE city SET
+        poi_counts = COALESCE((
+            SELECT JSONB_OBJECT_AGG(pc.category, pc.cnt)
+            FROM (
+                SELECT p.category, COUNT(*) AS cnt
+                FROM poi p
+                WHERE p.geom && v_bounds
+                  AND p.deleted_at IS NULL
+                GROUP BY p.category
+            ) pc
+        ), '{}'::jsonb),
+        node_count = (
+            SELECT COUNT(*) FROM ways_vertices_pgr v WHERE v.the_geom && v_bounds
+        ),
+        edge_count = (
+            SELECT COUNT(*) FROM ways w WHERE w.the_geom && v_bounds
+        ),
+        network_km = (
+            SELECT COALESCE(ROUND((SUM(w.length_m) / 1000)::numeric, 2), 0)
+            FROM ways w WHERE w.the_geom && v_bounds
+        )
+    WHERE code = p_code;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,134 @@
+-- ============================================================
+-- v2.8 最近设施路径
+-- 按路网步行时间（而非直线距离）查找各子类型最近的 k 个设施，
+-- 并返回步行路径几何
+-- ============================================================
+
+DROP FUNCTION IF EXISTS nearest_facilities(DOUBLE PRECISION, DOUBLE PRECISION, TEXT[], INTEGER, DOUBLE PRECISION, INTEGER, BOOLEAN);
+
+CREATE OR REPLACE FUNCTION nearest_facilities(
+    p_lng DOUBLE PRECISION,
+    p_lat DOUBLE PRECISION,
+    p_sub_types TEXT[],
+    p_k INTEGER DEFAULT 1,
+    p_walk_speed_kmh DOUBLE PRECISION DEFAULT 5.0,
+    p_max_minutes INTEGER DEFAULT 30,
+    p_with_route BOOLEAN DEFAULT TRUE
+)
+RETURNS TABLE (
+    sub_type VARCHAR,
+    category VARCHAR,
+    rank INTEGER,
+    poi_id BIGINT,
+    name VARCHAR,
+    lng DOUBLE PRECISION,
+    lat DOUBLE PRECISION,
+    distance_m DOUBLE PRECISION,
+    walk_time_min DOUBLE PRECISION,
+    route TEXT
+) AS $$
+#variable_conflict use_column
+DECLARE
+    v_source_id BIGINT;
+    v_origin GEOMETRY;
+    v_speed DOUBLE PRECISION;   -- 米/分钟
+    v_edges_sql TEXT;
+BEGIN
+    v_origin := ST_SetSRID(ST_MakePoint(p_lng, p_lat), 4326);
+    v_speed := p_walk_speed_kmh * 1000.0 / 60.0;
+    v_source_id := find_nearest_node(p_lng, p_lat);
+
+    IF v_source_id IS NULL THEN
+        RETURN;
+    END IF;
+
+    v_edges_sql := 'SELECT gid AS id, source, target,
+                           length_m / (' || v_speed || ') AS cost,
+                           length_m / (' || v_speed || ') AS reverse_cost
+                    FROM ways';
+
+    -- 一次路网分析得到所有可达节点的步行时间
+    DROP TABLE IF EXISTS temp_facility_reach;
+    CREATE TEMP TABLE temp_facility_reach AS
+    SELECT dd.node, dd.agg_cost
+    FROM pgr_drivingDistance(v_edges_sql, v_source_id, p_max_minutes, FALSE) AS dd;
+    CREATE INDEX ON temp_facility_reach (node);
+
+    -- 候选设施吸附到最近的可达节点，总时间 = 路网时间 + 节点到设施的直线步行时间
+    DROP TABLE IF EXISTS temp_facility_candidates;
+    CREATE TEMP TABLE temp_facility_candidates AS
+    SELECT
+        ranked.*
+    FROM (
+        SELECT
+            p.id AS poi_id,
+            p.sub_type,
+            p.category,
+            p.name,
+            p.geom,
+            snap.node,
+            snap.node_geom,
+            r.agg_cost * v_speed + snap.snap_m AS total_m,
+            r.agg_cost + snap.snap_m / v_speed AS total_min,
+            ROW_NUMBER() OVER (
+                PARTITION BY p.sub_type
+                ORDER BY r.agg_cost + snap.snap_m / v_speed, p.id
+            )::INTEGER AS rn
+        FROM poi p
+        CROSS JOIN LATERAL (
+            SELECT v.id AS node, v.the_geom AS node_geom,
+                   ST_Distance(v.the_geom::geography, p.geom::geography) AS snap_m
+            FROM ways_vertices_pgr v
+            ORDER BY v.the_geom <-> p.geom
+            LIMIT 1
+        ) snap
+        JOIN temp_facility_reach r ON r.node = snap.node
+        WHERE p.sub_type = ANY(p_sub_types)
+          AND p.deleted_at IS NULL
+          AND ST_DWithin(p.geom::geography, v_origin::geography, v_speed * p_max_minutes)
+    ) ranked
+    WHERE ranked.rn <= p_k;
+
+    IF NOT p_with_route THEN
+        RETURN QUERY
+        SELECT c.sub_type, c.category, c.rn, c.poi_id, c.name,
+               ST_X(c.geom), ST_Y(c.geom), c.total_m, c.total_min, NULL::TEXT
+        FROM temp_facility_candidates c
+        ORDER BY c.sub_type, c.rn;
+    ELSE
+        -- 一次 pgr_dijkstra 计算起点到所有候选节点的路径
+        RETURN QUERY
+        WITH paths AS (
+            SELECT d.end_vid, d.path_seq, w.the_geom
+            FROM pgr_dijkstra(
+                v_edges_sql,
+                v_source_id,
+                (SELECT ARRAY_AGG(DISTINCT c.node) FROM temp_facility_candidates c),
+                FALSE
+            ) AS d
+            JOIN ways w ON w.gid = d.edge
+        ),
+        routes AS (
+            SELECT pa.end_vid, ST_LineMerge(ST_Collect(pa.the_geom ORDER BY pa.path_seq)) AS geom
+            FROM paths pa
+            GROUP BY pa.end_vid
+        )
+        SELECT c.sub_type, c.category, c.rn, c.poi_id, c.name,
+               ST_X(c.geom), ST_Y(c.geom), c.total_m, c.total_min,
+               -- 路网路径 + 起点到路网、路网到设施的连接段
+               ST_AsGeoJSON(ST_LineMerge(ST_Collect(ARRAY[
+                   ST_MakeLine(v_origin, (SELECT v.the_geom FROM ways_vertices_pgr v WHERE v.id = v_source_id)),
+                   rt.geom,
+                   ST_MakeLine(c.node_geom, c.geom)
+               ])))
+        FROM temp_facility_candidates c
+        LEFT JOIN routes rt ON rt.end_vid = c.node
+        ORDER BY c.sub_type, c.rn;
+    END IF;
+
+    DROP TABLE IF EXISTS temp_facility_reach;
+    DROP TABLE IF EXISTS temp_facility_candidates;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION nearest_facilities IS '按路网步行时间查找各子类型最近的 k 个设施及步行路径';
@@ -0,0 +1,157 @@
+-- ============================================================
+-- v2.9 设施服务范围（反向等时圈）
+-- 计算“哪些地方能在 N 分钟内到达该设施”，
+-- 骑行模式按反向图遵守单行道限制，并可统计覆盖人口
+-- ============================================================
+
+-- ============================================================
+-- 1. 人口格网（可选数据）
+-- 可导入 WorldPop / 街道普查等数据，按面积比例分摊到等时圈
+-- ============================================================
+
+CREATE TABLE IF NOT EXISTS population_grid (
+    id BIGSERIAL PRIMARY KEY,
+    geom GEOMETRY(MultiPolygon, 4326) NOT NULL,
+    population DOUBLE PRECISION NOT NULL,   -- 格网内常住人口
+    city VARCHAR(50),
+    source VARCHAR(100),                    -- 数据来源，如 worldpop-2020
+    imported_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_population_grid_geom ON population_grid USING GIST (geom);
+CREATE INDEX IF NOT EXISTS idx_population_grid_city ON population_grid (city);
+
+COMMENT ON TABLE population_grid IS '人口格网，用于统计等时圈/服务范围覆盖人口';
+
+-- ============================================================
+-- 2. 多边形覆盖人口（按面积比例分摊），无人口数据时返回 NULL
+-- ============================================================
+
+CREATE OR REPLACE FUNCTION population_within(p_geom GEOMETRY)
+RETURNS DOUBLE PRECISION AS $$
+    SELECT CASE
+        WHEN NOT EXISTS (SELECT 1 FROM population_grid) THEN NULL
+        ELSE COALESCE((
+            SELECT SUM(
+                pg.population
+                * ST_Area(ST_Intersection(pg.geom, p_geom)::geography)
+                / NULLIF(ST_Area(pg.geom::geography), 0)
+            )
+            FROM population_grid pg
+            WHERE pg.geom && p_geom
+              AND ST_Intersects(pg.geom, p_geom)
+        ), 0)
+    END;
+$$ LANGUAGE sql STABLE;
+
+COMMENT ON FUNCTION population_within IS '统计多边形内人口（格网按面积比例分摊），无人口数据时返回 NULL';
+
+-- ============================================================
+-- 3. 反向等时圈
+-- p_mode = 'walk'  步行，道路双向通行
+-- p_mode = 'bike'  骑行，遵守单行道（oneway:bicycle=no 除外）
+--
+-- 反向图：原图中 u->v 可通行时，反向图中 v->u 可通行。
+-- 因此反向图中 source->target 的代价取原图 target->source 的代价。
+-- ============================================================
+
+DROP FUNCTION IF EXISTS calculate_catchment(DOUBLE PRECISION, DOUBLE PRECISION, INTEGER[], DOUBLE PRECISION, TEXT);
+
+CREATE OR REPLACE FUNCTION calculate_catchment(
+    p_lng DOUBLE PRECISION,
+    p_lat DOUBLE PRECISION,
+    p_time_thresholds INTEGER[] DEFAULT ARRAY[5, 10, 15],
+    p_speed_kmh DOUBLE PRECISION DEFAULT 5.0,
+    p_mode TEXT DEFAULT 'walk'
+)
+RETURNS TABLE (
+    minutes INTEGER,
+    geom GEOMETRY,
+    geojson TEXT,
+    fallback TEXT,
+    population DOUBLE PRECISION
+) AS $$
+DECLARE
+    v_target_id BIGINT;
+    v_origin GEOMETRY;
+    v_speed DOUBLE PRECISION;   -- 米/分钟
+    v_max_cost DOUBLE PRECISION;
+    v_edges_sql TEXT;
+    v_directed BOOLEAN;
+    v_threshold INTEGER;
+    v_collected GEOMETRY;
+    v_cnt INTEGER;
+    v_result GEOMETRY;
+BEGIN
+    v_origin := ST_SetSRID(ST_MakePoint(p_lng, p_lat), 4326);
+    v_speed := p_speed_kmh * 1000.0 / 60.0;
+    v_target_id := find_nearest_node(p_lng, p_lat);
+    SELECT MAX(t) INTO v_max_cost FROM unnest(p_time_thresholds) AS t;
+
+    IF p_mode = 'bike' THEN
+        v_directed := TRUE;
+        v_edges_sql := 'SELECT gid AS id, source, target,
+                CASE WHEN one_way = 1 AND COALESCE(tags->''oneway:bicycle'', '''') <> ''no''
+                     THEN -1 ELSE length_m / ' || v_speed || ' END AS cost,
+                CASE WHEN one_way = -1 AND COALESCE(tags->''oneway:bicycle'', '''') <> ''no''
+                     THEN -1 ELSE length_m / ' || v_speed || ' END AS reverse_cost
+            FROM ways';
+    ELSE
+        v_directed := FALSE;
+        v_edges_sql := 'SELECT gid AS id, source, target,
+                length_m / ' || v_speed || ' AS cost,
+                length_m / ' || v_speed || ' AS reverse_cost
+            FROM ways';
+    END IF;
+
+    DROP TABLE IF EXISTS temp_catchment_nodes;
+    CREATE TEMP TABLE temp_catchment_nodes (
+        node BIGINT,
+        agg_cost DOUBLE PRECISION
+    );
+
+    IF v_target_id IS NOT NULL THEN
+        INSERT INTO temp_catchment_nodes (node, agg_cost)
+        SELECT dd.node, dd.agg_cost
+        FROM pgr_drivingDistance(v_edges_sql, v_target_id, v_max_cost, v_directed) AS dd;
+    END IF;
+
+    FOREACH v_threshold IN ARRAY p_time_thresholds
+    LOOP
+        SELECT ST_Collect(pt.the_geom), COUNT(*)
+        INTO v_collected, v_cnt
+        FROM (
+            SELECT v_origin AS the_geom
+            UNION ALL
+            SELECT v.the_geom
+            FROM temp_catchment_nodes n
+            JOIN ways_vertices_pgr v ON v.id = n.node
+            WHERE n.agg_cost <= v_threshold
+        ) AS pt;
+
+        fallback := NULL;
+        IF v_cnt IS NULL OR v_cnt < 10 THEN
+            v_result := ST_Transform(
+                ST_Buffer(ST_Transform(v_origin, 3857), v_speed * v_threshold),
+                4326
+            );
+            fallback := 'buffer';
+        ELSE
+            v_result := COALESCE(ST_ConcaveHull(v_collected, 0.5), ST_ConvexHull(v_collected));
+            IF NOT ST_Within(v_origin, v_result) THEN
+                v_result := ST_Union(v_result, ST_Transform(ST_Buffer(ST_Transform(v_origin, 3857), 50), 4326));
+            END IF;
+        END IF;
+
+        minutes := v_threshold;
+        geom := v_result;
+        geojson := ST_AsGeoJSON(v_result);
+        population := population_within(v_result);
+        RETURN NEXT;
+    END LOOP;
+
+    DROP TABLE IF EXISTS temp_catchment_nodes;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION calculate_catchment IS '设施服务范围（反向等时圈），骑行模式遵守单行道，可统计覆盖人口';
@@ -0,0 +1,192 @@
+-- ============================================================
+-- v2.10 多起点家庭等时圈
+-- 各起点可使用不同出行方式与速度，返回并集、交集与各自的等时圈
+-- ============================================================
+
+-- ============================================================
+-- 1. 按出行方式计算可达范围（正向或反向）
+-- 由 013 的 calculate_catchment 泛化而来：
+--   p_reverse = FALSE  从该点出发 N 分钟可达的范围（等时圈）
+--   p_reverse = TRUE   N 分钟内能到达该点的范围（服务范围）
+-- 骑行模式遵守单行道（oneway:bicycle=no 除外），步行双向通行
+-- ============================================================
+
+DROP FUNCTION IF EXISTS calculate_reachability(DOUBLE PRECISION, DOUBLE PRECISION, INTEGER[], DOUBLE PRECISION, TEXT, BOOLEAN);
+
+CREATE OR REPLACE FUNCTION calculate_reachability(
+    p_lng DOUBLE PRECISION,
+    p_lat DOUBLE PRECISION,
+    p_time_thresholds INTEGER[] DEFAULT ARRAY[5, 10, 15],
+    p_speed_kmh DOUBLE PRECISION DEFAULT 5.0,
+    p_mode TEXT DEFAULT 'walk',
+    p_reverse BOOLEAN DEFAULT FALSE
+)
+RETURNS TABLE (
+    minutes INTEGER,
+    geom GEOMETRY,
+    geojson TEXT,
+    fallback TEXT,
+    population DOUBLE PRECISION
+) AS $$
+DECLARE
+    v_node_id BIGINT;
+    v_origin GEOMETRY;
+    v_speed DOUBLE PRECISION;   -- 米/分钟
+    v_max_cost DOUBLE PRECISION;
+    v_edges_sql TEXT;
+    v_directed BOOLEAN;
+    v_threshold INTEGER;
+    v_collected GEOMETRY;
+    v_cnt INTEGER;
+    v_result GEOMETRY;
+BEGIN
+    v_origin := ST_SetSRID(ST_MakePoint(p_lng, p_lat), 4326);
+    v_speed := p_speed_kmh * 1000.0 / 60.0;
+    v_node_id := find_nearest_node(p_lng, p_lat);
+    SELECT MAX(t) INTO v_max_cost FROM unnest(p_time_thresholds) AS t;
+
+    IF p_mode = 'bike' THEN
+        v_directed := TRUE;
+        -- 正向：source->target 禁止逆行 one_way = -1；反向图则相反
+        v_edges_sql := 'SELECT gid AS id, source, target,
+                CASE WHEN one_way = ' || CASE WHEN p_reverse THEN '1' ELSE '-1' END || '
+                      AND COALESCE(tags->''oneway:bicycle'', '''') <> ''no''
+                     THEN -1 ELSE length_m / ' || v_speed || ' END AS cost,
+                CASE WHEN one_way = ' || CASE WHEN p_reverse THEN '-1' ELSE '1' END || '
+                      AND COALESCE(tags->''oneway:bicycle'', '''') <> ''no''
+                     THEN -1 ELSE length_m / ' || v_speed || ' END AS reverse_cost
+            FROM ways';
+    ELSE
+        v_directed := FALSE;
+        v_edges_sql := 'SELECT gid AS id, source, target,
+                length_m / ' || v_speed || ' AS cost,
+                length_m / ' || v_speed || ' AS reverse_cost
+            FROM ways';
+    END IF;
+
+    DROP TABLE IF EXISTS temp_reachability_nodes;
+    CREATE TEMP TABLE temp_reachability_nodes (
+        node BIGINT,
+        agg_cost DOUBLE PRECISION
+    );
+
+    IF v_node_id IS NOT NULL THEN
+        INSERT INTO temp_reachability_nodes (node, agg_cost)
+        SELECT dd.node, dd.agg_cost
+        FROM pgr_drivingDistance(v_edges_sql, v_node_id, v_max_cost, v_directed) AS dd;
+    END IF;
+
+    FOREACH v_threshold IN ARRAY p_time_thresholds
+    LOOP
+        SELECT ST_Collect(pt.the_geom), COUNT(*)
+        INTO v_collected, v_cnt
+        FROM (
+            SELECT v_origin AS the_geom
+            UNION ALL
+            SELECT v.the_geom
+            FROM temp_reachability_nodes n
+            JOIN ways_vertices_pgr v ON v.id = n.node
+            WHERE n.agg_cost <= v_threshold
+        ) AS pt;
+
+        fallback := NULL;
+        IF v_cnt IS NULL OR v_cnt < 10 THEN
+            v_result := ST_Transform(
+                ST_Buffer(ST_Transform(v_origin, 3857), v_speed * v_threshold),
+                4326
+            );
+            fallback := 'buffer';
+        ELSE
+            v_result := COALESCE(ST_ConcaveHull(v_collected, 0.5), ST_ConvexHull(v_collected));
+            IF NOT ST_Within(v_origin, v_result) THEN
+                v_result := ST_Union(v_result, ST_Transform(ST_Buffer(ST_Transform(v_origin, 3857), 50), 4326));
+            END IF;
+        END IF;
+
+        minutes := v_threshold;
+        geom := v_result;
+        geojson := ST_AsGeoJSON(v_result);
+        population := population_within(v_result);
+        RETURN NEXT;
+    END LOOP;
+
+    DROP TABLE IF EXISTS temp_reachability_nodes;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION calculate_reachability IS '按出行方式计算正向等时圈或反向服务范围，可统计覆盖人口';
+
+CREATE OR REPLACE FUNCTION calculate_catchment(
+    p_lng DOUBLE PRECISION,
+    p_lat DOUBLE PRECISION,
+    p_time_thresholds INTEGER[] DEFAULT ARRAY[5, 10, 15],
+    p_speed_kmh DOUBLE PRECISION DEFAULT 5.0,
+    p_mode TEXT DEFAULT 'walk'
+)
+RETURNS TABLE (
+    minutes INTEGER,
+    geom GEOMETRY,
+    geojson TEXT,
+    fallback TEXT,
+    population DOUBLE PRECISION
+) AS $$
+    SELECT * FROM calculate_reachability(p_lng, p_lat, p_time_thresholds, p_speed_kmh, p_mode, TRUE);
+$$ LANGUAGE sql;
+
+COMMENT ON FUNCTION calculate_catchment IS '设施服务范围（反向等时圈），骑行模式遵守单行道，可统计覆盖人口';
+
+-- ============================================================
+-- 2. 多边形交集聚合
+-- ============================================================
+
+-- PostGIS 3.1 起 ST_Intersection 带 gridSize 默认参数，不能直接作为 SFUNC
+CREATE OR REPLACE FUNCTION intersection_agg_sfunc(p_state GEOMETRY, p_geom GEOMETRY)
+RETURNS GEOMETRY AS $$
+    SELECT CASE WHEN p_state IS NULL THEN p_geom ELSE ST_Intersection(p_state, p_geom) END;
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE OR REPLACE AGGREGATE intersection_agg(GEOMETRY) (
+    SFUNC = intersection_agg_sfunc,
+    STYPE = GEOMETRY
+);
+
+COMMENT ON AGGREGATE intersection_agg(GEOMETRY) IS '多个几何的交集';
+
+-- ============================================================
+-- 3. 多个等时圈的并集与交集
+-- 交集为空时 intersection_geojson 为 NULL
+-- ============================================================
+
+DROP FUNCTION IF EXISTS household_areas(TEXT[]);
+
+CREATE OR REPLACE FUNCTION household_areas(p_geojsons TEXT[])
+RETURNS TABLE (
+    union_geojson TEXT,
+    union_area_m2 DOUBLE PRECISION,
+    union_population DOUBLE PRECISION,
+    intersection_geojson TEXT,
+    intersection_area_m2 DOUBLE PRECISION,
+    intersection_population DOUBLE PRECISION
+) AS $$
+    WITH polys AS (
+        SELECT ST_SetSRID(ST_GeomFromGeoJSON(g), 4326) AS geom
+        FROM unnest(p_geojsons) AS g
+    ),
+    agg AS (
+        SELECT
+            ST_Union(geom) AS u,
+            -- 交集可能包含线/点（边界相切），只保留面
+            ST_CollectionExtract(intersection_agg(geom), 3) AS i
+        FROM polys
+    )
+    SELECT
+        ST_AsGeoJSON(agg.u),
+        ST_Area(agg.u::geography),
+        population_within(agg.u),
+        CASE WHEN ST_IsEmpty(agg.i) THEN NULL ELSE ST_AsGeoJSON(agg.i) END,
+        CASE WHEN ST_IsEmpty(agg.i) THEN 0 ELSE ST_Area(agg.i::geography) END,
+        CASE WHEN ST_IsEmpty(agg.i) THEN NULL ELSE population_within(agg.i) END
+    FROM agg;
+$$ LANGUAGE sql STABLE;
+
+COMMENT ON FUNCTION household_areas IS '多个等时圈的并集与交集（面积、覆盖人口）';
@@ -0,0 +1,176 @@
+-- ============================================================
+-- v2.11 可选的等时圈多边形生成算法
+--
+-- 006/007 固定使用 ST_ConcaveHull(points, 0.5)，容易把河流、铁路
+-- 另一侧不可达的街区包进等时圈。calculate_isochrones_method 支持：
+--
+--   concave       凹壳，p_param = 凹度 (0, 1]，默认 0.5
+--   alpha         alpha 形状：Delaunay 三角网中去掉最长边超过
+--                 p_param 米的三角形后合并，默认 150 米
+--   road_buffer   可达道路（两端节点均可达）缓冲 p_param 米后合并，默认 50 米
+--   interpolated  同 road_buffer，但对只走得到一部分的道路按剩余时间
+--                 截取到精确位置，默认缓冲 25 米
+--
+-- 找不到路网节点或结果为空时仍退化为圆形缓冲区（fallback = 'buffer'）
+-- ============================================================
+
+DROP FUNCTION IF EXISTS calculate_isochrones_method(DOUBLE PRECISION, DOUBLE PRECISION, INTEGER[], DOUBLE PRECISION, TEXT, DOUBLE PRECISION);
+
+CREATE OR REPLACE FUNCTION calculate_isochrones_method(
+    p_lng DOUBLE PRECISION,
+    p_lat DOUBLE PRECISION,
+    p_time_thresholds INTEGER[] DEFAULT ARRAY[5, 10, 15],
+    p_walk_speed_kmh DOUBLE PRECISION DEFAULT 5.0,
+    p_method TEXT DEFAULT 'concave',
+    p_param DOUBLE PRECISION DEFAULT NULL
+)
+RETURNS TABLE (
+    minutes INTEGER,
+    distance_m DOUBLE PRECISION,
+    geom GEOMETRY,
+    geojson TEXT,
+    fallback TEXT,
+    method TEXT
+) AS $$
+DECLARE
+    v_source_id BIGINT;
+    v_origin GEOMETRY;
+    v_speed DOUBLE PRECISION;   -- 米/分钟
+    v_max_cost DOUBLE PRECISION;
+    v_threshold INTEGER;
+    v_collected GEOMETRY;
+    v_cnt INTEGER;
+    v_result GEOMETRY;
+    v_width DOUBLE PRECISION;
+BEGIN
+    IF p_method NOT IN ('concave', 'alpha', 'road_buffer', 'interpolated') THEN
+        RAISE EXCEPTION 'unknown polygon method: %', p_method;
+    END IF;
+
+    v_origin := ST_SetSRID(ST_MakePoint(p_lng, p_lat), 4326);
+    v_speed := p_walk_speed_kmh * 1000.0 / 60.0;
+    v_source_id := find_nearest_node(p_lng, p_lat);
+    SELECT MAX(t) INTO v_max_cost FROM unnest(p_time_thresholds) AS t;
+
+    DROP TABLE IF EXISTS temp_method_nodes;
+    CREATE TEMP TABLE temp_method_nodes (
+        node BIGINT PRIMARY KEY,
+        agg_cost DOUBLE PRECISION
+    );
+
+    IF v_source_id IS NOT NULL THEN
+        INSERT INTO temp_method_nodes (node, agg_cost)
+        SELECT dd.node, dd.agg_cost
+        FROM pgr_drivingDistance(
+            'SELECT gid AS id, source, target,
+                    length_m / ' || v_speed || ' AS cost,
+                    length_m / ' || v_speed || ' AS reverse_cost
+             FROM ways',
+            v_source_id,
+            v_max_cost,
+            FALSE
+        ) AS dd;
+    END IF;
+
+    FOREACH v_threshold IN ARRAY p_time_thresholds
+    LOOP
+        v_result := NULL;
+
+        IF p_method IN ('concave', 'alpha') THEN
+            SELECT ST_Collect(pt.the_geom), COUNT(*)
+            INTO v_collected, v_cnt
+            FROM (
+                SELECT v_origin AS the_geom
+                UNION ALL
+                SELECT v.the_geom
+                FROM temp_method_nodes n
+                JOIN ways_vertices_pgr v ON v.id = n.node
+                WHERE n.agg_cost <= v_threshold
+            ) AS pt;
+
+            IF v_cnt >= 10 THEN
+                IF p_method = 'concave' THEN
+                    v_result := ST_ConcaveHull(v_collected, COALESCE(p_param, 0.5));
+                ELSE
+                    SELECT ST_Union(tri.geom) INTO v_result
+                    FROM (
+                        SELECT (ST_Dump(ST_DelaunayTriangles(v_collected))).geom
+                    ) AS tri
+                    WHERE ST_Length(ST_LongestLine(tri.geom, tri.geom)::geography) <= COALESCE(p_param, 150);
+                END IF;
+            END IF;
+        ELSE
+            v_width := COALESCE(p_param, CASE WHEN p_method = 'road_buffer' THEN 50 ELSE 25 END);
+
+            WITH edge_reach AS (
+                SELECT
+                    w.the_geom,
+                    w.length_m,
+                    -- 从两端还能沿该道路走多远（占道路长度的比例）
+                    (v_threshold - s.agg_cost) * v_speed / NULLIF(w.length_m, 0) AS fs,
+                    (v_threshold - t.agg_cost) * v_speed / NULLIF(w.length_m, 0) AS ft,
+                    s.agg_cost <= v_threshold AS s_ok,
+                    t.agg_cost <= v_threshold AS t_ok
+                FROM ways w
+                LEFT JOIN temp_method_nodes s ON s.node = w.source
+                LEFT JOIN temp_method_nodes t ON t.node = w.target
+                WHERE s.agg_cost <= v_threshold OR t.agg_cost <= v_threshold
+            ),
+            segments AS (
+                -- 整条可达道路
+                SELECT er.the_geom AS g
+                FROM edge_reach er
+                WHERE CASE
+                    WHEN p_method = 'road_buffer' THEN COALESCE(er.s_ok, FALSE) AND COALESCE(er.t_ok, FALSE)
+                    ELSE er.length_m = 0
+                      OR GREATEST(COALESCE(er.fs, 0), 0) + GREATEST(COALESCE(er.ft, 0), 0) >= 1
+                END
+                UNION ALL
+                -- 从起点端截取
+                SELECT ST_LineSubstring(er.the_geom, 0, er.fs)
+                FROM edge_reach er
+                WHERE p_method = 'interpolated'
+                  AND er.fs > 0
+                  AND er.fs + GREATEST(COALESCE(er.ft, 0), 0) < 1
+                UNION ALL
+                -- 从终点端截取
+                SELECT ST_LineSubstring(er.the_geom, 1 - er.ft, 1)
+                FROM edge_reach er
+                WHERE p_method = 'interpolated'
+                  AND er.ft > 0
+                  AND er.ft + GREATEST(COALESCE(er.fs, 0), 0) < 1
+            )
+            SELECT ST_Union(ST_Buffer(sg.g::geography, v_width)::geometry), COUNT(*)
+            INTO v_result, v_cnt
+            FROM segments sg;
+
+            IF v_result IS NOT NULL THEN
+                -- 起点到路网的连接段
+                v_result := ST_Union(v_result, ST_Buffer(v_origin::geography, v_width)::geometry);
+            END IF;
+        END IF;
+
+        fallback := NULL;
+        IF v_result IS NULL OR ST_IsEmpty(v_result) THEN
+            v_result := ST_Transform(
+                ST_Buffer(ST_Transform(v_origin, 3857), v_speed * v_threshold),
+                4326
+            );
+            fallback := 'buffer';
+        ELSIF NOT ST_Intersects(v_origin, v_result) THEN
+            v_result := ST_Union(v_result, ST_Transform(ST_Buffer(ST_Transform(v_origin, 3857), 50), 4326));
+        END IF;
+
+        minutes := v_threshold;
+        distance_m := v_speed * v_threshold;
+        geom := v_result;
+        geojson := ST_AsGeoJSON(v_result);
+        method := p_method;
+        RETURN NEXT;
+    END LOOP;
+
+    DROP TABLE IF EXISTS temp_method_nodes;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION calculate_isochrones_method IS '按指定算法生成等时圈多边形：concave / alpha / road_buffer / interpolated';
@@ -0,0 +1,210 @@
+-- ============================================================
+-- v2.12 步行屏障裁剪等时圈
+--
+-- 凹壳/alpha 等多边形会跨过钱塘江、铁路走廊把对岸不可达的街区包进来。
+-- 导入器（cmd/importer）从 OSM 提取三类屏障写入 barrier 表：
+--   water     水面（natural=water、waterway=riverbank、水库等面状要素，
+--             以及 waterway=river/canal 中心线）
+--   rail      铁路（rail / light_rail / subway / narrow_gauge，桥梁、隧道除外）
+--   motorway  高速公路（桥梁、隧道除外）
+--
+-- calculate_isochrones_barrier 在原有算法结果上：
+--   1. 扣除水面；
+--   2. 用线状屏障切分多边形；
+--   3. 只保留包含起点、可达路网节点或可达道路的碎片。
+-- 因此屏障对岸只有在确实存在可达道路（桥梁、地道、平交道口）时才会保留。
+-- ============================================================
+
+-- ============================================================
+-- 1. 屏障表
+-- ============================================================
+
+CREATE TABLE IF NOT EXISTS barrier (
+    id BIGSERIAL PRIMARY KEY,
+    osm_id BIGINT,
+    osm_type VARCHAR(10),                   -- way / relation
+    kind VARCHAR(20) NOT NULL,              -- water / rail / motorway
+    geom GEOMETRY(Geometry, 4326) NOT NULL, -- 面状水体为 MultiPolygon，其余为线
+    city VARCHAR(50),
+    import_version VARCHAR(50),
+    imported_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_barrier_geom ON barrier USING GIST (geom);
+CREATE INDEX IF NOT EXISTS idx_barrier_city ON barrier (city);
+
+COMMENT ON TABLE barrier IS '步行屏障（水面、铁路、高速公路），用于裁剪等时圈';
+
+-- ============================================================
+-- 2. 按屏障裁剪的等时圈
+-- p_method 为 NULL 时使用 calculate_isochrones（默认凹壳），
+-- 否则使用 calculate_isochrones_method（见 015）
+-- ============================================================
+
+DROP FUNCTION IF EXISTS calculate_isochrones_barrier(DOUBLE PRECISION, DOUBLE PRECISION, INTEGER[], DOUBLE PRECISION, TEXT, DOUBLE PRECISION);
+
+CREATE OR REPLACE FUNCTION calculate_isochrones_barrier(
+    p_lng DOUBLE PRECISION,
+    p_lat DOUBLE PRECISION,
+    p_time_thresholds INTEGER[] DEFAULT ARRAY[5, 10, 15],
+    p_walk_speed_kmh DOUBLE PRECISION DEFAULT 5.0,
+    p_method TEXT DEFAULT NULL,
+    p_param DOUBLE PRECISION DEFAULT NULL
+)
+RETURNS TABLE (
+    minutes INTEGER,
+    distance_m DOUBLE PRECISION,
+    geom GEOMETRY,
+    geojson TEXT,
+    fallback TEXT,
+    method TEXT,
+    clipped BOOLEAN
+) AS $$
+#variable_conflict use_column
+DECLARE
+    v_source_id BIGINT;
+    v_origin GEOMETRY;
+    v_speed DOUBLE PRECISION;   -- 米/分钟
+    v_max_cost DOUBLE PRECISION;
+    v_extent GEOMETRY;
+    v_row RECORD;
+    v_water GEOMETRY;
+    v_lines GEOMETRY;
+    v_result GEOMETRY;
+BEGIN
+    v_origin := ST_SetSRID(ST_MakePoint(p_lng, p_lat), 4326);
+    v_speed := p_walk_speed_kmh * 1000.0 / 60.0;
+    SELECT MAX(t) INTO v_max_cost FROM unnest(p_time_thresholds) AS t;
+
+    DROP TABLE IF EXISTS temp_barrier_raw;
+    CREATE TEMP TABLE temp_barrier_raw (
+        minutes INTEGER,
+        distance_m DOUBLE PRECISION,
+        geom GEOMETRY,
+        fallback TEXT,
+        method TEXT
+    );
+
+    IF p_method IS NULL THEN
+        INSERT INTO temp_barrier_raw
+        SELECT iso.minutes, iso.distance_m, iso.geom, iso.fallback, 'concave'
+        FROM calculate_isochrones(p_lng, p_lat, p_time_thresholds, p_walk_speed_kmh) AS iso;
+    ELSE
+        INSERT INTO temp_barrier_raw
+        SELECT iso.minutes, iso.distance_m, iso.geom, iso.fallback, iso.method
+        FROM calculate_isochrones_method(p_lng, p_lat, p_time_thresholds, p_walk_speed_kmh, p_method, p_param) AS iso;
+    END IF;
+
+    SELECT ST_Union(r.geom) INTO v_extent FROM temp_barrier_raw r;
+
+    -- 范围内没有屏障时原样返回
+    IF v_extent IS NULL OR NOT EXISTS (
+        SELECT 1 FROM barrier b
+        WHERE b.geom && v_extent AND ST_Intersects(b.geom, v_extent)
+    ) THEN
+        RETURN QUERY
+        SELECT r.minutes, r.distance_m, r.geom, ST_AsGeoJSON(r.geom), r.fallback, r.method, FALSE
+        FROM temp_barrier_raw r
+        ORDER BY r.minutes;
+        DROP TABLE IF EXISTS temp_barrier_raw;
+        RETURN;
+    END IF;
+
+    -- 一次路网分析得到可达节点，供各阈值判断碎片是否可达
+    DROP TABLE IF EXISTS temp_barrier_nodes;
+    CREATE TEMP TABLE temp_barrier_nodes (
+        node BIGINT PRIMARY KEY,
+        agg_cost DOUBLE PRECISION
+    );
+
+    v_source_id := find_nearest_node(p_lng, p_lat);
+    IF v_source_id IS NOT NULL THEN
+        INSERT INTO temp_barrier_nodes (node, agg_cost)
+        SELECT dd.node, dd.agg_cost
+        FROM pgr_drivingDistance(
+            'SELECT gid AS id, source, target,
+                    length_m / ' || v_speed || ' AS cost,
+                    length_m / ' || v_speed || ' AS reverse_cost
+             FROM ways',
+            v_source_id,
+            v_max_cost,
+            FALSE
+        ) AS dd;
+    END IF;
+
+    FOR v_row IN SELECT * FROM temp_barrier_raw r ORDER BY r.minutes
+    LOOP
+        SELECT ST_Union(b.geom) INTO v_water
+        FROM barrier b
+        WHERE b.kind = 'water'
+          AND ST_Dimension(b.geom) = 2
+          AND b.geom && v_row.geom
+          AND ST_Intersects(b.geom, v_row.geom);
+
+        SELECT ST_Union(b.geom) INTO v_lines
+        FROM barrier b
+        WHERE ST_Dimension(b.geom) = 1
+          AND b.geom && v_row.geom
+          AND ST_Intersects(b.geom, v_row.geom);
+
+        v_result := v_row.geom;
+        IF v_water IS NOT NULL THEN
+            v_result := ST_CollectionExtract(ST_Difference(v_result, v_water), 3);
+        END IF;
+
+        -- 按线状屏障切分后，只保留起点所在或含可达节点/道路的碎片
+        WITH parts AS (
+            SELECT (ST_Dump(v_result)).geom AS g
+        ),
+        pieces AS (
+            SELECT (ST_Dump(
+                CASE WHEN v_lines IS NULL THEN p.g ELSE ST_Split(p.g, v_lines) END
+            )).geom AS g
+            FROM parts p
+        )
+        SELECT ST_Union(pc.g) INTO v_result
+        FROM pieces pc
+        WHERE ST_Dimension(pc.g) = 2
+          AND (
+            ST_DWithin(pc.g::geography, v_origin::geography, 1)
+            OR EXISTS (
+                SELECT 1
+                FROM temp_barrier_nodes n
+                JOIN ways_vertices_pgr v ON v.id = n.node
+                WHERE n.agg_cost <= v_row.minutes
+                  AND v.the_geom && pc.g
+                  AND ST_Contains(pc.g, v.the_geom)
+            )
+            OR EXISTS (
+                -- 道路内部穿过碎片内部（只在边界相接的断头路不算）
+                SELECT 1
+                FROM ways w
+                JOIN temp_barrier_nodes n ON n.node IN (w.source, w.target)
+                WHERE n.agg_cost <= v_row.minutes
+                  AND w.the_geom && pc.g
+                  AND ST_Relate(pc.g, w.the_geom, 'T********')
+            )
+          );
+
+        minutes := v_row.minutes;
+        distance_m := v_row.distance_m;
+        fallback := v_row.fallback;
+        method := v_row.method;
+        IF v_result IS NULL OR ST_IsEmpty(v_result) THEN
+            -- 裁剪后一无所剩（如起点落在水面上），保留原结果
+            geom := v_row.geom;
+            clipped := FALSE;
+        ELSE
+            geom := v_result;
+            clipped := ST_Area(v_result) < ST_Area(v_row.geom) * 0.999;
+        END IF;
+        geojson := ST_AsGeoJSON(geom);
+        RETURN NEXT;
+    END LOOP;
+
+    DROP TABLE IF EXISTS temp_barrier_raw;
+    DROP TABLE IF EXISTS temp_barrier_nodes;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION calculate_isochrones_barrier IS '按水面、铁路、高速公路屏障裁剪等时圈，屏障对岸仅在有可达道路时保留';
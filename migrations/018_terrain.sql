@@ -0,0 +1,416 @@
+-- ============================================================
+-- v2.14 考虑地形的步行时间
+--
+-- 导入器（cmd/importer -dem）在路网节点处采样 GeoTIFF 高程，
+-- 并按 source -> target 方向写入各边坡度 gradient（升高 / 长度）。
+-- 请求 terrain: true 时，边代价乘以坡度系数 terrain_factor：
+--
+--   tobler   Tobler 徒步函数 v = 6·exp(-3.5·|S + 0.05|)，以平地速度归一化，
+--            缓下坡（约 -5%）最快，陡坡明显变慢
+--   penalty  上坡按 1 + p·S 加罚（p 默认 5，即 10% 坡度耗时 1.5 倍），下坡不变
+--
+-- 没有高程数据的边（gradient 为 NULL）按平地计算
+-- ============================================================
+
+-- ============================================================
+-- 1. 高程与坡度字段
+-- ============================================================
+
+ALTER TABLE ways_vertices_pgr ADD COLUMN IF NOT EXISTS elevation DOUBLE PRECISION;
+ALTER TABLE ways ADD COLUMN IF NOT EXISTS rise_m DOUBLE PRECISION;
+ALTER TABLE ways ADD COLUMN IF NOT EXISTS gradient DOUBLE PRECISION;
+
+COMMENT ON COLUMN ways_vertices_pgr.elevation IS '节点高程（米），由 DEM 采样';
+COMMENT ON COLUMN ways.rise_m IS 'source 到 target 的高程变化（米）';
+COMMENT ON COLUMN ways.gradient IS 'source 到 target 方向的坡度（升高/长度）';
+
+-- ============================================================
+-- 2. 坡度系数：沿坡度 p_gradient 方向通行的耗时倍数
+-- ============================================================
+
+CREATE OR REPLACE FUNCTION terrain_factor(
+    p_gradient DOUBLE PRECISION,
+    p_terrain TEXT,
+    p_slope_penalty DOUBLE PRECISION DEFAULT NULL
+)
+RETURNS DOUBLE PRECISION AS $$
+    SELECT CASE
+        WHEN p_terrain IS NULL OR p_gradient IS NULL THEN 1.0
+        WHEN p_terrain = 'tobler' THEN exp(3.5 * abs(p_gradient + 0.05)) / exp(3.5 * 0.05)
+        WHEN p_terrain = 'penalty' THEN 1.0 + COALESCE(p_slope_penalty, 5.0) * GREATEST(p_gradient, 0)
+        ELSE 1.0
+    END;
+$$ LANGUAGE sql IMMUTABLE;
+
+COMMENT ON FUNCTION terrain_factor IS '坡度耗时系数：tobler（Tobler 徒步函数）/ penalty（上坡线性加罚）';
+
+-- ============================================================
+-- 3. 步行路网边 SQL（供 pgr_drivingDistance 使用）
+-- p_speed 为平地速度（米/分钟），反向通行时坡度取反
+-- ============================================================
+
+CREATE OR REPLACE FUNCTION walk_edges_sql(
+    p_speed DOUBLE PRECISION,
+    p_terrain TEXT DEFAULT NULL,
+    p_slope_penalty DOUBLE PRECISION DEFAULT NULL
+)
+RETURNS TEXT AS $$
+    SELECT CASE
+        WHEN p_terrain IS NULL THEN
+            'SELECT gid AS id, source, target,
+                    length_m / ' || p_speed || ' AS cost,
+                    length_m / ' || p_speed || ' AS reverse_cost
+             FROM ways'
+        ELSE
+            'SELECT gid AS id, source, target,
+                    length_m / ' || p_speed || ' * terrain_factor(gradient, ' || quote_literal(p_terrain) || ', ' || COALESCE(p_slope_penalty::TEXT, 'NULL') || ') AS cost,
+                    length_m / ' || p_speed || ' * terrain_factor(-gradient, ' || quote_literal(p_terrain) || ', ' || COALESCE(p_slope_penalty::TEXT, 'NULL') || ') AS reverse_cost
+             FROM ways'
+    END;
+$$ LANGUAGE sql IMMUTABLE;
+
+COMMENT ON FUNCTION walk_edges_sql IS '生成步行路网边 SQL，可按坡度调整通行代价';
+
+-- ============================================================
+-- 4. 多边形算法函数增加地形参数（其余同 015）
+-- ============================================================
+
+DROP FUNCTION IF EXISTS calculate_isochrones_method(DOUBLE PRECISION, DOUBLE PRECISION, INTEGER[], DOUBLE PRECISION, TEXT, DOUBLE PRECISION);
+DROP FUNCTION IF EXISTS calculate_isochrones_method(DOUBLE PRECISION, DOUBLE PRECISION, INTEGER[], DOUBLE PRECISION, TEXT, DOUBLE PRECISION, TEXT, DOUBLE PRECISION);
+
+CREATE OR REPLACE FUNCTION calculate_isochrones_method(
+    p_lng DOUBLE PRECISION,
+    p_lat DOUBLE PRECISION,
+    p_time_thresholds INTEGER[] DEFAULT ARRAY[5, 10, 15],
+    p_walk_speed_kmh DOUBLE PRECISION DEFAULT 5.0,
+    p_method TEXT DEFAULT 'concave',
+    p_param DOUBLE PRECISION DEFAULT NULL,
+    p_terrain TEXT DEFAULT NULL,
+    p_slope_penalty DOUBLE PRECISION DEFAULT NULL
+)
+RETURNS TABLE (
+    minutes INTEGER,
+    distance_m DOUBLE PRECISION,
+    geom GEOMETRY,
+    geojson TEXT,
+    fallback TEXT,
+    method TEXT
+) AS $$
+DECLARE
+    v_source_id BIGINT;
+    v_origin GEOMETRY;
+    v_speed DOUBLE PRECISION;   -- 米/分钟
+    v_max_cost DOUBLE PRECISION;
+    v_threshold INTEGER;
+    v_collected GEOMETRY;
+    v_cnt INTEGER;
+    v_result GEOMETRY;
+    v_width DOUBLE PRECISION;
+BEGIN
+    IF p_method NOT IN ('concave', 'alpha', 'road_buffer', 'interpolated') THEN
+        RAISE EXCEPTION 'unknown polygon method: %', p_method;
+    END IF;
+    IF p_terrain IS NOT NULL AND p_terrain NOT IN ('tobler', 'penalty') THEN
+        RAISE EXCEPTION 'unknown terrain model: %', p_terrain;
+    END IF;
+
+    v_origin := ST_SetSRID(ST_MakePoint(p_lng, p_lat), 4326);
+    v_speed := p_walk_speed_kmh * 1000.0 / 60.0;
+    v_source_id := find_nearest_node(p_lng, p_lat);
+    SELECT MAX(t) INTO v_max_cost FROM unnest(p_time_thresholds) AS t;
+
+    DROP TABLE IF EXISTS temp_method_nodes;
+    CREATE TEMP TABLE temp_method_nodes (
+        node BIGINT PRIMARY KEY,
+        agg_cost DOUBLE PRECISION
+    );
+
+    IF v_source_id IS NOT NULL THEN
+        INSERT INTO temp_method_nodes (node, agg_cost)
+        SELECT dd.node, dd.agg_cost
+        FROM pgr_drivingDistance(
+            walk_edges_sql(v_speed, p_terrain, p_slope_penalty),
+            v_source_id,
+            v_max_cost,
+            FALSE
+        ) AS dd;
+    END IF;
+
+    FOREACH v_threshold IN ARRAY p_time_thresholds
+    LOOP
+        v_result := NULL;
+
+        IF p_method IN ('concave', 'alpha') THEN
+            SELECT ST_Collect(pt.the_geom), COUNT(*)
+            INTO v_collected, v_cnt
+            FROM (
+                SELECT v_origin AS the_geom
+                UNION ALL
+                SELECT v.the_geom
+                FROM temp_method_nodes n
+                JOIN ways_vertices_pgr v ON v.id = n.node
+                WHERE n.agg_cost <= v_threshold
+            ) AS pt;
+
+            IF v_cnt >= 10 THEN
+                IF p_method = 'concave' THEN
+                    v_result := ST_ConcaveHull(v_collected, COALESCE(p_param, 0.5));
+                ELSE
+                    SELECT ST_Union(tri.geom) INTO v_result
+                    FROM (
+                        SELECT (ST_Dump(ST_DelaunayTriangles(v_collected))).geom
+                    ) AS tri
+                    WHERE ST_Length(ST_LongestLine(tri.geom, tri.geom)::geography) <= COALESCE(p_param, 150);
+                END IF;
+            END IF;
+        ELSE
+            v_width := COALESCE(p_param, CASE WHEN p_method = 'road_buffer' THEN 50 ELSE 25 END);
+
+            WITH edge_reach AS (
+                SELECT
+                    w.the_geom,
+                    w.length_m,
+                    -- 从两端还能沿该道路走多远（占道路长度的比例），上下坡代价不同
+                    (v_threshold - s.agg_cost)
+                        / NULLIF(w.length_m / v_speed * terrain_factor(w.gradient, p_terrain, p_slope_penalty), 0) AS fs,
+                    (v_threshold - t.agg_cost)
+                        / NULLIF(w.length_m / v_speed * terrain_factor(-w.gradient, p_terrain, p_slope_penalty), 0) AS ft,
+                    s.agg_cost <= v_threshold AS s_ok,
+                    t.agg_cost <= v_threshold AS t_ok
+                FROM ways w
+                LEFT JOIN temp_method_nodes s ON s.node = w.source
+                LEFT JOIN temp_method_nodes t ON t.node = w.target
+                WHERE s.agg_cost <= v_threshold OR t.agg_cost <= v_threshold
+            ),
+            segments AS (
+                -- 整条可达道路
+                SELECT er.the_geom AS g
+                FROM edge_reach er
+                WHERE CASE
+                    WHEN p_method = 'road_buffer' THEN COALESCE(er.s_ok, FALSE) AND COALESCE(er.t_ok, FALSE)
+                    ELSE er.length_m = 0
+                      OR GREATEST(COALESCE(er.fs, 0), 0) + GREATEST(COALESCE(er.ft, 0), 0) >= 1
+                END
+                UNION ALL
+                -- 从起点端截取
+                SELECT ST_LineSubstring(er.the_geom, 0, er.fs)
+                FROM edge_reach er
+                WHERE p_method = 'interpolated'
+                  AND er.fs > 0
+                  AND er.fs + GREATEST(COALESCE(er.ft, 0), 0) < 1
+                UNION ALL
+                -- 从终点端截取
+                SELECT ST_LineSubstring(er.the_geom, 1 - er.ft, 1)
+                FROM edge_reach er
+                WHERE p_method = 'interpolated'
+                  AND er.ft > 0
+                  AND er.ft + GREATEST(COALESCE(er.fs, 0), 0) < 1
+            )
+            SELECT ST_Union(ST_Buffer(sg.g::geography, v_width)::geometry), COUNT(*)
+            INTO v_result, v_cnt
+            FROM segments sg;
+
+            IF v_result IS NOT NULL THEN
+                -- 起点到路网的连接段
+                v_result := ST_Union(v_result, ST_Buffer(v_origin::geography, v_width)::geometry);
+            END IF;
+        END IF;
+
+        fallback := NULL;
+        IF v_result IS NULL OR ST_IsEmpty(v_result) THEN
+            v_result := ST_Transform(
+                ST_Buffer(ST_Transform(v_origin, 3857), v_speed * v_threshold),
+                4326
+            );
+            fallback := 'buffer';
+        ELSIF NOT ST_Intersects(v_origin, v_result) THEN
+            v_result := ST_Union(v_result, ST_Transform(ST_Buffer(ST_Transform(v_origin, 3857), 50), 4326));
+        END IF;
+
+        minutes := v_threshold;
+        distance_m := v_speed * v_threshold;
+        geom := v_result;
+        geojson := ST_AsGeoJSON(v_result);
+        method := p_method;
+        RETURN NEXT;
+    END LOOP;
+
+    DROP TABLE IF EXISTS temp_method_nodes;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION calculate_isochrones_method IS '按指定算法生成等时圈多边形：concave / alpha / road_buffer / interpolated，可按坡度计算步行时间';
+
+-- ============================================================
+-- 5. 屏障裁剪函数增加地形参数（其余同 016）
+-- ============================================================
+
+DROP FUNCTION IF EXISTS calculate_isochrones_barrier(DOUBLE PRECISION, DOUBLE PRECISION, INTEGER[], DOUBLE PRECISION, TEXT, DOUBLE PRECISION);
+DROP FUNCTION IF EXISTS calculate_isochrones_barrier(DOUBLE PRECISION, DOUBLE PRECISION, INTEGER[], DOUBLE PRECISION, TEXT, DOUBLE PRECISION, TEXT, DOUBLE PRECISION);
+
+CREATE OR REPLACE FUNCTION calculate_isochrones_barrier(
+    p_lng DOUBLE PRECISION,
+    p_lat DOUBLE PRECISION,
+    p_time_thresholds INTEGER[] DEFAULT ARRAY[5, 10, 15],
+    p_walk_speed_kmh DOUBLE PRECISION DEFAULT 5.0,
+    p_method TEXT DEFAULT NULL,
+    p_param DOUBLE PRECISION DEFAULT NULL,
+    p_terrain TEXT DEFAULT NULL,
+    p_slope_penalty DOUBLE PRECISION DEFAULT NULL
+)
+RETURNS TABLE (
+    minutes INTEGER,
+    distance_m DOUBLE PRECISION,
+    geom GEOMETRY,
+    geojson TEXT,
+    fallback TEXT,
+    method TEXT,
+    clipped BOOLEAN
+) AS $$
+#variable_conflict use_column
+DECLARE
+    v_source_id BIGINT;
+    v_origin GEOMETRY;
+    v_speed DOUBLE PRECISION;   -- 米/分钟
+    v_max_cost DOUBLE PRECISION;
+    v_extent GEOMETRY;
+    v_row RECORD;
+    v_water GEOMETRY;
+    v_lines GEOMETRY;
+    v_result GEOMETRY;
+BEGIN
+    v_origin := ST_SetSRID(ST_MakePoint(p_lng, p_lat), 4326);
+    v_speed := p_walk_speed_kmh * 1000.0 / 60.0;
+    SELECT MAX(t) INTO v_max_cost FROM unnest(p_time_thresholds) AS t;
+
+    DROP TABLE IF EXISTS temp_barrier_raw;
+    CREATE TEMP TABLE temp_barrier_raw (
+        minutes INTEGER,
+        distance_m DOUBLE PRECISION,
+        geom GEOMETRY,
+        fallback TEXT,
+        method TEXT
+    );
+
+    -- 默认凹壳且不考虑地形时沿用 calculate_isochrones，地形只在 calculate_isochrones_method 中实现
+    IF p_method IS NULL AND p_terrain IS NULL THEN
+        INSERT INTO temp_barrier_raw
+        SELECT iso.minutes, iso.distance_m, iso.geom, iso.fallback, 'concave'
+        FROM calculate_isochrones(p_lng, p_lat, p_time_thresholds, p_walk_speed_kmh) AS iso;
+    ELSE
+        INSERT INTO temp_barrier_raw
+        SELECT iso.minutes, iso.distance_m, iso.geom, iso.fallback, iso.method
+        FROM calculate_isochrones_method(p_lng, p_lat, p_time_thresholds, p_walk_speed_kmh,
+                                         COALESCE(p_method, 'concave'), p_param, p_terrain, p_slope_penalty) AS iso;
+    END IF;
+
+    SELECT ST_Union(r.geom) INTO v_extent FROM temp_barrier_raw r;
+
+    -- 范围内没有屏障时原样返回
+    IF v_extent IS NULL OR NOT EXISTS (
+        SELECT 1 FROM barrier b
+        WHERE b.geom && v_extent AND ST_Intersects(b.geom, v_extent)
+    ) THEN
+        RETURN QUERY
+        SELECT r.minutes, r.distance_m, r.geom, ST_AsGeoJSON(r.geom), r.fallback, r.method, FALSE
+        FROM temp_barrier_raw r
+        ORDER BY r.minutes;
+        DROP TABLE IF EXISTS temp_barrier_raw;
+        RETURN;
+    END IF;
+
+    -- 一次路网分析得到可达节点，供各阈值判断碎片是否可达
+    DROP TABLE IF EXISTS temp_barrier_nodes;
+    CREATE TEMP TABLE temp_barrier_nodes (
+        node BIGINT PRIMARY KEY,
+        agg_cost DOUBLE PRECISION
+    );
+
+    v_source_id := find_nearest_node(p_lng, p_lat);
+    IF v_source_id IS NOT NULL THEN
+        INSERT INTO temp_barrier_nodes (node, agg_cost)
+        SELECT dd.node, dd.agg_cost
+        FROM pgr_drivingDistance(
+            walk_edges_sql(v_speed, p_terrain, p_slope_penalty),
+            v_source_id,
+            v_max_cost,
+            FALSE
+        ) AS dd;
+    END IF;
+
+    FOR v_row IN SELECT * FROM temp_barrier_raw r ORDER BY r.minutes
+    LOOP
+        SELECT ST_Union(b.geom) INTO v_water
+        FROM barrier b
+        WHERE b.kind = 'water'
+          AND ST_Dimension(b.geom) = 2
+          AND b.geom && v_row.geom
+          AND ST_Intersects(b.geom, v_row.geom);
+
+        SELECT ST_Union(b.geom) INTO v_lines
+        FROM barrier b
+        WHERE ST_Dimension(b.geom) = 1
+          AND b.geom && v_row.geom
+          AND ST_Intersects(b.geom, v_row.geom);
+
+        v_result := v_row.geom;
+        IF v_water IS NOT NULL THEN
+            v_result := ST_CollectionExtract(ST_Difference(v_result, v_water), 3);
+        END IF;
+
+        -- 按线状屏障切分后，只保留起点所在或含可达节点/道路的碎片
+        WITH parts AS (
+            SELECT (ST_Dump(v_result)).geom AS g
+        ),
+        pieces AS (
+            SELECT (ST_Dump(
+                CASE WHEN v_lines IS NULL THEN p.g ELSE ST_Split(p.g, v_lines) END
+            )).geom AS g
+            FROM parts p
+        )
+        SELECT ST_Union(pc.g) INTO v_result
+        FROM pieces pc
+        WHERE ST_Dimension(pc.g) = 2
+          AND (
+            ST_DWithin(pc.g::geography, v_origin::geography, 1)
+            OR EXISTS (
+                SELECT 1
+                FROM temp_barrier_nodes n
+                JOIN ways_vertices_pgr v ON v.id = n.node
+                WHERE n.agg_cost <= v_row.minutes
+                  AND v.the_geom && pc.g
+                  AND ST_Contains(pc.g, v.the_geom)
+            )
+            OR EXISTS (
+                -- 道路内部穿过碎片内部（只在边界相接的断头路不算）
+                SELECT 1
+                FROM ways w
+                JOIN temp_barrier_nodes n ON n.node IN (w.source, w.target)
+                WHERE n.agg_cost <= v_row.minutes
+                  AND w.the_geom && pc.g
+                  AND ST_Relate(pc.g, w.the_geom, 'T********')
+            )
+          );
+
+        minutes := v_row.minutes;
+        distance_m := v_row.distance_m;
+        fallback := v_row.fallback;
+        method := v_row.method;
+        IF v_result IS NULL OR ST_IsEmpty(v_result) THEN
+            -- 裁剪后一无所剩（如起点落在水面上），保留原结果
+            geom := v_row.geom;
+            clipped := FALSE;
+        ELSE
+            geom := v_result;
+            clipped := ST_Area(v_result) < ST_Area(v_row.geom) * 0.999;
+        END IF;
+        geojson := ST_AsGeoJSON(geom);
+        RETURN NEXT;
+    END LOOP;
+
+    DROP TABLE IF EXISTS temp_barrier_raw;
+    DROP TABLE IF EXISTS temp_barrier_nodes;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION calculate_isochrones_barrier IS '按水面、铁路、高速公路屏障裁剪等时圈，屏障对岸仅在有可达道路时保留；可按坡度计算步行时间';
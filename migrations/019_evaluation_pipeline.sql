@@ -0,0 +1,405 @@
+-- ============================================================
+-- v2.15 单次路网分析的评价流程
+--
+-- 原 /analyze 一次请求至少做五次路网分析：
+--   evaluate_life_circle 内三次 calculate_isochrone、等时圈两次、
+--   query_pois_in_isochrone 一次、get_reachable_roads 内两次 pgr_drivingDistance
+--
+-- 新流程由服务端编排：
+--   1. evaluation_reach      吸附一次、路网分析一次，返回可达节点及累计时间
+--   2. isochrones_from_reach 由可达节点生成 5/10/15 分钟等时圈（凹壳 + 屏障裁剪）
+--   3. pois_in_isochrones    一次查询得到各 POI 所在的最小圈层
+--   4. roads_from_reach      由可达节点得到可达道路
+--   5. nearest_facilities_from_reach 由可达节点查找必备设施的最近距离
+--      （nearest_facilities 会再吸附一次并做 30 分钟的路网分析，查找范围即评价的可达范围）
+--   评分在服务端按圈层计数完成
+--
+-- 可达节点以数组在各步骤间传递，各步骤可在不同连接上并发执行
+-- ============================================================
+
+-- ============================================================
+-- 1. 吸附与路网分析
+-- 吸附距离与覆盖检查同 check_origin_coverage，
+-- 超过 p_max_snap_m 时不做路网分析（各等时圈退化为缓冲区）
+-- ============================================================
+
+CREATE OR REPLACE FUNCTION evaluation_reach(
+    p_lng DOUBLE PRECISION,
+    p_lat DOUBLE PRECISION,
+    p_walk_speed_kmh DOUBLE PRECISION DEFAULT 5.0,
+    p_max_minutes INTEGER DEFAULT 15,
+    p_max_snap_m DOUBLE PRECISION DEFAULT 500
+)
+RETURNS TABLE (
+    in_coverage BOOLEAN,
+    city VARCHAR,
+    snap_distance_m DOUBLE PRECISION,
+    source_node BIGINT,
+    nodes BIGINT[],
+    costs DOUBLE PRECISION[]
+) AS $$
+#variable_conflict use_column
+DECLARE
+    v_nearest BIGINT;
+BEGIN
+    SELECT c.in_coverage, c.city, c.nearest_node, c.snap_distance_m
+    INTO in_coverage, city, v_nearest, snap_distance_m
+    FROM check_origin_coverage(p_lng, p_lat) AS c;
+
+    IF v_nearest IS NOT NULL AND snap_distance_m <= p_max_snap_m THEN
+        source_node := v_nearest;
+        SELECT array_agg(dd.node ORDER BY dd.agg_cost), array_agg(dd.agg_cost ORDER BY dd.agg_cost)
+        INTO nodes, costs
+        FROM pgr_drivingDistance(
+            walk_edges_sql(p_walk_speed_kmh * 1000.0 / 60.0),
+            v_nearest,
+            p_max_minutes,
+            FALSE
+        ) AS dd;
+    END IF;
+
+    nodes := COALESCE(nodes, ARRAY[]::BIGINT[]);
+    costs := COALESCE(costs, ARRAY[]::DOUBLE PRECISION[]);
+    RETURN NEXT;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+COMMENT ON FUNCTION evaluation_reach IS '吸附起点并做一次路网分析，返回可达节点及累计步行时间（分钟）';
+
+-- ============================================================
+-- 2. 屏障裁剪（逻辑同 calculate_isochrones_barrier，可达节点由参数传入）
+-- 无需裁剪或裁剪后为空时返回 NULL
+-- ============================================================
+
+CREATE OR REPLACE FUNCTION clip_isochrone_barriers(
+    p_geom GEOMETRY,
+    p_origin GEOMETRY,
+    p_minutes INTEGER,
+    p_nodes BIGINT[],
+    p_costs DOUBLE PRECISION[]
+)
+RETURNS GEOMETRY AS $$
+DECLARE
+    v_water GEOMETRY;
+    v_lines GEOMETRY;
+    v_result GEOMETRY;
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM barrier b
+        WHERE b.geom && p_geom AND ST_Intersects(b.geom, p_geom)
+    ) THEN
+        RETURN NULL;
+    END IF;
+
+    SELECT ST_Union(b.geom) INTO v_water
+    FROM barrier b
+    WHERE b.kind = 'water'
+      AND ST_Dimension(b.geom) = 2
+      AND b.geom && p_geom
+      AND ST_Intersects(b.geom, p_geom);
+
+    SELECT ST_Union(b.geom) INTO v_lines
+    FROM barrier b
+    WHERE ST_Dimension(b.geom) = 1
+      AND b.geom && p_geom
+      AND ST_Intersects(b.geom, p_geom);
+
+    v_result := p_geom;
+    IF v_water IS NOT NULL THEN
+        v_result := ST_CollectionExtract(ST_Difference(v_result, v_water), 3);
+    END IF;
+
+    WITH reach AS (
+        SELECT r.node, r.agg_cost
+        FROM unnest(p_nodes, p_costs) AS r(node, agg_cost)
+        WHERE r.agg_cost <= p_minutes
+    ),
+    parts AS (
+        SELECT (ST_Dump(v_result)).geom AS g
+    ),
+    pieces AS (
+        SELECT (ST_Dump(
+            CASE WHEN v_lines IS NULL THEN p.g ELSE ST_Split(p.g, v_lines) END
+        )).geom AS g
+        FROM parts p
+    )
+    SELECT ST_Union(pc.g) INTO v_result
+    FROM pieces pc
+    WHERE ST_Dimension(pc.g) = 2
+      AND (
+        ST_DWithin(pc.g::geography, p_origin::geography, 1)
+        OR EXISTS (
+            SELECT 1
+            FROM reach n
+            JOIN ways_vertices_pgr v ON v.id = n.node
+            WHERE v.the_geom && pc.g
+              AND ST_Contains(pc.g, v.the_geom)
+        )
+        OR EXISTS (
+            SELECT 1
+            FROM ways w
+            JOIN reach n ON n.node IN (w.source, w.target)
+            WHERE w.the_geom && pc.g
+              AND ST_Relate(pc.g, w.the_geom, 'T********')
+        )
+      );
+
+    IF v_result IS NULL OR ST_IsEmpty(v_result) THEN
+        RETURN NULL;
+    END IF;
+    RETURN v_result;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+COMMENT ON FUNCTION clip_isochrone_barriers IS '按屏障裁剪等时圈，可达节点由参数传入；无需裁剪时返回 NULL';
+
+-- ============================================================
+-- 3. 由可达节点生成等时圈
+-- 多边形算法同 calculate_isochrones_optimized（节点 + 道路端点/中点的凹壳），
+-- 再按屏障裁剪
+-- ============================================================
+
+CREATE OR REPLACE FUNCTION isochrones_from_reach(
+    p_lng DOUBLE PRECISION,
+    p_lat DOUBLE PRECISION,
+    p_time_thresholds INTEGER[],
+    p_walk_speed_kmh DOUBLE PRECISION,
+    p_nodes BIGINT[],
+    p_costs DOUBLE PRECISION[]
+)
+RETURNS TABLE (
+    minutes INTEGER,
+    distance_m DOUBLE PRECISION,
+    geojson TEXT,
+    fallback TEXT,
+    clipped BOOLEAN
+) AS $$
+DECLARE
+    v_origin GEOMETRY;
+    v_threshold INTEGER;
+    v_collected GEOMETRY;
+    v_cnt INTEGER;
+    v_result GEOMETRY;
+    v_clipped GEOMETRY;
+BEGIN
+    v_origin := ST_SetSRID(ST_MakePoint(p_lng, p_lat), 4326);
+
+    FOREACH v_threshold IN ARRAY p_time_thresholds
+    LOOP
+        WITH reach AS (
+            SELECT r.node
+            FROM unnest(p_nodes, p_costs) AS r(node, agg_cost)
+            WHERE r.agg_cost <= v_threshold
+        ),
+        reach_ways AS (
+            SELECT w.the_geom
+            FROM ways w
+            WHERE w.source IN (SELECT node FROM reach)
+              AND w.target IN (SELECT node FROM reach)
+        )
+        SELECT ST_Collect(pt.the_geom), COUNT(*)
+        INTO v_collected, v_cnt
+        FROM (
+            SELECT v_origin AS the_geom
+            UNION ALL
+            SELECT v.the_geom
+            FROM reach n
+            JOIN ways_vertices_pgr v ON v.id = n.node
+            UNION ALL
+            SELECT ST_StartPoint(rw.the_geom) FROM reach_ways rw
+            UNION ALL
+            SELECT ST_EndPoint(rw.the_geom) FROM reach_ways rw
+            UNION ALL
+            SELECT ST_LineInterpolatePoint(rw.the_geom, 0.5)
+            FROM reach_ways rw
+            WHERE ST_Length(rw.the_geom) > 0.0001
+        ) AS pt;
+
+        fallback := NULL;
+        v_result := NULL;
+        IF v_cnt >= 10 THEN
+            v_result := COALESCE(ST_ConcaveHull(v_collected, 0.5), ST_ConvexHull(v_collected));
+        END IF;
+
+        IF v_result IS NULL THEN
+            v_result := ST_Transform(
+                ST_Buffer(ST_Transform(v_origin, 3857), p_walk_speed_kmh * v_threshold / 60.0 * 1000),
+                4326
+            );
+            fallback := 'buffer';
+        ELSIF NOT ST_Within(v_origin, v_result) THEN
+            v_result := ST_Union(v_result, ST_Transform(ST_Buffer(ST_Transform(v_origin, 3857), 50), 4326));
+        END IF;
+
+        clipped := FALSE;
+        v_clipped := clip_isochrone_barriers(v_result, v_origin, v_threshold, p_nodes, p_costs);
+        IF v_clipped IS NOT NULL THEN
+            clipped := ST_Area(v_clipped) < ST_Area(v_result) * 0.999;
+            v_result := v_clipped;
+        END IF;
+
+        minutes := v_threshold;
+        distance_m := p_walk_speed_kmh * v_threshold / 60.0 * 1000;
+        geojson := ST_AsGeoJSON(v_result);
+        RETURN NEXT;
+    END LOOP;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+COMMENT ON FUNCTION isochrones_from_reach IS '由可达节点生成各时间阈值的等时圈（凹壳，按屏障裁剪）';
+
+-- ============================================================
+-- 4. 各 POI 所在的最小圈层
+-- p_minutes 与 p_geojsons 一一对应，返回最大等时圈内的全部 POI
+-- ============================================================
+
+CREATE OR REPLACE FUNCTION pois_in_isochrones(
+    p_lng DOUBLE PRECISION,
+    p_lat DOUBLE PRECISION,
+    p_walk_speed_kmh DOUBLE PRECISION,
+    p_minutes INTEGER[],
+    p_geojsons TEXT[]
+)
+RETURNS TABLE (
+    id BIGINT,
+    name VARCHAR,
+    category VARCHAR,
+    sub_type VARCHAR,
+    lng DOUBLE PRECISION,
+    lat DOUBLE PRECISION,
+    distance_m DOUBLE PRECISION,
+    walk_time_min DOUBLE PRECISION,
+    opening_hours TEXT,
+    within_minutes INTEGER
+) AS $$
+    WITH rings AS (
+        SELECT r.minutes, ST_SetSRID(ST_GeomFromGeoJSON(r.geojson), 4326) AS geom
+        FROM unnest(p_minutes, p_geojsons) AS r(minutes, geojson)
+    ),
+    outer_ring AS (
+        SELECT geom FROM rings ORDER BY minutes DESC LIMIT 1
+    ),
+    origin AS (
+        SELECT ST_SetSRID(ST_MakePoint(p_lng, p_lat), 4326)::geography AS g
+    )
+    SELECT
+        p.id,
+        p.name,
+        p.category,
+        p.sub_type,
+        ST_X(p.geom),
+        ST_Y(p.geom),
+        ST_Distance(p.geom::geography, o.g) AS distance_m,
+        ST_Distance(p.geom::geography, o.g) / (p_walk_speed_kmh * 1000 / 60),
+        p.tags->'opening_hours',
+        (
+            SELECT MIN(r.minutes)
+            FROM rings r
+            WHERE ST_Within(p.geom, r.geom)
+        )
+    FROM poi p
+    CROSS JOIN outer_ring x
+    CROSS JOIN origin o
+    WHERE p.geom && x.geom
+      AND ST_Within(p.geom, x.geom)
+      AND p.deleted_at IS NULL
+    ORDER BY distance_m;
+$$ LANGUAGE sql STABLE;
+
+COMMENT ON FUNCTION pois_in_isochrones IS '查询最大等时圈内的 POI 及其所在的最小圈层（分钟）';
+
+-- ============================================================
+-- 5. 由可达节点得到可达道路（输出同 get_reachable_roads）
+-- ============================================================
+
+CREATE OR REPLACE FUNCTION roads_from_reach(
+    p_minutes INTEGER,
+    p_nodes BIGINT[],
+    p_costs DOUBLE PRECISION[]
+)
+RETURNS TEXT AS $$
+    WITH reach AS (
+        SELECT r.node, r.agg_cost
+        FROM unnest(p_nodes, p_costs) AS r(node, agg_cost)
+        WHERE r.agg_cost <= p_minutes
+    )
+    SELECT json_build_object(
+        'type', 'FeatureCollection',
+        'features', COALESCE(json_agg(
+            json_build_object(
+                'type', 'Feature',
+                'geometry', ST_AsGeoJSON(w.the_geom)::json,
+                'properties', json_build_object(
+                    'name', COALESCE(w.name, ''),
+                    'type', 'road',
+                    'cost', LEAST(t1.agg_cost, t2.agg_cost)
+                )
+            )
+        ), '[]'::json)
+    )::text
+    FROM ways w
+    JOIN reach t1 ON w.source = t1.node
+    JOIN reach t2 ON w.target = t2.node;
+$$ LANGUAGE sql STABLE;
+
+COMMENT ON FUNCTION roads_from_reach IS '由可达节点得到两端均可达的道路 FeatureCollection';
+
+-- ============================================================
+-- 6. 由可达节点查找各子类型最近的设施（不含路径）
+-- 候选设施吸附到最近的节点，不在可达节点内的设施不计
+-- ============================================================
+
+CREATE OR REPLACE FUNCTION nearest_facilities_from_reach(
+    p_lng DOUBLE PRECISION,
+    p_lat DOUBLE PRECISION,
+    p_sub_types TEXT[],
+    p_walk_speed_kmh DOUBLE PRECISION,
+    p_max_minutes INTEGER,
+    p_nodes BIGINT[],
+    p_costs DOUBLE PRECISION[]
+)
+RETURNS TABLE (
+    sub_type VARCHAR,
+    category VARCHAR,
+    poi_id BIGINT,
+    name VARCHAR,
+    distance_m DOUBLE PRECISION,
+    walk_time_min DOUBLE PRECISION
+) AS $$
+    WITH reach AS (
+        SELECT r.node, r.agg_cost
+        FROM unnest(p_nodes, p_costs) AS r(node, agg_cost)
+    ),
+    -- 候选设施吸附到最近的节点，总时间 = 路网时间 + 节点到设施的直线步行时间（同 nearest_facilities）
+    candidates AS (
+        SELECT
+            p.id,
+            p.sub_type,
+            p.category,
+            p.name,
+            r.agg_cost * (p_walk_speed_kmh * 1000.0 / 60.0) + snap.snap_m AS total_m,
+            r.agg_cost + snap.snap_m / (p_walk_speed_kmh * 1000.0 / 60.0) AS total_min
+        FROM poi p
+        CROSS JOIN LATERAL (
+            SELECT v.id AS node,
+                   ST_Distance(v.the_geom::geography, p.geom::geography) AS snap_m
+            FROM ways_vertices_pgr v
+            ORDER BY v.the_geom <-> p.geom
+            LIMIT 1
+        ) snap
+        JOIN reach r ON r.node = snap.node
+        WHERE p.sub_type = ANY(p_sub_types)
+          AND p.deleted_at IS NULL
+          AND ST_DWithin(
+              p.geom::geography,
+              ST_SetSRID(ST_MakePoint(p_lng, p_lat), 4326)::geography,
+              p_walk_speed_kmh * 1000.0 / 60.0 * p_max_minutes
+          )
+    )
+    SELECT DISTINCT ON (c.sub_type)
+        c.sub_type, c.category, c.id, c.name, c.total_m, c.total_min
+    FROM candidates c
+    ORDER BY c.sub_type, c.total_min, c.id;
+$$ LANGUAGE sql STABLE;
+
+COMMENT ON FUNCTION nearest_facilities_from_reach IS '由可达节点查找各子类型最近的设施（不做路网分析、不含路径）';
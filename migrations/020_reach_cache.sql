@@ -0,0 +1,217 @@
+-- ============================================================
+-- v2.16 可达性预计算
+--
+-- 点击位置最终都吸附到有限的路网节点上，同一节点、同一速度的路网分析可以复用：
+--   node_reach  按节点、速度档预计算 15 分钟内可达节点及累计时间
+--              （cmd/precompute 生成），evaluation_reach 命中时跳过路网分析
+--
+-- 只复用路网分析，不缓存多边形：凹壳包含点击位置、吸附偏离时追加起点 50 米缓冲、
+-- 屏障裁剪保留起点所在部分，同一节点不同点击位置的多边形并不相同，
+-- 多边形按每次的起点重新生成（见 IsochroneService.Calculate）。
+--
+-- node_reach 记录生成时城市路网的数据版本 network_version，
+-- 导入器重新导入路网或高程时调用 bump_network_version 使旧条目失效
+-- 步行速度按 0.1 km/h 分档（speed_bucket），同档请求共用结果
+-- ============================================================
+
+-- ============================================================
+-- 1. 路网数据版本
+-- 未导入过的城市（含 city 为空的旧数据，记为 ''）版本为 0
+-- ============================================================
+
+CREATE TABLE IF NOT EXISTS network_version (
+    city VARCHAR(50) PRIMARY KEY,
+    version BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE network_version IS '各城市路网数据版本，重新导入时递增，用于缓存失效';
+
+-- 节点所在城市的当前路网版本
+CREATE OR REPLACE FUNCTION node_network_version(p_node BIGINT)
+RETURNS BIGINT AS $$
+    SELECT COALESCE((
+        SELECT nv.version
+        FROM ways_vertices_pgr v
+        JOIN network_version nv ON nv.city = COALESCE(v.city, '')
+        WHERE v.id = p_node
+    ), 0);
+$$ LANGUAGE sql STABLE;
+
+COMMENT ON FUNCTION node_network_version IS '节点所在城市的当前路网数据版本';
+
+-- 速度分档
+CREATE OR REPLACE FUNCTION speed_bucket(p_walk_speed_kmh DOUBLE PRECISION)
+RETURNS NUMERIC AS $$
+    SELECT round(p_walk_speed_kmh::NUMERIC, 1);
+$$ LANGUAGE sql IMMUTABLE;
+
+-- ============================================================
+-- 2. 节点可达性预计算
+-- nodes / costs 同 evaluation_reach，按累计时间升序；
+-- 数组较大，由 TOAST 压缩存储（PostgreSQL 14+ 使用 lz4）
+-- ============================================================
+
+CREATE TABLE IF NOT EXISTS node_reach (
+    node BIGINT NOT NULL,
+    speed_bucket NUMERIC(4, 1) NOT NULL,
+    city VARCHAR(50) NOT NULL DEFAULT '',
+    max_minutes INTEGER NOT NULL,
+    network_version BIGINT NOT NULL,
+    node_count INTEGER NOT NULL,
+    nodes BIGINT[] NOT NULL,
+    costs REAL[] NOT NULL,
+    computed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (node, speed_bucket)
+);
+
+CREATE INDEX IF NOT EXISTS idx_node_reach_city ON node_reach (city, network_version);
+
+DO $$
+BEGIN
+    IF current_setting('server_version_num')::INT >= 140000 THEN
+        ALTER TABLE node_reach ALTER COLUMN nodes SET COMPRESSION lz4;
+        ALTER TABLE node_reach ALTER COLUMN costs SET COMPRESSION lz4;
+    END IF;
+EXCEPTION WHEN feature_not_supported OR invalid_parameter_value THEN
+    -- 未编译 lz4 时保留默认 pglz
+    NULL;
+END $$;
+
+COMMENT ON TABLE node_reach IS '按节点、速度档预计算的可达节点及累计步行时间（分钟）';
+
+-- 计算并保存单个节点的可达性，返回可达节点数
+CREATE OR REPLACE FUNCTION precompute_node_reach(
+    p_node BIGINT,
+    p_walk_speed_kmh DOUBLE PRECISION DEFAULT 5.0,
+    p_max_minutes INTEGER DEFAULT 15
+)
+RETURNS INTEGER AS $$
+DECLARE
+    v_speed NUMERIC := speed_bucket(p_walk_speed_kmh);
+    v_nodes BIGINT[];
+    v_costs REAL[];
+BEGIN
+    SELECT array_agg(dd.node ORDER BY dd.agg_cost), array_agg(dd.agg_cost::REAL ORDER BY dd.agg_cost)
+    INTO v_nodes, v_costs
+    FROM pgr_drivingDistance(
+        walk_edges_sql(v_speed::DOUBLE PRECISION * 1000.0 / 60.0),
+        p_node,
+        p_max_minutes,
+        FALSE
+    ) AS dd;
+
+    v_nodes := COALESCE(v_nodes, ARRAY[p_node]);
+    v_costs := COALESCE(v_costs, ARRAY[0]::REAL[]);
+
+    INSERT INTO node_reach (node, speed_bucket, city, max_minutes, network_version, node_count, nodes, costs)
+    SELECT p_node, v_speed, COALESCE(v.city, ''), p_max_minutes, node_network_version(p_node),
+           cardinality(v_nodes), v_nodes, v_costs
+    FROM ways_vertices_pgr v
+    WHERE v.id = p_node
+    ON CONFLICT (node, speed_bucket) DO UPDATE SET
+        city = EXCLUDED.city,
+        max_minutes = EXCLUDED.max_minutes,
+        network_version = EXCLUDED.network_version,
+        node_count = EXCLUDED.node_count,
+        nodes = EXCLUDED.nodes,
+        costs = EXCLUDED.costs,
+        computed_at = NOW();
+
+    RETURN cardinality(v_nodes);
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION precompute_node_reach IS '预计算节点在 p_max_minutes 内的可达节点并写入 node_reach';
+
+-- ============================================================
+-- 3. 路网版本递增与预计算失效
+-- 在导入事务内调用，新路网提交时旧条目同时删除
+-- ============================================================
+
+CREATE OR REPLACE FUNCTION bump_network_version(p_city VARCHAR)
+RETURNS BIGINT AS $$
+DECLARE
+    v_version BIGINT;
+BEGIN
+    INSERT INTO network_version (city, version)
+    VALUES (COALESCE(p_city, ''), 1)
+    ON CONFLICT (city) DO UPDATE SET
+        version = network_version.version + 1,
+        updated_at = NOW()
+    RETURNING version INTO v_version;
+
+    DELETE FROM node_reach WHERE city = COALESCE(p_city, '') AND network_version < v_version;
+
+    RETURN v_version;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION bump_network_version IS '递增城市路网版本并删除该城市过期的节点可达性预计算';
+
+-- ============================================================
+-- 4. evaluation_reach 优先使用预计算结果（其余同 019）
+-- 命中条件：同速度档、路网版本一致、预计算时长不小于 p_max_minutes
+-- ============================================================
+
+DROP FUNCTION IF EXISTS evaluation_reach(DOUBLE PRECISION, DOUBLE PRECISION, DOUBLE PRECISION, INTEGER, DOUBLE PRECISION);
+
+CREATE OR REPLACE FUNCTION evaluation_reach(
+    p_lng DOUBLE PRECISION,
+    p_lat DOUBLE PRECISION,
+    p_walk_speed_kmh DOUBLE PRECISION DEFAULT 5.0,
+    p_max_minutes INTEGER DEFAULT 15,
+    p_max_snap_m DOUBLE PRECISION DEFAULT 500
+)
+RETURNS TABLE (
+    in_coverage BOOLEAN,
+    city VARCHAR,
+    snap_distance_m DOUBLE PRECISION,
+    source_node BIGINT,
+    nodes BIGINT[],
+    costs DOUBLE PRECISION[],
+    cached BOOLEAN
+) AS $$
+#variable_conflict use_column
+DECLARE
+    v_nearest BIGINT;
+BEGIN
+    SELECT c.in_coverage, c.city, c.nearest_node, c.snap_distance_m
+    INTO in_coverage, city, v_nearest, snap_distance_m
+    FROM check_origin_coverage(p_lng, p_lat) AS c;
+
+    cached := FALSE;
+    IF v_nearest IS NOT NULL AND snap_distance_m <= p_max_snap_m THEN
+        source_node := v_nearest;
+
+        SELECT array_agg(u.node ORDER BY u.ord), array_agg(u.cost::DOUBLE PRECISION ORDER BY u.ord), TRUE
+        INTO nodes, costs, cached
+        FROM node_reach r
+        CROSS JOIN LATERAL unnest(r.nodes, r.costs) WITH ORDINALITY AS u(node, cost, ord)
+        WHERE r.node = v_nearest
+          AND r.speed_bucket = speed_bucket(p_walk_speed_kmh)
+          AND r.max_minutes >= p_max_minutes
+          AND r.network_version = node_network_version(v_nearest)
+          AND u.cost <= p_max_minutes
+        GROUP BY r.node;
+
+        IF NOT COALESCE(cached, FALSE) THEN
+            cached := FALSE;
+            SELECT array_agg(dd.node ORDER BY dd.agg_cost), array_agg(dd.agg_cost ORDER BY dd.agg_cost)
+            INTO nodes, costs
+            FROM pgr_drivingDistance(
+                walk_edges_sql(p_walk_speed_kmh * 1000.0 / 60.0),
+                v_nearest,
+                p_max_minutes,
+                FALSE
+            ) AS dd;
+        END IF;
+    END IF;
+
+    nodes := COALESCE(nodes, ARRAY[]::BIGINT[]);
+    costs := COALESCE(costs, ARRAY[]::DOUBLE PRECISION[]);
+    RETURN NEXT;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+COMMENT ON FUNCTION evaluation_reach IS '吸附起点并做一次路网分析（有预计算结果时直接读取），返回可达节点及累计步行时间（分钟）';
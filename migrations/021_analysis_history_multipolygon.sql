@@ -0,0 +1,11 @@
+-- ============================================================
+-- v2.17 评价记录保存
+--
+-- /analyze 的结果写入 analysis_history（HistoryStore，见 internal/store），
+-- 等时圈经屏障裁剪后可能是 MultiPolygon，三个等时圈列改为 MultiPolygon
+-- ============================================================
+
+ALTER TABLE analysis_history
+    ALTER COLUMN isochrone_5 TYPE GEOMETRY(MultiPolygon, 4326) USING ST_Multi(isochrone_5),
+    ALTER COLUMN isochrone_10 TYPE GEOMETRY(MultiPolygon, 4326) USING ST_Multi(isochrone_10),
+    ALTER COLUMN isochrone_15 TYPE GEOMETRY(MultiPolygon, 4326) USING ST_Multi(isochrone_15);
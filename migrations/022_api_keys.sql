@@ -0,0 +1,55 @@
+-- ============================================================
+-- v2.18 API Key 认证、配额与用量
+--
+-- 客户端通过 X-API-Key 头（或 Authorization: ApiKey <key>）调用 /api/v1：
+--   api_key        只保存 SHA-256 摘要，明文仅在签发时返回一次
+--   api_key_quota  高开销接口（analyze、household、catchment 等）每日用量，
+--                  按 (key, 日期) 原子计数，多实例部署时共享
+--   api_key_usage  按 (key, 日期, 路由) 汇总的请求数，服务端定期批量写入
+-- 速率限制（令牌桶）在各实例内存中进行，不落库
+-- ============================================================
+
+CREATE TABLE IF NOT EXISTS api_key (
+    id SERIAL PRIMARY KEY,
+    -- 客户端名称，如 "规划院内部平台"
+    name VARCHAR(100) NOT NULL,
+    -- 明文前缀（lc_ 之后 8 位），用于在列表和日志中识别，不足以还原密钥
+    prefix VARCHAR(16) NOT NULL UNIQUE,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    -- 等级：public / internal，默认限额见配置 auth.tiers
+    tier VARCHAR(20) NOT NULL DEFAULT 'public',
+    -- 单个 Key 覆盖等级的限额，NULL 时使用等级默认值
+    rate_per_minute INTEGER CHECK (rate_per_minute > 0),
+    burst INTEGER CHECK (burst > 0),
+    daily_quota INTEGER CHECK (daily_quota >= 0),
+    created_by VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ,
+    revoked_by VARCHAR(100),
+    last_used_at TIMESTAMPTZ
+);
+
+COMMENT ON TABLE api_key IS 'API Key，只保存 SHA-256 摘要';
+
+CREATE TABLE IF NOT EXISTS api_key_quota (
+    key_id INTEGER NOT NULL REFERENCES api_key(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    used INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (key_id, day)
+);
+
+COMMENT ON TABLE api_key_quota IS '高开销接口每日已用次数';
+
+CREATE TABLE IF NOT EXISTS api_key_usage (
+    key_id INTEGER NOT NULL REFERENCES api_key(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    route VARCHAR(100) NOT NULL,
+    requests INTEGER NOT NULL DEFAULT 0,
+    -- 因速率限制或配额被拒绝的请求
+    rejected INTEGER NOT NULL DEFAULT 0,
+    -- 服务端错误（5xx）
+    errors INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (key_id, day, route)
+);
+
+COMMENT ON TABLE api_key_usage IS '按 Key、日期、路由汇总的请求数';
@@ -0,0 +1,57 @@
+-- ============================================================
+-- v2.19 用户账号、常用地点与评价历史
+--
+--   app_user      本地账号，密码以 bcrypt 保存
+--   user_session  登录会话，只保存令牌的 SHA-256 摘要
+--   saved_place   用户保存的地点（"家"、"候选房源 A"）
+--
+-- 登录用户的 /analyze 结果写入 analysis_history 时记录 user_id（及 place_id），
+-- params 保存完整的评价请求，用于按当前数据重新评价
+-- ============================================================
+
+CREATE TABLE IF NOT EXISTS app_user (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(32) NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    display_name VARCHAR(100),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_login_at TIMESTAMPTZ
+);
+
+COMMENT ON TABLE app_user IS '用户账号';
+
+CREATE TABLE IF NOT EXISTS user_session (
+    token_hash CHAR(64) PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES app_user(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    user_agent TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_session_user ON user_session (user_id);
+CREATE INDEX IF NOT EXISTS idx_user_session_expires ON user_session (expires_at);
+
+COMMENT ON TABLE user_session IS '登录会话，只保存令牌摘要';
+
+CREATE TABLE IF NOT EXISTS saved_place (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES app_user(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    geom GEOMETRY(Point, 4326) NOT NULL,
+    note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, name)
+);
+
+COMMENT ON TABLE saved_place IS '用户保存的地点';
+
+ALTER TABLE analysis_history
+    ADD COLUMN IF NOT EXISTS user_id INTEGER REFERENCES app_user(id) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS place_id INTEGER REFERENCES saved_place(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS params JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_analysis_user ON analysis_history (user_id, created_at DESC)
+    WHERE user_id IS NOT NULL;
+
+COMMENT ON COLUMN analysis_history.params IS '完整的评价请求（EvaluationRequest），重新评价时使用';
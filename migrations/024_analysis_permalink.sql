@@ -0,0 +1,22 @@
+-- ============================================================
+-- v2.20 评价结果分享链接
+--
+-- 每条评价记录分配随机的短 ID（share_id，12 位 URL 安全字符），
+-- 通过 /a/{share_id} 与 GET /api/v1/analyses/{share_id} 访问；
+-- 记录的 UUID 只在用户自己的历史中使用，不出现在分享链接里。
+--
+-- 同时记录评价时起点所在城市及其路网版本（network_version，020 迁移），
+-- 查看时与当前版本比较，提示路网已重新导入。
+-- 本迁移之前的记录没有 share_id，不能分享。
+-- ============================================================
+
+ALTER TABLE analysis_history
+    ADD COLUMN IF NOT EXISTS share_id VARCHAR(16),
+    ADD COLUMN IF NOT EXISTS city VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS network_version BIGINT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_analysis_share_id ON analysis_history (share_id)
+    WHERE share_id IS NOT NULL;
+
+COMMENT ON COLUMN analysis_history.share_id IS '分享链接 ID（/a/{share_id}）';
+COMMENT ON COLUMN analysis_history.network_version IS '评价时起点所在城市的路网版本';
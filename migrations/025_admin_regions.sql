@@ -0,0 +1,75 @@
+-- ============================================================
+-- v2.21 行政区划（街道、社区）生活圈评分汇总
+--
+--   admin_region    街道 / 社区边界，由 cmd/regions import 从 GeoJSON 导入
+--   region_sample   区域内的格网样点：样点代表的面积与人口、
+--                   15 分钟步行圈内各子类型 POI 数量（counts），以及按当前标准的评分
+--   region_summary  区域汇总：面积加权平均分、中位数、人口加权平均分、
+--                   各必备设施达标的面积（人口）占比
+--
+-- counts 只依赖路网与 POI，评价标准变化后按 counts 重新评分即可（cmd/regions rescore
+-- 或 POST /api/v1/admin/regions/rescore），无需重新做路网分析；
+-- region_summary.standards_hash 与当前标准不一致、或有样点的 counts 已过时
+-- （按其他步行速度或旧路网版本计算，stale_samples）时接口返回 stale
+-- ============================================================
+
+CREATE TABLE IF NOT EXISTS admin_region (
+    id SERIAL PRIMARY KEY,
+    city VARCHAR(50) NOT NULL,
+    code VARCHAR(50) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    level VARCHAR(20) NOT NULL CHECK (level IN ('subdistrict', 'community')),
+    parent_id INTEGER REFERENCES admin_region(id) ON DELETE SET NULL,
+    geom GEOMETRY(MultiPolygon, 4326) NOT NULL,
+    area_m2 DOUBLE PRECISION NOT NULL,
+    source VARCHAR(100),
+    imported_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (city, code)
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_region_geom ON admin_region USING GIST (geom);
+CREATE INDEX IF NOT EXISTS idx_admin_region_city ON admin_region (city, level);
+
+COMMENT ON TABLE admin_region IS '行政区划边界（街道、社区）';
+
+CREATE TABLE IF NOT EXISTS region_sample (
+    id BIGSERIAL PRIMARY KEY,
+    region_id INTEGER NOT NULL REFERENCES admin_region(id) ON DELETE CASCADE,
+    geom GEOMETRY(Point, 4326) NOT NULL,
+    -- 样点代表的面积（格网单元与区域的交集）与人口，无人口格网时人口为空
+    area_m2 DOUBLE PRECISION NOT NULL,
+    population DOUBLE PRECISION,
+    -- 15 分钟步行圈内各子类型 POI 数量 {"medical/clinic": 2}，未计算时为空
+    counts JSONB,
+    walk_speed DOUBLE PRECISION,
+    network_version BIGINT,
+    measured_at TIMESTAMP,
+    -- 按当前评价标准的评分
+    total_score DOUBLE PRECISION,
+    category_scores JSONB,
+    missing_required TEXT[]
+);
+
+CREATE INDEX IF NOT EXISTS idx_region_sample_region ON region_sample (region_id);
+
+COMMENT ON TABLE region_sample IS '区域格网样点及其 15 分钟圈 POI 数量与评分';
+
+CREATE TABLE IF NOT EXISTS region_summary (
+    region_id INTEGER PRIMARY KEY REFERENCES admin_region(id) ON DELETE CASCADE,
+    sample_count INTEGER NOT NULL,
+    sampled_area_m2 DOUBLE PRECISION NOT NULL,
+    mean_score DOUBLE PRECISION NOT NULL,
+    median_score DOUBLE PRECISION NOT NULL,
+    weighted_mean_score DOUBLE PRECISION,
+    population DOUBLE PRECISION,
+    grade VARCHAR(2) NOT NULL,
+    category_means JSONB NOT NULL,
+    required JSONB NOT NULL,
+    standards_hash CHAR(64) NOT NULL,
+    stale_samples INTEGER NOT NULL DEFAULT 0,
+    computed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE region_summary IS '区域生活圈评分汇总';
+COMMENT ON COLUMN region_summary.standards_hash IS '计算时评价标准与分类权重的摘要，标准变化后需重新评分';
+COMMENT ON COLUMN region_summary.stale_samples IS 'POI 数量按其他步行速度或旧路网版本计算、未计入汇总的样点数，需重新计算';
@@ -0,0 +1,9 @@
+// Package migrations 内嵌数据库迁移脚本，由 internal/migrate 按文件名顺序执行
+package migrations
+
+import "embed"
+
+// FS 全部迁移脚本（*.sql）
+//
+//go:embed *.sql
+var FS embed.FS
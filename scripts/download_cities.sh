@@ -20,6 +20,13 @@ declare -A CITIES=(
     ["shenyang"]="辽宁省沈阳市"
 )
 
+# 显示名称
+declare -A CITY_NAMES=(
+    ["hangzhou"]="杭州"
+    ["zhuji"]="诸暨"
+    ["shenyang"]="沈阳"
+)
+
 # 边界框 (west,south,east,north)
 declare -A CITY_BOUNDS=(
     ["hangzhou"]="119.9,30.1,120.5,30.5"
@@ -45,6 +52,12 @@ download_city() {
     curl -s "https://overpass-api.de/api/map?bbox=$bounds" -o "$output"
     
     if [ -s "$output" ]; then
+        # 写入城市元数据，供导入脚本登记城市
+        cat > "$DATA_DIR/${city}.meta" << EOF
+name=${CITY_NAMES[$city]}
+description=${CITIES[$city]}
+bounds=${bounds}
+EOF
         echo "   ✅ 下载完成: $output ($(du -h "$output" | cut -f1))"
     else
         echo "   ❌ 下载失败"
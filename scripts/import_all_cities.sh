@@ -15,6 +15,26 @@ DB_USER="${DB_USER:-postgres}"
 DB_HOST="${DB_HOST:-localhost}"
 DB_PORT="${DB_PORT:-5432}"
 
+# 城市列表来自数据目录：每个 <城市代码>.osm 为一个城市，
+# 可选的 <城市代码>.meta（由 download_cities.sh 生成）提供名称、描述和边界框：
+#   name=杭州
+#   description=浙江省杭州市
+#   bounds=119.9,30.1,120.5,30.5
+CITY_LIST=()
+for osm_file in "$DATA_DIR"/*.osm; do
+    [ -f "$osm_file" ] && CITY_LIST+=("$(basename "$osm_file" .osm)")
+done
+
+# 读取城市元数据字段
+city_meta() {
+    local city=$1
+    local key=$2
+    local meta_file="$DATA_DIR/${city}.meta"
+    if [ -f "$meta_file" ]; then
+        grep "^${key}=" "$meta_file" | head -1 | cut -d= -f2-
+    fi
+}
+
 echo "================================================"
 echo "15分钟生活圈 - 多城市数据导入"
 echo "================================================"
@@ -36,9 +56,13 @@ check_city_data() {
 
 echo "检查城市数据文件..."
 echo "---"
-check_city_data "hangzhou" || true
-check_city_data "zhuji" || true
-check_city_data "shenyang" || true
+if [ ${#CITY_LIST[@]} -eq 0 ]; then
+    echo "❌ ${DATA_DIR} 中没有 .osm 数据文件，请先运行 ./scripts/download_cities.sh"
+    exit 1
+fi
+for city in "${CITY_LIST[@]}"; do
+    check_city_data "$city" || true
+done
 echo ""
 
 # 创建步行网络配置
@@ -99,6 +123,7 @@ import_city() {
             -H "$DB_HOST" \
             -P "$DB_PORT" \
             --slim \
+            --hstore-all \
             -C 2000 \
             -a \
             "$osm_file" 2>/dev/null || true
@@ -114,9 +139,11 @@ echo ""
 create_pedestrian_config
 
 # 按顺序导入
-import_city "hangzhou" "first"
-import_city "zhuji"
-import_city "shenyang"
+first="first"
+for city in "${CITY_LIST[@]}"; do
+    import_city "$city" "$first"
+    first=""
+done
 
 echo ""
 echo "更新索引和统计..."
@@ -136,10 +163,34 @@ CREATE INDEX IF NOT EXISTS idx_ways_vertices_geom ON ways_vertices_pgr USING GIS
 -- 更新统计信息
 ANALYZE ways;
 ANALYZE ways_vertices_pgr;
+
+-- 首个城市以 --clean 重建路网，全部节点重新编号，预计算的节点可达性一并清空
+TRUNCATE node_reach;
 EOF
 
-# 提取 POI
-PGPASSWORD=$DB_PASSWORD psql -h "$DB_HOST" -p "$DB_PORT" -U "$DB_USER" -d "$DB_NAME" -f "${SCRIPT_DIR}/../migrations/003_import_osm_poi.sql"
+# 按 poi_rule 中的分类规则提取 POI（与 cmd/importer 读取 PBF 使用同一规则集）
+(cd "${SCRIPT_DIR}/.." && DB_NAME=$DB_NAME DB_USER=$DB_USER DB_HOST=$DB_HOST DB_PORT=$DB_PORT DB_PASSWORD=$DB_PASSWORD \
+    go run ./cmd/importer -osm2pgsql)
+
+# 登记城市（刷新覆盖范围、中心点与数据统计，前端城市列表由此读取），
+# 并递增路网版本（network_version），使按旧路网计算的可达性失效
+echo "登记城市..."
+for city in "${CITY_LIST[@]}"; do
+    name="$(city_meta "$city" name)"
+    description="$(city_meta "$city" description)"
+    bounds="$(city_meta "$city" bounds)"
+    if [ -n "$bounds" ]; then
+        IFS=',' read -r west south east north <<< "$bounds"
+        bounds_args="$west, $south, $east, $north"
+    else
+        bounds_args="NULL, NULL, NULL, NULL"
+    fi
+    PGPASSWORD=$DB_PASSWORD psql -h "$DB_HOST" -p "$DB_PORT" -U "$DB_USER" -d "$DB_NAME" -q -c \
+        "SELECT register_city('$city', NULLIF('$name', ''), NULLIF('$description', ''), $bounds_args);"
+    PGPASSWORD=$DB_PASSWORD psql -h "$DB_HOST" -p "$DB_PORT" -U "$DB_USER" -d "$DB_NAME" -q -c \
+        "SELECT bump_network_version('$city');"
+    echo "  ✅ ${city}"
+done
 
 echo ""
 echo "================================================"
@@ -8,16 +8,19 @@ set -e
 
 # 检查参数
 if [ -z "$1" ]; then
-    echo "用法: $0 <osm_file.osm.pbf>"
+    echo "用法: $0 <osm_file.osm.pbf> [城市代码] [城市名称]"
     echo ""
     echo "示例:"
-    echo "  $0 ./data/beijing.osm.pbf"
+    echo "  $0 ./data/beijing.osm.pbf beijing 北京"
     echo ""
     echo "提示: 可以从 https://download.geofabrik.de/ 下载 OSM 数据"
     exit 1
 fi
 
 OSM_FILE="$1"
+# 城市代码（用于登记覆盖范围），默认取文件名
+CITY="${2:-$(basename "$OSM_FILE" | cut -d. -f1)}"
+CITY_NAME="${3:-$CITY}"
 
 # 检查文件存在
 if [ ! -f "$OSM_FILE" ]; then
@@ -126,17 +129,22 @@ if command -v osm2pgsql &> /dev/null; then
         -H "$DB_HOST" \
         -P "$DB_PORT" \
         --slim \
+        --hstore-all \
         -C 2000 \
         "$OSM_FILE"
     
-    # 提取 POI
+    # 按 poi_rule 中的分类规则提取 POI
     SCRIPT_DIR="$(cd "$(dirname "$0")" && pwd)"
-    sudo -u postgres psql -d "$DB_NAME" -f "${SCRIPT_DIR}/../migrations/003_import_osm_poi.sql"
+    (cd "${SCRIPT_DIR}/.." && DB_NAME=${DB_NAME} DB_USER=${DB_USER} DB_HOST=${DB_HOST} DB_PORT=${DB_PORT} \
+        go run ./cmd/importer -osm2pgsql -city "$CITY")
 else
     echo "警告: osm2pgsql 未安装，跳过 POI 导入"
-    echo "请手动安装 osm2pgsql 并运行 POI 导入脚本"
+    echo "请手动安装 osm2pgsql 后运行 go run ./cmd/importer -osm2pgsql，或直接使用 cmd/importer 导入 PBF"
 fi
 
+# 登记城市（边界取整个路网范围，同时刷新覆盖范围与统计）
+sudo -u postgres psql -d "$DB_NAME" -c "SELECT register_city('$CITY', '$CITY_NAME');"
+
 echo ""
 echo "================================================"
 echo "✅ OSM 数据导入完成！"
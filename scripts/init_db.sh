@@ -28,15 +28,10 @@ sudo -u postgres psql -d ${DB_NAME} -c "CREATE EXTENSION IF NOT EXISTS hstore;"
 
 # 运行迁移脚本
 echo "[3/4] 运行数据库迁移..."
+# 迁移脚本内嵌在服务端程序中，执行记录保存在 schema_migrations
 SCRIPT_DIR="$(cd "$(dirname "$0")" && pwd)"
-MIGRATIONS_DIR="${SCRIPT_DIR}/../migrations"
-
-for sql_file in ${MIGRATIONS_DIR}/*.sql; do
-    if [ -f "$sql_file" ]; then
-        echo "  - 执行: $(basename $sql_file)"
-        sudo -u postgres psql -d ${DB_NAME} -f "$sql_file"
-    fi
-done
+(cd "${SCRIPT_DIR}/.." && DB_NAME=${DB_NAME} DB_USER=${DB_USER} DB_HOST=${DB_HOST} DB_PORT=${DB_PORT} \
+    go run ./cmd/server migrate up)
 
 # 验证安装
 echo "[4/4] 验证安装..."
@@ -57,6 +52,6 @@ echo "================================================"
 echo ""
 echo "下一步："
 echo "1. 下载 OSM 数据并使用 osm2pgrouting 导入路网"
-echo "2. 使用 osm2pgsql 导入 POI 数据"
-echo "3. 运行 migrations/003_import_osm_poi.sql 提取 POI"
+echo "2. 使用 osm2pgsql --hstore-all 导入 OSM 数据"
+echo "3. 运行 go run ./cmd/importer -osm2pgsql 按分类规则提取 POI"
 echo ""
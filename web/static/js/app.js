@@ -6,28 +6,17 @@
 // 城市配置
 // ============================================
 
-const CITIES = {
-    hangzhou: {
-        name: '杭州',
-        center: [30.2741, 120.1551],
-        zoom: 14,
-        bounds: [[30.1, 119.9], [30.5, 120.5]],  // [[南, 西], [北, 东]]
-        description: '浙江省杭州市'
-    },
-    zhuji: {
-        name: '诸暨',
-        center: [29.85, 120.08],
-        zoom: 14,
-        bounds: [[29.6, 120.0], [29.9, 120.4]],
-        description: '浙江省诸暨市'
-    },
-    shenyang: {
-        name: '沈阳',
-        center: [41.80, 123.43],
-        zoom: 13,
-        bounds: [[41.65, 123.2], [41.95, 123.6]],
-        description: '辽宁省沈阳市'
-    }
+// 城市列表由后端 /api/v1/cities 提供（导入数据时自动登记）
+// 结构: { code: { name, center: [lat, lng], zoom, bounds: [[南, 西], [北, 东]], description, ... } }
+let CITIES = {};
+
+// 城市列表加载失败时使用的默认视图
+const FALLBACK_CITY = {
+    name: '杭州',
+    center: [30.2741, 120.1551],
+    zoom: 14,
+    bounds: [[30.1, 119.9], [30.5, 120.5]],
+    description: '浙江省杭州市'
 };
 
 // ============================================
@@ -149,15 +138,23 @@ const state = {
 // 初始化
 // ============================================
 
-document.addEventListener('DOMContentLoaded', () => {
+document.addEventListener('DOMContentLoaded', async () => {
     // 检测移动端
     state.isMobile = window.innerWidth <= 768;
     
+    await loadCities();
+    
     initMap();
     initEventListeners();
     initRadarChart();
     initCitySelector();
     initMobileControls();
+    
+    // 分享链接 /a/{id}：加载保存的评价结果
+    const shared = window.location.pathname.match(/^\/a\/([A-Za-z0-9_-]+)$/);
+    if (shared) {
+        await loadSharedAnalysis(shared[1]);
+    }
 });
 
 /**
@@ -293,6 +290,12 @@ function initEventListeners() {
         }
     });
     
+    // 分享链接
+    const shareBtn = document.getElementById('share-link-btn');
+    if (shareBtn) {
+        shareBtn.addEventListener('click', handleShareLink);
+    }
+    
     // 搜索功能
     const searchInput = document.getElementById('search-input');
     const searchBtn = document.getElementById('search-btn');
@@ -902,6 +905,11 @@ async function analyzePoint(lng, lat) {
         // 渲染等时圈
         renderIsochrone(result.isochrone);
         
+        // 起点不在路网覆盖范围内时提示（等时圈为直线缓冲区估算）
+        if (result.fallback === 'buffer') {
+            showToast('该位置不在已导入路网范围内，等时圈为直线距离估算', 'warning');
+        }
+        
         updateLastProgress('completed');
         addProgressItem(`渲染 ${state.currentPOIs.length} 个设施点...`);
         await delay(30);
@@ -916,6 +924,11 @@ async function analyzePoint(lng, lat) {
         // 渲染评估结果
         renderEvaluationResult(result);
         
+        // 地址栏更新为本次结果的分享链接
+        if (result.permalink) {
+            history.replaceState(null, '', result.permalink);
+        }
+        
         updateLastProgress('completed');
         addProgressItem('分析完成！', 'completed');
         
@@ -1232,8 +1245,108 @@ function renderEvaluationResult(result) {
     // 渲染雷达图
     renderRadarChart(result.category_scores || []);
     
+    // 最近必备设施
+    renderNearestRequired(result.nearest_required || []);
+    
     // 建议
     renderSuggestions(result.suggestions || []);
+    
+    // 分享链接（结果保存失败时没有）
+    document.getElementById('share-link-btn').style.display = result.permalink ? 'block' : 'none';
+}
+
+/**
+ * 加载分享链接对应的评价结果
+ */
+async function loadSharedAnalysis(id) {
+    try {
+        const response = await fetch(`${CONFIG.apiBase}/analyses/${encodeURIComponent(id)}`);
+        if (response.status === 404) {
+            showError('分享链接不存在或已被删除');
+            history.replaceState(null, '', '/');
+            return;
+        }
+        if (!response.ok) {
+            throw new Error(`HTTP error! status: ${response.status}`);
+        }
+        
+        const shared = await response.json();
+        const result = shared.result;
+        result.share_id = shared.id;
+        result.permalink = shared.permalink;
+        
+        // 切换到结果所在城市
+        const city = shared.data_version.city;
+        if (city && CITIES[city] && city !== CONFIG.currentCity) {
+            switchCity(city);
+            const selector = document.getElementById('city-selector');
+            if (selector) selector.value = city;
+        }
+        
+        const { lng, lat } = shared.params;
+        state.selectedLocation = { lat, lng };
+        updateLocationDisplay(lat, lng);
+        updateMarker(lat, lng);
+        state.map.setView([lat, lng], 15);
+        
+        state.currentPOIsGeoJSON = result.pois;
+        state.currentPOIs = result.pois && result.pois.features ? result.pois.features : [];
+        if (result.roads) {
+            renderRoads(result.roads);
+        }
+        renderIsochrone(result.isochrone);
+        renderPOIs(result.pois);
+        renderEvaluationResult(result);
+        // 切换城市时 clearAnalysis 会重置地址栏
+        history.replaceState(null, '', shared.permalink);
+        
+        const created = new Date(shared.created_at).toLocaleString('zh-CN');
+        if (shared.data_version.stale) {
+            showToast(`该结果生成于 ${created}，此后路网或设施数据已更新，点击地图可重新分析`, 'warning');
+        } else {
+            showToast(`分享的分析结果（${created}，步行 ${shared.params.walk_speed} km/h）`, 'info');
+        }
+    } catch (error) {
+        console.error('Load shared analysis failed:', error);
+        showError('加载分享结果失败: ' + (error.message || '请重试'));
+    }
+}
+
+/**
+ * 复制当前结果的分享链接
+ */
+async function handleShareLink() {
+    const permalink = state.currentResult && state.currentResult.permalink;
+    if (!permalink) return;
+    
+    const url = window.location.origin + permalink;
+    try {
+        await navigator.clipboard.writeText(url);
+        showToast('分享链接已复制', 'success');
+    } catch (error) {
+        // 非 HTTPS 等环境下剪贴板不可用
+        window.prompt('复制分享链接', url);
+    }
+}
+
+/**
+ * 渲染必备设施的最近步行时间
+ */
+function renderNearestRequired(nearest) {
+    const container = document.getElementById('nearest-required');
+    const list = document.getElementById('nearest-required-list');
+    if (!nearest.length) {
+        container.style.display = 'none';
+        return;
+    }
+    container.style.display = 'block';
+    list.innerHTML = nearest.map(n => {
+        const label = getSubTypeName(n.sub_type);
+        if (!n.found) {
+            return `<li><span>${label}</span><span class="missing">30分钟内无</span></li>`;
+        }
+        return `<li><span>${label}${n.name ? ' · ' + n.name : ''}</span><span>${n.walk_time_min.toFixed(1)} 分钟</span></li>`;
+    }).join('');
 }
 
 /**
@@ -1650,6 +1763,46 @@ function getRadarShortName(name) {
 // 城市选择器
 // ============================================
 
+/**
+ * 从后端加载城市列表
+ */
+async function loadCities() {
+    try {
+        const response = await fetch(`${CONFIG.apiBase}/cities`);
+        if (!response.ok) {
+            throw new Error(`HTTP error! status: ${response.status}`);
+        }
+        const data = await response.json();
+        
+        CITIES = {};
+        (data.cities || []).forEach(city => {
+            // 后端坐标为 [lng, lat]，Leaflet 使用 [lat, lng]
+            CITIES[city.code] = {
+                name: city.name,
+                center: [city.center[1], city.center[0]],
+                zoom: city.zoom,
+                bounds: [
+                    [city.bounds[0][1], city.bounds[0][0]],
+                    [city.bounds[1][1], city.bounds[1][0]]
+                ],
+                description: city.description,
+                dataImportedAt: city.data_imported_at,
+                poiCounts: city.poi_counts || {},
+                network: city.network
+            };
+        });
+    } catch (error) {
+        console.error('Failed to load cities:', error);
+    }
+    
+    if (Object.keys(CITIES).length === 0) {
+        CITIES = { default: FALLBACK_CITY };
+    }
+    if (!CITIES[CONFIG.currentCity]) {
+        CONFIG.currentCity = Object.keys(CITIES)[0];
+    }
+}
+
 /**
  * 初始化城市选择器
  */
@@ -1723,7 +1876,12 @@ function updateCityInfo() {
     const city = CITIES[CONFIG.currentCity];
     const infoEl = document.getElementById('city-info');
     if (infoEl) {
-        infoEl.textContent = city.description;
+        let info = city.description;
+        if (city.dataImportedAt) {
+            const poiTotal = Object.values(city.poiCounts || {}).reduce((a, b) => a + b, 0);
+            info += ` · ${poiTotal} 个设施 · 数据更新于 ${city.dataImportedAt.slice(0, 10)}`;
+        }
+        infoEl.textContent = info;
     }
 }
 
@@ -1748,5 +1906,8 @@ function clearAnalysis() {
     
     // 隐藏结果面板
     document.getElementById('result-panel').style.display = 'none';
+    if (window.location.pathname !== '/') {
+        history.replaceState(null, '', '/');
+    }
     document.getElementById('current-location').innerHTML = '<p class="placeholder">请在地图上点击选择位置</p>';
 }